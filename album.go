@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+	"sptsong/internal/webapi"
+)
+
+// albumResult is a track's place in its album, looked up in the background.
+type albumResult struct {
+	trackID string
+	album   webapi.AlbumPosition
+}
+
+// updateAlbum starts looking up where a newly settled Spotify track sits in
+// its album, for the album line under the artist. It needs the Web API, so
+// it does nothing without a client id; lookups are cached per track.
+func (sd *SpotifyDisplay) updateAlbum(trackID string) {
+	if trackID == sd.albumTrack {
+		return
+	}
+	sd.albumTrack, sd.album = trackID, nil
+	uri := artwork.SpotifyURI(trackID)
+	if !sd.Progress.Album || sd.Spotify.ClientID == "" || !strings.HasPrefix(uri, "spotify:track:") {
+		return
+	}
+	if info, ok := sd.tracks.Get(trackID); ok && info.Album != nil {
+		sd.album = info.Album
+		return
+	}
+
+	client := newWebAPI(sd.Config)
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		album, err := client.AlbumPosition(ctx, strings.TrimPrefix(uri, "spotify:track:"))
+		if err != nil {
+			return
+		}
+		select {
+		case sd.albumReady <- albumResult{trackID, album}:
+		default:
+		}
+	}()
+}
+
+// setAlbum takes a finished lookup into use if its track is still playing.
+func (sd *SpotifyDisplay) setAlbum(result albumResult) {
+	sd.tracks.Update(result.trackID, fieldAlbum, func(info *TrackInfo) { info.Album = &result.album })
+	if result.trackID == sd.albumTrack {
+		sd.album = &result.album
+	}
+}
+
+// drawAlbumLine shows "track 5 of 12" and a thin bar for the whole album on
+// the row under the artist.
+func (sd *SpotifyDisplay) drawAlbumLine(metadata *mpris.Metadata, text ui.Rect) {
+	theme := sd.theme()
+	label := fmt.Sprintf("track %d of %d ", sd.album.Track, sd.album.Tracks)
+	width := text.Width - len(label)
+	if width < 4 {
+		return
+	}
+	fraction := sd.album.Fraction(time.Duration(metadata.Position) * time.Second)
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+3)+theme.Time.Render(label)+ui.Bar(theme, false, false, fraction, width))
+}
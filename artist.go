@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sptsong/internal/artistinfo"
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// artistRows is the height of the artist panel: the name, the genres and
+// two lines of biography.
+const artistRows = 4
+
+// artistResult is an artist's details, looked up in the background.
+type artistResult struct {
+	name string
+	info artistinfo.Info
+	err  error
+}
+
+// toggleArtist shows or hides the artist panel, looking the current artist
+// up when it opens.
+func (sd *SpotifyDisplay) toggleArtist() {
+	sd.showArtist = !sd.showArtist
+	sd.updateArtist(&sd.current)
+}
+
+// updateArtist starts looking up the artist of a newly settled track while
+// the panel is open: on Spotify through the Web API when there's a client
+// id, otherwise on MusicBrainz and Wikipedia. Lookups are cached on disk per
+// artist, next to the per-track details.
+func (sd *SpotifyDisplay) updateArtist(m *mpris.Metadata) {
+	if !sd.showArtist || m.Artist == sd.artistName {
+		return
+	}
+	sd.artistName, sd.artist, sd.artistErr = m.Artist, nil, nil
+	if m.Artist == "" {
+		return
+	}
+	if info, ok := sd.tracks.Get("artist:" + m.Artist); ok && info.Artist != nil {
+		sd.artist = info.Artist
+		return
+	}
+
+	lookup := sd.artistLookup(m)
+	go func() {
+		defer guard.Recover()
+		result := lookup()
+		select {
+		case sd.artistDone <- result:
+		default:
+		}
+	}()
+}
+
+// artistLookup returns a function that looks up m's artist, to run in the
+// background.
+func (sd *SpotifyDisplay) artistLookup(m *mpris.Metadata) func() artistResult {
+	name, uri := m.Artist, artwork.SpotifyURI(m.TrackID)
+	var spotify func(context.Context) (artistinfo.Info, error)
+	if sd.Spotify.ClientID != "" && strings.HasPrefix(uri, "spotify:track:") {
+		client := newWebAPI(sd.Config)
+		spotify = func(ctx context.Context) (artistinfo.Info, error) {
+			a, err := client.TrackArtist(ctx, strings.TrimPrefix(uri, "spotify:track:"))
+			return artistinfo.Info{Name: a.Name, Genres: a.Genres, Popularity: a.Popularity, Followers: a.Followers.Total, Source: "Spotify"}, err
+		}
+	}
+	keyless := artistinfo.New(artwork.HTTPClient)
+	return func() artistResult {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		var info artistinfo.Info
+		err := artistinfo.ErrNotFound
+		if spotify != nil {
+			if info, err = spotify(ctx); err == nil {
+				info.Bio, _ = keyless.Bio(ctx, info.Name)
+			}
+		}
+		if err != nil {
+			info, err = keyless.Lookup(ctx, name)
+		}
+		return artistResult{name, info, err}
+	}
+}
+
+// setArtist takes a finished lookup into use if its artist is still
+// playing. Only successful lookups are cached.
+func (sd *SpotifyDisplay) setArtist(result artistResult) {
+	sd.cacheArtist(result)
+	if result.name != sd.artistName {
+		return
+	}
+	sd.artist, sd.artistErr = &result.info, result.err
+	if result.err != nil {
+		sd.artist = nil
+	}
+}
+
+// cacheArtist keeps a successful lookup for the artist's next track.
+func (sd *SpotifyDisplay) cacheArtist(result artistResult) {
+	if result.err == nil {
+		sd.tracks.Update("artist:"+result.name, fieldArtist, func(info *TrackInfo) { info.Artist = &result.info })
+	}
+}
+
+// drawArtist shows the current artist's genres, following and biography in
+// the rows from top, next to the now-playing frame.
+func (sd *SpotifyDisplay) drawArtist(term TerminalSize, top int) {
+	frame := term.frame
+	theme := sd.theme()
+	line := func(row int, text string, style ui.Style) {
+		fmt.Fprint(sd.out, ui.MoveTo(frame.X, top+row)+strings.Repeat(" ", frame.Width)+ui.MoveTo(frame.X, top+row)+style.Render(ui.Truncate(text, frame.Width)))
+	}
+
+	a := sd.artist
+	if a == nil {
+		status := "looking up the artist…"
+		if sd.artistErr != nil || sd.artistName == "" {
+			status = "nothing found about this artist"
+		}
+		line(0, sd.artistName, theme.Accent)
+		line(1, status, theme.Time)
+		line(2, "", theme.Time)
+		line(3, "", theme.Time)
+		return
+	}
+
+	header := a.Name
+	if a.Followers > 0 {
+		header += " · " + followers(a.Followers) + " followers"
+	}
+	if a.Popularity > 0 {
+		header += " · popularity " + strconv.Itoa(a.Popularity)
+	}
+	line(0, header, theme.Accent)
+	genres := strings.Join(a.Genres, ", ")
+	if genres == "" {
+		genres = "no genres listed"
+	}
+	line(1, genres+" ("+a.Source+")", theme.Artist)
+	bio := wrapWords(a.Bio, frame.Width, artistRows-2)
+	for row := range artistRows - 2 {
+		text := ""
+		if row < len(bio) {
+			text = bio[row]
+		}
+		line(row+2, text, theme.Time)
+	}
+}
+
+// followers shortens a follower count the way Spotify shows it, as 1.2M or
+// 45K.
+func followers(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return strconv.FormatFloat(float64(n)/1_000_000, 'f', 1, 64) + "M"
+	case n >= 10_000:
+		return strconv.Itoa(n/1000) + "K"
+	}
+	return strconv.Itoa(n)
+}
+
+// wrapWords breaks text into at most lines lines of width columns, marking
+// with an ellipsis that it goes on.
+func wrapWords(text string, width, lines int) []string {
+	var wrapped []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case current == "":
+			current = word
+		case ui.Width(current)+1+ui.Width(word) <= width:
+			current += " " + word
+		default:
+			wrapped = append(wrapped, current)
+			current = word
+		}
+		if len(wrapped) == lines {
+			wrapped[lines-1] = ui.Clip(wrapped[lines-1], width-1) + "…"
+			return wrapped
+		}
+	}
+	if current != "" {
+		wrapped = append(wrapped, current)
+	}
+	return wrapped
+}
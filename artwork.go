@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// artJob describes a cover to fetch and where to draw it. A zero width means
+// the current view has no room for artwork and only the accent is wanted.
+type artJob struct {
+	url, trackID        string
+	artist, album       string
+	x, y, width, height int
+	generation          int
+
+	// The settings the job runs with, copied on the run loop so that a
+	// config reload doesn't change them under the goroutine.
+	covers         artwork.Cache
+	render         artwork.Options
+	lookup, accent bool
+}
+
+// newArtJob describes the cover of metadata's track under the current
+// settings, with no room to draw it.
+func (sd *SpotifyDisplay) newArtJob(metadata *mpris.Metadata, fontRatio float64) artJob {
+	render := artwork.Options{
+		FontRatio: fontRatio,
+		Symbols:   sd.Art.Symbols,
+		Dither:    sd.Art.Dither,
+		Work:      sd.Art.Work,
+		Colors:    sd.artColors(),
+	}
+	if sd.ASCII {
+		render.Symbols = "ascii"
+	}
+	if sd.noColor() {
+		render.Colors = "none"
+	}
+	return artJob{
+		url:     metadata.ArtURL,
+		trackID: metadata.TrackID,
+		artist:  metadata.Artist,
+		album:   metadata.Album,
+		covers:  sd.covers,
+		render:  render,
+		lookup:  sd.ArtLookup,
+		accent:  sd.ArtAccent,
+	}
+}
+
+// errDownload marks a cover that couldn't be downloaded, as opposed to one
+// chafa couldn't draw.
+var errDownload = errors.New("cover download failed")
+
+type artResult struct {
+	generation int
+	lines      []string
+	x, y       int
+	accent     string
+	imagePath  string
+	err        error
+}
+
+// startArtwork fetches and renders a cover in the background, cancelling
+// whatever cover was still in flight. The result is delivered on sd.artReady
+// so only the run loop ever writes to the terminal.
+func (sd *SpotifyDisplay) startArtwork(job artJob) {
+	if sd.cancelArt != nil {
+		sd.cancelArt()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sd.cancelArt = cancel
+	sd.artGeneration++
+	sd.artRetry = time.Time{}
+	job.generation = sd.artGeneration
+
+	go func() {
+		defer guard.Recover()
+		result, err := sd.loadArtwork(ctx, job)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("no cover", "track", job.trackID, "url", job.url, "err", err)
+			// A track without a cover and a missing chafa, which the
+			// health banner reports, aren't worth a toast.
+			if errors.Is(err, artwork.ErrNoCover) || errors.Is(err, exec.ErrNotFound) {
+				return
+			}
+			result.err = err
+		}
+		select {
+		case sd.artReady <- result:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+func (sd *SpotifyDisplay) loadArtwork(ctx context.Context, job artJob) (artResult, error) {
+	result := artResult{generation: job.generation, x: job.x, y: job.y}
+
+	imagePath, err := sd.artworkPath(ctx, job)
+	if errors.Is(err, artwork.ErrBudgetExceeded) && job.width > 0 {
+		result.lines = artPlaceholder(job.width, job.height, "art budget used up today")
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", errDownload, err)
+	}
+	result.imagePath = imagePath
+	if job.width > 0 {
+		options := job.render
+		options.Width, options.Height = job.width, job.height
+		if result.lines, err = sd.renders.Render(ctx, imagePath, options); err != nil {
+			return result, fmt.Errorf("chafa failed: %w", err)
+		}
+	}
+	if job.accent {
+		result.accent = sd.trackAccent(job.trackID, imagePath)
+	}
+	return result, nil
+}
+
+// artworkPath returns a local file holding the track's cover: the one found
+// when the track last played, or else from the player's art URL, then the
+// CDN copy of a sandboxed client's cover, then an album lookup.
+func (sd *SpotifyDisplay) artworkPath(ctx context.Context, job artJob) (string, error) {
+	if info, ok := sd.tracks.Get(job.trackID); ok && info.ArtPath != "" && job.covers.Reuse(info.ArtPath) {
+		return info.ArtPath, nil
+	}
+	imagePath, err := job.covers.Download(ctx, job.url)
+	if errors.Is(err, artwork.ErrBudgetExceeded) {
+		return "", err
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		// A sandboxed client's cover that isn't visible from here; use the
+		// CDN copy instead.
+		var cdnURL string
+		if cdnURL, err = artwork.OEmbedURL(ctx, job.trackID); err == nil {
+			imagePath, err = job.covers.Download(ctx, cdnURL)
+		}
+	}
+	if err != nil && job.lookup && ctx.Err() == nil && !errors.Is(err, artwork.ErrBudgetExceeded) {
+		// No usable cover from the player; look the album up instead.
+		var lookupURL string
+		if lookupURL, err = sd.lookupArtURL(ctx, job.trackID, job.artist, job.album); err == nil {
+			imagePath, err = job.covers.Download(ctx, lookupURL)
+		}
+	}
+	if err == nil {
+		sd.tracks.Update(job.trackID, fieldArtPath, func(info *TrackInfo) { info.ArtPath = imagePath })
+	}
+	return imagePath, err
+}
+
+// artPlaceholder fills the art area with a faint pattern and a centered
+// note, standing in for a cover that wasn't downloaded.
+func artPlaceholder(width, height int, note string) []string {
+	style := ui.Style{Faint: true}
+	fill := strings.Repeat("░", width)
+	lines := make([]string, height)
+	for i := range lines {
+		lines[i] = style.Render(fill)
+	}
+
+	text := ui.Clip(note, width-2)
+	if text != "" {
+		left := (width - ui.Width(text)) / 2
+		right := width - left - ui.Width(text)
+		lines[height/2] = style.Render(strings.Repeat("░", left)) + text + style.Render(strings.Repeat("░", right))
+	}
+	return lines
+}
+
+// drawImage writes rendered artwork rows starting at (startX, startY). Each
+// row is placed explicitly; chafa's own newlines would return to the first
+// column.
+func (sd *SpotifyDisplay) drawImage(lines []string, startX, startY int) {
+	fmt.Fprint(sd.out, "\0337")
+	for i, line := range lines {
+		fmt.Fprint(sd.out, ui.MoveTo(startX, startY+i)+line)
+	}
+	fmt.Fprint(sd.out, "\0338")
+}
+
+// trackAccent returns the art-derived accent for a track, computing and
+// caching it on first play.
+func (sd *SpotifyDisplay) trackAccent(trackID, imagePath string) string {
+	info, ok := sd.tracks.Get(trackID)
+	if ok && info.Accent != "" {
+		return info.Accent
+	}
+
+	accent, err := artwork.DominantColor(imagePath)
+	if err != nil {
+		return ""
+	}
+	sd.tracks.Update(trackID, fieldAccent, func(info *TrackInfo) { info.Accent = accent })
+	return accent
+}
+
+// lookupArtURL looks up the album's cover for tracks whose player reports no
+// usable art. Results are remembered per track so the lookup happens once.
+func (sd *SpotifyDisplay) lookupArtURL(ctx context.Context, trackID, artist, album string) (string, error) {
+	info, _ := sd.tracks.Get(trackID)
+	if info.LookupArtURL != "" {
+		return info.LookupArtURL, nil
+	}
+	artURL, err := artwork.LookupURL(ctx, artist, album)
+	if err != nil {
+		return "", err
+	}
+	sd.tracks.Update(trackID, fieldLookupArtURL, func(info *TrackInfo) { info.LookupArtURL = artURL })
+	return artURL, nil
+}
+
+// artFailed reports a cover that couldn't be shown. A download that failed
+// for a passing reason is tried again after artwork.RetryDelay; one that
+// failed recently has been reported already.
+func (sd *SpotifyDisplay) artFailed(err error) {
+	if errors.Is(err, artwork.ErrFailedRecently) {
+		return
+	}
+	if errors.Is(err, errDownload) && artwork.Transient(err) {
+		sd.artRetry = sd.clock.Now().Add(artwork.RetryDelay)
+		sd.toast(fmt.Sprintf("%v, retrying in %s", err, artwork.RetryDelay))
+		return
+	}
+	sd.toast(err.Error())
+}
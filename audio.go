@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"sptsong/internal/pulse"
+)
+
+// watchOutput follows the audio output when [audio] asks to pause on a
+// disconnect. It returns nil otherwise; the channel closes if pactl isn't
+// there.
+func (sd *SpotifyDisplay) watchOutput(ctx context.Context) <-chan pulse.Output {
+	if !sd.Audio.PauseOnDisconnect || sd.companion {
+		return nil
+	}
+	return pulse.Watch(ctx)
+}
+
+// outputChanged pauses playback when the output it was going to went away.
+func (sd *SpotifyDisplay) outputChanged(out pulse.Output) {
+	before := sd.output
+	sd.output = out
+	slog.Debug("audio output", "sink", out.Sink, "port", out.Port, "unplugged", out.Unplugged)
+	if !pulse.Disconnected(before, out) || sd.paused {
+		return
+	}
+	if err := sd.player.Call("Pause"); err != nil {
+		sd.showNotice("audio output disconnected; couldn't pause: " + err.Error())
+		return
+	}
+	sd.showNotice("audio output disconnected: paused")
+}
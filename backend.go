@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/godbus/dbus/v5"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpd"
+	"sptsong/internal/mpris"
+	"sptsong/internal/webapi"
+)
+
+// openPlayer connects to the configured backend. The session bus is only
+// needed for MPRIS players, or to export our own player for the others.
+func openPlayer(cfg config.Config) (mpris.Player, *dbus.Conn, error) {
+	switch cfg.Backend {
+	case "mpris":
+		if cfg.ExportMPRIS {
+			// The player is on the bus already; exporting it again would
+			// show a second copy in every media applet.
+			return nil, nil, fmt.Errorf("%w: export_mpris is for the mpd and webapi backends, the MPRIS player is exported already", errConfig)
+		}
+		if !spotifyRunning() {
+			return nil, nil, errSpotifyNotRunning
+		}
+	case "mpd":
+	case "webapi":
+		player := webapi.NewPlayer(newWebAPI(cfg))
+		if _, err := player.Metadata(); errors.Is(err, webapi.ErrNotLoggedIn) || errors.Is(err, webapi.ErrNoClientID) {
+			return nil, nil, apiError(err)
+		}
+	case "applescript", "gsmtc":
+		newPlayer := newAppleScriptPlayer
+		if cfg.Backend == "gsmtc" {
+			newPlayer = newGSMTCPlayer
+		}
+		player, err := newPlayer()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", errConfig, err)
+		}
+		// Spotify on macOS and Windows has no MPRIS, and there is no
+		// session bus to export one on.
+		return player, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown backend %q, want mpris, mpd, webapi, applescript or gsmtc", errConfig, cfg.Backend)
+	}
+
+	var conn *dbus.Conn
+	if cfg.Backend == "mpris" || cfg.ExportMPRIS {
+		var err error
+		if conn, err = mpris.ConnectSessionBus(cfg.DBusAddress); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", errDBusUnavailable, err)
+		}
+	}
+
+	switch cfg.Backend {
+	case "mpd":
+		return mpd.New(cfg.MPD.Host), conn, nil
+	case "webapi":
+		return webapi.NewPlayer(newWebAPI(cfg)), conn, nil
+	}
+	return mpris.NewClient(conn.Object(mpris.SpotifyBusName, mpris.Path)), conn, nil
+}
+
+func spotifyRunning() bool {
+	return exec.Command("pgrep", "spotify").Run() == nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"errors"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/osascript"
+)
+
+func newAppleScriptPlayer() (mpris.Player, error) {
+	return osascript.New(), nil
+}
+
+func newGSMTCPlayer() (mpris.Player, error) {
+	return nil, errors.New("the gsmtc backend is only available on Windows")
+}
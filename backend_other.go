@@ -0,0 +1,17 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"errors"
+
+	"sptsong/internal/mpris"
+)
+
+func newAppleScriptPlayer() (mpris.Player, error) {
+	return nil, errors.New("the applescript backend is only available on macOS")
+}
+
+func newGSMTCPlayer() (mpris.Player, error) {
+	return nil, errors.New("the gsmtc backend is only available on Windows")
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"sptsong/internal/gsmtc"
+	"sptsong/internal/mpris"
+)
+
+func newAppleScriptPlayer() (mpris.Player, error) {
+	return nil, errors.New("the applescript backend is only available on macOS")
+}
+
+// newGSMTCPlayer reads the media controls, keeping the cover apps hand
+// over in the temporary directory.
+func newGSMTCPlayer() (mpris.Player, error) {
+	return gsmtc.New(filepath.Join(os.TempDir(), "sptsong")), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"sptsong/internal/config"
+	"sptsong/internal/ui"
+)
+
+// darkBackground is taken for the terminal's background until it answers
+// the OSC 11 query, and for good if it doesn't.
+const darkBackground = "#000000"
+
+// backgroundReplies carries the terminal's answer to the query from the
+// input reader to the run loop.
+var backgroundReplies = make(chan string, 1)
+
+// configuredBackground is cfg's background, with "auto" taken for dark
+// where there's no terminal to ask.
+func configuredBackground(cfg config.Config) string {
+	if cfg.Background == "auto" {
+		return darkBackground
+	}
+	return cfg.Background
+}
+
+// background is the terminal background text is checked and dimmed against:
+// as configured, or as the terminal reported it.
+func (sd *SpotifyDisplay) background() string {
+	if sd.Background == "auto" && sd.termBg != "" {
+		return sd.termBg
+	}
+	return configuredBackground(sd.Config)
+}
+
+// setBackground takes the reported background into use and, with theme =
+// "auto", switches to the light theme on a light one.
+func (sd *SpotifyDisplay) setBackground(color string) {
+	sd.termBg = color
+	slog.Debug("terminal background", "color", color, "light", ui.IsLight(color))
+	if sd.Theme == "auto" && ui.IsLight(color) {
+		sd.themeIndex = ui.FindTheme(sd.themes, "light")
+	}
+	sd.requestRedraw()
+}
+
+// parseColorReply reads a terminal's answer to a color query, such as
+// "\033]11;rgb:ffff/ffff/dddd\a", as "#rrggbb". Terminals give each
+// component in one to four hex digits.
+func parseColorReply(reply string) (string, bool) {
+	_, spec, ok := strings.Cut(reply, ";rgb:")
+	if !ok {
+		return "", false
+	}
+	spec = strings.TrimRight(spec, "\a\033\\")
+	parts := strings.Split(spec, "/")
+	if len(parts) != 3 {
+		return "", false
+	}
+	color := "#"
+	for _, part := range parts {
+		if len(part) < 1 || len(part) > 4 {
+			return "", false
+		}
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil {
+			return "", false
+		}
+		max := uint64(1)<<(4*len(part)) - 1
+		color += fmt.Sprintf("%02x", v*255/max)
+	}
+	return color, true
+}
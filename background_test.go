@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestParseColorReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  string
+		ok    bool
+	}{
+		{"four digits, BEL", "\033]11;rgb:ffff/ffff/dddd\a", "#ffffdd", true},
+		{"four digits, ST", "\033]11;rgb:1e1e/1e1e/2e2e\033\\", "#1e1e2e", true},
+		{"two digits", "\033]11;rgb:28/2c/34\a", "#282c34", true},
+		{"one digit", "\033]11;rgb:f/0/8\033\\", "#ff0088", true},
+		{"three digits", "\033]11;rgb:fff/000/800\a", "#ff007f", true},
+		{"mixed case", "\033]11;rgb:FFFF/8080/0000\a", "#ff8000", true},
+		{"no terminator", "\033]11;rgb:0000/0000/0000", "#000000", true},
+		{"not rgb", "\033]11;#ffffff\a", "", false},
+		{"two components", "\033]11;rgb:ffff/ffff\a", "", false},
+		{"four components", "\033]11;rgb:ff/ff/ff/ff\a", "", false},
+		{"too many digits", "\033]11;rgb:fffff/ffff/ffff\a", "", false},
+		{"empty component", "\033]11;rgb:ffff//ffff\a", "", false},
+		{"not hex", "\033]11;rgb:gggg/ffff/ffff\a", "", false},
+		{"garbage", "\033[?1;2c", "", false},
+		{"empty, as when the terminal never answers", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseColorReply(tt.reply)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseColorReply(%q) = %q, %v; want %q, %v", tt.reply, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sptsong/internal/mpris"
+)
+
+var errNoFiles = errors.New("local files play through the mpd backend")
+
+// audioExtensions are the files the browser lists besides directories.
+var audioExtensions = []string{".aac", ".aiff", ".alac", ".ape", ".flac", ".m4a", ".mp3", ".mpc", ".ogg", ".opus", ".wav", ".wma", ".wv"}
+
+// Kinds of browser entries, in pickerItem.id.
+const (
+	browseUp   = "up"
+	browseAll  = "all"
+	browseDir  = "dir"
+	browseFile = "file"
+)
+
+// openBrowser lists the music directory for playing local files through
+// MPD, which shares it.
+func (sd *SpotifyDisplay) openBrowser() {
+	switch _, ok := sd.player.(mpris.FilePlayer); {
+	case sd.MPD.MusicDir == "":
+		sd.showNotice("set [mpd] music_dir to browse your music")
+	case !ok || sd.Backend != "mpd":
+		sd.showNotice(errNoFiles.Error())
+	default:
+		sd.browse("")
+	}
+}
+
+// browse opens the browser on dir, relative to the music directory. Enter
+// opens a directory or plays a file, and Tab queues either.
+func (sd *SpotifyDisplay) browse(dir string) {
+	items, err := browseItems(expandHome(sd.MPD.MusicDir), dir)
+	if err != nil {
+		slog.Error("can't list the music directory", "dir", dir, "err", err)
+		sd.showNotice("can't list " + path.Join(sd.MPD.MusicDir, dir) + ": " + err.Error())
+		return
+	}
+	caption := dir
+	if caption == "" {
+		caption = sd.MPD.MusicDir
+	}
+	var none []pickerItem
+	sd.picker = &picker{
+		heading: "music",
+		caption: caption,
+		hint:    "Enter open/play · Tab queue · Esc",
+		items:   items,
+		cache:   &none,
+		pick: func(sd *SpotifyDisplay, item pickerItem) {
+			switch item.id {
+			case browseUp, browseDir:
+				sd.browse(item.uri)
+			default:
+				sd.playFile(item, false)
+			}
+		},
+		alt: func(sd *SpotifyDisplay, item pickerItem) {
+			if item.id != browseUp {
+				sd.playFile(item, true)
+			}
+		},
+	}
+}
+
+// browseItems lists dir under root: the way up, an entry for playing all
+// of it, then its directories and audio files by name.
+func browseItems(root, dir string) ([]pickerItem, error) {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil, err
+	}
+	var items, files []pickerItem
+	if dir != "" {
+		items = append(items, pickerItem{name: "../", uri: path.Dir(dir), id: browseUp})
+		if items[0].uri == "." {
+			items[0].uri = ""
+		}
+	}
+	if dir == "" {
+		// MPD's name for its whole music directory.
+		items = append(items, pickerItem{name: "▶ everything", uri: "/", id: browseAll})
+	} else {
+		items = append(items, pickerItem{name: "▶ all of " + path.Base(dir), uri: dir, id: browseAll})
+	}
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+		case e.IsDir():
+			items = append(items, pickerItem{name: name + "/", uri: path.Join(dir, name), id: browseDir})
+		case slices.Contains(audioExtensions, strings.ToLower(filepath.Ext(name))):
+			files = append(files, pickerItem{name: name, uri: path.Join(dir, name), id: browseFile})
+		}
+	}
+	// Directories first, each kind in ReadDir's name order.
+	return append(items, files...), nil
+}
+
+// playFile plays a file or directory picked in the browser, or queues it.
+func (sd *SpotifyDisplay) playFile(item pickerItem, queue bool) {
+	player, ok := sd.player.(mpris.FilePlayer)
+	if !ok {
+		sd.showNotice(errNoFiles.Error())
+		return
+	}
+	name := path.Base(item.uri)
+	if item.uri == "/" {
+		name = "everything"
+	}
+	var err error
+	if queue {
+		err = player.QueueFile(item.uri)
+		name = "queued " + name
+	} else {
+		err = player.PlayFile(item.uri)
+		name = "playing " + name
+	}
+	if err != nil {
+		slog.Error("can't play a local file", "path", item.uri, "err", err)
+		sd.showNotice(err.Error())
+		return
+	}
+	sd.showNotice(name)
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(p string) string {
+	if rest, ok := strings.CutPrefix(p, "~"); ok && (rest == "" || rest[0] == '/') {
+		home, _ := os.UserHomeDir()
+		return home + rest
+	}
+	return p
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBrowseItems(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"Band/LP/01 Song.flac", "Band/LP/cover.jpg", "Band/LP/02 Other.MP3", "Band/.hidden/x.mp3", "Band/Single.ogg", "Zebra/a.opus"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		dir  string
+		want []pickerItem
+	}{
+		{"", []pickerItem{
+			{"▶ everything", "/", browseAll},
+			{"Band/", "Band", browseDir},
+			{"Zebra/", "Zebra", browseDir},
+		}},
+		{"Band", []pickerItem{
+			{"../", "", browseUp},
+			{"▶ all of Band", "Band", browseAll},
+			{"LP/", "Band/LP", browseDir},
+			{"Single.ogg", "Band/Single.ogg", browseFile},
+		}},
+		{"Band/LP", []pickerItem{
+			{"../", "Band", browseUp},
+			{"▶ all of LP", "Band/LP", browseAll},
+			{"01 Song.flac", "Band/LP/01 Song.flac", browseFile},
+			{"02 Other.MP3", "Band/LP/02 Other.MP3", browseFile},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := browseItems(root, tt.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("browseItems(%q) = %+v, want %+v", tt.dir, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("browseItems(%q)[%d] = %+v, want %+v", tt.dir, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
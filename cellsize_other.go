@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"sptsong/internal/ui"
+)
+
+func fontRatio() float64 {
+	return ui.DefaultFontRatio
+}
+
+// terminalAlive can't tell on this platform, where hangups aren't delivered
+// as signals anyway.
+func terminalAlive() bool {
+	return true
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"sptsong/internal/ui"
+)
+
+// fontRatio returns the terminal's cell width divided by its cell height,
+// from the pixel size the terminal reports through TIOCGWINSZ. Terminals that
+// don't report pixel sizes get the usual 1:2.
+func fontRatio() float64 {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Xpixel == 0 || ws.Ypixel == 0 || ws.Col == 0 || ws.Row == 0 {
+		return ui.DefaultFontRatio
+	}
+	cellWidth := float64(ws.Xpixel) / float64(ws.Col)
+	cellHeight := float64(ws.Ypixel) / float64(ws.Row)
+	return cellWidth / cellHeight
+}
+
+// terminalAlive reports whether the terminal is still there; once it has hung
+// up, asking for its size fails.
+func terminalAlive() bool {
+	_, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	return err == nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"sptsong/internal/config"
+	"sptsong/internal/ui"
+)
+
+// printer writes the output of the non-TUI subcommands in the configured
+// theme. Colors are left out when stdout isn't a terminal, NO_COLOR is set
+// or --no-color was given, so the output stays easy to pipe.
+type printer struct {
+	out   io.Writer
+	theme ui.Theme
+	color bool
+}
+
+// field is one labelled line of printer output.
+type field struct {
+	label string
+	value string
+	style ui.Style
+}
+
+// noColorFlag adds --no-color to a subcommand's flags.
+func noColorFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("no-color", false, "don't color the output (also set by NO_COLOR)")
+}
+
+func newPrinter(cfg config.Config, noColor bool) *printer {
+	themes := loadThemes(cfg)
+	theme := themes[ui.FindTheme(themes, cfg.Theme)].WithContrast(configuredBackground(cfg), cfg.MinContrast)
+	ui.SetColorDepth(colorDepth(cfg.Terminal))
+	return &printer{
+		out:   os.Stdout,
+		theme: theme,
+		color: !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout),
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *printer) render(style ui.Style, text string) string {
+	if !p.color {
+		return text
+	}
+	return style.Render(text)
+}
+
+// fields prints one line per field with the values lined up after the
+// widest label, measured in terminal columns. Labels are drawn faint in the theme's time color.
+func (p *printer) fields(fields ...field) {
+	width := 0
+	for _, f := range fields {
+		width = max(width, ui.Width(f.label))
+	}
+	label := p.theme.Time
+	label.Faint = true
+	for _, f := range fields {
+		padding := strings.Repeat(" ", width-ui.Width(f.label)+2)
+		fmt.Fprintln(p.out, p.render(label, f.label)+padding+p.render(f.style, f.value))
+	}
+}
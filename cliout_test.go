@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"sptsong/internal/ui"
+)
+
+func TestFieldsAlignWideLabels(t *testing.T) {
+	var out strings.Builder
+	p := &printer{out: &out}
+	p.fields(field{label: "Title", value: "a"}, field{label: "曲名", value: "b"}, field{label: "🎵", value: "c"})
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	for _, line := range lines {
+		value := line[len(line)-1:]
+		if got := ui.Width(line) - 1; got != len("Title")+2 {
+			t.Errorf("value %q starts at column %d in %q, want %d", value, got, line, len("Title")+2)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+)
+
+// noticeDuration is how long a notice such as "copied" stays on the line
+// under the artist.
+const noticeDuration = 3 * time.Second
+
+// trackLink returns the open.spotify.com link of the track, or "" for
+// tracks that aren't Spotify's.
+func trackLink(trackID string) string {
+	uri := artwork.SpotifyURI(trackID)
+	if uri == "" {
+		return ""
+	}
+	parts := strings.Split(uri, ":")
+	return "https://open.spotify.com/" + parts[1] + "/" + parts[2]
+}
+
+// copyText puts text on the clipboard: with wl-copy on Wayland, xclip on
+// X11 or pbcopy on macOS, and otherwise, or over SSH where those would fill
+// the wrong machine's clipboard, with an OSC 52 sequence that asks the
+// terminal to do it.
+func (sd *SpotifyDisplay) copyText(text string) error {
+	if os.Getenv("SSH_CONNECTION") == "" {
+		var cmd *exec.Cmd
+		switch {
+		case runtime.GOOS == "darwin":
+			cmd = exec.Command("pbcopy")
+		case os.Getenv("WAYLAND_DISPLAY") != "":
+			cmd = exec.Command("wl-copy")
+		case os.Getenv("DISPLAY") != "":
+			cmd = exec.Command("xclip", "-selection", "clipboard")
+		}
+		if cmd != nil {
+			if _, err := exec.LookPath(cmd.Path); err == nil {
+				cmd.Stdin = strings.NewReader(text)
+				return cmd.Run()
+			}
+		}
+	}
+	_, err := fmt.Fprintf(sd.out, "\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// copyTrack copies the current track's Spotify link, or with text set its
+// "artist – title", and says so under the artist.
+func (sd *SpotifyDisplay) copyTrack(text bool) {
+	m := sd.current
+	what, value := "link", trackLink(m.TrackID)
+	if text {
+		what, value = "artist – title", m.Artist+" – "+m.Title
+	}
+	switch {
+	case m.Title == "":
+		sd.showNotice("nothing playing to copy")
+	case value == "":
+		sd.showNotice("no Spotify link for this track")
+	case sd.copyText(value) != nil:
+		sd.showNotice("couldn't copy the " + what)
+	default:
+		sd.showNotice("copied the " + what)
+	}
+}
+
+// showNotice shows text under the artist for a few seconds.
+func (sd *SpotifyDisplay) showNotice(text string) {
+	sd.notice, sd.noticeAt = text, sd.clock.Now()
+	slog.Info("notice", "text", text)
+}
+
+// activeNotice returns the notice to show, if it hasn't expired.
+func (sd *SpotifyDisplay) activeNotice() string {
+	if sd.notice == "" || sd.clock.Now().Sub(sd.noticeAt) > noticeDuration {
+		return ""
+	}
+	return sd.notice
+}
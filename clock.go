@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Clock is the display's source of time. Everything that reads the time or
+// waits on a ticker goes through it, so tests and replays can swap in a
+// clock that runs faster than the wall clock.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the part of *time.Ticker the display uses.
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// scaledClock runs speed times faster than the wall clock, starting from
+// the moment it was created. Tickers fire at the scaled rate and deliver
+// scaled times.
+type scaledClock struct {
+	start time.Time
+	speed float64
+}
+
+func (c scaledClock) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.start)) * c.speed))
+}
+
+func (c scaledClock) NewTicker(d time.Duration) Ticker {
+	t := &scaledTicker{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		ticker: time.NewTicker(c.wall(d)),
+		stop:   make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// wall converts a scaled duration to wall-clock time.
+func (c scaledClock) wall(d time.Duration) time.Duration {
+	return max(time.Duration(float64(d)/c.speed), time.Millisecond)
+}
+
+type scaledTicker struct {
+	clock  scaledClock
+	c      chan time.Time
+	ticker *time.Ticker
+	stop   chan struct{}
+}
+
+func (t *scaledTicker) run() {
+	for {
+		select {
+		case <-t.ticker.C:
+			select {
+			case t.c <- t.clock.Now():
+			default:
+			}
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *scaledTicker) C() <-chan time.Time   { return t.c }
+func (t *scaledTicker) Reset(d time.Duration) { t.ticker.Reset(t.clock.wall(d)) }
+
+func (t *scaledTicker) Stop() {
+	t.ticker.Stop()
+	close(t.stop)
+}
+
+// newClock returns the wall clock, or an accelerated one when
+// SPTSONG_CLOCK_SPEED is set, e.g. to 10 for watching a watchdog timeout or
+// a sleep timer play out in a tenth of the time.
+func newClock() Clock {
+	if speed, err := strconv.ParseFloat(os.Getenv("SPTSONG_CLOCK_SPEED"), 64); err == nil && speed > 0 && speed != 1 {
+		return scaledClock{start: time.Now(), speed: speed}
+	}
+	return realClock{}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// controlMethods maps the one-shot control subcommands to the MPRIS method
+// each one calls.
+var controlMethods = map[string]string{
+	"play":   "Play",
+	"pause":  "Pause",
+	"toggle": "PlayPause",
+	"next":   "Next",
+	"prev":   "Previous",
+}
+
+// playerFlags adds the flags that pick the player, shared by the display and
+// the subcommands that talk to the player directly.
+func playerFlags(flags *flag.FlagSet, cfg *config.Config) {
+	flags.StringVar(&cfg.Backend, "backend", cfg.Backend, "player backend: mpris, mpd, webapi, applescript (macOS) or gsmtc (Windows)")
+	flags.StringVar(&cfg.MPD.Host, "mpd-host", cfg.MPD.Host, "MPD server as host:port, password@host:port or a socket path")
+	flags.StringVar(&cfg.DBusAddress, "dbus-address", cfg.DBusAddress, `session bus address, or "auto" to search all users' sessions`)
+}
+
+// openControlPlayer opens the configured player the way the display does,
+// overrides included, but without exporting anything on the bus.
+func openControlPlayer(cfg config.Config) (mpris.Player, error) {
+	cfg.ExportMPRIS = false
+	player, _, err := openPlayer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if player, err = withOverrides(player, cfg.Overrides); err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	}
+	return player, nil
+}
+
+// runControl makes a single player call for play, pause, toggle, next or
+// prev, for window manager key bindings and scripts.
+func runControl(cfg config.Config, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: sptsong %s [--backend name]\n", command)
+		flags.PrintDefaults()
+	}
+	playerFlags(flags, &cfg)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w: %s takes no arguments", errUsage, command)
+	}
+
+	player, err := openControlPlayer(cfg)
+	if err != nil {
+		return err
+	}
+	return player.Call(controlMethods[command])
+}
+
+// runStatus prints the current track, either as labelled fields or through
+// --format for status bars.
+func runStatus(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sptsong status [--no-color] [--format template] [--backend name]")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "", "print this instead of the fields, e.g. '{artist} - {title}'; "+
+		"also {album}, {status}, {icon}, {position}, {length} and {volume}")
+	noColor := noColorFlag(flags)
+	playerFlags(flags, &cfg)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	player, err := openControlPlayer(cfg)
+	if err != nil {
+		return err
+	}
+	metadata, err := player.Metadata()
+	if err != nil {
+		return err
+	}
+	cleanMetadata(metadata)
+
+	if *format != "" {
+		fmt.Fprintln(os.Stdout, formatStatus(*format, metadata))
+		return nil
+	}
+	out := newPrinter(cfg, *noColor)
+	out.fields(
+		field{"status", metadata.Status, out.theme.Accent},
+		field{"title", metadata.Title, out.theme.Title},
+		field{"artist", metadata.Artist, out.theme.Artist},
+		field{"album", metadata.Album, out.theme.Artist},
+		field{"time", ui.FormatDuration(metadata.Position) + "/" + ui.FormatDuration(metadata.Length), out.theme.Time},
+		field{"volume", strconv.Itoa(int(metadata.Volume*100+0.5)) + "%", out.theme.Time},
+	)
+	return nil
+}
+
+// formatStatus fills in the {placeholders} of a --format template.
+func formatStatus(format string, m *mpris.Metadata) string {
+	return strings.NewReplacer(
+		"{title}", m.Title,
+		"{artist}", m.Artist,
+		"{album}", m.Album,
+		"{status}", m.Status,
+		"{position}", ui.FormatDuration(m.Position),
+		"{length}", ui.FormatDuration(m.Length),
+		"{volume}", strconv.Itoa(int(m.Volume*100+0.5)),
+		"{icon}", statusGlyph(m.Status),
+	).Replace(format)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"sptsong/internal/ui"
+)
+
+// drawDebugOverlay shows runtime statistics on the top row so leaks in long
+// runs are visible, along with the average gap between tracks.
+func (sd *SpotifyDisplay) drawDebugOverlay(term TerminalSize) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := fmt.Sprintf(" goroutines %d · heap %.1f MiB · sys %.1f MiB · gc %d · up %s ",
+		runtime.NumGoroutine(),
+		float64(mem.HeapAlloc)/(1<<20),
+		float64(mem.Sys)/(1<<20),
+		mem.NumGC,
+		sd.clock.Now().Sub(sd.started).Truncate(time.Second))
+	if gap, n := sd.gaps.average(); n > 0 {
+		stats += fmt.Sprintf("· gap %+dms avg of %d ", gap.Milliseconds(), n)
+	}
+
+	fmt.Fprint(sd.out, ui.MoveTo(0, 0)+"\033[2K"+sd.theme().Time.Render(stats))
+}
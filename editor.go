@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/config"
+	"sptsong/internal/ui"
+)
+
+const (
+	paletteHues   = 12
+	paletteShades = 6 // five lightness steps plus a grayscale row
+	editorWidth   = 64
+)
+
+var editorFields = []string{"accent", "title", "artist", "bar_filled", "bar_end", "bar_empty", "time", "border"}
+
+// themeEditor edits a copy of a theme in place. While it is open the widget
+// is drawn with the edited theme, so every change previews live.
+type themeEditor struct {
+	theme   ui.Theme
+	field   int
+	hue     int
+	shade   int
+	naming  bool
+	name    []rune
+	message string
+}
+
+func newThemeEditor(base ui.Theme) *themeEditor {
+	return &themeEditor{theme: base, shade: 2, name: []rune(base.Name + "-edit")}
+}
+
+func (e *themeEditor) style(field int) *ui.Style {
+	return []*ui.Style{
+		&e.theme.Accent, &e.theme.Title, &e.theme.Artist, &e.theme.BarFilled,
+		&e.theme.BarEnd, &e.theme.BarEmpty, &e.theme.Time, &e.theme.Border,
+	}[field]
+}
+
+// paletteColor returns the swatch at hue, shade. The last shade row is a
+// grayscale ramp.
+func paletteColor(hue, shade int) string {
+	if shade == paletteShades-1 {
+		v := hue * 255 / (paletteHues - 1)
+		return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+	}
+	lightness := 0.25 + 0.15*float64(shade)
+	return ui.HSLHex(float64(hue)*360/paletteHues, 0.7, lightness)
+}
+
+// handleEditorKey processes a key while the editor is open and reports
+// whether the screen needs a full repaint.
+func (sd *SpotifyDisplay) handleEditorKey(event *tcell.EventKey) bool {
+	e := sd.editor
+	ch := keyRune(event)
+	if e.naming {
+		switch key := event.Key(); {
+		case key == tcell.KeyEnter:
+			e.naming = false
+			e.message = sd.saveEditedTheme(string(e.name))
+		case key == tcell.KeyEsc:
+			e.naming = false
+		case key == tcell.KeyBackspace || key == tcell.KeyBackspace2:
+			if len(e.name) > 0 {
+				e.name = e.name[:len(e.name)-1]
+			}
+		case ch == '-' || ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch):
+			e.name = append(e.name, ch)
+		}
+		return false
+	}
+
+	pick := func() {
+		e.style(e.field).Fg = paletteColor(e.hue, e.shade)
+	}
+	switch event.Key() {
+	case tcell.KeyEsc:
+		sd.editor = nil
+		return true
+	case tcell.KeyUp:
+		e.field = (e.field + len(editorFields) - 1) % len(editorFields)
+	case tcell.KeyDown:
+		e.field = (e.field + 1) % len(editorFields)
+	case tcell.KeyLeft:
+		e.hue = (e.hue + paletteHues - 1) % paletteHues
+		pick()
+	case tcell.KeyRight:
+		e.hue = (e.hue + 1) % paletteHues
+		pick()
+	default:
+		switch ch {
+		case '+':
+			e.shade = min(e.shade+1, paletteShades-1)
+			pick()
+		case '-':
+			e.shade = max(e.shade-1, 0)
+			pick()
+		case 'x':
+			e.style(e.field).Fg = ""
+		case 's':
+			e.naming = true
+			e.message = ""
+		case 'e':
+			sd.editor = nil
+			return true
+		}
+	}
+	return false
+}
+
+// saveEditedTheme writes the edited theme to the themes directory, adds it
+// to the theme list and switches to it. It returns a status line.
+func (sd *SpotifyDisplay) saveEditedTheme(name string) string {
+	if name == "" {
+		return "a theme needs a name"
+	}
+	dir := config.ThemesDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err.Error()
+	}
+
+	var buf strings.Builder
+	if err := toml.NewEncoder(&buf).Encode(sd.editor.theme); err != nil {
+		return err.Error()
+	}
+	path := filepath.Join(dir, name+".toml")
+	if err := os.WriteFile(path, []byte(buf.String()), 0o644); err != nil {
+		return err.Error()
+	}
+
+	theme := sd.editor.theme
+	theme.Name = name
+	if i := ui.FindTheme(sd.themes, name); sd.themes[i].Name == name {
+		sd.themes[i] = theme
+	} else {
+		sd.themes = append(sd.themes, theme)
+	}
+	sd.themeIndex = ui.FindTheme(sd.themes, name)
+	return "saved " + path
+}
+
+// drawThemeEditor lists the theme's fields with their colors and the palette
+// for the current shade in the top left corner.
+func (sd *SpotifyDisplay) drawThemeEditor() {
+	e := sd.editor
+	blank := strings.Repeat(" ", editorWidth)
+	line := func(row int, s string) {
+		fmt.Fprint(sd.out, ui.MoveTo(0, row)+blank+ui.MoveTo(0, row)+s)
+	}
+
+	line(0, " theme editor · ↑↓ field · ←→ color · +/- shade · x clear · s save · esc")
+	for i, field := range editorFields {
+		style := *e.style(i)
+		marker := "  "
+		if i == e.field {
+			marker = "› "
+		}
+		color := style.Fg
+		if color == "" {
+			color = "default"
+		}
+		line(i+1, fmt.Sprintf(" %s%-11s %s %s", marker, field, style.Render("██"), color))
+	}
+
+	var palette strings.Builder
+	palette.WriteString("   ")
+	for hue := range paletteHues {
+		swatch := ui.Style{Fg: paletteColor(hue, e.shade)}.Render("██")
+		if hue == e.hue {
+			swatch = "[" + swatch + "]"
+		} else {
+			swatch = " " + swatch + " "
+		}
+		palette.WriteString(swatch)
+	}
+	line(len(editorFields)+1, palette.String())
+
+	status := e.message
+	if e.naming {
+		status = "save as: " + string(e.name) + "▏ (enter to save, esc to cancel)"
+	}
+	line(len(editorFields)+2, " "+status)
+}
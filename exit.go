@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Exit codes are part of the CLI's interface; scripts and status bars branch
+// on them, so existing values must not change.
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitPlayerNotRunning = 2
+	exitDBusUnavailable  = 3
+	exitAuthRequired     = 4
+	exitUsage            = 5
+	exitConfig           = 6
+)
+
+var (
+	errSpotifyNotRunning = errors.New("Spotify is not running. Please start Spotify first.")
+	errDBusUnavailable   = errors.New("cannot connect to the D-Bus session bus")
+	errAuthRequired      = errors.New("Spotify Web API authorization required")
+	errUsage             = errors.New("invalid usage")
+	errConfig            = errors.New("invalid configuration")
+)
+
+var exitKinds = []struct {
+	err  error
+	code int
+	kind string
+}{
+	{errSpotifyNotRunning, exitPlayerNotRunning, "player_not_running"},
+	{errDBusUnavailable, exitDBusUnavailable, "dbus_unavailable"},
+	{errAuthRequired, exitAuthRequired, "auth_required"},
+	{errUsage, exitUsage, "usage"},
+	{errConfig, exitConfig, "config"},
+}
+
+func classifyError(err error) (code int, kind string) {
+	for _, k := range exitKinds {
+		if errors.Is(err, k.err) {
+			return k.code, k.kind
+		}
+	}
+	return exitError, "error"
+}
+
+// parseFlags parses a subcommand's flags, reporting bad flags as usage errors
+// rather than letting the flag package exit with its own status.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+// extractJSONErrors removes the global --json-errors switch from args so it
+// can be given before or after any subcommand.
+func extractJSONErrors(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == "--json-errors" || arg == "-json-errors" {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// exitWithError reports err on stderr, as plain text or as a JSON object,
+// and exits with the matching code.
+func exitWithError(err error, jsonErrors bool) {
+	code, kind := classifyError(err)
+	if jsonErrors {
+		json.NewEncoder(os.Stderr).Encode(struct {
+			Error string `json:"error"`
+			Kind  string `json:"kind"`
+			Code  int    `json:"code"`
+		}{err.Error(), kind, code})
+	} else {
+		fmt.Fprintln(os.Stderr, "sptsong:", err)
+	}
+	os.Exit(code)
+}
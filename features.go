@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/ui"
+	"sptsong/internal/webapi"
+)
+
+// featureResult is a track's audio features and whether it's in the user's
+// Liked Songs, looked up in the background. liked is nil if that lookup
+// failed.
+type featureResult struct {
+	trackID  string
+	features webapi.AudioFeatures
+	err      error
+	liked    *bool
+}
+
+// toggleFeatures shows or hides the audio features panel, looking up the
+// current track's features when it opens.
+func (sd *SpotifyDisplay) toggleFeatures() {
+	sd.showFeatures = !sd.showFeatures
+	sd.updateFeatures(sd.current.TrackID)
+}
+
+// updateFeatures starts looking up the audio features of a newly settled
+// Spotify track while the panel is open. Like the album line it needs the
+// Web API, and results are cached per track.
+func (sd *SpotifyDisplay) updateFeatures(trackID string) {
+	if !sd.showFeatures || trackID == sd.featureTrack {
+		return
+	}
+	sd.featureTrack, sd.features, sd.featureErr, sd.liked = trackID, nil, nil, nil
+	uri := artwork.SpotifyURI(trackID)
+	if sd.Spotify.ClientID == "" {
+		sd.featureErr = webapi.ErrNoClientID
+		return
+	}
+	if !strings.HasPrefix(uri, "spotify:track:") {
+		return
+	}
+	if info, ok := sd.tracks.Get(trackID); ok && info.Features != nil {
+		sd.features, sd.liked = info.Features, info.Liked
+		return
+	}
+
+	client := newWebAPI(sd.Config)
+	go func() {
+		defer guard.Recover()
+		result := lookupFeatures(client, trackID)
+		select {
+		case sd.featureDone <- result:
+		default:
+		}
+	}()
+}
+
+// lookupFeatures fetches a Spotify track's audio features and whether it is
+// liked.
+func lookupFeatures(client *webapi.Client, trackID string) featureResult {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	id := strings.TrimPrefix(artwork.SpotifyURI(trackID), "spotify:track:")
+	features, err := client.AudioFeatures(ctx, id)
+	var liked *bool
+	if in, err := client.Liked(ctx, id); err == nil {
+		liked = &in
+	}
+	return featureResult{trackID, features, err, liked}
+}
+
+// setFeatures takes a finished lookup into use if its track is still
+// playing. Only successful lookups are cached.
+func (sd *SpotifyDisplay) setFeatures(result featureResult) {
+	sd.cacheFeatures(result)
+	if result.trackID != sd.featureTrack {
+		return
+	}
+	sd.liked = result.liked
+	if result.err != nil {
+		sd.featureErr = result.err
+		return
+	}
+	sd.features = &result.features
+}
+
+// cacheFeatures keeps what a lookup found for the track's next play.
+func (sd *SpotifyDisplay) cacheFeatures(result featureResult) {
+	if result.err == nil {
+		sd.tracks.Update(result.trackID, fieldFeatures, func(info *TrackInfo) { info.Features = &result.features })
+	}
+	if result.liked != nil {
+		sd.tracks.Update(result.trackID, fieldLiked, func(info *TrackInfo) { info.Liked = result.liked })
+	}
+}
+
+// featureRows is the height of the audio features panel.
+const featureRows = 3
+
+// drawFeatures shows the current track's tempo, key, energy and
+// danceability in the rows from top, next to the now-playing frame.
+func (sd *SpotifyDisplay) drawFeatures(term TerminalSize, top int) {
+	frame := term.frame
+	theme := sd.theme()
+	line := func(row int, text string) {
+		fmt.Fprint(sd.out, ui.MoveTo(frame.X, top+row)+strings.Repeat(" ", frame.Width)+ui.MoveTo(frame.X, top+row)+text)
+	}
+
+	f := sd.features
+	if f == nil {
+		status := "no audio features for this track"
+		var apiErr *webapi.APIError
+		switch {
+		case errors.As(sd.featureErr, &apiErr) && apiErr.Status == http.StatusForbidden:
+			status = "audio features aren't available to this Spotify app"
+		case errors.Is(sd.featureErr, webapi.ErrNoClientID):
+			status = "audio features need [spotify] client_id"
+		case sd.featureErr == nil && sd.featureTrack == sd.current.TrackID && strings.Contains(sd.current.TrackID, "/track/"):
+			status = "looking up audio features…"
+		}
+		line(0, theme.Accent.Render("Audio features"))
+		line(1, theme.Time.Render(status))
+		if sd.liked != nil && *sd.liked {
+			line(2, theme.Artist.Render("♥ in your Liked Songs"))
+		} else {
+			line(2, "")
+		}
+		return
+	}
+
+	header := fmt.Sprintf("%.0f BPM", f.Tempo)
+	if key := f.KeyName(); key != "" {
+		header += " · " + key
+	}
+	if sd.liked != nil && *sd.liked {
+		header += " · ♥"
+	}
+	line(0, theme.Accent.Render(header))
+	barWidth := max(frame.Width-len("danceability ")-5, 1)
+	meter := func(row int, label string, value float64) {
+		line(row, theme.Artist.Render(fmt.Sprintf("%-13s", label))+
+			ui.Bar(theme, false, false, value, barWidth)+
+			theme.Time.Render(fmt.Sprintf(" %3.0f%%", value*100)))
+	}
+	meter(1, "energy", f.Energy)
+	meter(2, "danceability", f.Danceability)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"sptsong/internal/ui"
+)
+
+// frameBuffer collects everything the display writes while it handles one
+// event, so the terminal gets the whole frame in a single write instead of
+// a stream of small ones it may paint halfway through. With ascii set, the
+// frame is brought down to plain ASCII on the way out.
+type frameBuffer struct {
+	bytes.Buffer
+	out   io.Writer
+	ascii bool
+}
+
+func newFrameBuffer(out io.Writer) *frameBuffer {
+	return &frameBuffer{out: out}
+}
+
+// Flush writes the frame collected so far to out, if there is one.
+func (f *frameBuffer) Flush() error {
+	if f.Len() == 0 {
+		return nil
+	}
+	frame := f.Bytes()
+	if f.ascii {
+		frame = []byte(ui.ASCII(string(frame)))
+	}
+	_, err := f.out.Write(frame)
+	f.Reset()
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+// naturalEnd is how close to its end a track must have been when the next one
+// appeared for the change to count as a transition rather than a skip.
+const naturalEnd = 3 * time.Second
+
+// gapMeter measures transitions between tracks: from when the previous track
+// should have ended to when the next one started advancing, both on the
+// monotonic clock. Negative gaps mean the tracks overlapped, as with
+// crossfade. Players that only report whole seconds make single gaps rough;
+// the average is what to look at then.
+type gapMeter struct {
+	trackID   string
+	lastSeen  time.Time
+	remaining time.Duration
+	ended     time.Time
+	total     time.Duration
+	count     int
+}
+
+func (g *gapMeter) observe(metadata *mpris.Metadata, now time.Time) {
+	if metadata.TrackID != g.trackID {
+		g.ended = time.Time{}
+		if g.trackID != "" && g.remaining <= naturalEnd {
+			g.ended = g.lastSeen.Add(g.remaining)
+		}
+		g.trackID = metadata.TrackID
+	}
+	position, length := precise(metadata)
+	if !g.ended.IsZero() && metadata.Status == "Playing" && position > 0 {
+		started := now.Add(-position)
+		g.total += started.Sub(g.ended)
+		g.count++
+		g.ended = time.Time{}
+	}
+	g.lastSeen = now
+	g.remaining = length - position
+}
+
+// precise returns the position and length of the track as precisely as the
+// player reports them.
+func precise(metadata *mpris.Metadata) (position, length time.Duration) {
+	position, length = metadata.Elapsed, metadata.Duration
+	if position == 0 && length == 0 {
+		position = time.Duration(metadata.Position) * time.Second
+		length = time.Duration(metadata.Length) * time.Second
+	}
+	return position, length
+}
+
+// average returns the mean gap and how many transitions it covers.
+func (g *gapMeter) average() (time.Duration, int) {
+	if g.count == 0 {
+		return 0, 0
+	}
+	return g.total / time.Duration(g.count), g.count
+}
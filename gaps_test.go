@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+func TestGapMeter(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+	tests := []struct {
+		name  string
+		polls []mpris.Metadata
+		times []time.Time
+		want  time.Duration
+		count int
+	}{
+		{
+			name: "gapless, sub-second positions",
+			polls: []mpris.Metadata{
+				{TrackID: "a", Status: "Playing", Duration: ms(180000), Elapsed: ms(179900)},
+				{TrackID: "b", Status: "Playing", Duration: ms(200000), Elapsed: ms(100)},
+			},
+			times: []time.Time{at(0), at(200)},
+			want:  0,
+			count: 1,
+		},
+		{
+			name: "a 350ms pause between tracks",
+			polls: []mpris.Metadata{
+				{TrackID: "a", Status: "Playing", Duration: ms(180000), Elapsed: ms(179800)},
+				{TrackID: "b", Status: "Playing", Duration: ms(200000)},
+				{TrackID: "b", Status: "Playing", Duration: ms(200000), Elapsed: ms(50)},
+			},
+			times: []time.Time{at(0), at(300), at(600)},
+			want:  ms(350),
+			count: 1,
+		},
+		{
+			name: "crossfade overlaps",
+			polls: []mpris.Metadata{
+				{TrackID: "a", Status: "Playing", Duration: ms(180000), Elapsed: ms(178000)},
+				{TrackID: "b", Status: "Playing", Duration: ms(200000), Elapsed: ms(1500)},
+			},
+			times: []time.Time{at(0), at(500)},
+			want:  ms(-3000),
+			count: 1,
+		},
+		{
+			name: "a skip isn't a transition",
+			polls: []mpris.Metadata{
+				{TrackID: "a", Status: "Playing", Duration: ms(180000), Elapsed: ms(60000)},
+				{TrackID: "b", Status: "Playing", Duration: ms(200000), Elapsed: ms(100)},
+			},
+			times: []time.Time{at(0), at(200)},
+		},
+		{
+			name: "whole seconds only",
+			polls: []mpris.Metadata{
+				{TrackID: "a", Status: "Playing", Length: 180, Position: 179},
+				{TrackID: "b", Status: "Playing", Length: 200, Position: 1},
+			},
+			times: []time.Time{at(0), at(2000)},
+			want:  0,
+			count: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g gapMeter
+			for i := range tt.polls {
+				g.observe(&tt.polls[i], tt.times[i])
+			}
+			gap, count := g.average()
+			if gap != tt.want || count != tt.count {
+				t.Errorf("average() = %v, %d; want %v, %d", gap, count, tt.want, tt.count)
+			}
+		})
+	}
+}
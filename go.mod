@@ -3,8 +3,21 @@ module sptsong
 go 1.23.5
 
 require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/gdamore/tcell/v2 v2.8.1
 	github.com/godbus/dbus/v5 v5.1.0
-	github.com/nsf/termbox-go v1.1.1
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-runewidth v0.0.16
+	github.com/mattn/go-sqlite3 v1.14.32
+	golang.org/x/image v0.25.0
+	golang.org/x/sys v0.30.0
+	golang.org/x/text v0.23.0
 )
 
-require github.com/mattn/go-runewidth v0.0.9 // indirect
+require (
+	github.com/gdamore/encoding v1.0.1 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
+	golang.org/x/term v0.28.0 // indirect
+)
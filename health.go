@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpd"
+	"sptsong/internal/ui"
+	"sptsong/internal/webapi"
+)
+
+// healthIssue is something the startup check found misconfigured, with the
+// setting or command that fixes it.
+type healthIssue struct {
+	problem string
+	fix     string
+}
+
+// checkHealth looks for the setups that otherwise fail quietly: no chafa, an
+// unreachable MPD, a Web API login that has lapsed, an event log that can't
+// be written. It makes network calls, so it runs off the run loop, on its
+// own copy of the config and its own connections.
+func checkHealth(cfg config.Config) []healthIssue {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var issues []healthIssue
+	if !cfg.Compact && cfg.Layout != "no-art" {
+		if _, err := exec.LookPath("chafa"); err != nil {
+			issues = append(issues, healthIssue{"chafa is not installed, so there is no album art", `install chafa, or set layout = "no-art"`})
+		}
+	}
+	if cfg.Backend == "mpd" {
+		if _, err := mpd.New(cfg.MPD.Host).Metadata(); err != nil {
+			issues = append(issues, healthIssue{"can't reach MPD: " + err.Error(), "[mpd] host or --mpd-host"})
+		}
+	}
+	if cfg.Spotify.ClientID != "" && cfg.Backend != "webapi" {
+		// The webapi backend reports its login at startup already.
+		err := newWebAPI(cfg).Do(ctx, "GET", "/me", nil, nil, nil)
+		if errors.Is(err, webapi.ErrNotLoggedIn) {
+			issues = append(issues, healthIssue{"the Spotify Web API login is missing or has expired", "run `sptsong login`"})
+		}
+	}
+	if cfg.EventLog != "" {
+		f, err := os.OpenFile(cfg.EventLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			issues = append(issues, healthIssue{"can't write the event log: " + err.Error(), "event_log or --event-log"})
+		} else {
+			f.Close()
+		}
+	}
+	return issues
+}
+
+// drawHealthBanner lists the startup check's findings across the top of the
+// screen until dismissed with Esc.
+func (sd *SpotifyDisplay) drawHealthBanner(term TerminalSize) {
+	theme := sd.theme()
+	for i, issue := range sd.issues {
+		text := fmt.Sprintf("⚠ %s — fix: %s", issue.problem, issue.fix)
+		if i == len(sd.issues)-1 {
+			text += "  (Esc dismisses)"
+		}
+		fmt.Fprint(sd.out, ui.MoveTo(0, i)+"\033[2K"+theme.Accent.Render(ui.Truncate(text, term.width)))
+	}
+}
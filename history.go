@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sptsong/internal/config"
+	"sptsong/internal/guard"
+	"sptsong/internal/history"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// trackHistory is the last size tracks played, newest first. With a store,
+// it is kept there across restarts.
+type trackHistory struct {
+	entries []history.Entry
+	size    int
+	store   history.Store
+	host    string
+	// readOnly leaves the store to another display.
+	readOnly bool
+	visible  bool
+	scroll   int
+	// relative shows how long ago tracks played rather than when.
+	relative bool
+}
+
+// newTrackHistory loads the latest size tracks from store, which may be
+// nil to keep them for this session only.
+func newTrackHistory(size int, store history.Store) *trackHistory {
+	h := &trackHistory{size: size, store: store}
+	h.host, _ = os.Hostname()
+	if store != nil && size > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		entries, err := store.Recent(ctx, size)
+		if err != nil {
+			slog.Warn("can't read the history", "err", err)
+		}
+		h.entries = entries
+	}
+	return h
+}
+
+// openHistoryStore opens the store configured under [history], or returns
+// nil if the history isn't kept. The files go in cacheDir, where the
+// history.json of older versions is taken into a new store once.
+func openHistoryStore(cfg config.HistoryConfig, cacheDir string) (history.Store, error) {
+	if !cfg.Persist {
+		return nil, nil
+	}
+	location := cfg.DSN
+	switch {
+	case location != "":
+	case cfg.Store == "sqlite":
+		location = filepath.Join(cacheDir, "history.db")
+	case cfg.Store == "jsonl":
+		location = filepath.Join(cacheDir, "history.jsonl")
+	}
+	store, err := history.Open(cfg.Store, location)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	importHistory(store, filepath.Join(cacheDir, "history.json"))
+	return store, nil
+}
+
+// importHistory adds the entries of an older version's history file to an
+// empty store, and renames the file so it isn't imported again.
+func importHistory(store history.Store, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if recent, err := store.Recent(ctx, 1); err != nil || len(recent) > 0 {
+		return
+	}
+	var entries []history.Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	// The file is newest first.
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := store.Add(ctx, entries[i]); err != nil {
+			slog.Warn("can't import the old history", "err", err)
+			return
+		}
+	}
+	os.Rename(path, path+".imported")
+}
+
+// add records m as played at now.
+func (h *trackHistory) add(m *mpris.Metadata, now time.Time) {
+	if h.size <= 0 || m.Title == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[0].TrackID == m.TrackID && h.entries[0].Title == m.Title {
+		// Restarted during the track it last recorded.
+		return
+	}
+	entry := history.Entry{TrackID: m.TrackID, Title: m.Title, Artist: m.Artist, Album: m.Album, Played: now, Host: h.host}
+	h.entries = append([]history.Entry{entry}, h.entries...)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[:h.size]
+	}
+	if h.scroll > 0 {
+		// Keep the rows being read where they are.
+		h.scroll = min(h.scroll+1, len(h.entries)-1)
+	}
+
+	if h.store == nil || h.readOnly {
+		return
+	}
+	// A database across the network shouldn't hold up the display.
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := h.store.Add(ctx, entry); err != nil {
+			slog.Warn("can't save to the history", "err", err)
+		}
+	}()
+}
+
+// scrollBy moves the pane by lines, newer tracks being up.
+func (h *trackHistory) scrollBy(lines int) {
+	if !h.visible {
+		return
+	}
+	h.scroll = max(min(h.scroll+lines, len(h.entries)-1), 0)
+}
+
+// drawHistory lists as many of the tracks played as fit in rows rows from
+// top, next to the now-playing frame.
+func (sd *SpotifyDisplay) drawHistory(term TerminalSize, top, rows int) {
+	frame := term.frame
+	rows = min(rows, sd.history.size+1)
+	if rows < 2 {
+		return
+	}
+
+	theme := sd.theme()
+	line := func(row int, text string, style ui.Style) {
+		text = ui.Truncate(text, frame.Width)
+		pad := strings.Repeat(" ", max(frame.Width-ui.Width(text), 0))
+		fmt.Fprint(sd.out, ui.MoveTo(frame.X, top+row)+style.Render(text)+pad)
+	}
+
+	h := sd.history
+	header := fmt.Sprintf("History (%d)", len(h.entries))
+	if len(h.entries) > rows-1 {
+		header += "  j/k scroll"
+	}
+	line(0, header, theme.Accent)
+	now := sd.clock.Now()
+	stamps := make([]string, rows)
+	stampWidth := 0
+	for row := 1; row < rows && h.scroll+row-1 < len(h.entries); row++ {
+		stamps[row] = sd.timeLocale.played(h.entries[h.scroll+row-1].Played, now, h.relative)
+		stampWidth = max(stampWidth, ui.Width(stamps[row]))
+	}
+	for row := 1; row < rows; row++ {
+		i := h.scroll + row - 1
+		if i >= len(h.entries) {
+			line(row, "", theme.Time)
+			continue
+		}
+		e := h.entries[i]
+		line(row, ui.Pad(stamps[row], stampWidth)+"  "+e.Artist+" – "+e.Title, theme.Artist)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sptsong/internal/config"
+)
+
+func TestOpenHistoryStoreImportsOldFile(t *testing.T) {
+	dir := t.TempDir()
+	old := `[{"title": "Newer", "artist": "Band", "played": "2026-03-14T15:34:00Z"},
+		{"title": "Older", "artist": "Band", "played": "2026-03-14T15:30:00Z"}]`
+	if err := os.WriteFile(filepath.Join(dir, "history.json"), []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := openHistoryStore(config.HistoryConfig{Persist: true, Store: "jsonl"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newTrackHistory(10, store)
+	if len(h.entries) != 2 || h.entries[0].Title != "Newer" || h.entries[1].Title != "Older" {
+		t.Fatalf("entries = %+v, want Newer then Older", h.entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "history.json")); !os.IsNotExist(err) {
+		t.Error("the old file is still there to import again")
+	}
+
+	// Opening again imports nothing twice.
+	store, _ = openHistoryStore(config.HistoryConfig{Persist: true, Store: "jsonl"}, dir)
+	if entries, _ := store.Recent(context.Background(), 10); len(entries) != 2 {
+		t.Errorf("%d entries after reopening, want 2", len(entries))
+	}
+}
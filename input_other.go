@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/config"
+)
+
+// queryBackground does nothing: the console's answer wouldn't come back
+// through tcell here, so the background is taken to be dark.
+func queryBackground(cfg config.Config) {}
+
+// newScreen opens the console for tcell.
+func newScreen() (tcell.Screen, error) {
+	return tcell.NewScreen()
+}
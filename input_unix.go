@@ -0,0 +1,124 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/config"
+)
+
+// queryBackground asks the terminal for its background color with OSC 11,
+// when the background or the theme is left to it. The answer comes in as
+// input, see replyTty.
+func queryBackground(cfg config.Config) {
+	if cfg.Background == "auto" || cfg.Theme == "auto" {
+		fmt.Fprint(os.Stdout, "\033]11;?\033\\")
+	}
+}
+
+// newScreen opens the terminal for tcell, with the answer to the background
+// query taken out of the input before tcell would read it as keys.
+func newScreen() (tcell.Screen, error) {
+	tty, err := tcell.NewDevTty()
+	if err != nil {
+		return nil, err
+	}
+	return tcell.NewTerminfoScreenFromTty(&replyTty{Tty: tty})
+}
+
+// replyTty is a terminal whose input has the answers to the background
+// query taken out and sent to backgroundReplies. Only tcell's input loop
+// reads it.
+type replyTty struct {
+	tcell.Tty
+	pending []byte
+}
+
+func (t *replyTty) Read(p []byte) (int, error) {
+	for {
+		reply, keys, rest := splitColorReply(t.pending)
+		if reply != "" {
+			select {
+			case backgroundReplies <- reply:
+			default:
+			}
+			t.pending = rest
+			continue
+		}
+		if len(keys) > 0 {
+			n := copy(p, keys)
+			t.pending = append(slices.Clone(keys[n:]), rest...)
+			return n, nil
+		}
+		t.pending = rest
+		buf := make([]byte, max(len(p), maxColorReply))
+		n, err := t.Tty.Read(buf)
+		t.pending = append(t.pending, buf[:n]...)
+		if n == 0 || err != nil {
+			return 0, err
+		}
+	}
+}
+
+// colorReplyPrefix starts the terminal's answer to the OSC 11 background
+// query.
+const colorReplyPrefix = "\033]11;"
+
+// maxColorReply bounds a reply still coming in; one that never ends is
+// given up on rather than holding up the keys behind it.
+const maxColorReply = 64
+
+// replyBody returns how much of s could be the color in an answer to the
+// background query, such as rgb:1e1e/1e1e/2e2e.
+func replyBody(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F' || strings.IndexByte("rgab:/#", c) >= 0) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// splitColorReply takes apart the input read so far: keys, which can go on
+// to tcell, a complete answer to the background query ended by BEL or ST,
+// and the rest, which has to wait for more input. At most one of keys and
+// reply is set. A reply cut short, by something that can't be part of it or
+// by running past maxColorReply, is dropped up to there and what follows is
+// passed on as keys.
+func splitColorReply(input []byte) (reply string, keys, rest []byte) {
+	s := string(input)
+	start := strings.Index(s, colorReplyPrefix)
+	if start < 0 {
+		// Hold back the start of a reply cut short by the read, but not
+		// a lone Esc, which is a key of its own.
+		for i := max(len(s)-len(colorReplyPrefix)+1, 0); i < len(s); i++ {
+			if len(s)-i >= 2 && strings.HasPrefix(colorReplyPrefix, s[i:]) {
+				return "", input[:i], input[i:]
+			}
+		}
+		return "", input, nil
+	}
+	if start > 0 {
+		return "", input[:start], input[start:]
+	}
+	end := strings.IndexByte(s, '\a') + 1
+	if st := strings.Index(s[1:], "\033\\"); st >= 0 && (end == 0 || st+3 < end) {
+		end = st + 3
+	}
+	if end == 0 {
+		body := len(colorReplyPrefix) + replyBody(s[len(colorReplyPrefix):])
+		// An Esc at the end may start the ST still to come.
+		if after := s[body:]; after != "" && after != "\033" || len(s) > maxColorReply {
+			return "", input[body:], nil
+		}
+		return "", nil, input
+	}
+	return s[:end], nil, input[end:]
+}
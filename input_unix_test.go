@@ -0,0 +1,99 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSplitColorReply(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		reply, keys string
+		rest        string
+	}{
+		{"keys only", "abc", "", "abc", ""},
+		{"reply ended by BEL", "\033]11;rgb:ffff/ffff/ffff\aq", "\033]11;rgb:ffff/ffff/ffff\a", "", "q"},
+		{"reply ended by ST", "\033]11;rgb:00/00/00\033\\q", "\033]11;rgb:00/00/00\033\\", "", "q"},
+		{"keys before the reply", "ab\033]11;rgb:00/00/00\a", "", "ab", "\033]11;rgb:00/00/00\a"},
+		{"reply still coming in", "\033]11;rgb:00/0", "", "", "\033]11;rgb:00/0"},
+		{"start of the prefix held back", "ab\033]1", "", "ab", "\033]1"},
+		{"lone Esc is a key", "ab\033", "", "ab\033", ""},
+		{"arrow key", "\033[A", "", "\033[A", ""},
+		{"other OSC", "\033]10;rgb:ff/ff/ff\a", "", "\033]10;rgb:ff/ff/ff\a", ""},
+		{"reply waiting for its ST", "\033]11;rgb:00/00/00\033", "", "", "\033]11;rgb:00/00/00\033"},
+		{"reply cut short by keys", "\033]11;rgb:00/0q\033[A", "", "q\033[A", ""},
+		{"reply that never ends is dropped", "\033]11;" + strings.Repeat("f", maxColorReply), "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, keys, rest := splitColorReply([]byte(tt.input))
+			if reply != tt.reply || string(keys) != tt.keys || string(rest) != tt.rest {
+				t.Errorf("splitColorReply(%q) = %q, %q, %q; want %q, %q, %q", tt.input, reply, keys, rest, tt.reply, tt.keys, tt.rest)
+			}
+		})
+	}
+}
+
+// chunkTty hands out its input in the given chunks, as separate reads.
+type chunkTty struct {
+	tcell.Tty
+	chunks []string
+}
+
+func (t *chunkTty) Read(p []byte) (int, error) {
+	if len(t.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, t.chunks[0])
+	t.chunks[0] = t.chunks[0][n:]
+	if t.chunks[0] == "" {
+		t.chunks = t.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestReplyTtyFiltersReply(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		keys   string
+		reply  string
+	}{
+		{"no reply", []string{"q"}, "q", ""},
+		{"reply alone", []string{"\033]11;rgb:1e1e/1e1e/2e2e\a"}, "", "\033]11;rgb:1e1e/1e1e/2e2e\a"},
+		{"reply between keys", []string{"a\033]11;rgb:ff/ff/ff\033\\b"}, "ab", "\033]11;rgb:ff/ff/ff\033\\"},
+		{"reply split across reads", []string{"a\033]1", "1;rgb:ff/f", "f/ff\ab"}, "ab", "\033]11;rgb:ff/ff/ff\a"},
+		{"garbage after the prefix", []string{"\033]11;" + strings.Repeat("x", 70), "q"}, strings.Repeat("x", 70) + "q", ""},
+		{"keys typed after a cut reply", []string{"\033]11;rgb:ff/f", "qw"}, "qw", ""},
+		{"reply that never ends", []string{"\033]11;" + strings.Repeat("f", 70), "q"}, "q", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for len(backgroundReplies) > 0 {
+				<-backgroundReplies
+			}
+			tty := &replyTty{Tty: &chunkTty{chunks: tt.chunks}}
+			keys, err := io.ReadAll(tty)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(keys) != tt.keys {
+				t.Errorf("keys = %q, want %q", keys, tt.keys)
+			}
+			reply := ""
+			select {
+			case reply = <-backgroundReplies:
+			default:
+			}
+			if reply != tt.reply {
+				t.Errorf("reply = %q, want %q", reply, tt.reply)
+			}
+		})
+	}
+}
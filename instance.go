@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errTakenOver is returned by the run loop when a newer display took over.
+var errTakenOver = errors.New("another sptsong took over")
+
+// roles are what a display can do when it finds another already running.
+var roles = []string{"mirror", "takeover", "companion"}
+
+// instanceSocket is where the running display answers the ones started after
+// it, so two of them don't both export MPRIS, send notifications and write
+// the caches.
+func instanceSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sptsong-display.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sptsong-%d-display.sock", os.Getuid()))
+}
+
+// otherInstance is a display found on the instance socket: an interactive
+// one, or a detached session.
+type otherInstance struct {
+	pid  int
+	kind string
+}
+
+// dialInstance connects to the running display and reads its greeting.
+func dialInstance() (net.Conn, *bufio.Reader, *otherInstance, error) {
+	conn, err := net.DialTimeout("unix", instanceSocket(), time.Second)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	var other otherInstance
+	if err == nil {
+		_, err = fmt.Sscanf(line, "sptsong %d %s", &other.pid, &other.kind)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	return conn, r, &other, nil
+}
+
+// findInstance returns the display already running, or nil.
+func findInstance() *otherInstance {
+	conn, _, other, err := dialInstance()
+	if err != nil {
+		return nil
+	}
+	// Hanging up without a request leaves the other display alone.
+	conn.Close()
+	return other
+}
+
+// claimInstance makes this process the one later displays find.
+func claimInstance() (net.Listener, error) {
+	path := instanceSocket()
+	os.Remove(path)
+	return net.Listen("unix", path)
+}
+
+// chooseRole returns role if it was given with --role or --takeover, or asks
+// the user what to do about other. An empty result means quit.
+func chooseRole(other *otherInstance, role string, takeover bool) (string, error) {
+	if takeover {
+		if role != "" && role != "takeover" {
+			return "", fmt.Errorf("%w: --takeover and --role %s", errUsage, role)
+		}
+		return "takeover", nil
+	}
+	if role != "" {
+		for _, r := range roles {
+			if role == r {
+				return role, nil
+			}
+		}
+		return "", fmt.Errorf("%w: unknown role %q, want mirror, takeover or companion", errUsage, role)
+	}
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("%w: sptsong is already running (pid %d); pass --role mirror, takeover or companion", errUsage, other.pid)
+	}
+
+	fmt.Printf("sptsong is already running (pid %d).\n", other.pid)
+	if other.kind == "session" {
+		fmt.Println("It is detached; `sptsong attach` brings it here instead.")
+	}
+	fmt.Print("[m]irror it, [t]ake over, open a [c]ompanion view, or [q]uit? ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "m", "mirror":
+		return "mirror", nil
+	case "t", "takeover", "take over":
+		return "takeover", nil
+	case "c", "companion":
+		return "companion", nil
+	}
+	return "", nil
+}
+
+// mirrorInstance shows the running display's frames, like mirror --connect.
+func mirrorInstance() error {
+	conn, r, _, err := dialInstance()
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(conn, "mirror"); err != nil {
+		conn.Close()
+		return err
+	}
+	return showFrames(conn, r)
+}
+
+// takeOver asks the running display to quit and waits until it has. It
+// returns the view the display handed over, or nil if it sent none.
+func takeOver() (*viewState, error) {
+	conn, r, _, err := dialInstance()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, "takeover"); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var v *viewState
+	if line, err := r.ReadBytes('\n'); err == nil {
+		if json.Unmarshal(line, &v) != nil {
+			v = nil
+		}
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, fmt.Errorf("taking over: %w", err)
+	}
+	return v, nil
+}
+
+// handOver sends the display taking over the view to carry on with, and
+// keeps the connection open: it closes as this process exits, which tells
+// the new display it can start.
+func (sd *SpotifyDisplay) handOver(conn net.Conn) {
+	json.NewEncoder(conn).Encode(sd.view())
+	sd.taker = conn
+}
+
+// serveInstances makes sd answer later displays on listener: it greets each
+// one, mirrors its frames to those that ask, and leaves the run loop when
+// one takes over.
+func (sd *SpotifyDisplay) serveInstances(listener net.Listener, kind string) {
+	m := newMirror(sd.out, sd.requestRedraw)
+	sd.out = m
+	sd.takeover = make(chan net.Conn, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				fmt.Fprintf(conn, "sptsong %d %s\n", os.Getpid(), kind)
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				request, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.SetReadDeadline(time.Time{})
+				switch strings.TrimSpace(request) {
+				case "mirror":
+					m.add(conn)
+				case "takeover":
+					select {
+					case sd.takeover <- conn:
+					default:
+						conn.Close()
+					}
+				default:
+					conn.Close()
+				}
+			}()
+		}
+	}()
+}
+
+// beCompanion keeps sd away from everything the running display owns: the
+// MPRIS export, notifications, the event log and the cache and history
+// files.
+func (sd *SpotifyDisplay) beCompanion() {
+	sd.companion = true
+	sd.tracks.readOnly = true
+	sd.history.readOnly = true
+}
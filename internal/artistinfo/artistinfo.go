@@ -0,0 +1,150 @@
+// Package artistinfo looks artists up without a Spotify account: their
+// genres from MusicBrainz and a short biography from Wikipedia.
+package artistinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned when neither source knows the artist.
+var ErrNotFound = errors.New("artist not found")
+
+// Info is what the artist panel shows. Popularity and Followers only come
+// from Spotify.
+type Info struct {
+	Name       string   `json:"name"`
+	Genres     []string `json:"genres,omitempty"`
+	Popularity int      `json:"popularity,omitempty"`
+	Followers  int      `json:"followers,omitempty"`
+	Bio        string   `json:"bio,omitempty"`
+	Source     string   `json:"source"`
+}
+
+// Client queries MusicBrainz and Wikipedia.
+type Client struct {
+	http        *http.Client
+	musicBrainz string
+	wikipedia   string
+}
+
+func New(httpClient *http.Client) *Client {
+	return &Client{
+		http:        httpClient,
+		musicBrainz: "https://musicbrainz.org/ws/2",
+		wikipedia:   "https://en.wikipedia.org/api/rest_v1",
+	}
+}
+
+// maxGenres keeps the genre line to the tags most voted for.
+const maxGenres = 5
+
+// Lookup finds the artist on MusicBrainz, for its genres, and on Wikipedia,
+// for its biography. Either is enough.
+func (c *Client) Lookup(ctx context.Context, name string) (Info, error) {
+	info := Info{Name: name, Source: "MusicBrainz"}
+	genres, err := c.genres(ctx, name)
+	if ctx.Err() != nil {
+		return Info{}, ctx.Err()
+	}
+	info.Genres = genres
+	info.Bio, _ = c.Bio(ctx, name)
+	if err != nil && info.Bio == "" {
+		return Info{}, err
+	}
+	if info.Genres == nil {
+		info.Source = "Wikipedia"
+	}
+	return info, nil
+}
+
+func (c *Client) genres(ctx context.Context, name string) ([]string, error) {
+	query := url.Values{
+		"query": {fmt.Sprintf(`artist:"%s"`, name)},
+		"fmt":   {"json"},
+		"limit": {"1"},
+	}
+	var body struct {
+		Artists []struct {
+			Score int `json:"score"`
+			Tags  []struct {
+				Name  string `json:"name"`
+				Count int    `json:"count"`
+			} `json:"tags"`
+		} `json:"artists"`
+	}
+	if err := c.getJSON(ctx, c.musicBrainz+"/artist/?"+query.Encode(), &body); err != nil {
+		return nil, err
+	}
+	// Search results always come back; a low score is somebody else.
+	if len(body.Artists) == 0 || body.Artists[0].Score < 90 {
+		return nil, ErrNotFound
+	}
+	tags := body.Artists[0].Tags
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Count > tags[j].Count })
+	var genres []string
+	for _, tag := range tags[:min(len(tags), maxGenres)] {
+		genres = append(genres, tag.Name)
+	}
+	return genres, nil
+}
+
+// musicWords tell an article about a musician from one about something
+// else sharing the name.
+var musicWords = []string{"band", "singer", "musician", "rapper", "composer", "songwriter", "producer", "group", "duo", "dj", "orchestra", "ensemble"}
+
+// Bio returns the lead of the artist's Wikipedia article. Pages for a
+// plain name are often about something else, so the usual disambiguated
+// titles are tried after it.
+func (c *Client) Bio(ctx context.Context, name string) (string, error) {
+	for _, title := range []string{name, name + " (band)", name + " (musician)", name + " (singer)"} {
+		var page struct {
+			Type        string `json:"type"`
+			Description string `json:"description"`
+			Extract     string `json:"extract"`
+		}
+		endpoint := c.wikipedia + "/page/summary/" + url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+		if err := c.getJSON(ctx, endpoint, &page); err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			continue
+		}
+		if page.Type == "standard" && aboutMusic(page.Description) {
+			return page.Extract, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+func aboutMusic(description string) bool {
+	for _, word := range strings.Fields(strings.ToLower(description)) {
+		for _, music := range musicWords {
+			if strings.Trim(word, ",.;()") == music {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Client) getJSON(ctx context.Context, endpoint string, v any) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	req.Header.Set("User-Agent", "sptsong/1.0 (https://github.com/Zelferion/sptsong)")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
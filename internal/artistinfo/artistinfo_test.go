@@ -0,0 +1,61 @@
+package artistinfo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func fakeSources(t *testing.T, pages map[string]string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	c := New(server.Client())
+	c.musicBrainz, c.wikipedia = server.URL+"/mb", server.URL+"/wiki"
+	return c
+}
+
+func TestLookup(t *testing.T) {
+	c := fakeSources(t, map[string]string{
+		"/mb/artist/": `{"artists": [{"score": 100, "tags": [
+			{"name": "dream pop", "count": 2}, {"name": "shoegaze", "count": 7}, {"name": "british", "count": 1}
+		]}]}`,
+		// The plain title is a disambiguation page, the band has its own.
+		"/wiki/page/summary/Slowdive":        `{"type": "disambiguation", "extract": "Slowdive may refer to:"}`,
+		"/wiki/page/summary/Slowdive_(band)": `{"type": "standard", "description": "English rock band", "extract": "Slowdive are an English rock band."}`,
+	})
+
+	got, err := c.Lookup(context.Background(), "Slowdive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{
+		Name:   "Slowdive",
+		Genres: []string{"shoegaze", "dream pop", "british"},
+		Bio:    "Slowdive are an English rock band.",
+		Source: "MusicBrainz",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	c := fakeSources(t, map[string]string{
+		"/mb/artist/":               `{"artists": [{"score": 40, "tags": [{"name": "jazz", "count": 3}]}]}`,
+		"/wiki/page/summary/Nobody": `{"type": "standard", "description": "2007 film", "extract": "Nobody is a film."}`,
+	})
+	if _, err := c.Lookup(context.Background(), "Nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want ErrNotFound", err)
+	}
+}
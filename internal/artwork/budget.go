@@ -0,0 +1,82 @@
+package artwork
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned instead of downloading once today's budget
+// is used up.
+var ErrBudgetExceeded = errors.New("daily artwork download budget used up")
+
+// Budget caps the bytes of artwork downloaded per day, for metered
+// connections. Usage is kept in the file at Path so restarts and other
+// sptsong processes count towards the same day.
+type Budget struct {
+	Path  string
+	Limit int64
+
+	mu sync.Mutex
+}
+
+type budgetUsage struct {
+	Day   string `json:"day"`
+	Bytes int64  `json:"bytes"`
+}
+
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// usage reads today's usage; a missing file or an earlier day counts as
+// nothing used. b.mu must be held.
+func (b *Budget) usage() budgetUsage {
+	var u budgetUsage
+	if data, err := os.ReadFile(b.Path); err == nil {
+		json.Unmarshal(data, &u)
+	}
+	if u.Day != today() {
+		u = budgetUsage{Day: today()}
+	}
+	return u
+}
+
+// Used returns the bytes downloaded today.
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usage().Bytes
+}
+
+// Allow reports whether another download fits in today's budget. A nil
+// budget allows everything.
+func (b *Budget) Allow() bool {
+	if b == nil {
+		return true
+	}
+	return b.Used() < b.Limit
+}
+
+// Add counts n downloaded bytes towards today.
+func (b *Budget) Add(n int64) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u := b.usage()
+	u.Bytes += n
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	tmp := b.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.Path)
+}
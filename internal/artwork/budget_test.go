@@ -0,0 +1,62 @@
+package artwork
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBudget(t *testing.T) {
+	dir := t.TempDir()
+	budget := &Budget{Path: filepath.Join(dir, "budget.json"), Limit: 100}
+
+	if !budget.Allow() {
+		t.Fatal("fresh budget doesn't allow downloads")
+	}
+	budget.Add(60)
+	budget.Add(60)
+	if budget.Allow() || budget.Used() != 120 {
+		t.Errorf("after 120 of 100 bytes: Allow() = %v, Used() = %d", budget.Allow(), budget.Used())
+	}
+
+	// Yesterday's usage doesn't count.
+	os.WriteFile(budget.Path, []byte(`{"day":"2000-01-01","bytes":500}`), 0o644)
+	if !budget.Allow() || budget.Used() != 0 {
+		t.Errorf("old usage counted: Used() = %d", budget.Used())
+	}
+
+	var nilBudget *Budget
+	if !nilBudget.Allow() || nilBudget.Add(1) != nil {
+		t.Error("nil budget should allow everything")
+	}
+}
+
+func TestDownloadBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 80)))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := Cache{Dir: dir, Size: 10, Budget: &Budget{Path: filepath.Join(t.TempDir(), "budget.json"), Limit: 100}}
+	ctx := context.Background()
+
+	first, err := cache.Download(ctx, server.URL+"/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Download(ctx, server.URL+"/b"); err != nil {
+		t.Fatalf("second download within budget: %v", err)
+	}
+	if _, err := cache.Download(ctx, server.URL+"/c"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("third download error = %v, want ErrBudgetExceeded", err)
+	}
+	if path, err := cache.Download(ctx, server.URL+"/a"); err != nil || path != first {
+		t.Errorf("cached cover = %q, %v, want it served despite the budget", path, err)
+	}
+}
@@ -0,0 +1,177 @@
+package artwork
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrTooLarge is returned for a cover bigger than the cache's MaxBytes.
+var ErrTooLarge = errors.New("cover too large")
+
+// Cache stores downloaded covers in Dir, keeping at most Size of them.
+// Downloads stop for the day once Budget, if set, is used up, and covers
+// that failed aren't requested again for a while if Failures is set. Covers
+// over MaxBytes, if positive, aren't downloaded.
+type Cache struct {
+	Dir      string
+	Size     int
+	MaxBytes int64
+	Budget   *Budget
+	Failures *Failures
+}
+
+// Path is where the cover at artURL is stored, named by a hash of the URL.
+func (c Cache) Path(artURL string) string {
+	sum := sha1.Sum([]byte(artURL))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Evict removes the least recently used covers once the cache holds more
+// than Size of them. Cache hits refresh a cover's modification time, so the
+// oldest mtime is the least recently used entry.
+func (c Cache) Evict() {
+	dir := c.Dir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type cover struct {
+		path  string
+		mtime int64
+	}
+	covers := make([]cover, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), "download-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		covers = append(covers, cover{filepath.Join(dir, entry.Name()), info.ModTime().UnixNano()})
+	}
+	if len(covers) <= c.Size {
+		return
+	}
+
+	sort.Slice(covers, func(i, j int) bool { return covers[i].mtime < covers[j].mtime })
+	for _, cover := range covers[:len(covers)-c.Size] {
+		os.Remove(cover.path)
+	}
+}
+
+// Reuse reports whether imagePath, a cover Download returned before, is
+// still there. A cover in the cache is marked as used, so it is evicted
+// last; local files are left alone.
+func (c Cache) Reuse(imagePath string) bool {
+	if _, err := os.Stat(imagePath); err != nil {
+		return false
+	}
+	if filepath.Dir(imagePath) == filepath.Clean(c.Dir) {
+		now := time.Now()
+		os.Chtimes(imagePath, now, now)
+	}
+	return true
+}
+
+// Download returns a local path for the cover at artURL. Local files are
+// used in place, looking inside Flatpak/Snap sandboxes if needed; remote
+// covers are stored under a hash of their URL so a cover is only downloaded
+// once while it stays in the cache.
+func (c Cache) Download(ctx context.Context, artURL string) (string, error) {
+	if artURL == "" {
+		return "", ErrNoCover
+	}
+	if filepath.IsAbs(artURL) {
+		return ResolveLocal(artURL)
+	}
+
+	imagePath := c.Path(artURL)
+	if c.Reuse(imagePath) {
+		return imagePath, nil
+	}
+	if err := c.Failures.check(artURL); err != nil {
+		return "", err
+	}
+
+	var err error
+	for retry := 0; retry < attempts; retry++ {
+		if retry > 0 {
+			if err := sleep(ctx, backoff(retry-1)); err != nil {
+				return "", err
+			}
+		}
+		if err = c.fetch(ctx, artURL, imagePath); err == nil || !Transient(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			c.Failures.add(artURL, err)
+		}
+		return "", err
+	}
+	c.Evict()
+	return imagePath, nil
+}
+
+// fetch makes one attempt at downloading artURL to imagePath.
+func (c Cache) fetch(ctx context.Context, artURL, imagePath string) error {
+	if !c.Budget.Allow() {
+		return ErrBudgetExceeded
+	}
+	if err := hosts.wait(ctx, artURL); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", artURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "spotify-display/1.0")
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{resp.StatusCode, resp.Status}
+	}
+	body := io.Reader(resp.Body)
+	if c.MaxBytes > 0 {
+		if resp.ContentLength > c.MaxBytes {
+			return ErrTooLarge
+		}
+		body = io.LimitReader(resp.Body, c.MaxBytes+1)
+	}
+
+	output, err := os.CreateTemp(filepath.Dir(imagePath), "download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(output.Name())
+
+	n, err := CopyPooled(output, body)
+	c.Budget.Add(n)
+	if err == nil && c.MaxBytes > 0 && n > c.MaxBytes {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		output.Close()
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	return os.Rename(output.Name(), imagePath)
+}
@@ -0,0 +1,32 @@
+package artwork
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDownloadTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/streamed" {
+			// No Content-Length, so the limit is only found while copying.
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(strings.Repeat("x", 200)))
+	}))
+	defer server.Close()
+
+	cache := Cache{Dir: t.TempDir(), Size: 10, MaxBytes: 100}
+	for _, path := range []string{"/sized", "/streamed"} {
+		if _, err := cache.Download(context.Background(), server.URL+path); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: error = %v, want ErrTooLarge", path, err)
+		}
+	}
+	cache.MaxBytes = 1000
+	if _, err := cache.Download(context.Background(), server.URL+"/sized"); err != nil {
+		t.Errorf("cover within the limit: %v", err)
+	}
+}
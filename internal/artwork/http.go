@@ -0,0 +1,65 @@
+package artwork
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// HTTPClient is shared by every network lookup so connections are reused
+// across tracks instead of piling up over long runs. It goes through the
+// proxy named by HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+var HTTPClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: transport,
+}
+
+var transport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConns:        8,
+	MaxIdleConnsPerHost: 2,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// ConfigureHTTP sets HTTPClient's timeout for a whole request, if positive,
+// and has it trust the certificate authorities in the PEM file caBundle as
+// well as the system's, for networks that intercept TLS. It must be called
+// before the client is first used.
+func ConfigureHTTP(timeout time.Duration, caBundle string) error {
+	if timeout > 0 {
+		HTTPClient.Timeout = timeout
+	}
+	if caBundle == "" {
+		return nil
+	}
+	pem, err := os.ReadFile(caBundle)
+	if err != nil {
+		return fmt.Errorf("CA bundle: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("CA bundle %s: no PEM certificates", caBundle)
+	}
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	return nil
+}
+
+var copyBuffers = sync.Pool{
+	New: func() any { return make([]byte, 32*1024) },
+}
+
+// CopyPooled is io.Copy with a buffer from a pool rather than a fresh 32 KiB
+// allocation per call.
+func CopyPooled(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().([]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, buf)
+}
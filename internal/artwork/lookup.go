@@ -0,0 +1,88 @@
+package artwork
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrNoCover = errors.New("no cover found")
+
+// LookupURL searches the iTunes Search API and then the Cover Art Archive
+// for the cover of an album, for tracks whose player reports no usable art.
+func LookupURL(ctx context.Context, artist, album string) (string, error) {
+	if album == "" {
+		return "", ErrNoCover
+	}
+	for _, lookup := range []func(context.Context, string, string) (string, error){itunesArtURL, coverArtArchiveURL} {
+		artURL, err := lookup(ctx, artist, album)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			continue
+		}
+		return artURL, nil
+	}
+	return "", ErrNoCover
+}
+
+func getJSON(ctx context.Context, endpoint string, v any) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	req.Header.Set("User-Agent", "sptsong/1.0 (https://github.com/Zelferion/sptsong)")
+	req.Header.Set("Accept", "application/json")
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func itunesArtURL(ctx context.Context, artist, album string) (string, error) {
+	query := url.Values{
+		"term":   {artist + " " + album},
+		"entity": {"album"},
+		"limit":  {"1"},
+	}
+	var body struct {
+		Results []struct {
+			ArtworkURL100 string `json:"artworkUrl100"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, "https://itunes.apple.com/search?"+query.Encode(), &body); err != nil {
+		return "", err
+	}
+	if len(body.Results) == 0 || body.Results[0].ArtworkURL100 == "" {
+		return "", ErrNoCover
+	}
+	// The 100px thumbnail URL serves other sizes by changing its name.
+	return strings.Replace(body.Results[0].ArtworkURL100, "100x100bb", "600x600bb", 1), nil
+}
+
+func coverArtArchiveURL(ctx context.Context, artist, album string) (string, error) {
+	query := url.Values{
+		"query": {fmt.Sprintf(`artist:"%s" AND releasegroup:"%s"`, artist, album)},
+		"fmt":   {"json"},
+		"limit": {"1"},
+	}
+	var body struct {
+		ReleaseGroups []struct {
+			ID string `json:"id"`
+		} `json:"release-groups"`
+	}
+	if err := getJSON(ctx, "https://musicbrainz.org/ws/2/release-group/?"+query.Encode(), &body); err != nil {
+		return "", err
+	}
+	if len(body.ReleaseGroups) == 0 {
+		return "", ErrNoCover
+	}
+	return "https://coverartarchive.org/release-group/" + body.ReleaseGroups[0].ID + "/front-500", nil
+}
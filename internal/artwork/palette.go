@@ -0,0 +1,147 @@
+package artwork
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"sort"
+)
+
+type rgb struct{ r, g, b uint8 }
+
+// colorBox is one bucket of the median cut.
+type colorBox []rgb
+
+// DominantColor decodes the image at path and returns its most prominent
+// color as "#rrggbb". The palette is built with a median cut over a
+// downsampled set of pixels, and vivid colors are preferred over grays so the
+// result works as an accent.
+func DominantColor(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", err
+	}
+
+	pixels := samplePixels(img, 64)
+	if len(pixels) == 0 {
+		return "", fmt.Errorf("image has no opaque pixels")
+	}
+
+	best, bestScore := rgb{}, -1.0
+	for _, box := range medianCut(pixels, 8) {
+		c := box.average()
+		score := float64(len(box)) * (0.15 + saturation(c))
+		if l := luminance(c); l < 0.08 || l > 0.92 {
+			score /= 4
+		}
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r, best.g, best.b), nil
+}
+
+// samplePixels reads roughly size*size evenly spaced pixels, skipping mostly
+// transparent ones.
+func samplePixels(img image.Image, size int) []rgb {
+	bounds := img.Bounds()
+	stepX := max(bounds.Dx()/size, 1)
+	stepY := max(bounds.Dy()/size, 1)
+
+	pixels := make([]rgb, 0, size*size)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a < 0x8000 {
+				continue
+			}
+			pixels = append(pixels, rgb{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)})
+		}
+	}
+	return pixels
+}
+
+// medianCut splits pixels into at most n boxes, each time cutting the box
+// with the widest channel range at its median.
+func medianCut(pixels []rgb, n int) []colorBox {
+	boxes := []colorBox{pixels}
+	for len(boxes) < n {
+		widest, widestRange, channel := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if c, r := box.widestChannel(); r > widestRange {
+				widest, widestRange, channel = i, r, c
+			}
+		}
+		if widest < 0 {
+			break
+		}
+
+		box := boxes[widest]
+		sort.Slice(box, func(i, j int) bool {
+			return box[i].channel(channel) < box[j].channel(channel)
+		})
+		mid := len(box) / 2
+		boxes[widest] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+	return boxes
+}
+
+func (c rgb) channel(i int) uint8 {
+	switch i {
+	case 0:
+		return c.r
+	case 1:
+		return c.g
+	}
+	return c.b
+}
+
+func (box colorBox) widestChannel() (channel, width int) {
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := uint8(255), uint8(0)
+		for _, c := range box {
+			v := c.channel(ch)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if int(hi)-int(lo) > width {
+			channel, width = ch, int(hi)-int(lo)
+		}
+	}
+	return channel, width
+}
+
+func (box colorBox) average() rgb {
+	var r, g, b int
+	for _, c := range box {
+		r += int(c.r)
+		g += int(c.g)
+		b += int(c.b)
+	}
+	n := max(len(box), 1)
+	return rgb{uint8(r / n), uint8(g / n), uint8(b / n)}
+}
+
+func saturation(c rgb) float64 {
+	hi := max(c.r, c.g, c.b)
+	lo := min(c.r, c.g, c.b)
+	if hi == 0 {
+		return 0
+	}
+	return float64(hi-lo) / float64(hi)
+}
+
+func luminance(c rgb) float64 {
+	return (0.2126*float64(c.r) + 0.7152*float64(c.g) + 0.0722*float64(c.b)) / 255
+}
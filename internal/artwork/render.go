@@ -0,0 +1,122 @@
+package artwork
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const renderCacheSize = 32
+
+// Options are the chafa settings for a rendering. Width and Height are in
+// cells; FontRatio is the cell width over its height.
+type Options struct {
+	Width, Height int
+	FontRatio     float64
+	Symbols       string
+	Dither        string
+	Work          int
+	Colors        string
+}
+
+// Renderer runs chafa and keeps its output per (image, options) so redraws
+// after a resize or realignment don't have to run chafa again.
+type Renderer struct {
+	mu      sync.Mutex
+	order   []string
+	renders map[string][]string
+}
+
+func NewRenderer() *Renderer {
+	return &Renderer{renders: make(map[string][]string)}
+}
+
+func (c *Renderer) get(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lines, ok := c.renders[key]
+	if ok {
+		c.touch(key)
+	}
+	return lines, ok
+}
+
+func (c *Renderer) put(key string, lines []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.renders[key]; !ok && len(c.order) >= renderCacheSize {
+		delete(c.renders, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.renders[key] = lines
+	c.touch(key)
+}
+
+// touch moves key to the most recently used end. c.mu must be held.
+func (c *Renderer) touch(key string) {
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, key)
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Render returns chafa's rendering of the image as one string per terminal
+// row, reusing a cached rendering of the same image with the same options.
+func (c *Renderer) Render(ctx context.Context, imagePath string, opts Options) ([]string, error) {
+	hash, err := hashFile(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"--format=symbols",
+		fmt.Sprintf("--size=%dx%d", opts.Width, opts.Height),
+		fmt.Sprintf("--font-ratio=%.3f", opts.FontRatio),
+		"--symbols=" + opts.Symbols,
+		"--dither=" + opts.Dither,
+		fmt.Sprintf("--work=%d", opts.Work),
+		"--colors=" + opts.Colors,
+	}
+	key := hash + " " + strings.Join(args, " ")
+	if lines, ok := c.get(key); ok {
+		return lines, nil
+	}
+
+	chafaPath, err := exec.LookPath("chafa")
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := exec.CommandContext(ctx, chafaPath, append(args, imagePath)...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	c.put(key, lines)
+	return lines, nil
+}
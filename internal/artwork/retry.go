@@ -0,0 +1,156 @@
+package artwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrFailedRecently is returned instead of downloading a cover that failed
+// a short while ago, wrapping the error it failed with.
+var ErrFailedRecently = errors.New("failed recently")
+
+// RetryDelay is how long a cover that failed for a passing reason, such as
+// a dropped connection or a busy server, is left alone before it's tried
+// again. Covers that are gone or forbidden are left alone for an hour.
+const RetryDelay = 30 * time.Second
+
+const permanentDelay = time.Hour
+
+// Download makes up to attempts requests for a cover, backing off from
+// retryBase with jitter between them.
+var (
+	attempts  = 3
+	retryBase = 500 * time.Millisecond
+)
+
+// StatusError is a download the server answered with something other than
+// 200 OK.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return "HTTP " + e.Status
+}
+
+// Transient reports whether a failed download may work if tried again
+// later: network errors and servers that are busy or broken are, a cover
+// that isn't there or out of budget isn't.
+func Transient(err error) bool {
+	var status *StatusError
+	switch {
+	case errors.As(err, &status):
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	case errors.Is(err, ErrNoCover), errors.Is(err, ErrBudgetExceeded), errors.Is(err, ErrTooLarge),
+		errors.Is(err, fs.ErrNotExist), errors.Is(err, context.Canceled):
+		return false
+	}
+	return err != nil
+}
+
+// Failures remembers covers that failed to download so a broken URL isn't
+// requested again on every redraw. The zero value is ready to use; a nil
+// Failures remembers nothing.
+type Failures struct {
+	mu     sync.Mutex
+	failed map[string]failure
+}
+
+type failure struct {
+	err   error
+	until time.Time
+}
+
+// check returns the error artURL failed with if it's too soon to try again.
+func (f *Failures) check(artURL string) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failed, ok := f.failed[artURL]
+	if !ok {
+		return nil
+	}
+	if !time.Now().Before(failed.until) {
+		delete(f.failed, artURL)
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrFailedRecently, failed.err)
+}
+
+// add records that artURL failed with err.
+func (f *Failures) add(artURL string, err error) {
+	if f == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrBudgetExceeded) {
+		return
+	}
+	delay := permanentDelay
+	if Transient(err) {
+		delay = RetryDelay
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failed == nil {
+		f.failed = make(map[string]failure)
+	}
+	f.failed[artURL] = failure{err, time.Now().Add(delay)}
+}
+
+// backoff returns how long to wait before the given retry, counting from
+// zero: retryBase doubled for each earlier retry, of which a random half or
+// more so that clients that failed together don't come back together.
+func backoff(retry int) time.Duration {
+	d := retryBase << retry
+	return d/2 + rand.N(d/2+1)
+}
+
+// hostGap is the least time between two requests to the same host.
+const hostGap = 250 * time.Millisecond
+
+// hosts spaces out requests per host, so skipping through a playlist
+// doesn't burst requests at the cover CDN.
+var hosts = hostLimiter{next: make(map[string]time.Time)}
+
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait blocks until a request to rawURL's host is due, or ctx is done.
+func (l *hostLimiter) wait(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	due := l.next[u.Host]
+	if due.Before(now) {
+		due = now
+	}
+	l.next[u.Host] = due.Add(hostGap)
+	l.mu.Unlock()
+	return sleep(ctx, due.Sub(now))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
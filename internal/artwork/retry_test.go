@@ -0,0 +1,74 @@
+package artwork
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setRetryBase sets retryBase for the rest of the test.
+func setRetryBase(t *testing.T, d time.Duration) {
+	old := retryBase
+	retryBase = d
+	t.Cleanup(func() { retryBase = old })
+}
+
+func TestDownloadRetries(t *testing.T) {
+	setRetryBase(t, time.Millisecond)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("cover"))
+	}))
+	defer server.Close()
+
+	cache := Cache{Dir: t.TempDir(), Size: 10, Failures: &Failures{}}
+	if _, err := cache.Download(context.Background(), server.URL+"/busy"); err != nil {
+		t.Fatalf("download after two 503s: %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
+
+func TestDownloadRemembersFailures(t *testing.T) {
+	setRetryBase(t, time.Millisecond)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cache := Cache{Dir: t.TempDir(), Size: 10, Failures: &Failures{}}
+	ctx := context.Background()
+	_, err := cache.Download(ctx, server.URL+"/gone")
+	var status *StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusNotFound || Transient(err) {
+		t.Fatalf("error = %v, want a permanent 404", err)
+	}
+	_, err = cache.Download(ctx, server.URL+"/gone")
+	if !errors.Is(err, ErrFailedRecently) || !errors.As(err, &status) {
+		t.Errorf("second error = %v, want the 404 remembered", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests, want the 404 requested once", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	setRetryBase(t, 100*time.Millisecond)
+	for retry := range 3 {
+		d, full := backoff(retry), retryBase<<retry
+		if d < full/2 || d > full {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", retry, d, full/2, full)
+		}
+	}
+}
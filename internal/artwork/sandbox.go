@@ -0,0 +1,101 @@
+package artwork
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const flatpakSpotifyID = "com.spotify.Client"
+
+var flatpakRuntimeDir = regexp.MustCompile(`^/run/user/\d+/app/` + regexp.QuoteMeta(flatpakSpotifyID) + `/`)
+
+// ResolveLocal finds a cover reported as a local path. Flatpak and Snap
+// builds of Spotify report paths inside their sandbox, so when the path
+// doesn't exist on the host the equivalent cache locations of both sandboxes
+// are tried as well.
+func ResolveLocal(path string) (string, error) {
+	for _, candidate := range localArtCandidates(path) {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("artwork %s: %w", path, fs.ErrNotExist)
+}
+
+func localArtCandidates(path string) []string {
+	candidates := []string{path}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return candidates
+	}
+
+	flatpakCache := filepath.Join(homeDir, ".var", "app", flatpakSpotifyID, "cache")
+	snapDirs := []string{
+		filepath.Join(homeDir, "snap", "spotify", "current", ".cache"),
+		filepath.Join(homeDir, "snap", "spotify", "common", ".cache"),
+	}
+
+	// Paths in the sandbox's private runtime dir end up in its cache on the
+	// host.
+	if loc := flatpakRuntimeDir.FindStringIndex(path); loc != nil {
+		candidates = append(candidates, filepath.Join(flatpakCache, path[loc[1]:]))
+	}
+
+	// Paths under the sandbox's idea of ~/.cache.
+	if rest, ok := strings.CutPrefix(path, filepath.Join(homeDir, ".cache")+"/"); ok {
+		candidates = append(candidates, filepath.Join(flatpakCache, rest))
+		for _, dir := range snapDirs {
+			candidates = append(candidates, filepath.Join(dir, rest))
+		}
+	}
+	return candidates
+}
+
+// SpotifyURI turns an MPRIS track id such as /com/spotify/track/<id> into
+// spotify:track:<id>.
+func SpotifyURI(trackID string) string {
+	parts := strings.Split(strings.Trim(trackID, "/"), "/")
+	if len(parts) != 4 || parts[0] != "com" || parts[1] != "spotify" {
+		return ""
+	}
+	return "spotify:" + parts[2] + ":" + parts[3]
+}
+
+// OEmbedURL asks Spotify's public oEmbed endpoint for the CDN cover of a
+// track. It needs no credentials, which makes it a good fallback when the
+// local path from a sandboxed client can't be resolved.
+func OEmbedURL(ctx context.Context, trackID string) (string, error) {
+	uri := SpotifyURI(trackID)
+	if uri == "" {
+		return "", fmt.Errorf("no Spotify URI for track %q", trackID)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://open.spotify.com/oembed?url="+url.QueryEscape(uri), nil)
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oembed lookup failed: %s", resp.Status)
+	}
+
+	var body struct {
+		ThumbnailURL string `json:"thumbnail_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.ThumbnailURL == "" {
+		return "", fmt.Errorf("oembed response for %s has no thumbnail", uri)
+	}
+	return body.ThumbnailURL, nil
+}
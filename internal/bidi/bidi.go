@@ -0,0 +1,270 @@
+// Package bidi puts right-to-left text in the order a terminal without
+// bidirectional support should draw it, for Arabic and Hebrew track names.
+// It implements the parts of the Unicode Bidirectional Algorithm (UAX #9) a
+// single line of metadata needs: the paragraph direction, the weak, neutral
+// and implicit rules, and reordering with mirrored brackets. Explicit
+// embeddings and isolates are taken for neutrals.
+package bidi
+
+import (
+	"slices"
+
+	ubidi "golang.org/x/text/unicode/bidi"
+)
+
+// cluster is a character with the combining marks after it, which must stay
+// behind it whichever way the line runs.
+type cluster struct {
+	runes []rune
+	class ubidi.Class
+}
+
+// Visual returns s in visual order: its characters as they appear from left
+// to right on screen. Text without right-to-left characters comes back as
+// it is.
+func Visual(s string) string {
+	clusters := split(s)
+	if !slices.ContainsFunc(clusters, func(c cluster) bool { return c.class == ubidi.R || c.class == ubidi.AL }) {
+		return s
+	}
+
+	classes := make([]ubidi.Class, len(clusters))
+	for i, c := range clusters {
+		classes[i] = c.class
+	}
+	base := paragraphLevel(classes)
+	resolveWeak(classes, base)
+	resolveNeutral(classes, base)
+	levels := implicitLevels(classes, base)
+	// L1: whitespace at the end of the line goes back to the paragraph level.
+	for i := len(clusters) - 1; i >= 0 && isSpace(clusters[i].class); i-- {
+		levels[i] = base
+	}
+
+	var out []rune
+	for _, i := range reorder(levels) {
+		runes := clusters[i].runes
+		if levels[i]%2 == 1 {
+			runes = append([]rune{mirror(runes[0])}, runes[1:]...)
+		}
+		out = append(out, runes...)
+	}
+	return string(out)
+}
+
+// split groups s into clusters, classed by their first character.
+func split(s string) []cluster {
+	var clusters []cluster
+	for _, r := range s {
+		props, _ := ubidi.LookupRune(r)
+		class := props.Class()
+		if class == ubidi.NSM && len(clusters) > 0 {
+			last := &clusters[len(clusters)-1]
+			last.runes = append(last.runes, r)
+			continue
+		}
+		switch class {
+		case ubidi.NSM, ubidi.BN, ubidi.Control, ubidi.LRO, ubidi.RLO, ubidi.LRE, ubidi.RLE,
+			ubidi.PDF, ubidi.LRI, ubidi.RLI, ubidi.FSI, ubidi.PDI:
+			class = ubidi.ON
+		}
+		clusters = append(clusters, cluster{[]rune{r}, class})
+	}
+	return clusters
+}
+
+// paragraphLevel is 1 if the first strong character is right-to-left (P2,
+// P3), otherwise 0.
+func paragraphLevel(classes []ubidi.Class) int {
+	for _, c := range classes {
+		switch c {
+		case ubidi.L:
+			return 0
+		case ubidi.R, ubidi.AL:
+			return 1
+		}
+	}
+	return 0
+}
+
+func isSpace(c ubidi.Class) bool {
+	return c == ubidi.WS || c == ubidi.S || c == ubidi.B
+}
+
+// strongBefore returns the strong type last seen before i, or the
+// paragraph's direction at its start.
+func strongBefore(classes []ubidi.Class, i, base int) ubidi.Class {
+	for i--; i >= 0; i-- {
+		switch classes[i] {
+		case ubidi.L, ubidi.R, ubidi.AL:
+			return classes[i]
+		}
+	}
+	return direction(base)
+}
+
+func direction(level int) ubidi.Class {
+	if level%2 == 1 {
+		return ubidi.R
+	}
+	return ubidi.L
+}
+
+// resolveWeak applies rules W2 to W7 to the numbers and separators. W1 was
+// applied by split, which puts combining marks in their base's cluster.
+func resolveWeak(classes []ubidi.Class, base int) {
+	// W2: European digits after Arabic letters are Arabic numbers.
+	for i, c := range classes {
+		if c == ubidi.EN && strongBefore(classes, i, base) == ubidi.AL {
+			classes[i] = ubidi.AN
+		}
+	}
+	// W3
+	for i, c := range classes {
+		if c == ubidi.AL {
+			classes[i] = ubidi.R
+		}
+	}
+	// W4: a single separator between two numbers of a kind joins them.
+	for i := 1; i+1 < len(classes); i++ {
+		before, after := classes[i-1], classes[i+1]
+		switch {
+		case classes[i] == ubidi.ES && before == ubidi.EN && after == ubidi.EN:
+			classes[i] = ubidi.EN
+		case classes[i] == ubidi.CS && before == after && (before == ubidi.EN || before == ubidi.AN):
+			classes[i] = before
+		}
+	}
+	// W5: terminators such as % and currency signs next to European
+	// numbers are numbers too.
+	for i := 0; i < len(classes); {
+		if classes[i] != ubidi.ET {
+			i++
+			continue
+		}
+		end := i
+		for end < len(classes) && classes[end] == ubidi.ET {
+			end++
+		}
+		if (i > 0 && classes[i-1] == ubidi.EN) || (end < len(classes) && classes[end] == ubidi.EN) {
+			for j := i; j < end; j++ {
+				classes[j] = ubidi.EN
+			}
+		}
+		i = end
+	}
+	// W6
+	for i, c := range classes {
+		if c == ubidi.ES || c == ubidi.ET || c == ubidi.CS {
+			classes[i] = ubidi.ON
+		}
+	}
+	// W7: European numbers in left-to-right text are left-to-right.
+	for i, c := range classes {
+		if c == ubidi.EN && strongBefore(classes, i, base) == ubidi.L {
+			classes[i] = ubidi.L
+		}
+	}
+}
+
+// resolveNeutral applies rules N1 and N2: neutrals between text of one
+// direction take it, numbers counting as right-to-left, and other neutrals
+// take the paragraph's.
+func resolveNeutral(classes []ubidi.Class, base int) {
+	strong := func(c ubidi.Class) (ubidi.Class, bool) {
+		switch c {
+		case ubidi.L:
+			return ubidi.L, true
+		case ubidi.R, ubidi.EN, ubidi.AN:
+			return ubidi.R, true
+		}
+		return c, false
+	}
+	for i := 0; i < len(classes); {
+		if _, ok := strong(classes[i]); ok {
+			i++
+			continue
+		}
+		end := i
+		for end < len(classes) {
+			if _, ok := strong(classes[end]); ok {
+				break
+			}
+			end++
+		}
+		before, after := direction(base), direction(base)
+		if i > 0 {
+			before, _ = strong(classes[i-1])
+		}
+		if end < len(classes) {
+			after, _ = strong(classes[end])
+		}
+		resolved := direction(base)
+		if before == after {
+			resolved = before
+		}
+		for j := i; j < end; j++ {
+			classes[j] = resolved
+		}
+		i = end
+	}
+}
+
+// implicitLevels applies rules I1 and I2.
+func implicitLevels(classes []ubidi.Class, base int) []int {
+	levels := make([]int, len(classes))
+	for i, c := range classes {
+		switch {
+		case base%2 == 0 && c == ubidi.R:
+			levels[i] = base + 1
+		case base%2 == 0 && (c == ubidi.EN || c == ubidi.AN):
+			levels[i] = base + 2
+		case base%2 == 1 && c != ubidi.R:
+			levels[i] = base + 1
+		default:
+			levels[i] = base
+		}
+	}
+	return levels
+}
+
+// reorder applies rule L2: from the highest level down to the lowest odd
+// one, every run at that level or above is reversed. It returns the
+// logical index of each visual position.
+func reorder(levels []int) []int {
+	order := make([]int, len(levels))
+	for i := range order {
+		order[i] = i
+	}
+	highest, lowestOdd := 0, len(levels)+1
+	for _, l := range levels {
+		highest = max(highest, l)
+		if l%2 == 1 {
+			lowestOdd = min(lowestOdd, l)
+		}
+	}
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(order); {
+			if levels[order[i]] < level {
+				i++
+				continue
+			}
+			end := i
+			for end < len(order) && levels[order[end]] >= level {
+				end++
+			}
+			slices.Reverse(order[i:end])
+			i = end
+		}
+	}
+	return order
+}
+
+// mirror returns the counterpart of a bracket drawn in right-to-left text,
+// as in rule L4.
+func mirror(r rune) rune {
+	if props, _ := ubidi.LookupRune(r); !props.IsBracket() {
+		return r
+	}
+	return []rune(ubidi.ReverseString(string(r)))[0]
+}
@@ -0,0 +1,25 @@
+package bidi
+
+import "testing"
+
+func TestVisual(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Bohemian Rhapsody", "Bohemian Rhapsody"},
+		{"שלום", "םולש"},
+		{"Live in תל אביב", "Live in ביבא לת"},
+		// Numbers keep their order inside right-to-left text.
+		{"שיר 2", "2 ריש"},
+		{"Track שלום 3", "Track 3 םולש"},
+		{"أغنية 12", "12 ةينغأ"},
+		// Brackets are mirrored and combining marks follow their letters.
+		{"(שלום)", "(םולש)"},
+		{"שָׁלוֹם", "םוֹלשָׁ"},
+		// Trailing spaces go to the end of a right-to-left line: its left.
+		{"שלום ", " םולש"},
+	}
+	for _, tt := range tests {
+		if got := Visual(tt.in); got != tt.want {
+			t.Errorf("Visual(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"sptsong/internal/ui"
+)
+
+type Config struct {
+	Layout          string              `toml:"layout"`
+	Border          string              `toml:"border"`
+	Margin          int                 `toml:"margin"`
+	HorizontalAlign string              `toml:"horizontal_align"`
+	VerticalAlign   string              `toml:"vertical_align"`
+	X               int                 `toml:"x"`
+	Y               int                 `toml:"y"`
+	Theme           string              `toml:"theme"`
+	Themes          map[string]ui.Theme `toml:"themes"`
+	ArtAccent       bool                `toml:"art_accent"`
+	Background      string              `toml:"background"`
+	MinContrast     float64             `toml:"min_contrast"`
+	TrackCacheTTL   time.Duration       `toml:"track_cache_ttl"`
+	ArtCacheSize    int                 `toml:"art_cache_size"`
+	ArtBudgetMB     float64             `toml:"art_budget_mb"`
+	ArtLookup       bool                `toml:"art_lookup"`
+	Compact         bool                `toml:"compact"`
+	StuckTimeout    time.Duration       `toml:"stuck_timeout"`
+	StuckNudge      bool                `toml:"stuck_nudge"`
+	Progress        ProgressConfig      `toml:"progress"`
+	Spotify         SpotifyConfig       `toml:"spotify"`
+	Backend         string              `toml:"backend"`
+	MPD             MPDConfig           `toml:"mpd"`
+	DBusAddress     string              `toml:"dbus_address"`
+	SettleDelay     time.Duration       `toml:"settle_delay"`
+	Refresh         RefreshConfig       `toml:"refresh"`
+	ExportMPRIS     bool                `toml:"export_mpris"`
+	Art             ArtConfig           `toml:"art"`
+	Wallpaper       WallpaperConfig     `toml:"wallpaper"`
+	Notify          []NotifyConfig      `toml:"notify"`
+	EventLog        string              `toml:"event_log"`
+	Overrides       []Override          `toml:"override"`
+	ReduceMotion    bool                `toml:"reduce_motion"`
+	Silent          bool                `toml:"silent"`
+	NowPlaying      NowPlayingConfig    `toml:"now_playing"`
+	Terminal        TerminalConfig      `toml:"terminal"`
+	History         HistoryConfig       `toml:"history"`
+	UpNext          bool                `toml:"up_next"`
+	Lock            LockConfig          `toml:"lock"`
+	Audio           AudioConfig         `toml:"audio"`
+	Visualizer      string              `toml:"visualizer"`
+	LevelMeter      bool                `toml:"level_meter"`
+	NoColor         bool                `toml:"no_color"`
+	ASCII           bool                `toml:"ascii"`
+	LogLevel        string              `toml:"log_level"`
+	Network         NetworkConfig       `toml:"network"`
+	Keys            map[string]string   `toml:"keys"`
+}
+
+// ArtConfig holds the options passed to chafa when rendering covers.
+type ArtConfig struct {
+	Size    int    `toml:"size"`
+	Symbols string `toml:"symbols"`
+	Dither  string `toml:"dither"`
+	Work    int    `toml:"work"`
+	Colors  string `toml:"colors"`
+}
+
+// NetworkConfig tunes the HTTP client that fetches covers and talks to the
+// Web API and notification services; proxies come from the usual
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY. Timeout bounds each request and
+// MaxArtMB the size of a cover (0 for no limit). CABundle names a PEM file
+// of certificate authorities to trust besides the system's, which mqtts
+// brokers are checked against too.
+type NetworkConfig struct {
+	Timeout  time.Duration `toml:"timeout"`
+	MaxArtMB float64       `toml:"max_art_mb"`
+	CABundle string        `toml:"ca_bundle"`
+}
+
+// Default returns the settings used for anything config.toml leaves out.
+func Default() Config {
+	return Config{
+		Layout:          "art-left",
+		Border:          "none",
+		Margin:          2,
+		HorizontalAlign: "center",
+		VerticalAlign:   "bottom",
+		Theme:           "auto",
+		LogLevel:        "info",
+		ArtAccent:       true,
+		Background:      "auto",
+		MinContrast:     3,
+		TrackCacheTTL:   7 * 24 * time.Hour,
+		ArtCacheSize:    200,
+		ArtLookup:       true,
+		SettleDelay:     750 * time.Millisecond,
+		Backend:         defaultBackend(),
+		Spotify: SpotifyConfig{
+			RedirectURI: "http://127.0.0.1:8888/callback",
+		},
+		Refresh: RefreshConfig{
+			Playing: 100 * time.Millisecond,
+			Paused:  time.Second,
+			Idle:    5 * time.Second,
+		},
+		Art: ArtConfig{
+			Size:    18,
+			Symbols: "block",
+			Dither:  "none",
+			Work:    5,
+			Colors:  "auto",
+		},
+		NowPlaying: NowPlayingConfig{
+			Format: "{artist} – {title}",
+		},
+		Wallpaper: WallpaperConfig{
+			Width:  1920,
+			Height: 1080,
+		},
+		Terminal: TerminalConfig{
+			Title:    true,
+			Progress: "auto",
+			Bidi:     "auto",
+			Colors:   "auto",
+		},
+		UpNext: true,
+		Network: NetworkConfig{
+			Timeout:  30 * time.Second,
+			MaxArtMB: 10,
+		},
+		History: HistoryConfig{
+			Size:     50,
+			Relative: true,
+			Store:    "sqlite",
+		},
+		StuckTimeout: 10 * time.Second,
+		Progress: ProgressConfig{
+			Style:    "smooth",
+			Time:     "elapsed",
+			Gradient: true,
+			Album:    true,
+		},
+	}
+}
+
+// defaultBackend is AppleScript on macOS and the media controls on Windows,
+// which have no D-Bus, and MPRIS everywhere else.
+func defaultBackend() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "applescript"
+	case "windows":
+		return "gsmtc"
+	}
+	return "mpris"
+}
+
+// Dir is sptsong's config directory under $XDG_CONFIG_HOME.
+func Dir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "sptsong")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "sptsong")
+}
+
+// StateDir is where sptsong keeps its log, under $XDG_STATE_HOME.
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "sptsong")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".local", "state", "sptsong")
+}
+
+// ThemesDir holds theme files saved by the theme editor, one <name>.toml per
+// theme.
+func ThemesDir() string {
+	return filepath.Join(Dir(), "themes")
+}
+
+// WalColors is where pywal writes the palette it derived from the
+// wallpaper, read for the "wal" theme.
+func WalColors() string {
+	cache := os.Getenv("XDG_CACHE_HOME")
+	if cache == "" {
+		home, _ := os.UserHomeDir()
+		cache = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cache, "wal", "colors.json")
+}
+
+// Load reads config.toml on top of the defaults, then the position saved by
+// SavePosition. A missing file is not an error.
+func Load() (Config, error) {
+	cfg := Default()
+	for _, name := range []string{"config.toml", positionFile} {
+		_, err := toml.DecodeFile(filepath.Join(Dir(), name), &cfg)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return cfg, err
+		}
+	}
+	if address := os.Getenv("SPTSONG_DBUS_ADDRESS"); address != "" {
+		cfg.DBusAddress = address
+	}
+	// The accessibility switches can be set once for every program in the
+	// environment, and then win over the file.
+	if on, err := strconv.ParseBool(os.Getenv("SPTSONG_REDUCE_MOTION")); err == nil {
+		cfg.ReduceMotion = on
+	}
+	if on, err := strconv.ParseBool(os.Getenv("SPTSONG_SILENT")); err == nil {
+		cfg.Silent = on
+	}
+	return cfg, nil
+}
+
+// positionFile holds the position the widget was last moved to by hand. It
+// is kept apart from config.toml so saving it never rewrites the user's
+// file, and wins over config.toml's alignment. Delete it to go back to that.
+const positionFile = "position.toml"
+
+// SavePosition remembers the widget's alignment for future runs, with the x
+// and y used by "manual" alignment.
+func SavePosition(horizontal, vertical string, x, y int) error {
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return err
+	}
+	data := fmt.Sprintf("# Written by sptsong when the display is moved by hand.\n"+
+		"horizontal_align = %q\nvertical_align = %q\nx = %d\ny = %d\n", horizontal, vertical, x, y)
+	return os.WriteFile(filepath.Join(Dir(), positionFile), []byte(data), 0o644)
+}
+
+// ConfiguredAlignment returns the alignment set in config.toml, ignoring the
+// position saved by SavePosition.
+func ConfiguredAlignment() (horizontal, vertical string) {
+	cfg := Default()
+	toml.DecodeFile(filepath.Join(Dir(), "config.toml"), &cfg)
+	return cfg.HorizontalAlign, cfg.VerticalAlign
+}
+
+// NowPlayingConfig names files kept in sync with the current track, for
+// OBS text and image sources. Empty paths are not written.
+type NowPlayingConfig struct {
+	TextFile string `toml:"text_file"`
+	Format   string `toml:"format"`
+	ArtFile  string `toml:"art_file"`
+}
+
+// TerminalConfig is what the display tells the terminal emulator it runs
+// in. Title puts the current track in the window title. Progress shows the
+// track's position in the tab or taskbar with OSC 9;4: "on", "off", or
+// "auto" for the terminals known to support it. Bidi reorders right-to-left
+// track names for display: "on", "off", or "auto" to leave it to the
+// terminals that do it themselves. Colors is the color depth to draw with:
+// "16", "256", "full" (24-bit), or "auto" to ask the terminal's environment.
+type TerminalConfig struct {
+	Title    bool   `toml:"title"`
+	Progress string `toml:"progress"`
+	Bidi     string `toml:"bidi"`
+	Colors   string `toml:"colors"`
+}
+
+// HistoryConfig sizes the history pane. Persist keeps the history in the
+// cache directory across restarts; otherwise it covers this session only.
+// Relative shows when tracks played as "2 h ago" rather than the time.
+// Store is where a persisted history goes: "sqlite", "jsonl", or
+// "postgres" at the connection string DSN, for one history of several
+// machines. DSN can also name the file of the other two.
+type HistoryConfig struct {
+	Size     int    `toml:"size"`
+	Persist  bool   `toml:"persist"`
+	Relative bool   `toml:"relative"`
+	Store    string `toml:"store"`
+	DSN      string `toml:"dsn"`
+}
+
+// LockConfig follows the screen lock through logind. Pause pauses playback
+// when the session locks; Resume starts it again on unlock if the lock was
+// what paused it.
+type LockConfig struct {
+	Pause  bool `toml:"pause"`
+	Resume bool `toml:"resume"`
+}
+
+// AudioConfig reacts to the audio output. PauseOnDisconnect pauses playback
+// when the output in use goes away, such as Bluetooth headphones dropping
+// or headphones coming out of the jack; it needs pactl.
+type AudioConfig struct {
+	PauseOnDisconnect bool `toml:"pause_on_disconnect"`
+}
+
+type WallpaperConfig struct {
+	Width   int    `toml:"width"`
+	Height  int    `toml:"height"`
+	Output  string `toml:"output"`
+	Command string `toml:"command"`
+}
+
+// RefreshConfig sets how often the display polls the player in each state.
+// Slower rates while paused or with no player cut wakeups on laptops.
+type RefreshConfig struct {
+	Playing time.Duration `toml:"playing"`
+	Paused  time.Duration `toml:"paused"`
+	Idle    time.Duration `toml:"idle"`
+}
+
+// MPDConfig locates the server for the mpd backend. An empty Host uses
+// $MPD_HOST and $MPD_PORT, then localhost:6600. MusicDir is the server's
+// music directory as seen from here, for the file browser.
+type MPDConfig struct {
+	Host     string `toml:"host"`
+	MusicDir string `toml:"music_dir"`
+}
+
+// NotifyConfig is one [[notify]] sink: "desktop", "jsonl" appending to the
+// file at Path, "webhook", "discord" or "slack" posting to URL, or "mqtt"
+// publishing under Topic on the broker at URL, with Home Assistant discovery
+// if Discovery is set. Events
+// limits the sink to some of "track", "paused", "resumed", "seek" and
+// "stuck"; empty means all of them.
+type NotifyConfig struct {
+	Type   string   `toml:"type"`
+	URL    string   `toml:"url"`
+	Path   string   `toml:"path"`
+	Events []string `toml:"events"`
+	Silent bool     `toml:"silent"`
+
+	Topic     string `toml:"topic"`
+	Discovery bool   `toml:"discovery"`
+}
+
+// Override corrects the metadata of mis-tagged tracks. Every match field
+// that is set must equal the player's value, ignoring case; the fields set
+// in Set then replace the player's. TrackID takes the MPRIS form or a
+// spotify:track: URI.
+type Override struct {
+	TrackID string         `toml:"track_id"`
+	Title   string         `toml:"title"`
+	Artist  string         `toml:"artist"`
+	Album   string         `toml:"album"`
+	Set     OverrideFields `toml:"set"`
+}
+
+type OverrideFields struct {
+	Title  string `toml:"title"`
+	Artist string `toml:"artist"`
+	Album  string `toml:"album"`
+}
+
+// SpotifyConfig is the Web API app sptsong logs in with. Register an app at
+// developer.spotify.com with RedirectURI as its redirect URI.
+type SpotifyConfig struct {
+	ClientID    string `toml:"client_id"`
+	RedirectURI string `toml:"redirect_uri"`
+}
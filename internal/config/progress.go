@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type ProgressConfig struct {
+	Style    string   `toml:"style"`
+	Width    BarWidth `toml:"width"`
+	Time     string   `toml:"time"`
+	Percent  bool     `toml:"percent"`
+	Gradient bool     `toml:"gradient"`
+	Album    bool     `toml:"album"`
+}
+
+// BarWidth is a progress bar width in cells, or zero for "auto", which fills
+// the space available to the bar.
+type BarWidth int
+
+func (w *BarWidth) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case int64:
+		*w = BarWidth(v)
+	case string:
+		if v == "auto" {
+			*w = 0
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("progress width must be \"auto\" or a number, got %q", v)
+		}
+		*w = BarWidth(n)
+	default:
+		return fmt.Errorf("progress width must be \"auto\" or a number, got %v", value)
+	}
+	return nil
+}
+
+// Resolve returns the bar width to use given the space available to it.
+func (w BarWidth) Resolve(available int) int {
+	if w <= 0 || int(w) > available {
+		return available
+	}
+	return int(w)
+}
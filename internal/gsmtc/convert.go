@@ -0,0 +1,104 @@
+package gsmtc
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values of GlobalSystemMediaTransportControlsSessionPlaybackStatus.
+const (
+	statusClosed = iota
+	statusOpened
+	statusChanging
+	statusStopped
+	statusPlaying
+	statusPaused
+)
+
+// Values of MediaPlaybackAutoRepeatMode.
+const (
+	repeatNone = iota
+	repeatTrack
+	repeatList
+)
+
+// unixEpoch is 1970-01-01 as a WinRT DateTime, in 100 ns ticks since 1601.
+const unixEpoch = 116444736000000000
+
+// status maps a session's playback status onto MPRIS. A session changing
+// tracks counts as paused, so the position doesn't run on meanwhile.
+func status(s int32) string {
+	switch s {
+	case statusPlaying:
+		return "Playing"
+	case statusPaused, statusChanging:
+		return "Paused"
+	}
+	return "Stopped"
+}
+
+// loop maps an auto repeat mode onto an MPRIS loop status.
+func loop(mode int32) string {
+	switch mode {
+	case repeatTrack:
+		return "Track"
+	case repeatList:
+		return "Playlist"
+	}
+	return "None"
+}
+
+// duration converts a WinRT TimeSpan, in 100 ns ticks.
+func duration(ticks int64) time.Duration {
+	return time.Duration(ticks) * 100
+}
+
+// timeSpan converts a duration to a WinRT TimeSpan.
+func timeSpan(d time.Duration) int64 {
+	return int64(d / 100)
+}
+
+// dateTime converts a WinRT DateTime; zero, which apps that never set it
+// report, is the zero time.
+func dateTime(ticks int64) time.Time {
+	if ticks == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, (ticks-unixEpoch)*100)
+}
+
+// trackID makes up a track id, since the media controls have none: the
+// same title, artist and album from the same app make the same id.
+func trackID(app, title, artist, album string) string {
+	h := fnv.New64a()
+	for _, s := range []string{app, title, artist, album} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return "/com/microsoft/gsmtc/track/" + strconv.FormatUint(h.Sum64(), 16)
+}
+
+// isSpotify reports whether an app user model id is Spotify's, from the
+// installer ("Spotify.exe") or the Microsoft Store
+// ("SpotifyAB.SpotifyMusic_zpdnekdrzrea0!Spotify").
+func isSpotify(app string) bool {
+	return strings.Contains(strings.ToLower(app), "spotify")
+}
+
+// appName turns an app user model id into a name to show: the application
+// part of a packaged app's id, or the executable without ".exe".
+func appName(app string) string {
+	if isSpotify(app) {
+		return "Spotify"
+	}
+	if _, rest, ok := strings.Cut(app, "!"); ok {
+		app = rest
+	}
+	app = app[strings.LastIndexAny(app, `\/`)+1:]
+	if len(app) > 4 && strings.EqualFold(app[len(app)-4:], ".exe") {
+		app = app[:len(app)-4]
+	}
+	return app
+}
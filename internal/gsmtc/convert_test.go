@@ -0,0 +1,74 @@
+package gsmtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusAndLoop(t *testing.T) {
+	for s, want := range map[int32]string{
+		statusClosed:   "Stopped",
+		statusOpened:   "Stopped",
+		statusChanging: "Paused",
+		statusStopped:  "Stopped",
+		statusPlaying:  "Playing",
+		statusPaused:   "Paused",
+	} {
+		if got := status(s); got != want {
+			t.Errorf("status(%d) = %q, want %q", s, got, want)
+		}
+	}
+	for mode, want := range map[int32]string{repeatNone: "None", repeatTrack: "Track", repeatList: "Playlist"} {
+		if got := loop(mode); got != want {
+			t.Errorf("loop(%d) = %q, want %q", mode, got, want)
+		}
+	}
+}
+
+func TestTimes(t *testing.T) {
+	if got := duration(2_500_000); got != 250*time.Millisecond {
+		t.Errorf("duration = %v, want 250ms", got)
+	}
+	if got := timeSpan(90 * time.Second); got != 900_000_000 {
+		t.Errorf("timeSpan = %d, want 900000000", got)
+	}
+	want := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+	if got := dateTime(unixEpoch + want.UnixNano()/100); !got.Equal(want.Truncate(100)) {
+		t.Errorf("dateTime = %v, want %v", got, want)
+	}
+	if got := dateTime(0); !got.IsZero() {
+		t.Errorf("dateTime(0) = %v, want the zero time", got)
+	}
+}
+
+func TestTrackID(t *testing.T) {
+	a := trackID("Spotify.exe", "Song", "Artist", "Album")
+	if a != trackID("Spotify.exe", "Song", "Artist", "Album") {
+		t.Error("the same track got two ids")
+	}
+	for _, other := range []string{
+		trackID("Spotify.exe", "Song", "Artist", "Other"),
+		trackID("msedge.exe", "Song", "Artist", "Album"),
+		// The fields are kept apart.
+		trackID("Spotify.exe", "SongA", "rtist", "Album"),
+	} {
+		if other == a {
+			t.Errorf("different tracks share the id %s", a)
+		}
+	}
+}
+
+func TestAppName(t *testing.T) {
+	for app, want := range map[string]string{
+		"Spotify.exe": "Spotify",
+		"SpotifyAB.SpotifyMusic_zpdnekdrzrea0!Spotify":          "Spotify",
+		"Microsoft.ZuneMusic_8wekyb3d8bbwe!Microsoft.ZuneMusic": "Microsoft.ZuneMusic",
+		"msedge.exe":          "msedge",
+		`C:\Tools\foobar.EXE`: "foobar",
+		"308046B0AF4A39CB":    "308046B0AF4A39CB",
+	} {
+		if got := appName(app); got != want {
+			t.Errorf("appName(%q) = %q, want %q", app, got, want)
+		}
+	}
+}
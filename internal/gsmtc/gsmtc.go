@@ -0,0 +1,487 @@
+//go:build windows
+
+// Package gsmtc is the Windows player backend, reading and controlling
+// Spotify, or whatever app is playing, through the System Media Transport
+// Controls: the GlobalSystemMediaTransportControlsSessionManager behind the
+// media flyout.
+package gsmtc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+)
+
+var ErrNoSession = errors.New("no app is sharing what it plays with Windows")
+
+const managerClass = "Windows.Media.Control.GlobalSystemMediaTransportControlsSessionManager"
+
+// IGlobalSystemMediaTransportControlsSessionManagerStatics
+var iidManagerStatics = windows.GUID{Data1: 0x2050c4ee, Data2: 0x11a0, Data3: 0x57de, Data4: [8]byte{0xae, 0xd7, 0xc9, 0x7c, 0x70, 0x33, 0x82, 0x45}}
+
+// Vtable slots of the Windows.Media.Control interfaces used, and of the
+// collections and streams they return.
+const (
+	managerRequest = 6 // ...ManagerStatics.RequestAsync
+
+	managerCurrentSession = 6
+	managerSessions       = 7
+
+	sessionApp             = 6
+	sessionMediaProperties = 7
+	sessionTimeline        = 8
+	sessionPlaybackInfo    = 9
+	sessionPlay            = 10
+	sessionPause           = 11
+	sessionStop            = 12
+	sessionNext            = 16
+	sessionPrevious        = 17
+	sessionPlayPause       = 20
+	sessionPosition        = 24
+
+	propertiesTitle       = 6
+	propertiesAlbumArtist = 8
+	propertiesArtist      = 9
+	propertiesAlbum       = 10
+	propertiesThumbnail   = 12
+
+	timelineStart    = 6
+	timelineEnd      = 7
+	timelinePosition = 10
+	timelineUpdated  = 11
+
+	playbackStatus  = 7
+	playbackRepeat  = 9
+	playbackShuffle = 11
+
+	vectorGetAt = 6
+	vectorSize  = 7
+
+	streamReferenceOpen = 6
+)
+
+// maxThumbnail is the largest cover read from an app.
+const maxThumbnail = 16 << 20
+
+// PollInterval is how often Client reads the session. In between, the
+// position is extrapolated from when the app last reported it.
+const PollInterval = time.Second
+
+// Client implements mpris.Player on the media controls. The Windows Runtime
+// is only called from one goroutine, locked to its thread: the session is
+// read there in the background, and commands are handed to it.
+type Client struct {
+	artDir string
+	start  sync.Once
+	ready  chan struct{}
+	calls  chan command
+
+	mu       sync.Mutex
+	state    *mpris.Metadata
+	app      string
+	position time.Duration
+	updated  time.Time
+	err      error
+}
+
+// command is a call for the session, with the channel its error goes back
+// on.
+type command struct {
+	run  func(*controls) error
+	done chan error
+}
+
+// New returns a Client that keeps the cover of the playing track in artDir.
+func New(artDir string) *Client {
+	return &Client{artDir: artDir, ready: make(chan struct{}), calls: make(chan command)}
+}
+
+// poll reads the session every PollInterval, or as soon as a command was
+// run, for as long as the program runs.
+func (c *Client) poll() {
+	defer guard.Recover()
+	runtime.LockOSThread()
+	w, initErr := open(c.artDir)
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		r := reading{err: initErr}
+		if initErr == nil {
+			r = w.read()
+		}
+		c.mu.Lock()
+		c.state, c.position, c.updated, c.err = r.state, r.position, r.updated, r.err
+		if r.app != "" {
+			c.app = r.app
+		}
+		c.mu.Unlock()
+		if first {
+			close(c.ready)
+		}
+		select {
+		case <-ticker.C:
+		case call := <-c.calls:
+			if initErr != nil {
+				call.done <- initErr
+			} else {
+				call.done <- call.run(w)
+			}
+		}
+	}
+}
+
+// do runs f on the polling goroutine and reads the session again after.
+func (c *Client) do(f func(*controls) error) error {
+	c.start.Do(func() { go c.poll() })
+	done := make(chan error, 1)
+	c.calls <- command{run: f, done: done}
+	return <-done
+}
+
+// Metadata returns the session last read, with the position moved on by the
+// time since the app reported it. The first call waits for the first read.
+func (c *Client) Metadata() (*mpris.Metadata, error) {
+	c.start.Do(func() { go c.poll() })
+	<-c.ready
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	metadata := *c.state
+	position := c.position
+	if metadata.Status == "Playing" && !c.updated.IsZero() {
+		position += max(time.Since(c.updated), 0)
+	}
+	metadata.Elapsed = min(position, metadata.Duration)
+	metadata.Position = int64(metadata.Elapsed / time.Second)
+	return &metadata, nil
+}
+
+// Identity names the app whose session was read last.
+func (c *Client) Identity() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.app == "" {
+		return "Spotify"
+	}
+	return appName(c.app)
+}
+
+// SetVolume fails: the media controls leave volume to the system mixer.
+func (c *Client) SetVolume(float64) error {
+	return errors.New("gsmtc: the media controls can't set the volume")
+}
+
+// commands are the session's commands that MPRIS methods without arguments
+// map onto.
+var commands = map[string]int{
+	"Play":      sessionPlay,
+	"Pause":     sessionPause,
+	"Stop":      sessionStop,
+	"PlayPause": sessionPlayPause,
+	"Next":      sessionNext,
+	"Previous":  sessionPrevious,
+}
+
+// Call maps MPRIS Player methods onto the session's commands. Seek and
+// SetPosition take microseconds, as in MPRIS.
+func (c *Client) Call(method string, args ...any) error {
+	if slot, ok := commands[method]; ok {
+		return c.do(func(w *controls) error {
+			return w.command(method, func(session *object, op **object) error {
+				return session.call(slot, uintptr(unsafe.Pointer(op)))
+			})
+		})
+	}
+	switch method {
+	case "Seek", "SetPosition":
+		us, ok := firstInt64(args)
+		if !ok {
+			return fmt.Errorf("gsmtc: %s takes microseconds as int64", method)
+		}
+		offset := time.Duration(us) * time.Microsecond
+		return c.do(func(w *controls) error {
+			return w.command(method, func(session *object, op **object) error {
+				timeline, err := w.timeline(session)
+				if err != nil {
+					return err
+				}
+				target := timeline.start + offset
+				if method == "Seek" {
+					target += timeline.position
+					if status := c.status(); status == "Playing" && !timeline.updated.IsZero() {
+						target += max(time.Since(timeline.updated), 0)
+					}
+				}
+				position := timeSpan(min(max(target, timeline.start), timeline.end))
+				if unsafe.Sizeof(uintptr(0)) == 8 {
+					return session.call(sessionPosition, uintptr(position), uintptr(unsafe.Pointer(op)))
+				}
+				// An INT64 takes two arguments on 32-bit Windows.
+				return session.call(sessionPosition, uintptr(position), uintptr(uint64(position)>>32), uintptr(unsafe.Pointer(op)))
+			})
+		})
+	}
+	return fmt.Errorf("gsmtc: %s is not supported", method)
+}
+
+// status is the playback status last read.
+func (c *Client) status() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == nil {
+		return ""
+	}
+	return c.state.Status
+}
+
+// controls holds the session manager on the polling goroutine's thread.
+type controls struct {
+	manager *object
+	artDir  string
+	// cover is the file holding the cover of the track coverID.
+	coverID string
+	cover   string
+}
+
+// open joins the Windows Runtime and asks for the session manager.
+func open(artDir string) (*controls, error) {
+	if err := initialize(); err != nil {
+		return nil, fmt.Errorf("gsmtc: %w", err)
+	}
+	statics, err := factory(managerClass, &iidManagerStatics)
+	if err != nil {
+		// The media controls came with Windows 10 1809.
+		return nil, fmt.Errorf("gsmtc: the media controls need Windows 10 1809 or later: %w", err)
+	}
+	defer statics.release()
+	var op, manager *object
+	if err := statics.call(managerRequest, uintptr(unsafe.Pointer(&op))); err != nil {
+		return nil, fmt.Errorf("gsmtc: %w", err)
+	}
+	if err := await(op, unsafe.Pointer(&manager)); err != nil {
+		return nil, fmt.Errorf("gsmtc: %w", err)
+	}
+	return &controls{manager: manager, artDir: artDir}, nil
+}
+
+// session returns Spotify's session, or the one Windows shows in the media
+// flyout if Spotify has none, with its app user model id. The caller
+// releases it.
+func (w *controls) session() (*object, string, error) {
+	var sessions *object
+	if err := w.manager.call(managerSessions, uintptr(unsafe.Pointer(&sessions))); err != nil {
+		return nil, "", err
+	}
+	defer sessions.release()
+	var n uint32
+	if err := sessions.call(vectorSize, uintptr(unsafe.Pointer(&n))); err != nil {
+		return nil, "", err
+	}
+	for i := range n {
+		var session *object
+		if err := sessions.call(vectorGetAt, uintptr(i), uintptr(unsafe.Pointer(&session))); err != nil {
+			return nil, "", err
+		}
+		if app, err := session.string(sessionApp); err == nil && isSpotify(app) {
+			return session, app, nil
+		}
+		session.release()
+	}
+
+	var session *object
+	if err := w.manager.call(managerCurrentSession, uintptr(unsafe.Pointer(&session))); err != nil {
+		return nil, "", err
+	}
+	if session == nil {
+		return nil, "", ErrNoSession
+	}
+	app, err := session.string(sessionApp)
+	if err != nil {
+		session.release()
+		return nil, "", err
+	}
+	return session, app, nil
+}
+
+// reading is one read of the session: the track and player state, with the
+// position apart as of when the app last reported it.
+type reading struct {
+	state    *mpris.Metadata
+	app      string
+	position time.Duration
+	updated  time.Time
+	err      error
+}
+
+// read reads the session's track, timeline and playback state.
+func (w *controls) read() reading {
+	session, app, err := w.session()
+	if err != nil {
+		return reading{err: err}
+	}
+	defer session.release()
+
+	var op, properties *object
+	if err := session.call(sessionMediaProperties, uintptr(unsafe.Pointer(&op))); err != nil {
+		return reading{err: err}
+	}
+	if err := await(op, unsafe.Pointer(&properties)); err != nil {
+		return reading{err: err}
+	}
+	defer properties.release()
+	// Volume is the system mixer's, which leaves the app's at full.
+	metadata := &mpris.Metadata{Loop: "None", Volume: 1}
+	for slot, field := range map[int]*string{propertiesTitle: &metadata.Title, propertiesArtist: &metadata.Artist, propertiesAlbum: &metadata.Album} {
+		if *field, err = properties.string(slot); err != nil {
+			return reading{err: err}
+		}
+	}
+	if metadata.Artist == "" {
+		metadata.Artist, _ = properties.string(propertiesAlbumArtist)
+	}
+	if metadata.Artist == "" {
+		metadata.Artist = "Unknown Artist"
+	}
+	metadata.TrackID = trackID(app, metadata.Title, metadata.Artist, metadata.Album)
+	metadata.ArtURL = w.thumbnail(metadata.TrackID, properties)
+
+	timeline, err := w.timeline(session)
+	if err != nil {
+		return reading{err: err}
+	}
+	metadata.Duration = max(timeline.end-timeline.start, 0)
+	metadata.Length = int64(metadata.Duration / time.Second)
+
+	var info *object
+	if err := session.call(sessionPlaybackInfo, uintptr(unsafe.Pointer(&info))); err != nil {
+		return reading{err: err}
+	}
+	defer info.release()
+	var playback, repeat int32
+	var shuffle bool
+	if err := info.call(playbackStatus, uintptr(unsafe.Pointer(&playback))); err != nil {
+		return reading{err: err}
+	}
+	metadata.Status = status(playback)
+	if ok, _ := info.reference(playbackRepeat, unsafe.Pointer(&repeat)); ok {
+		metadata.Loop = loop(repeat)
+	}
+	info.reference(playbackShuffle, unsafe.Pointer(&shuffle))
+	metadata.Shuffle = shuffle
+
+	return reading{
+		state:    metadata,
+		app:      app,
+		position: max(timeline.position-timeline.start, 0),
+		updated:  timeline.updated,
+	}
+}
+
+// timelineState is a session's timeline, in its own terms: apps may start
+// theirs at other than zero.
+type timelineState struct {
+	start, end, position time.Duration
+	updated              time.Time
+}
+
+func (w *controls) timeline(session *object) (timelineState, error) {
+	var timeline *object
+	if err := session.call(sessionTimeline, uintptr(unsafe.Pointer(&timeline))); err != nil {
+		return timelineState{}, err
+	}
+	defer timeline.release()
+	var start, end, position, updated int64
+	for slot, value := range map[int]*int64{timelineStart: &start, timelineEnd: &end, timelinePosition: &position, timelineUpdated: &updated} {
+		if err := timeline.call(slot, uintptr(unsafe.Pointer(value))); err != nil {
+			return timelineState{}, err
+		}
+	}
+	return timelineState{duration(start), duration(end), duration(position), dateTime(updated)}, nil
+}
+
+// thumbnail returns the path of the track's cover, writing the app's
+// thumbnail to artDir the first time the track is read and removing the
+// previous track's. It is "" while the app has no thumbnail to give.
+func (w *controls) thumbnail(trackID string, properties *object) string {
+	if trackID == w.coverID {
+		return w.cover
+	}
+	var reference *object
+	if err := properties.call(propertiesThumbnail, uintptr(unsafe.Pointer(&reference))); err != nil || reference == nil {
+		// Apps often set the cover after the title; look again next time.
+		return ""
+	}
+	defer reference.release()
+
+	// The track counts as read from here, cover or not, so a thumbnail that
+	// can't be read isn't tried every second.
+	if w.cover != "" {
+		os.Remove(w.cover)
+	}
+	w.coverID, w.cover = trackID, ""
+	var op, stream *object
+	if err := reference.call(streamReferenceOpen, uintptr(unsafe.Pointer(&op))); err != nil {
+		return ""
+	}
+	if err := await(op, unsafe.Pointer(&stream)); err != nil {
+		return ""
+	}
+	defer stream.release()
+	data, err := readStream(stream, maxThumbnail)
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	if err := os.MkdirAll(w.artDir, 0o755); err != nil {
+		return ""
+	}
+	path := filepath.Join(w.artDir, "gsmtc-"+filepath.Base(trackID))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return ""
+	}
+	w.cover = path
+	return path
+}
+
+// command runs one of the session's Try...Async commands, which start
+// begins, and fails if the app turns it down.
+func (w *controls) command(method string, start func(session *object, op **object) error) error {
+	session, app, err := w.session()
+	if err != nil {
+		return err
+	}
+	defer session.release()
+	var op *object
+	if err := start(session, &op); err != nil {
+		return err
+	}
+	var done uint32
+	if err := await(op, unsafe.Pointer(&done)); err != nil {
+		return err
+	}
+	// The result is a boolean, in the first byte.
+	if done&0xff == 0 {
+		return fmt.Errorf("gsmtc: %s turned down %s", appName(app), method)
+	}
+	return nil
+}
+
+func firstInt64(args []any) (int64, bool) {
+	if len(args) != 1 {
+		return 0, false
+	}
+	v, ok := args[0].(int64)
+	return v, ok
+}
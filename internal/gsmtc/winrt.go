@@ -0,0 +1,230 @@
+//go:build windows
+
+package gsmtc
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The Windows Runtime is called into directly, through the vtables of its
+// interfaces, rather than through generated bindings.
+var (
+	combase = windows.NewLazySystemDLL("combase.dll")
+	shcore  = windows.NewLazySystemDLL("shcore.dll")
+
+	procRoInitialize                       = combase.NewProc("RoInitialize")
+	procRoGetActivationFactory             = combase.NewProc("RoGetActivationFactory")
+	procWindowsCreateString                = combase.NewProc("WindowsCreateString")
+	procWindowsDeleteString                = combase.NewProc("WindowsDeleteString")
+	procWindowsGetStringRawBuffer          = combase.NewProc("WindowsGetStringRawBuffer")
+	procCreateStreamOverRandomAccessStream = shcore.NewProc("CreateStreamOverRandomAccessStream")
+)
+
+var (
+	// IAsyncInfo, which every IAsyncOperation also implements.
+	iidAsyncInfo = windows.GUID{Data1: 0x00000036, Data4: [8]byte{0xc0, 0, 0, 0, 0, 0, 0, 0x46}}
+	iidStream    = windows.GUID{Data1: 0x0000000c, Data4: [8]byte{0xc0, 0, 0, 0, 0, 0, 0, 0x46}}
+)
+
+const (
+	roInitMultithreaded = 1
+
+	asyncStarted   = 0
+	asyncCompleted = 1
+	asyncCanceled  = 2
+
+	// awaitTimeout bounds the wait for an app to answer, so a hung one
+	// doesn't hang the display.
+	awaitTimeout = 5 * time.Second
+)
+
+// Methods of IUnknown, IAsyncInfo and IAsyncOperation<T>, by vtable slot.
+// Slots 3 to 5 of every WinRT interface are IInspectable's.
+const (
+	methodQueryInterface = 0
+	methodRelease        = 2
+	methodAsyncStatus    = 7
+	methodAsyncErrorCode = 8
+	methodAsyncCancel    = 9
+	methodGetResults     = 8
+)
+
+// object is a COM interface: a pointer to its table of methods.
+type object struct {
+	vtable *[64]uintptr
+}
+
+// call calls the object's method in slot method and turns a failed HRESULT
+// into an error. Out parameters are pointers to the results.
+//
+//go:uintptrescapes
+func (o *object) call(method int, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(o.vtable[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	return hresult(r)
+}
+
+// release drops a reference to the object; nil is left alone.
+func (o *object) release() {
+	if o != nil {
+		syscall.SyscallN(o.vtable[methodRelease], uintptr(unsafe.Pointer(o)))
+	}
+}
+
+// string reads a property that is a string.
+func (o *object) string(method int) (string, error) {
+	var h uintptr
+	if err := o.call(method, uintptr(unsafe.Pointer(&h))); err != nil {
+		return "", err
+	}
+	defer procWindowsDeleteString.Call(h)
+	return hstring(h), nil
+}
+
+// reference reads a property that is an IReference<T>, storing its value
+// in value. Apps that don't set the property return none, and ok is false.
+func (o *object) reference(method int, value unsafe.Pointer) (ok bool, err error) {
+	var ref *object
+	if err := o.call(method, uintptr(unsafe.Pointer(&ref))); err != nil || ref == nil {
+		return false, err
+	}
+	defer ref.release()
+	// IReference<T>.Value is its first method after IInspectable's.
+	return true, ref.call(6, uintptr(value))
+}
+
+// hresult turns an HRESULT into an error, nil for the success codes. Only
+// the low 32 bits of a return register hold it.
+func hresult(r uintptr) error {
+	if int32(r) >= 0 {
+		return nil
+	}
+	return syscall.Errno(uint32(r))
+}
+
+// newHString makes an HSTRING of s, which the caller deletes.
+func newHString(s string) (uintptr, error) {
+	u, err := windows.UTF16FromString(s)
+	if err != nil {
+		return 0, err
+	}
+	var h uintptr
+	r, _, _ := procWindowsCreateString.Call(uintptr(unsafe.Pointer(&u[0])), uintptr(len(u)-1), uintptr(unsafe.Pointer(&h)))
+	return h, hresult(r)
+}
+
+// hstring reads an HSTRING, of which the null handle is the empty string.
+func hstring(h uintptr) string {
+	if h == 0 {
+		return ""
+	}
+	var n uint32
+	buffer, _, _ := procWindowsGetStringRawBuffer.Call(h, uintptr(unsafe.Pointer(&n)))
+	if buffer == 0 {
+		return ""
+	}
+	return windows.UTF16ToString(unsafe.Slice(*(**uint16)(unsafe.Pointer(&buffer)), n))
+}
+
+// initialize joins the calling thread to the Windows Runtime's
+// multithreaded apartment, where asynchronous operations finish on their
+// own threads, with no message loop to run.
+func initialize() error {
+	if err := procRoInitialize.Find(); err != nil {
+		return err
+	}
+	r, _, _ := procRoInitialize.Call(roInitMultithreaded)
+	return hresult(r)
+}
+
+// factory returns the activation factory of a runtime class, as the
+// interface iid.
+func factory(class string, iid *windows.GUID) (*object, error) {
+	if err := procRoGetActivationFactory.Find(); err != nil {
+		return nil, err
+	}
+	name, err := newHString(class)
+	if err != nil {
+		return nil, err
+	}
+	defer procWindowsDeleteString.Call(name)
+	var f *object
+	r, _, _ := procRoGetActivationFactory.Call(name, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&f)))
+	if err := hresult(r); err != nil {
+		return nil, fmt.Errorf("%s: %w", class, err)
+	}
+	return f, nil
+}
+
+var errTimeout = errors.New("timed out waiting for the app")
+
+// await waits for the IAsyncOperation op to finish, stores its result in
+// result and releases it. It polls, so that nothing has to implement the
+// completion handler's interface.
+func await(op *object, result unsafe.Pointer) error {
+	defer op.release()
+	var info *object
+	if err := op.call(methodQueryInterface, uintptr(unsafe.Pointer(&iidAsyncInfo)), uintptr(unsafe.Pointer(&info))); err != nil {
+		return err
+	}
+	defer info.release()
+	deadline := time.Now().Add(awaitTimeout)
+	for delay := time.Millisecond; ; delay = min(delay*2, 50*time.Millisecond) {
+		var status int32
+		if err := info.call(methodAsyncStatus, uintptr(unsafe.Pointer(&status))); err != nil {
+			return err
+		}
+		switch status {
+		case asyncStarted:
+		case asyncCompleted:
+			return op.call(methodGetResults, uintptr(result))
+		case asyncCanceled:
+			return errors.New("operation canceled")
+		default:
+			var code int32
+			if err := info.call(methodAsyncErrorCode, uintptr(unsafe.Pointer(&code))); err != nil {
+				return err
+			}
+			return hresult(uintptr(uint32(code)))
+		}
+		if time.Now().After(deadline) {
+			info.call(methodAsyncCancel)
+			return errTimeout
+		}
+		time.Sleep(delay)
+	}
+}
+
+// readStream reads an IRandomAccessStream to its end, up to limit bytes,
+// through the IStream the shell wraps around it.
+func readStream(stream *object, limit int) ([]byte, error) {
+	if err := procCreateStreamOverRandomAccessStream.Find(); err != nil {
+		return nil, err
+	}
+	var s *object
+	r, _, _ := procCreateStreamOverRandomAccessStream.Call(uintptr(unsafe.Pointer(stream)), uintptr(unsafe.Pointer(&iidStream)), uintptr(unsafe.Pointer(&s)))
+	if err := hresult(r); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	var data []byte
+	buffer := make([]byte, 64<<10)
+	for {
+		// ISequentialStream.Read, right after IUnknown's methods.
+		var n uint32
+		if err := s.call(3, uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)), uintptr(unsafe.Pointer(&n))); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return data, nil
+		}
+		if data = append(data, buffer[:n]...); len(data) > limit {
+			return nil, fmt.Errorf("more than %d bytes", limit)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Package guard lets the program clean up after a panic in any of its
+// goroutines, those started by internal packages included. A panic ends the
+// program without running the deferred calls of the other goroutines, so the
+// goroutine that panicked has to do it.
+package guard
+
+import "sync/atomic"
+
+var hook atomic.Pointer[func()]
+
+// OnPanic sets f to run when a guarded goroutine panics, before the panic
+// carries on and ends the program.
+func OnPanic(f func()) {
+	hook.Store(&f)
+}
+
+// Recover is deferred at the top of a goroutine to guard it.
+func Recover() {
+	if r := recover(); r != nil {
+		if f := hook.Load(); f != nil {
+			(*f)()
+		}
+		panic(r)
+	}
+}
@@ -0,0 +1,31 @@
+package guard
+
+import "testing"
+
+func TestRecoverRunsHookAndPanics(t *testing.T) {
+	ran := false
+	OnPanic(func() { ran = true })
+	t.Cleanup(func() { hook.Store(nil) })
+
+	recovered := make(chan any)
+	go func() {
+		defer func() { recovered <- recover() }()
+		defer Recover()
+		panic("boom")
+	}()
+	if r := <-recovered; r != "boom" {
+		t.Errorf("panic after Recover = %v, want boom", r)
+	}
+	if !ran {
+		t.Error("hook didn't run")
+	}
+}
+
+func TestRecoverWithoutPanic(t *testing.T) {
+	OnPanic(func() { t.Error("hook ran without a panic") })
+	t.Cleanup(func() { hook.Store(nil) })
+
+	func() {
+		defer Recover()
+	}()
+}
@@ -0,0 +1,48 @@
+// Package history keeps the tracks played: in a SQLite database by
+// default, in a JSON lines file, or in a PostgreSQL database that several
+// machines can share.
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Entry is one track played.
+type Entry struct {
+	TrackID string    `json:"track_id,omitempty"`
+	Title   string    `json:"title"`
+	Artist  string    `json:"artist"`
+	Album   string    `json:"album,omitempty"`
+	Played  time.Time `json:"played"`
+	// Host is the machine the track played on, for stores shared by
+	// several.
+	Host string `json:"host,omitempty"`
+}
+
+// Store is where the history is kept.
+type Store interface {
+	// Recent returns the latest n entries, newest first.
+	Recent(ctx context.Context, n int) ([]Entry, error)
+	// Add records a track played.
+	Add(ctx context.Context, e Entry) error
+	Close() error
+}
+
+// Open opens the store of the given kind: "sqlite" or "jsonl" with the file
+// at location, or "postgres" with location as its connection string.
+func Open(kind, location string) (Store, error) {
+	switch kind {
+	case "sqlite":
+		return openSQLite(location)
+	case "jsonl":
+		return &jsonLines{path: location}, nil
+	case "postgres":
+		if location == "" {
+			return nil, fmt.Errorf("history store postgres needs a dsn")
+		}
+		return openPostgres(location)
+	}
+	return nil, fmt.Errorf("unknown history store %q, want sqlite, jsonl or postgres", kind)
+}
@@ -0,0 +1,68 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	for _, kind := range []string{"sqlite", "jsonl"} {
+		t.Run(kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history."+kind)
+			store, err := Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			start := time.Date(2026, 3, 14, 15, 30, 0, 0, time.FixedZone("CET", 3600))
+			for i, title := range []string{"One", "Two", "Three"} {
+				e := Entry{TrackID: "/t/" + title, Title: title, Artist: "Band", Played: start.Add(time.Duration(i) * time.Minute), Host: "box"}
+				if err := store.Add(ctx, e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store.Close()
+
+			// Reopened, as on the next start.
+			store, err = Open(kind, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			got, err := store.Recent(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 || got[0].Title != "Three" || got[1].Title != "Two" {
+				t.Fatalf("Recent(2) = %+v, want Three and Two", got)
+			}
+			if !got[0].Played.Equal(start.Add(2*time.Minute)) || got[0].Host != "box" || got[0].TrackID != "/t/Three" {
+				t.Errorf("Recent(2)[0] = %+v", got[0])
+			}
+		})
+	}
+}
+
+func TestJSONLinesSkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	data := `{"title": "One", "artist": "Band", "played": "2026-03-14T15:30:00Z"}` + "\n" + `{"title": "Tw`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := Open("jsonl", path)
+	got, err := store.Recent(context.Background(), 10)
+	if err != nil || len(got) != 1 || got[0].Title != "One" {
+		t.Errorf("Recent = %+v, %v; want just One", got, err)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	for _, kind := range []string{"postgres", "mysql"} {
+		if _, err := Open(kind, ""); err == nil {
+			t.Errorf("Open(%q, \"\") = nil error", kind)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// jsonLines appends each entry to a file as one JSON object per line, for
+// jq and friends.
+type jsonLines struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *jsonLines) Recent(ctx context.Context, n int) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			// A line cut short by a crash.
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	slices.Reverse(entries)
+	return entries, scanner.Err()
+}
+
+func (s *jsonLines) Add(ctx context.Context, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *jsonLines) Close() error { return nil }
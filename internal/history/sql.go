@@ -0,0 +1,93 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlStore keeps the history in a table of a SQL database. The queries
+// are the same for SQLite and PostgreSQL but for the type of played, which
+// is written in UTC so SQLite's text timestamps sort.
+type sqlStore struct {
+	db *sql.DB
+}
+
+const (
+	insertEntry   = `INSERT INTO history (played, host, track_id, title, artist, album) VALUES ($1, $2, $3, $4, $5, $6)`
+	recentEntries = `SELECT played, host, track_id, title, artist, album FROM history ORDER BY played DESC LIMIT $1`
+)
+
+func createTable(playedType string) string {
+	return `CREATE TABLE IF NOT EXISTS history (
+		played ` + playedType + ` NOT NULL,
+		host TEXT NOT NULL,
+		track_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		artist TEXT NOT NULL,
+		album TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS history_played ON history (played)`
+}
+
+// openSQLite opens the database file at path, creating it if needed.
+// SQLite needs cgo; a build without it fails here.
+func openSQLite(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	return initSQL(db, createTable("TIMESTAMP"))
+}
+
+// openPostgres connects to the database at dsn, such as
+// "postgres://me@nas/music".
+func openPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return initSQL(db, createTable("TIMESTAMPTZ"))
+}
+
+func initSQL(db *sql.DB, schema string) (Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlStore{db: db}, nil
+}
+
+func (s *sqlStore) Recent(ctx context.Context, n int) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx, recentEntries, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.Played, &e.Host, &e.TrackID, &e.Title, &e.Artist, &e.Album); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *sqlStore) Add(ctx context.Context, e Entry) error {
+	_, err := s.db.ExecContext(ctx, insertEntry, e.Played.UTC(), e.Host, e.TrackID, e.Title, e.Artist, e.Album)
+	return err
+}
+
+func (s *sqlStore) Close() error { return s.db.Close() }
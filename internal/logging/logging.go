@@ -0,0 +1,142 @@
+// Package logging writes sptsong's log: leveled key=value lines, kept in a
+// file that is rotated once it grows past a size cap so it never needs
+// cleaning up by hand.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseLevel reads a level as written in the config: "error", "warn",
+// "info" or "debug".
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "error":
+		return slog.LevelError, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q (error, warn, info or debug)", s)
+}
+
+// New returns a logger writing lines at level and above to w.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// File is a log file that moves itself aside to path.1, replacing the one
+// before, when a write would take it past MaxSize bytes. Several goroutines
+// may write to it.
+type File struct {
+	MaxSize int64
+
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+// Open opens the log at path for appending, creating it and its directory
+// if needed.
+func Open(path string, maxSize int64) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &File{MaxSize: maxSize, path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate moves the full log aside and starts a new one. f.mu must be held.
+func (f *File) rotate() error {
+	f.file.Close()
+	f.file = nil
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Limiter keeps a message that may repeat for as long as something stays
+// broken, such as a failing notification service, from filling the log: it
+// lets one through per Interval and counts the ones it holds back. Several
+// goroutines may use it.
+type Limiter struct {
+	Interval time.Duration
+
+	mu      sync.Mutex
+	last    time.Time
+	dropped int
+}
+
+// Allow reports whether a message may be logged now and, if so, how many
+// were held back since the last one that was.
+func (l *Limiter) Allow() (ok bool, dropped int) {
+	return l.allow(time.Now())
+}
+
+func (l *Limiter) allow(now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() && now.Sub(l.last) < l.Interval {
+		l.dropped++
+		return false, 0
+	}
+	dropped := l.dropped
+	l.last, l.dropped = now, 0
+	return true, dropped
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "sptsong.log")
+	f, err := Open(path, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first line\n", "second\n", "third line\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current, _ := os.ReadFile(path)
+	old, _ := os.ReadFile(path + ".1")
+	if string(current) != "third line\n" || string(old) != "first line\nsecond\n" {
+		t.Errorf("log = %q, rotated = %q", current, old)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	var b bytes.Buffer
+	level, err := ParseLevel("error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := New(&b, level)
+	logger.Info("quiet")
+	logger.Error("loud", "err", "boom")
+	if strings.Contains(b.String(), "quiet") || !strings.Contains(b.String(), `msg=loud err=boom`) {
+		t.Errorf("logged %q", b.String())
+	}
+
+	if level, _ := ParseLevel("DEBUG"); level != slog.LevelDebug {
+		t.Errorf("ParseLevel(DEBUG) = %v", level)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) gave no error")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := &Limiter{Interval: time.Minute}
+	start := time.Now()
+	for _, step := range []struct {
+		after   time.Duration
+		ok      bool
+		dropped int
+	}{
+		{0, true, 0},
+		{time.Second, false, 0},
+		{30 * time.Second, false, 0},
+		{time.Minute, true, 2},
+		{2 * time.Minute, true, 0},
+	} {
+		ok, dropped := l.allow(start.Add(step.after))
+		if ok != step.ok || dropped != step.dropped {
+			t.Errorf("after %v: allow = %v, %d; want %v, %d", step.after, ok, dropped, step.ok, step.dropped)
+		}
+	}
+}
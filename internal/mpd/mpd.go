@@ -0,0 +1,396 @@
+// Package mpd is a player backend speaking the MPD protocol, for MPD and
+// Mopidy users.
+package mpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+const defaultAddress = "localhost:6600"
+
+// Client implements mpris.Player over a single MPD connection, dialing again
+// whenever the connection drops.
+type Client struct {
+	address  string
+	password string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// New returns a client for the server at host, which may be "host:port",
+// "password@host:port" or a unix socket path. An empty host falls back to
+// $MPD_HOST and $MPD_PORT, then localhost:6600. Nothing is dialed until the
+// first request.
+func New(host string) *Client {
+	if host == "" {
+		host = os.Getenv("MPD_HOST")
+		if host != "" && !strings.HasPrefix(host, "/") && !strings.Contains(host, ":") {
+			port := os.Getenv("MPD_PORT")
+			if port == "" {
+				port = "6600"
+			}
+			host = net.JoinHostPort(host, port)
+		}
+	}
+	if host == "" {
+		host = defaultAddress
+	}
+
+	c := &Client{address: host}
+	if password, address, ok := strings.Cut(host, "@"); ok {
+		c.password, c.address = password, address
+	}
+	return c
+}
+
+func (c *Client) dial() error {
+	network := "tcp"
+	if strings.HasPrefix(c.address, "/") {
+		network = "unix"
+	}
+	conn, err := net.DialTimeout(network, c.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	greeting, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(greeting, "OK MPD ") {
+		conn.Close()
+		return fmt.Errorf("%s is not an MPD server", c.address)
+	}
+	c.conn, c.reader = conn, reader
+
+	if c.password != "" {
+		if _, err := c.exchange("password "+quote(c.password), ""); err != nil {
+			c.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn, c.reader = nil, nil
+	}
+}
+
+// command sends one command and returns the key/value pairs of its response,
+// dialing first if needed.
+func (c *Client) command(cmd string) (map[string]string, error) {
+	records, err := c.list(cmd, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return map[string]string{}, nil
+	}
+	return records[0], nil
+}
+
+// list sends one command and splits its response into records, a new one
+// starting at every start key, such as "file" for songs. An empty start
+// gives a single record.
+func (c *Client) list(cmd, start string) ([]map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return nil, err
+		}
+	}
+	records, err := c.exchange(cmd, start)
+	var ack *ackError
+	if err != nil && !errors.As(err, &ack) {
+		// The connection is in an unknown state; start over next time.
+		c.close()
+	}
+	return records, err
+}
+
+// ackError is an error reported by the server; the connection stays usable.
+type ackError struct{ message string }
+
+func (e *ackError) Error() string { return "mpd: " + e.message }
+
+// exchange writes cmd and reads the response up to its OK, split into
+// records as for list. c.mu must be held.
+func (c *Client) exchange(cmd, start string) ([]map[string]string, error) {
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := fmt.Fprintf(c.conn, "%s\n", cmd); err != nil {
+		return nil, err
+	}
+
+	var records []map[string]string
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "OK":
+			return records, nil
+		case strings.HasPrefix(line, "ACK "):
+			return nil, &ackError{strings.TrimPrefix(line, "ACK ")}
+		}
+		if key, value, ok := strings.Cut(line, ": "); ok {
+			if records == nil || key == start {
+				records = append(records, make(map[string]string))
+			}
+			// Keep the first of repeated tags such as Artist.
+			if record := records[len(records)-1]; !has(record, key) {
+				record[key] = value
+			}
+		}
+	}
+}
+
+func has(record map[string]string, key string) bool {
+	_, ok := record[key]
+	return ok
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Metadata combines MPD's status and currentsong into the MPRIS-shaped
+// snapshot the display uses. MPD has no cover URLs, so ArtURL is empty and
+// covers come from the album lookup.
+func (c *Client) Metadata() (*mpris.Metadata, error) {
+	status, err := c.command("status")
+	if err != nil {
+		return nil, err
+	}
+	song, err := c.command("currentsong")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := songMetadata(song)
+	metadata.Duration = duration(firstOf(status["duration"], song["duration"], song["Time"]))
+	metadata.Elapsed = duration(status["elapsed"])
+	metadata.Length = int64(metadata.Duration / time.Second)
+	metadata.Position = int64(metadata.Elapsed / time.Second)
+	metadata.Shuffle = status["random"] == "1"
+	metadata.Loop = "None"
+
+	switch status["state"] {
+	case "play":
+		metadata.Status = "Playing"
+	case "pause":
+		metadata.Status = "Paused"
+	default:
+		metadata.Status = "Stopped"
+	}
+	switch {
+	case status["repeat"] == "1" && status["single"] == "1":
+		metadata.Loop = "Track"
+	case status["repeat"] == "1":
+		metadata.Loop = "Playlist"
+	}
+	if volume, err := strconv.Atoi(status["volume"]); err == nil && volume >= 0 {
+		metadata.Volume = float64(volume) / 100
+	}
+	return metadata, nil
+}
+
+// songMetadata fills in the track fields of a song from currentsong or
+// playlistinfo.
+func songMetadata(song map[string]string) *mpris.Metadata {
+	metadata := &mpris.Metadata{
+		Title:  song["Title"],
+		Artist: song["Artist"],
+		Album:  song["Album"],
+		Length: int64(seconds(firstOf(song["duration"], song["Time"]))),
+	}
+	if id := song["Id"]; id != "" {
+		metadata.TrackID = "/org/musicpd/track/" + id
+	}
+	if metadata.Title == "" {
+		metadata.Title = song["file"]
+	}
+	if metadata.Artist == "" {
+		metadata.Artist = "Unknown Artist"
+	}
+	return metadata
+}
+
+// UpNext returns the songs after the current one in the queue, or with
+// random on the one MPD picked to play next.
+func (c *Client) UpNext(n int) ([]mpris.Metadata, error) {
+	status, err := c.command("status")
+	if err != nil {
+		return nil, err
+	}
+	var cmd string
+	if status["random"] == "1" {
+		if status["nextsong"] == "" {
+			return nil, nil
+		}
+		cmd = "playlistinfo " + status["nextsong"]
+	} else {
+		song, _ := strconv.Atoi(status["song"])
+		if status["song"] == "" {
+			song = -1
+		}
+		cmd = fmt.Sprintf("playlistinfo %d:%d", song+1, song+1+n)
+	}
+	songs, err := c.list(cmd, "file")
+	if err != nil {
+		var ack *ackError
+		if errors.As(err, &ack) {
+			// The range runs past the end of the queue.
+			return nil, nil
+		}
+		return nil, err
+	}
+	var queue []mpris.Metadata
+	for _, song := range songs[:min(len(songs), n)] {
+		queue = append(queue, *songMetadata(song))
+	}
+	return queue, nil
+}
+
+func (c *Client) Identity() string {
+	return "MPD"
+}
+
+// Call maps MPRIS Player methods onto MPD commands. Seek and SetPosition
+// take microseconds, as in MPRIS.
+func (c *Client) Call(method string, args ...any) error {
+	var cmd string
+	switch method {
+	case "Play":
+		cmd = "play"
+	case "Pause":
+		cmd = "pause 1"
+	case "PlayPause":
+		status, err := c.command("status")
+		if err != nil {
+			return err
+		}
+		cmd = "pause"
+		if status["state"] == "stop" {
+			cmd = "play"
+		}
+	case "Stop":
+		cmd = "stop"
+	case "Next":
+		cmd = "next"
+	case "Previous":
+		cmd = "previous"
+	case "Seek", "SetPosition":
+		if len(args) != 1 {
+			return fmt.Errorf("mpd: %s takes one argument", method)
+		}
+		us, ok := args[0].(int64)
+		if !ok {
+			return fmt.Errorf("mpd: %s takes microseconds as int64", method)
+		}
+		offset := strconv.FormatFloat(float64(us)/1e6, 'f', 3, 64)
+		if method == "Seek" && us >= 0 {
+			offset = "+" + offset
+		}
+		cmd = "seekcur " + offset
+	default:
+		return fmt.Errorf("mpd: %s is not supported", method)
+	}
+	_, err := c.command(cmd)
+	return err
+}
+
+func (c *Client) SetVolume(volume float64) error {
+	percent := int(min(max(volume, 0), 1)*100 + 0.5)
+	_, err := c.command("setvol " + strconv.Itoa(percent))
+	return err
+}
+
+// PlayFile replaces the queue with the file or directory at path, relative
+// to MPD's music directory, and starts playing it.
+func (c *Client) PlayFile(path string) error {
+	_, err := c.command("command_list_begin\nclear\nadd " + quote(path) + "\nplay\ncommand_list_end")
+	return err
+}
+
+// QueueFile adds the file or directory at path to the end of the queue.
+func (c *Client) QueueFile(path string) error {
+	_, err := c.command("add " + quote(path))
+	return err
+}
+
+// Settings returns MPD's crossfade and MixRamp settings. MPD leaves
+// crossfade and the MixRamp delay out of its status while they are off.
+func (c *Client) Settings() ([]mpris.Setting, error) {
+	status, err := c.command("status")
+	if err != nil {
+		return nil, err
+	}
+	delay := seconds(status["mixrampdelay"])
+	if math.IsNaN(delay) {
+		delay = 0
+	}
+	return []mpris.Setting{
+		{Name: "crossfade", Label: "crossfade", Unit: "s", Value: seconds(status["xfade"]), Max: 30, Step: 1, MinLabel: "off"},
+		{Name: "mixrampdb", Label: "MixRamp threshold", Unit: "dB", Value: seconds(status["mixrampdb"]), Min: -30, Step: 1},
+		{Name: "mixrampdelay", Label: "MixRamp delay", Unit: "s", Value: delay, Max: 10, Step: 0.5, MinLabel: "off"},
+	}, nil
+}
+
+// ChangeSetting sets one of the settings Settings returns.
+func (c *Client) ChangeSetting(name string, value float64) error {
+	arg := strconv.FormatFloat(value, 'f', -1, 64)
+	switch name {
+	case "crossfade":
+		arg = strconv.Itoa(int(max(value, 0)))
+	case "mixrampdb":
+	case "mixrampdelay":
+		if value <= 0 {
+			// MixRamp is off without a delay, and crossfade used instead.
+			arg = "nan"
+		}
+	default:
+		return fmt.Errorf("mpd: no setting %q", name)
+	}
+	_, err := c.command(name + " " + arg)
+	return err
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func seconds(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// duration parses MPD's fractional seconds.
+func duration(s string) time.Duration {
+	return time.Duration(seconds(s) * float64(time.Second))
+}
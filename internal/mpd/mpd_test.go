@@ -0,0 +1,236 @@
+package mpd
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+// fakeServer answers MPD commands from responses and records what it was
+// sent. Unknown commands get an ACK.
+func fakeServer(t *testing.T, responses map[string]string) (addr string, received *[]string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var commands []string
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("OK MPD 0.23.5\n"))
+				scanner := bufio.NewScanner(conn)
+				inList := false
+				for scanner.Scan() {
+					cmd := scanner.Text()
+					commands = append(commands, cmd)
+					// A command list is answered once, at its end.
+					switch {
+					case cmd == "command_list_begin":
+						inList = true
+						continue
+					case cmd == "command_list_end":
+						inList = false
+						conn.Write([]byte("OK\n"))
+						continue
+					case inList:
+						continue
+					}
+					response, ok := responses[cmd]
+					if !ok {
+						conn.Write([]byte("ACK [5@0] {" + cmd + "} unknown command\n"))
+						continue
+					}
+					conn.Write([]byte(response + "OK\n"))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), &commands
+}
+
+func TestMetadata(t *testing.T) {
+	addr, _ := fakeServer(t, map[string]string{
+		"status": "volume: 65\nrepeat: 1\nrandom: 1\nsingle: 0\nstate: pause\nsong: 3\nsongid: 42\n" +
+			"elapsed: 83.512\nduration: 215.001\n",
+		"currentsong": "file: a/b.flac\nArtist: First\nArtist: Second\nTitle: Song\nAlbum: Album\n" +
+			"Time: 215\nduration: 215.001\nId: 42\n",
+	})
+
+	got, err := New(addr).Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mpris.Metadata{
+		TrackID:  "/org/musicpd/track/42",
+		Title:    "Song",
+		Artist:   "First",
+		Album:    "Album",
+		Length:   215,
+		Position: 83,
+		Duration: 215001 * time.Millisecond,
+		Elapsed:  83512 * time.Millisecond,
+		Status:   "Paused",
+		Shuffle:  true,
+		Loop:     "Playlist",
+		Volume:   0.65,
+	}
+	if *got != want {
+		t.Errorf("Metadata() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestMetadataUntagged(t *testing.T) {
+	addr, _ := fakeServer(t, map[string]string{
+		"status":      "volume: -1\nstate: stop\n",
+		"currentsong": "file: radio/stream.mp3\n",
+	})
+
+	got, err := New(addr).Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "radio/stream.mp3" || got.Artist != "Unknown Artist" || got.Status != "Stopped" || got.Volume != 0 {
+		t.Errorf("Metadata() = %+v, want the file name as title, Stopped", *got)
+	}
+}
+
+func TestUpNext(t *testing.T) {
+	addr, received := fakeServer(t, map[string]string{
+		"status": "state: play\nsong: 3\nsongid: 42\n",
+		"playlistinfo 4:6": "file: a.flac\nArtist: One\nArtist: Two\nTitle: Next\nduration: 180.2\nPos: 4\nId: 43\n" +
+			"file: b.flac\nPos: 5\nId: 44\n",
+	})
+
+	got, err := New(addr).UpNext(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mpris.Metadata{
+		{TrackID: "/org/musicpd/track/43", Title: "Next", Artist: "One", Length: 180},
+		{TrackID: "/org/musicpd/track/44", Title: "b.flac", Artist: "Unknown Artist"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("UpNext(2) = %+v, want %+v", got, want)
+	}
+	if last := (*received)[len(*received)-1]; last != "playlistinfo 4:6" {
+		t.Errorf("sent %q, want playlistinfo 4:6", last)
+	}
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		method string
+		args   []any
+		want   string
+	}{
+		{"Next", nil, "next"},
+		{"Pause", nil, "pause 1"},
+		{"PlayPause", nil, "pause"},
+		{"Seek", []any{int64(5_000_000)}, "seekcur +5.000"},
+		{"Seek", []any{int64(-2_500_000)}, "seekcur -2.500"},
+		{"SetPosition", []any{int64(30_000_000)}, "seekcur 30.000"},
+	}
+	for _, tt := range tests {
+		addr, received := fakeServer(t, map[string]string{
+			"status": "state: play\n",
+			tt.want:  "",
+		})
+		if err := New(addr).Call(tt.method, tt.args...); err != nil {
+			t.Errorf("Call(%s): %v", tt.method, err)
+			continue
+		}
+		if last := (*received)[len(*received)-1]; last != tt.want {
+			t.Errorf("Call(%s) sent %q, want %q", tt.method, last, tt.want)
+		}
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	addr, _ := fakeServer(t, map[string]string{})
+	client := New(addr)
+	if err := client.Call("OpenUri", "spotify:track:x"); err == nil {
+		t.Error("Call(OpenUri) succeeded, want unsupported")
+	}
+	if err := client.Call("Next"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("Call(Next) error = %v, want the server's ACK", err)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	addr, received := fakeServer(t, map[string]string{
+		`password "s3cr\"t"`: "",
+		"next":               "",
+	})
+	if err := New(`s3cr"t@` + addr).Call("Next"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(*received, "; "); got != `password "s3cr\"t"; next` {
+		t.Errorf("sent %s", got)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	addr, received := fakeServer(t, map[string]string{
+		"status":           "volume: 65\nstate: play\nxfade: 5\nmixrampdb: -17.000000\n",
+		"crossfade 8":      "",
+		"mixrampdb -20":    "",
+		"mixrampdelay 1.5": "",
+		"mixrampdelay nan": "",
+	})
+	c := New(addr)
+
+	settings, err := c.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, s := range settings {
+		got[s.Name] = s.Value
+	}
+	if got["crossfade"] != 5 || got["mixrampdb"] != -17 || got["mixrampdelay"] != 0 {
+		t.Errorf("Settings() = %+v", settings)
+	}
+
+	for _, change := range []struct {
+		name  string
+		value float64
+	}{{"crossfade", 8}, {"mixrampdb", -20}, {"mixrampdelay", 1.5}, {"mixrampdelay", 0}} {
+		if err := c.ChangeSetting(change.name, change.value); err != nil {
+			t.Errorf("ChangeSetting(%s, %v): %v", change.name, change.value, err)
+		}
+	}
+	want := []string{"status", "crossfade 8", "mixrampdb -20", "mixrampdelay 1.5", "mixrampdelay nan"}
+	if strings.Join(*received, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", *received, want)
+	}
+	if err := c.ChangeSetting("volume", 1); err == nil {
+		t.Error("ChangeSetting(volume) = nil, want an error")
+	}
+}
+
+func TestPlayFile(t *testing.T) {
+	addr, received := fakeServer(t, map[string]string{`add "Band/LP"`: ""})
+	c := New(addr)
+	if err := c.PlayFile(`Band/LP`); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.QueueFile(`Band/LP`); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"command_list_begin", "clear", `add "Band/LP"`, "play", "command_list_end", `add "Band/LP"`}
+	if strings.Join(*received, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", *received, want)
+	}
+}
@@ -0,0 +1,49 @@
+package mpris
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const SpotifyBusName = "org.mpris.MediaPlayer2.spotify"
+
+// ConnectSessionBus connects to the session bus at address. An empty address
+// uses the caller's own session; "auto" looks through every user's session
+// bus under /run/user for one that has Spotify on it, for kiosk setups where
+// the display runs as a different user than the player.
+func ConnectSessionBus(address string) (*dbus.Conn, error) {
+	switch address {
+	case "":
+		return dbus.SessionBus()
+	case "auto":
+		return discoverSessionBus()
+	}
+	return dbus.Connect(address)
+}
+
+func discoverSessionBus() (*dbus.Conn, error) {
+	if conn, err := dbus.SessionBus(); err == nil && hasOwner(conn, SpotifyBusName) {
+		return conn, nil
+	}
+
+	sockets, _ := filepath.Glob("/run/user/*/bus")
+	for _, socket := range sockets {
+		conn, err := dbus.Connect("unix:path=" + socket)
+		if err != nil {
+			continue
+		}
+		if hasOwner(conn, SpotifyBusName) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+	return nil, errors.New("no session bus with Spotify on it found under /run/user")
+}
+
+func hasOwner(conn *dbus.Conn, name string) bool {
+	var owned bool
+	err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&owned)
+	return err == nil && owned
+}
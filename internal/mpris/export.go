@@ -0,0 +1,168 @@
+package mpris
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// ExportBusName is the name we publish our own player under.
+const ExportBusName = "org.mpris.MediaPlayer2.sptsong"
+
+// exportRate is the playback rate the exported player reports, and the only
+// one it accepts.
+const exportRate = 1.0
+
+// Exporter publishes our view of the player as an MPRIS player of its
+// own, so desktop media applets and media keys keep working with backends
+// that don't speak MPRIS themselves. Control calls are passed on to player.
+type Exporter struct {
+	props  *prop.Properties
+	player Player
+	last   Metadata
+}
+
+func Export(conn *dbus.Conn, player Player) (*Exporter, error) {
+	e := &Exporter{player: player}
+
+	props, err := prop.Export(conn, Path, prop.Map{
+		RootInterface: {
+			"Identity":            {Value: "sptsong", Emit: prop.EmitConst},
+			"CanQuit":             {Value: false, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: false, Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{}, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitConst},
+		},
+		PlayerInterface: {
+			"PlaybackStatus": {Value: "Stopped", Emit: prop.EmitTrue},
+			"Metadata":       {Value: map[string]dbus.Variant{}, Emit: prop.EmitTrue},
+			"Position":       {Value: int64(0), Emit: prop.EmitFalse},
+			"Rate":           {Value: exportRate, Emit: prop.EmitConst},
+			"MinimumRate":    {Value: exportRate, Emit: prop.EmitConst},
+			"MaximumRate":    {Value: exportRate, Emit: prop.EmitConst},
+			"Volume":         {Value: 1.0, Writable: true, Emit: prop.EmitTrue, Callback: e.setVolume},
+			"CanGoNext":      {Value: true, Emit: prop.EmitConst},
+			"CanGoPrevious":  {Value: true, Emit: prop.EmitConst},
+			"CanPlay":        {Value: true, Emit: prop.EmitConst},
+			"CanPause":       {Value: true, Emit: prop.EmitConst},
+			"CanSeek":        {Value: true, Emit: prop.EmitConst},
+			"CanControl":     {Value: true, Emit: prop.EmitConst},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.props = props
+
+	if err := conn.Export(mprisRoot{}, Path, RootInterface); err != nil {
+		return nil, err
+	}
+	renames := map[string]string{"SeekBy": "Seek"}
+	if err := conn.ExportWithMap(mprisPlayer{e}, renames, Path, PlayerInterface); err != nil {
+		return nil, err
+	}
+	playerMethods := introspect.Methods(mprisPlayer{e})
+	for i, m := range playerMethods {
+		if name, ok := renames[m.Name]; ok {
+			playerMethods[i].Name = name
+		}
+	}
+	node := &introspect.Node{
+		Name: string(Path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{Name: RootInterface, Methods: introspect.Methods(mprisRoot{}), Properties: props.Introspection(RootInterface)},
+			{Name: PlayerInterface, Methods: playerMethods, Properties: props.Introspection(PlayerInterface)},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(ExportBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("%s is taken by another process", ExportBusName)
+	}
+	return e, nil
+}
+
+// Update publishes the latest state. Only status and track changes are
+// signalled; applets read Position when they need it.
+func (e *Exporter) Update(metadata *Metadata) {
+	e.props.SetMust(PlayerInterface, "Position", metadata.Position*1000000)
+	if metadata.Status != e.last.Status {
+		e.props.SetMust(PlayerInterface, "PlaybackStatus", metadata.Status)
+	}
+	if metadata.Volume != e.last.Volume {
+		e.props.SetMust(PlayerInterface, "Volume", metadata.Volume)
+	}
+	if metadata.TrackID != e.last.TrackID || metadata.Title != e.last.Title {
+		fields := map[string]dbus.Variant{
+			"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/org/zelferion/sptsong/track/current")),
+			"mpris:length":  dbus.MakeVariant(metadata.Length * 1000000),
+			"xesam:title":   dbus.MakeVariant(metadata.Title),
+			"xesam:artist":  dbus.MakeVariant([]string{metadata.Artist}),
+			"xesam:album":   dbus.MakeVariant(metadata.Album),
+		}
+		if metadata.ArtURL != "" {
+			artURL := metadata.ArtURL
+			if artURL[0] == '/' {
+				artURL = "file://" + artURL
+			}
+			fields["mpris:artUrl"] = dbus.MakeVariant(artURL)
+		}
+		e.props.SetMust(PlayerInterface, "Metadata", fields)
+	}
+	e.last = *metadata
+}
+
+// setVolume passes volume changes from applets on to the player.
+func (e *Exporter) setVolume(change *prop.Change) *dbus.Error {
+	volume, ok := change.Value.(float64)
+	if !ok {
+		return prop.ErrInvalidArg
+	}
+	if err := e.player.SetVolume(volume); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+type mprisRoot struct{}
+
+func (mprisRoot) Raise() *dbus.Error { return nil }
+func (mprisRoot) Quit() *dbus.Error  { return nil }
+
+type mprisPlayer struct{ e *Exporter }
+
+func (p mprisPlayer) call(method string, args ...any) *dbus.Error {
+	if err := p.e.player.Call(method, args...); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (p mprisPlayer) Play() *dbus.Error      { return p.call("Play") }
+func (p mprisPlayer) Pause() *dbus.Error     { return p.call("Pause") }
+func (p mprisPlayer) PlayPause() *dbus.Error { return p.call("PlayPause") }
+func (p mprisPlayer) Stop() *dbus.Error      { return p.call("Stop") }
+func (p mprisPlayer) Next() *dbus.Error      { return p.call("Next") }
+func (p mprisPlayer) Previous() *dbus.Error  { return p.call("Previous") }
+
+// SeekBy is exported as Seek; naming it Seek would clash with io.Seeker.
+func (p mprisPlayer) SeekBy(offset int64) *dbus.Error { return p.call("Seek", offset) }
+
+// SetPosition only ever refers to the one track we export, so the player
+// gets just the position.
+func (p mprisPlayer) SetPosition(track dbus.ObjectPath, position int64) *dbus.Error {
+	return p.call("SetPosition", position)
+}
+
+func (p mprisPlayer) OpenUri(uri string) *dbus.Error { return p.call("OpenUri", uri) }
@@ -0,0 +1,290 @@
+package mpris
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	Path               = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	RootInterface      = "org.mpris.MediaPlayer2"
+	PlayerInterface    = "org.mpris.MediaPlayer2.Player"
+	TrackListInterface = "org.mpris.MediaPlayer2.TrackList"
+)
+
+// ErrNoTrackList is returned by UpNext for players that don't share their
+// queue.
+var ErrNoTrackList = errors.New("player has no track list")
+
+// Metadata is a snapshot of the player: the current track and the playback
+// state. Length and Position are in seconds.
+type Metadata struct {
+	TrackID  string
+	Title    string
+	Artist   string
+	Album    string
+	Length   int64
+	Position int64
+	// Duration and Elapsed are Length and Position as precisely as the
+	// player reports them, zero if it doesn't.
+	Duration time.Duration
+	Elapsed  time.Duration
+	ArtURL   string
+	Status   string
+	Shuffle  bool
+	Loop     string // None, Track or Playlist
+	Volume   float64
+	// Rate is the playback speed, 1 being normal, within MinRate and
+	// MaxRate. Zero means the player doesn't say.
+	Rate    float64
+	MinRate float64
+	MaxRate float64
+}
+
+// Player is what the display needs from a media player.
+type Player interface {
+	Metadata() (*Metadata, error)
+	Identity() string
+	// Call invokes an MPRIS Player method such as "PlayPause" or "Seek".
+	// SetPosition takes just the position; the current track is implied.
+	Call(method string, args ...any) error
+	// SetVolume sets the playback volume, from 0 to 1.
+	SetVolume(volume float64) error
+}
+
+// RateSetter is implemented by players whose playback speed can be set.
+type RateSetter interface {
+	// SetRate sets the playback rate, 1 being normal speed.
+	SetRate(rate float64) error
+}
+
+// TrackLister is implemented by players that can tell what plays next.
+type TrackLister interface {
+	// UpNext returns up to n of the tracks queued after the current one.
+	UpNext(n int) ([]Metadata, error)
+}
+
+// FilePlayer is implemented by players that play files from a music
+// directory of their own. Paths are relative to it, and may name a
+// directory to play everything under it.
+type FilePlayer interface {
+	// PlayFile replaces the queue with path and starts playing it.
+	PlayFile(path string) error
+	// QueueFile adds path to the end of the queue.
+	QueueFile(path string) error
+}
+
+// PlaybackSettings is implemented by players whose settings for how one
+// track runs into the next, such as crossfade, can be changed.
+type PlaybackSettings interface {
+	// Settings returns the player's settings in the order to show them.
+	Settings() ([]Setting, error)
+	// ChangeSetting sets the setting called name to value.
+	ChangeSetting(name string, value float64) error
+}
+
+// Setting is one numeric playback setting, changed in steps of Step
+// between Min and Max. MinLabel, if set, is shown for Min instead of the
+// number, such as "off" for no crossfade.
+type Setting struct {
+	Name     string
+	Label    string
+	Unit     string
+	Value    float64
+	Min      float64
+	Max      float64
+	Step     float64
+	MinLabel string
+}
+
+// Client is a Player backed by an MPRIS object on D-Bus.
+type Client struct {
+	obj dbus.BusObject
+}
+
+func NewClient(obj dbus.BusObject) *Client {
+	return &Client{obj: obj}
+}
+
+func (c *Client) Call(method string, args ...any) error {
+	if method == "SetPosition" {
+		metadata, err := c.Metadata()
+		if err != nil {
+			return err
+		}
+		args = append([]any{dbus.ObjectPath(metadata.TrackID)}, args...)
+	}
+	return c.obj.Call(PlayerInterface+"."+method, 0, args...).Err
+}
+
+func (c *Client) SetVolume(volume float64) error {
+	return c.obj.SetProperty(PlayerInterface+".Volume", dbus.MakeVariant(volume))
+}
+
+func (c *Client) SetRate(rate float64) error {
+	return c.obj.SetProperty(PlayerInterface+".Rate", dbus.MakeVariant(rate))
+}
+
+// Identity reads the player's human readable name from the MPRIS root
+// interface, falling back to its desktop entry and then the bus name.
+func (c *Client) Identity() string {
+	for _, property := range []string{"Identity", "DesktopEntry"} {
+		variant, err := c.obj.GetProperty("org.mpris.MediaPlayer2." + property)
+		if err != nil {
+			continue
+		}
+		if name, ok := variant.Value().(string); ok && name != "" {
+			return name
+		}
+	}
+	return strings.TrimPrefix(c.obj.Destination(), "org.mpris.MediaPlayer2.")
+}
+
+// stringValue returns the string held by a variant, or "" for missing or
+// non-string values.
+func stringValue(v dbus.Variant) string {
+	s, _ := v.Value().(string)
+	return s
+}
+
+// int64Value returns an integer variant's value; players disagree on whether
+// lengths and positions are signed.
+func int64Value(v dbus.Variant) int64 {
+	switch n := v.Value().(type) {
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	}
+	return 0
+}
+
+// Metadata reads the whole Player interface in a single GetAll round trip.
+func (c *Client) Metadata() (*Metadata, error) {
+	var props map[string]dbus.Variant
+	err := c.obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, PlayerInterface).Store(&props)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := props["Metadata"].Value().(map[string]dbus.Variant)
+	if !ok {
+		return nil, errors.New("player has no metadata")
+	}
+
+	m := trackMetadata(metadata)
+	m.Elapsed = time.Duration(int64Value(props["Position"])) * time.Microsecond
+	m.Position = int64(m.Elapsed / time.Second)
+	m.Status = stringValue(props["PlaybackStatus"])
+	m.Shuffle, _ = props["Shuffle"].Value().(bool)
+	m.Loop = stringValue(props["LoopStatus"])
+	m.Volume, _ = props["Volume"].Value().(float64)
+	m.Rate, _ = props["Rate"].Value().(float64)
+	m.MinRate, _ = props["MinimumRate"].Value().(float64)
+	m.MaxRate, _ = props["MaximumRate"].Value().(float64)
+	return m, nil
+}
+
+// trackMetadata reads the track fields of an MPRIS metadata map, as found
+// in the Metadata property and in GetTracksMetadata results.
+func trackMetadata(metadata map[string]dbus.Variant) *Metadata {
+	artists, _ := metadata["xesam:artist"].Value().([]string)
+	artist := "Unknown Artist"
+	if len(artists) > 0 {
+		artist = artists[0]
+	}
+
+	rawURL := stringValue(metadata["mpris:artUrl"])
+	artURL := ""
+	if strings.HasPrefix(rawURL, "https://i.scdn.co/image/") {
+		artURL = rawURL
+	} else if strings.HasPrefix(rawURL, "file://") {
+		artURL = strings.TrimPrefix(rawURL, "file://")
+	}
+
+	var trackID string
+	switch v := metadata["mpris:trackid"].Value().(type) {
+	case dbus.ObjectPath:
+		trackID = string(v)
+	case string:
+		trackID = v
+	}
+
+	duration := time.Duration(int64Value(metadata["mpris:length"])) * time.Microsecond
+	return &Metadata{
+		TrackID:  trackID,
+		Title:    stringValue(metadata["xesam:title"]),
+		Artist:   artist,
+		Album:    stringValue(metadata["xesam:album"]),
+		Length:   int64(duration / time.Second),
+		Duration: duration,
+		ArtURL:   artURL,
+	}
+}
+
+// UpNext reads the tracks after the current one from the TrackList
+// interface. Players without it, Spotify among them, return ErrNoTrackList;
+// other errors, such as a player too busy to answer, may pass.
+func (c *Client) UpNext(n int) ([]Metadata, error) {
+	if v, err := c.obj.GetProperty(RootInterface + ".HasTrackList"); err == nil {
+		if has, ok := v.Value().(bool); ok && !has {
+			return nil, ErrNoTrackList
+		}
+	}
+	variant, err := c.obj.GetProperty(TrackListInterface + ".Tracks")
+	if err != nil {
+		return nil, trackListError(err)
+	}
+	tracks, ok := variant.Value().([]dbus.ObjectPath)
+	if !ok {
+		return nil, ErrNoTrackList
+	}
+	current, err := c.obj.GetProperty(PlayerInterface + ".Metadata")
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := current.Value().(map[string]dbus.Variant)
+	currentID := trackMetadata(fields).TrackID
+
+	next := tracks
+	for i, id := range tracks {
+		if string(id) == currentID {
+			next = tracks[i+1:]
+			break
+		}
+	}
+	next = next[:min(len(next), n)]
+	if len(next) == 0 {
+		return nil, nil
+	}
+
+	var results []map[string]dbus.Variant
+	if err := c.obj.Call(TrackListInterface+".GetTracksMetadata", 0, next).Store(&results); err != nil {
+		return nil, trackListError(err)
+	}
+	queue := make([]Metadata, 0, len(results))
+	for _, r := range results {
+		queue = append(queue, *trackMetadata(r))
+	}
+	return queue, nil
+}
+
+// trackListError turns the errors of a player that lacks the TrackList
+// interface, or part of it, into ErrNoTrackList. Players answer a property
+// of a missing interface with InvalidArgs or UnknownInterface.
+func trackListError(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	switch dbusErr.Name {
+	case "org.freedesktop.DBus.Error.UnknownMethod",
+		"org.freedesktop.DBus.Error.UnknownProperty",
+		"org.freedesktop.DBus.Error.UnknownInterface",
+		"org.freedesktop.DBus.Error.InvalidArgs":
+		return ErrNoTrackList
+	}
+	return err
+}
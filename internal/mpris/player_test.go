@@ -0,0 +1,213 @@
+package mpris
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeObject is a dbus.BusObject serving canned properties. Methods the tests
+// don't need fall through to the nil embedded interface and panic.
+type fakeObject struct {
+	dbus.BusObject
+	props map[string]map[string]dbus.Variant
+	err   error
+	calls []call
+}
+
+type call struct {
+	method string
+	args   []any
+}
+
+func (f *fakeObject) Call(method string, flags dbus.Flags, args ...any) *dbus.Call {
+	f.calls = append(f.calls, call{method, args})
+	if f.err != nil {
+		return &dbus.Call{Err: f.err}
+	}
+	if method == "org.freedesktop.DBus.Properties.GetAll" {
+		return &dbus.Call{Body: []any{f.props[args[0].(string)]}}
+	}
+	return &dbus.Call{}
+}
+
+func (f *fakeObject) GetProperty(name string) (dbus.Variant, error) {
+	dot := strings.LastIndexByte(name, '.')
+	if f.err != nil {
+		return dbus.Variant{}, f.err
+	}
+	v, ok := f.props[name[:dot]][name[dot+1:]]
+	if !ok {
+		return dbus.Variant{}, dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownProperty"}
+	}
+	return v, nil
+}
+
+func (f *fakeObject) Destination() string {
+	return SpotifyBusName
+}
+
+func playerProps(metadata map[string]dbus.Variant) map[string]map[string]dbus.Variant {
+	return map[string]map[string]dbus.Variant{
+		PlayerInterface: {
+			"Metadata":       dbus.MakeVariant(metadata),
+			"Position":       dbus.MakeVariant(int64(83_000_000)),
+			"PlaybackStatus": dbus.MakeVariant("Playing"),
+			"Shuffle":        dbus.MakeVariant(true),
+			"LoopStatus":     dbus.MakeVariant("Playlist"),
+			"Volume":         dbus.MakeVariant(0.5),
+		},
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	obj := &fakeObject{props: playerProps(map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/com/spotify/track/abc")),
+		"mpris:length":  dbus.MakeVariant(uint64(215_000_000)),
+		"mpris:artUrl":  dbus.MakeVariant("https://i.scdn.co/image/ab67"),
+		"xesam:title":   dbus.MakeVariant("Song"),
+		"xesam:artist":  dbus.MakeVariant([]string{"First", "Second"}),
+		"xesam:album":   dbus.MakeVariant("Album"),
+	})}
+
+	got, err := NewClient(obj).Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{
+		TrackID:  "/com/spotify/track/abc",
+		Title:    "Song",
+		Artist:   "First",
+		Album:    "Album",
+		Length:   215,
+		Position: 83,
+		Duration: 215 * time.Second,
+		Elapsed:  83 * time.Second,
+		ArtURL:   "https://i.scdn.co/image/ab67",
+		Status:   "Playing",
+		Shuffle:  true,
+		Loop:     "Playlist",
+		Volume:   0.5,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Metadata() = %+v, want %+v", got, want)
+	}
+	if len(obj.calls) != 1 {
+		t.Errorf("Metadata() made %d calls, want a single GetAll", len(obj.calls))
+	}
+}
+
+func TestMetadataArtURL(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"https://i.scdn.co/image/ab67", "https://i.scdn.co/image/ab67"},
+		{"file:///home/me/.cache/cover.jpg", "/home/me/.cache/cover.jpg"},
+		{"https://open.spotify.com/image/ab67", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		obj := &fakeObject{props: playerProps(map[string]dbus.Variant{
+			"mpris:artUrl": dbus.MakeVariant(tt.raw),
+		})}
+		got, err := NewClient(obj).Metadata()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ArtURL != tt.want {
+			t.Errorf("artUrl %q: ArtURL = %q, want %q", tt.raw, got.ArtURL, tt.want)
+		}
+	}
+}
+
+func TestMetadataMissingFields(t *testing.T) {
+	obj := &fakeObject{props: playerProps(map[string]dbus.Variant{})}
+	got, err := NewClient(obj).Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Artist != "Unknown Artist" || got.Title != "" || got.Length != 0 {
+		t.Errorf("Metadata() = %+v, want empty track with Unknown Artist", got)
+	}
+}
+
+func TestMetadataErrors(t *testing.T) {
+	busErr := errors.New("no reply")
+	if _, err := NewClient(&fakeObject{err: busErr}).Metadata(); !errors.Is(err, busErr) {
+		t.Errorf("Metadata() error = %v, want %v", err, busErr)
+	}
+
+	obj := &fakeObject{props: map[string]map[string]dbus.Variant{PlayerInterface: {}}}
+	if _, err := NewClient(obj).Metadata(); err == nil {
+		t.Error("Metadata() without a Metadata property succeeded")
+	}
+}
+
+func TestUpNextUnsupported(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		obj  *fakeObject
+	}{
+		{"no track list", &fakeObject{props: map[string]map[string]dbus.Variant{
+			RootInterface: {"HasTrackList": dbus.MakeVariant(false)},
+		}}},
+		{"unknown property", &fakeObject{props: playerProps(nil)}},
+		{"unknown interface", &fakeObject{err: dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs"}}},
+		{"unknown method", &fakeObject{err: dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownMethod"}}},
+	} {
+		if _, err := NewClient(tt.obj).UpNext(3); !errors.Is(err, ErrNoTrackList) {
+			t.Errorf("%s: UpNext() error = %v, want ErrNoTrackList", tt.name, err)
+		}
+	}
+
+	busErr := dbus.Error{Name: "org.freedesktop.DBus.Error.NoReply"}
+	if _, err := NewClient(&fakeObject{err: busErr}).UpNext(3); errors.Is(err, ErrNoTrackList) || err == nil {
+		t.Errorf("UpNext() error = %v, want the bus error", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		root map[string]dbus.Variant
+		want string
+	}{
+		{"identity", map[string]dbus.Variant{"Identity": dbus.MakeVariant("Spotify")}, "Spotify"},
+		{"desktop entry", map[string]dbus.Variant{"DesktopEntry": dbus.MakeVariant("spotify-client")}, "spotify-client"},
+		{"bus name", nil, "spotify"},
+	}
+	for _, tt := range tests {
+		obj := &fakeObject{props: map[string]map[string]dbus.Variant{RootInterface: tt.root}}
+		if got := NewClient(obj).Identity(); got != tt.want {
+			t.Errorf("%s: Identity() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	obj := &fakeObject{}
+	if err := NewClient(obj).Call("Seek", int64(5_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	want := call{PlayerInterface + ".Seek", []any{int64(5_000_000)}}
+	if len(obj.calls) != 1 || !reflect.DeepEqual(obj.calls[0], want) {
+		t.Errorf("calls = %+v, want [%+v]", obj.calls, want)
+	}
+}
+
+func TestCallSetPosition(t *testing.T) {
+	obj := &fakeObject{props: playerProps(map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/com/spotify/track/abc")),
+	})}
+	if err := NewClient(obj).Call("SetPosition", int64(30_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	want := call{PlayerInterface + ".SetPosition", []any{dbus.ObjectPath("/com/spotify/track/abc"), int64(30_000_000)}}
+	if last := obj.calls[len(obj.calls)-1]; !reflect.DeepEqual(last, want) {
+		t.Errorf("last call = %+v, want %+v", last, want)
+	}
+}
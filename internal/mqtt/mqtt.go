@@ -0,0 +1,340 @@
+// Package mqtt is a small MQTT 3.1.1 client: it connects, publishes at QoS 0,
+// keeps the connection alive and disconnects, which is all sptsong needs to
+// feed home automation.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"sptsong/internal/guard"
+)
+
+// Packet types, in the high nibble of the first byte.
+const (
+	connect    = 1 << 4
+	connack    = 2 << 4
+	publish    = 3 << 4
+	pingreq    = 12 << 4
+	pingresp   = 13 << 4
+	disconnect = 14 << 4
+)
+
+// keepAlive is the longest the broker waits to hear from the client before
+// it drops the connection. The client pings it twice as often, and drops
+// the connection itself when a ping goes unanswered until the next.
+const keepAlive = 60 * time.Second
+
+// connackErrors are the CONNACK return codes that refuse a connection.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client id rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Client publishes to one broker, connecting on the first Publish and again
+// after the connection drops. Its methods may be called from one goroutine
+// at a time; the pings it sends on its own don't get in their way.
+type Client struct {
+	address   string
+	tls       bool
+	tlsConfig *tls.Config
+	username  string
+	password  string
+	clientID  string
+	ping      time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// New returns a client for the broker at rawURL: mqtt://host[:1883], or
+// mqtts://host[:8883] for TLS, with an optional user:password. A TLS
+// connection verifies the broker with tlsConfig, which may be nil for the
+// system's certificate authorities. Nothing is dialed until the first
+// Publish.
+func New(rawURL string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{tlsConfig: tlsConfig, ping: keepAlive / 2}
+	port := "1883"
+	switch u.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl":
+		c.tls, port = true, "8883"
+	default:
+		return nil, fmt.Errorf("mqtt: unknown scheme %q, want mqtt or mqtts", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("mqtt: %s has no host", rawURL)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	c.address = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	c.clientID = clientID()
+	return c, nil
+}
+
+// clientID names this display to the broker. Brokers drop the older of two
+// connections with the same id, so two displays on different machines must
+// not share one, but they only have to accept ids of up to 23 letters and
+// digits: the host name and process id are hashed to fit.
+func clientID() string {
+	host, _ := os.Hostname()
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s-%d", host, os.Getpid()))
+	return fmt.Sprintf("sptsong%x", sum[:6])
+}
+
+// Connected reports whether the client holds a connection, so callers can
+// tell when the next Publish starts a new one.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+func (c *Client) dial(ctx context.Context) error {
+	var conn net.Conn
+	var err error
+	if c.tls {
+		config := &tls.Config{}
+		if c.tlsConfig != nil {
+			config = c.tlsConfig.Clone()
+		}
+		config.ServerName, _, _ = net.SplitHostPort(c.address)
+		dialer := &tls.Dialer{Config: config}
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", c.address)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Clean session: sptsong only ever publishes, so there is nothing for
+	// the broker to keep between connections.
+	flags := byte(0x02)
+	payload := appendString(nil, c.clientID)
+	if c.username != "" {
+		flags |= 0x80
+		payload = appendString(payload, c.username)
+		if c.password != "" {
+			flags |= 0x40
+			payload = appendString(payload, c.password)
+		}
+	}
+	header := appendString(nil, "MQTT")
+	seconds := int(keepAlive / time.Second)
+	header = append(header, 4, flags, byte(seconds>>8), byte(seconds))
+	if _, err := conn.Write(packet(connect, append(header, payload...))); err != nil {
+		conn.Close()
+		return err
+	}
+
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		conn.Close()
+		return fmt.Errorf("mqtt: %s: %w", c.address, err)
+	}
+	if ack[0] != connack || ack[1] != 2 {
+		conn.Close()
+		return fmt.Errorf("mqtt: %s is not an MQTT broker", c.address)
+	}
+	if ack[3] != 0 {
+		conn.Close()
+		reason, ok := connackErrors[ack[3]]
+		if !ok {
+			reason = fmt.Sprintf("code %d", ack[3])
+		}
+		return fmt.Errorf("mqtt: %s refused the connection: %s", c.address, reason)
+	}
+	conn.SetDeadline(time.Time{})
+	c.conn = conn
+	go c.keepAlive(conn)
+	return nil
+}
+
+// keepAlive pings the broker while conn is the client's connection, so the
+// broker doesn't drop it between tracks, and drops it when the broker stops
+// answering, so the next Publish connects again rather than writing into a
+// dead connection.
+func (c *Client) keepAlive(conn net.Conn) {
+	defer guard.Recover()
+	answered := make(chan struct{}, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer guard.Recover()
+		defer close(closed)
+		// At QoS 0 without subscriptions, the broker only ever sends
+		// PINGRESP.
+		r := bufio.NewReader(conn)
+		for {
+			first, _, err := readPacket(r)
+			if err != nil {
+				return
+			}
+			if first&0xf0 == pingresp {
+				select {
+				case answered <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(c.ping)
+	defer ticker.Stop()
+	waiting := false
+	for {
+		select {
+		case <-closed:
+			c.drop(conn)
+			return
+		case <-answered:
+			waiting = false
+		case <-ticker.C:
+			if waiting {
+				c.drop(conn)
+				return
+			}
+			c.mu.Lock()
+			if c.conn != conn {
+				c.mu.Unlock()
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(c.ping))
+			_, err := conn.Write(packet(pingreq, nil))
+			conn.SetWriteDeadline(time.Time{})
+			c.mu.Unlock()
+			if err != nil {
+				c.drop(conn)
+				return
+			}
+			waiting = true
+		}
+	}
+}
+
+// drop closes conn if it is still the client's connection.
+func (c *Client) drop(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Publish sends payload to topic at QoS 0. Retained messages are kept by
+// the broker and handed to every later subscriber.
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, retain bool) error {
+	if topic == "" {
+		return errors.New("mqtt: empty topic")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.dial(ctx); err != nil {
+			return err
+		}
+	}
+	first := byte(publish)
+	if retain {
+		first |= 0x01
+	}
+	body := append(appendString(nil, topic), payload...)
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetWriteDeadline(deadline)
+	}
+	_, err := c.conn.Write(packet(first, body))
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Time{})
+	return nil
+}
+
+// Close says goodbye to the broker, so it doesn't wait out the connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	c.conn.Write(packet(disconnect, nil))
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// packet frames body with its fixed header: the first byte, then the
+// remaining length, seven bits at a time.
+func packet(first byte, body []byte) []byte {
+	p := []byte{first}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		p = append(p, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(p, body...)
+}
+
+// readPacket reads one packet, returning its first byte and its body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return first, body, err
+}
+
+// appendString appends s with its two-byte length, the way MQTT encodes
+// strings.
+func appendString(b []byte, s string) []byte {
+	return append(append(b, byte(len(s)>>8), byte(len(s))), s...)
+}
@@ -0,0 +1,238 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// message is a PUBLISH as the fake broker received it.
+type message struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// fakeBroker accepts connections, over TLS if tlsConfig is set, answers
+// CONNECT with returnCode and PINGREQ with PINGRESP, and sends what it
+// receives on the returned channels.
+func fakeBroker(t *testing.T, returnCode byte, tlsConfig *tls.Config) (addr string, connects chan []byte, messages chan message, pings chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	connects, messages, pings = make(chan []byte, 4), make(chan message, 16), make(chan struct{}, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					first, body, err := readPacket(r)
+					if err != nil {
+						return
+					}
+					switch first & 0xf0 {
+					case connect:
+						connects <- body
+						conn.Write([]byte{connack, 2, 0, returnCode})
+					case publish:
+						n := int(body[0])<<8 | int(body[1])
+						messages <- message{string(body[2 : 2+n]), string(body[2+n:]), first&1 == 1}
+					case pingreq:
+						pings <- struct{}{}
+						conn.Write([]byte{pingresp, 0})
+					case disconnect:
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), connects, messages, pings
+}
+
+func TestPublish(t *testing.T) {
+	addr, connects, messages, _ := fakeBroker(t, 0, nil)
+	c, err := New("mqtt://user:secret@"+addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	if err := c.Publish(ctx, "sptsong/state", []byte("playing"), true); err != nil {
+		t.Fatal(err)
+	}
+	payload := strings.Repeat("x", 300)
+	if err := c.Publish(ctx, "sptsong/event", []byte(payload), false); err != nil {
+		t.Fatal(err)
+	}
+
+	body := <-connects
+	if !bytes.HasPrefix(body, []byte("\x00\x04MQTT\x04\xc2\x00\x3c")) {
+		t.Errorf("CONNECT header = %q", body[:10])
+	}
+	if !bytes.HasSuffix(body, []byte("\x00\x04user\x00\x06secret")) {
+		t.Errorf("CONNECT payload = %q, want the user name and password last", body[10:])
+	}
+	want := []message{{"sptsong/state", "playing", true}, {"sptsong/event", payload, false}}
+	for _, w := range want {
+		if got := <-messages; got != w {
+			t.Errorf("got %+v, want %+v", got, w)
+		}
+	}
+}
+
+func TestRefused(t *testing.T) {
+	addr, _, _, _ := fakeBroker(t, 4, nil)
+	c, err := New("mqtt://"+addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Publish(context.Background(), "sptsong/state", []byte("paused"), true)
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Errorf("Publish = %v, want the refusal", err)
+	}
+	if c.Connected() {
+		t.Error("still connected after a refusal")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tt := range []struct{ url, address string }{
+		{"mqtt://broker", "broker:1883"},
+		{"mqtts://broker", "broker:8883"},
+		{"mqtt://broker:1884", "broker:1884"},
+	} {
+		c, err := New(tt.url, nil)
+		if err != nil {
+			t.Errorf("New(%q): %v", tt.url, err)
+			continue
+		}
+		if c.address != tt.address {
+			t.Errorf("New(%q) address = %q, want %q", tt.url, c.address, tt.address)
+		}
+	}
+	for _, bad := range []string{"http://broker", "mqtt://"} {
+		if _, err := New(bad, nil); err == nil {
+			t.Errorf("New(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestClientID(t *testing.T) {
+	id := clientID()
+	if len(id) > 23 || strings.Trim(id, "abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+		t.Errorf("clientID() = %q, want at most 23 letters and digits", id)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	addr, _, _, pings := fakeBroker(t, 0, nil)
+	c, err := New("mqtt://"+addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.ping = 10 * time.Millisecond
+	if err := c.Publish(context.Background(), "sptsong/state", []byte("playing"), true); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		<-pings
+	}
+	if !c.Connected() {
+		t.Error("dropped a connection whose pings were answered")
+	}
+}
+
+func TestKeepAliveUnanswered(t *testing.T) {
+	// A broker that accepts the connection and then hangs.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		readPacket(r)
+		conn.Write([]byte{connack, 2, 0, 0})
+		for {
+			if _, _, err := readPacket(r); err != nil {
+				return
+			}
+		}
+	}()
+
+	c, err := New("mqtt://"+listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ping = 10 * time.Millisecond
+	if err := c.Publish(context.Background(), "sptsong/state", []byte("playing"), true); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for c.Connected() {
+		if time.Now().After(deadline) {
+			t.Fatal("still connected to a broker that doesn't answer pings")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	// Borrow a certificate for 127.0.0.1 and a client that trusts it.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	trusted := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	addr, _, messages, _ := fakeBroker(t, 0, &tls.Config{Certificates: server.TLS.Certificates})
+	c, err := New("mqtts://"+addr, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Publish(context.Background(), "sptsong/state", []byte("playing"), true); err != nil {
+		t.Fatal(err)
+	}
+	<-messages
+
+	untrusting, err := New("mqtts://"+addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := untrusting.Publish(context.Background(), "sptsong/state", []byte("playing"), true); err == nil {
+		t.Error("connected to a broker whose certificate authority wasn't given")
+	}
+}
+
+func TestPacketLength(t *testing.T) {
+	p := packet(publish, make([]byte, 321))
+	if p[1] != 0xc1 || p[2] != 0x02 {
+		t.Errorf("remaining length = % x, want c1 02", p[1:3])
+	}
+}
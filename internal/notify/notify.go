@@ -0,0 +1,210 @@
+// Package notify delivers player events, such as track changes, to
+// notification sinks: desktop notifications, a JSON lines log, webhooks,
+// chat services and MQTT.
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"sptsong/internal/config"
+	"sptsong/internal/guard"
+	"sptsong/internal/logging"
+	"sptsong/internal/mpris"
+	"sptsong/internal/mqtt"
+)
+
+// Kind is the type of an event. Sinks can be limited to some kinds in the
+// config.
+type Kind string
+
+const (
+	TrackChanged Kind = "track"
+	Paused       Kind = "paused"
+	Resumed      Kind = "resumed"
+	Seeked       Kind = "seek"
+	Stuck        Kind = "stuck"
+)
+
+// Kinds lists every event kind.
+var Kinds = []Kind{TrackChanged, Paused, Resumed, Seeked, Stuck}
+
+// Event is something that happened to the player, with the track playing at
+// the time.
+type Event struct {
+	Kind   Kind
+	Time   time.Time
+	Player string
+	Track  mpris.Metadata
+}
+
+// Text returns a short summary line and body for the event, as shown by
+// desktop notifications and chat messages.
+func (e Event) Text() (summary, body string) {
+	track := e.Track.Title + " – " + e.Track.Artist
+	switch e.Kind {
+	case TrackChanged:
+		body = e.Track.Artist
+		if e.Track.Album != "" {
+			body += " – " + e.Track.Album
+		}
+		return e.Track.Title, body
+	case Paused:
+		return "Paused", track
+	case Resumed:
+		return "Playing", track
+	case Seeked:
+		return "Seeked to " + formatSeconds(e.Track.Position), track
+	case Stuck:
+		return "Playback is stuck", track
+	}
+	return string(e.Kind), track
+}
+
+func formatSeconds(s int64) string {
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// A Sink delivers events somewhere. Notify is called from one goroutine per
+// sink, so sinks need no locking of their own. Sinks that hold a connection
+// also implement io.Closer, which is called once the dispatcher closes.
+type Sink interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// queueSize bounds the events waiting for a slow sink; beyond it new events
+// are dropped rather than stalling the display.
+const queueSize = 16
+
+type route struct {
+	name   string
+	sink   Sink
+	kinds  []Kind
+	events chan Event
+	errors logging.Limiter
+}
+
+// errorInterval is how often a sink that keeps failing is logged.
+const errorInterval = time.Minute
+
+// Dispatcher hands each event to the sinks that want it, without waiting for
+// them.
+type Dispatcher struct {
+	routes []*route
+}
+
+// New builds the sinks described by configs. HTTP sinks use client, and
+// MQTT brokers are trusted by the same certificate authorities.
+func New(configs []config.NotifyConfig, client *http.Client) (*Dispatcher, error) {
+	d := &Dispatcher{}
+	for i, c := range configs {
+		sink, err := newSink(c, client)
+		if err != nil {
+			return nil, fmt.Errorf("notify #%d: %w", i+1, err)
+		}
+		r := &route{
+			name:   fmt.Sprintf("#%d %s", i+1, c.Type),
+			sink:   sink,
+			events: make(chan Event, queueSize),
+			errors: logging.Limiter{Interval: errorInterval},
+		}
+		for _, name := range c.Events {
+			if !slices.Contains(Kinds, Kind(name)) {
+				return nil, fmt.Errorf("notify #%d: unknown event %q, want one of %v", i+1, name, Kinds)
+			}
+			r.kinds = append(r.kinds, Kind(name))
+		}
+		d.routes = append(d.routes, r)
+	}
+	for _, r := range d.routes {
+		go r.run()
+	}
+	return d, nil
+}
+
+func newSink(c config.NotifyConfig, client *http.Client) (Sink, error) {
+	switch c.Type {
+	case "desktop":
+		return &Desktop{Silent: c.Silent}, nil
+	case "jsonl":
+		if c.Path == "" {
+			return nil, fmt.Errorf("jsonl needs a path")
+		}
+		return &EventLog{Path: c.Path}, nil
+	}
+	if c.URL == "" {
+		return nil, fmt.Errorf("%s needs a url", c.Type)
+	}
+	switch c.Type {
+	case "webhook":
+		return &Webhook{URL: c.URL, Client: client}, nil
+	case "discord":
+		return &Chat{URL: c.URL, Field: "content", Client: client}, nil
+	case "slack":
+		return &Chat{URL: c.URL, Field: "text", Client: client}, nil
+	case "mqtt":
+		var tlsConfig *tls.Config
+		if client != nil {
+			if transport, ok := client.Transport.(*http.Transport); ok {
+				tlsConfig = transport.TLSClientConfig
+			}
+		}
+		mqttClient, err := mqtt.New(c.URL, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		topic := strings.Trim(c.Topic, "/")
+		if topic == "" {
+			topic = "sptsong"
+		}
+		return &MQTT{Client: mqttClient, Topic: topic, Discovery: c.Discovery}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q, want desktop, jsonl, webhook, discord, slack or mqtt", c.Type)
+}
+
+func (r *route) run() {
+	defer guard.Recover()
+	for e := range r.events {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// A failed delivery is not retried; the next event is more useful
+		// than a late one.
+		// A sink that keeps failing is logged once per errorInterval,
+		// with the number of failures left out since.
+		if err := r.sink.Notify(ctx, e); err != nil {
+			if ok, dropped := r.errors.Allow(); ok {
+				slog.Warn("notification failed", "sink", r.name, "event", e.Kind, "err", err, "unlogged", dropped)
+			}
+		}
+		cancel()
+	}
+	if closer, ok := r.sink.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// Publish queues e for every sink that wants its kind.
+func (d *Dispatcher) Publish(e Event) {
+	for _, r := range d.routes {
+		if len(r.kinds) > 0 && !slices.Contains(r.kinds, e.Kind) {
+			continue
+		}
+		select {
+		case r.events <- e:
+		default:
+		}
+	}
+}
+
+// Close stops the sinks once their queued events are delivered.
+func (d *Dispatcher) Close() {
+	for _, r := range d.routes {
+		close(r.events)
+	}
+}
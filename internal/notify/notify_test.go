@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sptsong/internal/config"
+	"sptsong/internal/logging"
+	"sptsong/internal/mpris"
+)
+
+func TestNewErrors(t *testing.T) {
+	for _, c := range []config.NotifyConfig{
+		{Type: "pager", URL: "https://example.com"},
+		{Type: "webhook"},
+		{Type: "mqtt", URL: "http://broker.local"},
+		{Type: "desktop", Events: []string{"track", "skipped"}},
+	} {
+		if _, err := New([]config.NotifyConfig{c}, http.DefaultClient); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", c)
+		}
+	}
+}
+
+func TestPublishFilters(t *testing.T) {
+	bodies := make(chan map[string]any, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	d, err := New([]config.NotifyConfig{
+		{Type: "webhook", URL: server.URL + "/all"},
+		{Type: "discord", URL: server.URL + "/discord", Events: []string{"track"}},
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	track := mpris.Metadata{Title: "Song", Artist: "Band", Album: "LP"}
+	d.Publish(Event{Kind: Paused, Time: time.Now(), Track: track})
+	if body := <-bodies; body["event"] != "paused" {
+		t.Errorf("webhook got %v, want the paused event", body)
+	}
+
+	d.Publish(Event{Kind: TrackChanged, Time: time.Now(), Track: track})
+	got := map[string]bool{}
+	for range 2 {
+		body := <-bodies
+		if content, ok := body["content"]; ok {
+			got[content.(string)] = true
+		} else {
+			got[body["event"].(string)] = true
+		}
+	}
+	if !got["track"] || !got["🎵 Song · Band – LP"] {
+		t.Errorf("track change delivered %v, want the webhook event and a Discord message", got)
+	}
+
+	select {
+	case body := <-bodies:
+		t.Errorf("unexpected delivery %v", body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Notify(context.Context, Event) error {
+	return errors.New("service down")
+}
+
+func TestFailuresLogged(t *testing.T) {
+	var log bytes.Buffer
+	defer func(l *slog.Logger) { slog.SetDefault(l) }(slog.Default())
+	slog.SetDefault(logging.New(&log, slog.LevelInfo))
+
+	r := &route{name: "#1 webhook", sink: failingSink{}, events: make(chan Event, 3), errors: logging.Limiter{Interval: time.Hour}}
+	for range 3 {
+		r.events <- Event{Kind: Paused, Time: time.Now()}
+	}
+	close(r.events)
+	r.run()
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "service down") {
+		t.Errorf("logged %q, want the first failure only", lines)
+	}
+}
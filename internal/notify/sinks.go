@@ -0,0 +1,237 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/mqtt"
+)
+
+// Desktop shows events as desktop notifications: through
+// org.freedesktop.Notifications on the session bus, or Notification Center
+// on macOS. Each notification replaces the previous one. Silent asks the
+// notification server not to play a sound; on macOS they are silent anyway.
+type Desktop struct {
+	Silent bool
+
+	conn *dbus.Conn
+	id   uint32
+}
+
+func (d *Desktop) Notify(ctx context.Context, e Event) error {
+	summary, body := e.Text()
+	if runtime.GOOS == "darwin" {
+		script := "display notification " + strconv.Quote(body) + " with title " + strconv.Quote(summary)
+		return exec.CommandContext(ctx, "osascript", "-e", script).Run()
+	}
+
+	if d.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return err
+		}
+		d.conn = conn
+	}
+	hints := map[string]dbus.Variant{}
+	if d.Silent {
+		hints["suppress-sound"] = dbus.MakeVariant(true)
+	}
+	obj := d.conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.CallWithContext(ctx, "org.freedesktop.Notifications.Notify", 0,
+		"sptsong", d.id, "audio-x-generic", summary, body, []string{}, hints, int32(-1))
+	if call.Err != nil {
+		return call.Err
+	}
+	return call.Store(&d.id)
+}
+
+// Webhook posts every event as JSON to URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// trackJSON is the track part of eventJSON.
+type trackJSON struct {
+	ID       string  `json:"id,omitempty"`
+	Title    string  `json:"title"`
+	Artist   string  `json:"artist"`
+	Album    string  `json:"album,omitempty"`
+	Length   int64   `json:"length"`
+	Position int64   `json:"position"`
+	ArtURL   string  `json:"art_url,omitempty"`
+	Status   string  `json:"status"`
+	Volume   float64 `json:"volume"`
+}
+
+// eventJSON is the JSON form of an event sent by Webhook and written by
+// EventLog. Scripts parse it, so fields may be added but never renamed.
+type eventJSON struct {
+	Event  Kind      `json:"event"`
+	Time   time.Time `json:"time"`
+	Player string    `json:"player"`
+	Track  trackJSON `json:"track"`
+}
+
+func newTrackJSON(t mpris.Metadata) trackJSON {
+	return trackJSON{
+		ID: t.TrackID, Title: t.Title, Artist: t.Artist, Album: t.Album, Length: t.Length,
+		Position: t.Position, ArtURL: t.ArtURL, Status: t.Status, Volume: t.Volume,
+	}
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eventJSON{
+		Event:  e.Kind,
+		Time:   e.Time,
+		Player: e.Player,
+		Track:  newTrackJSON(e.Track),
+	})
+}
+
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, w.Client, w.URL, e)
+}
+
+// EventLog appends every event to the file at Path as one line of JSON. The
+// file is opened per event, so it can be rotated or removed at any time.
+type EventLog struct {
+	Path string
+}
+
+func (l *EventLog) Notify(ctx context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Chat posts a one-line message to a chat webhook that takes its text in
+// Field: "content" for Discord, "text" for Slack.
+type Chat struct {
+	URL    string
+	Field  string
+	Client *http.Client
+}
+
+func (c *Chat) Notify(ctx context.Context, e Event) error {
+	summary, body := e.Text()
+	return postJSON(ctx, c.Client, c.URL, map[string]string{c.Field: "🎵 " + summary + " · " + body})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// MQTT publishes to a broker for home automation, under Topic: the playback
+// status ("playing", "paused", "stopped") to Topic/state and the track as
+// JSON to Topic/track, both retained so new subscribers see them at once,
+// and every event to Topic/event. With Discovery, it also announces both as
+// Home Assistant sensors each time it connects.
+type MQTT struct {
+	Client    *mqtt.Client
+	Topic     string
+	Discovery bool
+}
+
+func (m *MQTT) Notify(ctx context.Context, e Event) error {
+	if m.Discovery && !m.Client.Connected() {
+		if err := m.announce(ctx); err != nil {
+			return err
+		}
+	}
+	track, err := json.Marshal(newTrackJSON(e.Track))
+	if err != nil {
+		return err
+	}
+	event, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := m.Client.Publish(ctx, m.Topic+"/state", []byte(strings.ToLower(e.Track.Status)), true); err != nil {
+		return err
+	}
+	if err := m.Client.Publish(ctx, m.Topic+"/track", track, true); err != nil {
+		return err
+	}
+	return m.Client.Publish(ctx, m.Topic+"/event", event, false)
+}
+
+func (m *MQTT) Close() error {
+	return m.Client.Close()
+}
+
+// announce publishes the Home Assistant MQTT discovery configs for the
+// track and state sensors. They are retained, so Home Assistant finds them
+// after its own restarts too.
+func (m *MQTT) announce(ctx context.Context) error {
+	node := strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(m.Topic)
+	device := map[string]any{"identifiers": []string{node}, "name": "sptsong", "model": "sptsong"}
+	sensors := []struct {
+		id     string
+		config map[string]any
+	}{
+		{"track", map[string]any{
+			"name":                  "Now playing",
+			"state_topic":           m.Topic + "/track",
+			"value_template":        "{{ value_json.artist }} – {{ value_json.title }}",
+			"json_attributes_topic": m.Topic + "/track",
+			"icon":                  "mdi:music",
+		}},
+		{"state", map[string]any{
+			"name":        "Playback",
+			"state_topic": m.Topic + "/state",
+			"icon":        "mdi:play-pause",
+		}},
+	}
+	for _, s := range sensors {
+		s.config["unique_id"] = node + "_" + s.id
+		s.config["device"] = device
+		data, err := json.Marshal(s.config)
+		if err != nil {
+			return err
+		}
+		if err := m.Client.Publish(ctx, "homeassistant/sensor/"+node+"/"+s.id+"/config", data, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
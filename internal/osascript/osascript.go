@@ -0,0 +1,238 @@
+//go:build darwin
+
+// Package osascript is the macOS player backend, talking to the Spotify app
+// through its AppleScript interface since there is no D-Bus.
+package osascript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+)
+
+var ErrNotRunning = errors.New("Spotify is not running")
+
+// nowPlaying returns the track and player state as one tab-separated line.
+// Checking "is running" first keeps osascript from launching Spotify.
+const nowPlaying = `if application "Spotify" is not running then return "not running"
+tell application "Spotify"
+	set t to current track
+	return (id of t) & tab & (name of t) & tab & (artist of t) & tab & (album of t) & tab & (duration of t) & tab & (player position) & tab & (player state as string) & tab & (artwork url of t) & tab & (shuffling) & tab & (repeating) & tab & (sound volume)
+end tell`
+
+// PollInterval is how often Client asks Spotify for the player state. In
+// between, the position is extrapolated, so a fast display refresh doesn't
+// start an osascript process every time or wait on a slow one.
+const PollInterval = time.Second
+
+// Client implements mpris.Player by running osascript: the player state is
+// read in the background, commands as they come.
+type Client struct {
+	start   sync.Once
+	ready   chan struct{}
+	refresh chan struct{}
+
+	mu       sync.Mutex
+	state    *mpris.Metadata
+	position float64 // seconds, as of fetched
+	fetched  time.Time
+	err      error
+}
+
+func New() *Client {
+	return &Client{ready: make(chan struct{}), refresh: make(chan struct{}, 1)}
+}
+
+// poll reads the player state every PollInterval, or as soon as a command
+// changed it, for as long as the program runs.
+func (c *Client) poll() {
+	defer guard.Recover()
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		state, position, err := fetch()
+		c.mu.Lock()
+		c.state, c.position, c.fetched, c.err = state, position, time.Now(), err
+		c.mu.Unlock()
+		if first {
+			close(c.ready)
+		}
+		select {
+		case <-ticker.C:
+		case <-c.refresh:
+		}
+	}
+}
+
+// changed has the state read again to show the effect of a command.
+func (c *Client) changed() {
+	select {
+	case c.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func run(script string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "osascript", "-e", script).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("osascript: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// Metadata returns the player state last read, with the position moved on
+// by the time since. The first call waits for the first read.
+func (c *Client) Metadata() (*mpris.Metadata, error) {
+	c.start.Do(func() { go c.poll() })
+	<-c.ready
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	metadata := *c.state
+	position := c.position
+	if metadata.Status == "Playing" {
+		position += time.Since(c.fetched).Seconds()
+	}
+	metadata.Elapsed = min(time.Duration(position*float64(time.Second)), metadata.Duration)
+	metadata.Position = int64(metadata.Elapsed / time.Second)
+	return &metadata, nil
+}
+
+// fetch asks Spotify for the player state, returning the position, in
+// seconds, apart.
+func fetch() (*mpris.Metadata, float64, error) {
+	output, err := run(nowPlaying)
+	if err != nil {
+		return nil, 0, err
+	}
+	if output == "not running" {
+		return nil, 0, ErrNotRunning
+	}
+	fields := strings.Split(output, "\t")
+	if len(fields) != 11 {
+		return nil, 0, fmt.Errorf("osascript: unexpected reply %q", output)
+	}
+
+	metadata := &mpris.Metadata{
+		TrackID:  trackID(fields[0]),
+		Title:    fields[1],
+		Artist:   fields[2],
+		Album:    fields[3],
+		Length:   int64(number(fields[4]) / 1000),
+		Duration: time.Duration(number(fields[4])) * time.Millisecond,
+		ArtURL:   fields[7],
+		Shuffle:  fields[8] == "true",
+		Loop:     "None",
+		Volume:   number(fields[10]) / 100,
+	}
+	switch fields[6] {
+	case "playing":
+		metadata.Status = "Playing"
+	case "paused":
+		metadata.Status = "Paused"
+	default:
+		metadata.Status = "Stopped"
+	}
+	if fields[9] == "true" {
+		metadata.Loop = "Playlist"
+	}
+	if metadata.Artist == "" {
+		metadata.Artist = "Unknown Artist"
+	}
+	return metadata, number(fields[5]), nil
+}
+
+func (c *Client) Identity() string {
+	return "Spotify"
+}
+
+func (c *Client) SetVolume(volume float64) error {
+	percent := int(min(max(volume, 0), 1)*100 + 0.5)
+	_, err := run(`tell application "Spotify" to set sound volume to ` + strconv.Itoa(percent))
+	c.changed()
+	return err
+}
+
+// Call maps MPRIS Player methods onto Spotify's AppleScript commands. Seek
+// and SetPosition take microseconds, as in MPRIS.
+func (c *Client) Call(method string, args ...any) error {
+	var command string
+	switch method {
+	case "Play":
+		command = "play"
+	case "Pause", "Stop":
+		command = "pause"
+	case "PlayPause":
+		command = "playpause"
+	case "Next":
+		command = "next track"
+	case "Previous":
+		command = "previous track"
+	case "Seek", "SetPosition":
+		us, ok := firstInt64(args)
+		if !ok {
+			return fmt.Errorf("osascript: %s takes microseconds as int64", method)
+		}
+		seconds := strconv.FormatFloat(float64(us)/1e6, 'f', 3, 64)
+		command = "set player position to " + seconds
+		if method == "Seek" {
+			command = "set player position to (player position + " + seconds + ")"
+		}
+	case "OpenUri":
+		if len(args) != 1 {
+			return errors.New("osascript: OpenUri takes a URI")
+		}
+		uri, ok := args[0].(string)
+		if !ok {
+			return errors.New("osascript: OpenUri takes a URI")
+		}
+		command = "play track " + strconv.Quote(uri)
+	default:
+		return fmt.Errorf("osascript: %s is not supported", method)
+	}
+	_, err := run(`tell application "Spotify" to ` + command)
+	c.changed()
+	return err
+}
+
+// trackID turns spotify:track:<id> into the MPRIS form /com/spotify/track/<id>
+// the rest of sptsong expects.
+func trackID(uri string) string {
+	parts := strings.Split(uri, ":")
+	if len(parts) != 3 || parts[0] != "spotify" {
+		return uri
+	}
+	return "/com/spotify/" + parts[1] + "/" + parts[2]
+}
+
+// number parses an AppleScript number, which uses the locale's decimal
+// separator.
+func number(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return v
+}
+
+func firstInt64(args []any) (int64, bool) {
+	if len(args) != 1 {
+		return 0, false
+	}
+	v, ok := args[0].(int64)
+	return v, ok
+}
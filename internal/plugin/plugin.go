@@ -0,0 +1,229 @@
+// Package plugin runs the executables in sptsong's plugins directory
+// alongside the display. Each plugin gets the player's events on stdin, one
+// JSON object per line in the form of the event log:
+//
+//	{"event":"track","time":"…","player":"Spotify","track":{"title":"…",…}}
+//
+// and may answer on stdout at any time with JSON lines of its own:
+//
+//	{"lines":["Oslo: 4°C, rain"]}   replace the plugin's panel; [] hides it
+//	{"action":"next"}               play, pause, toggle, next or prev
+//
+// A plugin runs until the display quits, which closes its stdin. What it
+// writes to stderr goes to the log.
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"sptsong/internal/guard"
+	"sptsong/internal/notify"
+)
+
+// Actions are the actions a plugin may ask for.
+var Actions = []string{"play", "pause", "toggle", "next", "prev"}
+
+// Message is something a plugin asked of the display. Lines is nil unless
+// the plugin replaced its panel; a plugin that exits leaves an empty panel.
+type Message struct {
+	Plugin string
+	Lines  []string
+	Action string
+}
+
+type message struct {
+	Lines  []string `json:"lines"`
+	Action string   `json:"action"`
+}
+
+// queueSize bounds the events waiting for a slow plugin; beyond it new
+// events are dropped rather than stalling the display.
+const queueSize = 16
+
+// stopTimeout is how long a plugin has to exit once its stdin is closed.
+const stopTimeout = 2 * time.Second
+
+type plugin struct {
+	name   string
+	cmd    *exec.Cmd
+	events chan notify.Event
+	done   chan struct{}
+}
+
+// Host runs the plugins. A nil Host runs none.
+type Host struct {
+	plugins  []*plugin
+	messages chan Message
+}
+
+// Start runs every executable file in dir, in name order. A missing
+// directory means no plugins; a plugin that fails to start is logged and
+// left out.
+func Start(dir string) (*Host, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := &Host{messages: make(chan Message, queueSize)}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0o111 == 0 {
+			continue
+		}
+		p, err := h.start(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			slog.Warn("starting plugin", "plugin", entry.Name(), "err", err)
+			continue
+		}
+		h.plugins = append(h.plugins, p)
+	}
+	if len(h.plugins) == 0 {
+		return nil, nil
+	}
+	return h, nil
+}
+
+func (h *Host) start(path string) (*plugin, error) {
+	p := &plugin{
+		name:   filepath.Base(path),
+		cmd:    exec.Command(path),
+		events: make(chan notify.Event, queueSize),
+		done:   make(chan struct{}),
+	}
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := p.cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cmd.Start(); err != nil {
+		return nil, err
+	}
+	slog.Info("plugin started", "plugin", p.name, "pid", p.cmd.Process.Pid)
+
+	go p.write(stdin)
+	logged := make(chan struct{})
+	go func() {
+		defer guard.Recover()
+		p.logStderr(stderr)
+		close(logged)
+	}()
+	go func() {
+		defer guard.Recover()
+		p.read(stdout, h.messages)
+		<-logged
+		err := p.cmd.Wait()
+		slog.Info("plugin exited", "plugin", p.name, "err", err)
+		h.messages <- Message{Plugin: p.name, Lines: []string{}}
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// write hands the plugin its events until the host closes.
+func (p *plugin) write(stdin io.WriteCloser) {
+	defer guard.Recover()
+	defer stdin.Close()
+	encoder := json.NewEncoder(stdin)
+	for e := range p.events {
+		if err := encoder.Encode(e); err != nil {
+			// The plugin stopped reading; keep draining so Publish
+			// never blocks.
+			for range p.events {
+			}
+			return
+		}
+	}
+}
+
+// read passes on what the plugin asks for, skipping lines that aren't a
+// message it may send.
+func (p *plugin) read(stdout io.Reader, messages chan<- Message) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			slog.Warn("plugin sent something other than JSON", "plugin", p.name, "line", scanner.Text())
+			continue
+		}
+		if m.Action != "" && !slices.Contains(Actions, m.Action) {
+			slog.Warn("plugin asked for an unknown action", "plugin", p.name, "action", m.Action)
+			m.Action = ""
+		}
+		if m.Lines == nil && m.Action == "" {
+			continue
+		}
+		messages <- Message{Plugin: p.name, Lines: m.Lines, Action: m.Action}
+	}
+}
+
+func (p *plugin) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		slog.Warn("plugin: "+scanner.Text(), "plugin", p.name)
+	}
+}
+
+// Messages delivers what the plugins ask for. It is nil for a nil Host.
+func (h *Host) Messages() <-chan Message {
+	if h == nil {
+		return nil
+	}
+	return h.messages
+}
+
+// Publish queues e for every plugin.
+func (h *Host) Publish(e notify.Event) {
+	if h == nil {
+		return
+	}
+	for _, p := range h.plugins {
+		select {
+		case p.events <- e:
+		default:
+		}
+	}
+}
+
+// Close closes the plugins' stdin and waits for them to exit, killing the
+// ones that take too long. Messages still unread are dropped.
+func (h *Host) Close() {
+	if h == nil {
+		return
+	}
+	for _, p := range h.plugins {
+		close(p.events)
+	}
+	deadline := time.After(stopTimeout)
+	for _, p := range h.plugins {
+		for waiting := true; waiting; {
+			select {
+			case <-p.done:
+				waiting = false
+			case <-h.messages:
+			case <-deadline:
+				for _, p := range h.plugins {
+					p.cmd.Process.Kill()
+				}
+				deadline = nil
+			}
+		}
+	}
+}
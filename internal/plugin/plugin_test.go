@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/notify"
+)
+
+func TestHost(t *testing.T) {
+	dir := t.TempDir()
+	// Answers every event with a panel naming the track, and asks for the
+	// next one when it hears of a pause.
+	script := `#!/bin/sh
+echo '{"lines":["ready"]}'
+while read -r line; do
+	case "$line" in
+	*'"event":"paused"'*) echo '{"action":"next"}' ;;
+	*) echo '{"lines":["got an event"]}' ;;
+	esac
+done
+`
+	os.WriteFile(filepath.Join(dir, "echo"), []byte(script), 0o755)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0o644)
+
+	h, err := Start(dir)
+	if err != nil || h == nil {
+		t.Fatalf("Start = %v, %v", h, err)
+	}
+	next := func() Message {
+		select {
+		case m := <-h.Messages():
+			return m
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message from the plugin")
+		}
+		return Message{}
+	}
+
+	if m := next(); m.Plugin != "echo" || len(m.Lines) != 1 || m.Lines[0] != "ready" {
+		t.Errorf("first message = %+v", m)
+	}
+	h.Publish(notify.Event{Kind: notify.TrackChanged, Time: time.Now(), Track: mpris.Metadata{Title: "Song"}})
+	if m := next(); len(m.Lines) != 1 || m.Lines[0] != "got an event" {
+		t.Errorf("after a track change = %+v", m)
+	}
+	h.Publish(notify.Event{Kind: notify.Paused, Time: time.Now()})
+	if m := next(); m.Action != "next" || m.Lines != nil {
+		t.Errorf("after a pause = %+v", m)
+	}
+	h.Close()
+}
+
+func TestStartWithoutPlugins(t *testing.T) {
+	h, err := Start(filepath.Join(t.TempDir(), "missing"))
+	if h != nil || err != nil {
+		t.Errorf("Start(missing) = %v, %v", h, err)
+	}
+	var none *Host
+	none.Publish(notify.Event{})
+	none.Close()
+	if none.Messages() != nil {
+		t.Error("a nil host has messages")
+	}
+}
@@ -0,0 +1,166 @@
+// Package pulse follows the audio output through pactl, which talks to
+// PulseAudio and to PipeWire's PulseAudio server alike.
+package pulse
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"sptsong/internal/guard"
+)
+
+// Output is where sound goes: the default sink and its active port, such as
+// headphones on a jack.
+type Output struct {
+	Sink string
+	Port string
+	// Unplugged lists the sink's ports known to be unplugged and Sinks the
+	// names of all sinks, separated by spaces so that an Output can be
+	// compared with ==.
+	Unplugged string
+	Sinks     string
+}
+
+// Plugged reports whether the active port isn't known to be unplugged.
+func (o Output) Plugged() bool {
+	return !o.unplugged(o.Port)
+}
+
+func (o Output) unplugged(port string) bool {
+	return port != "" && slices.Contains(strings.Fields(o.Unplugged), port)
+}
+
+// Disconnected reports whether the output went away between before and
+// after: the sink vanished and playback fell back to another one, as when
+// Bluetooth headphones drop, or the port in use was unplugged, as when
+// headphones come out of the jack, whether or not the sink then switched to
+// its speakers.
+func Disconnected(before, after Output) bool {
+	if before.Sink == "" {
+		return false
+	}
+	if after.Sink != before.Sink {
+		// Choosing another output by hand leaves the old one there.
+		return !slices.Contains(strings.Fields(after.Sinks), before.Sink)
+	}
+	return before.Plugged() && after.unplugged(before.Port)
+}
+
+func pactl(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "pactl", args...)
+	// The output is parsed, so it mustn't be translated.
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	return cmd
+}
+
+// Current asks the sound server for the output in use.
+func Current(ctx context.Context) (Output, error) {
+	info, err := pactl(ctx, "info").Output()
+	if err != nil {
+		return Output{}, err
+	}
+	sink := field(string(info), "Default Sink")
+	sinks, err := pactl(ctx, "list", "sinks").Output()
+	if err != nil {
+		return Output{}, err
+	}
+	return parseSinks(string(sinks), sink), nil
+}
+
+// field returns the value of a "Key: value" line.
+func field(text, key string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), key+": "); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// parseSinks finds sink in the output of `pactl list sinks` and returns it
+// with its active port and whether that is plugged in.
+func parseSinks(text, sink string) Output {
+	out := Output{Sink: sink}
+	var names []string
+	for _, block := range strings.Split(text, "\nSink #") {
+		name := field(block, "Name")
+		names = append(names, name)
+		if name != sink {
+			continue
+		}
+		out.Port = field(block, "Active Port")
+		var unplugged []string
+		for _, line := range strings.Split(block, "\n") {
+			name, desc, ok := strings.Cut(strings.TrimSpace(line), ": ")
+			if ok && strings.HasSuffix(desc, "not available)") {
+				unplugged = append(unplugged, name)
+			}
+		}
+		out.Unplugged = strings.Join(unplugged, " ")
+	}
+	out.Sinks = strings.Join(names, " ")
+	return out
+}
+
+// Watch sends the output in use, then again each time the sound server
+// reports a change to sinks, cards or the default sink, until ctx is done.
+// Bursts of events are coalesced, and unchanged outputs aren't sent again.
+// The channel closes if pactl can't be started or exits.
+func Watch(ctx context.Context) <-chan Output {
+	outputs := make(chan Output)
+	go func() {
+		defer guard.Recover()
+		defer close(outputs)
+		cmd := pactl(ctx, "subscribe")
+		stdout, err := cmd.StdoutPipe()
+		if err != nil || cmd.Start() != nil {
+			return
+		}
+		defer cmd.Wait()
+
+		events := make(chan struct{}, 1)
+		go func() {
+			defer guard.Recover()
+			defer close(events)
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				line := scanner.Text()
+				if strings.Contains(line, " on sink #") || strings.Contains(line, " on card #") || strings.Contains(line, " on server") {
+					select {
+					case events <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}()
+
+		var last Output
+		for {
+			out, err := Current(ctx)
+			if err == nil && out != last {
+				last = out
+				select {
+				case outputs <- out:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			}
+			// A disconnect is a few events in a row; look once they settle.
+			time.Sleep(300 * time.Millisecond)
+		}
+	}()
+	return outputs
+}
@@ -0,0 +1,63 @@
+package pulse
+
+import "testing"
+
+const sinks = `Sink #0
+	State: SUSPENDED
+	Name: alsa_output.pci-0000_00_1f.3.analog-stereo
+	Description: Built-in Audio Analog Stereo
+	Ports:
+		analog-output-speaker: Speakers (type: Speaker, priority: 10000, availability group: Legacy 1, availability unknown)
+		analog-output-headphones: Headphones (type: Headphones, priority: 9900, availability group: Legacy 2, not available)
+	Active Port: analog-output-headphones
+
+Sink #57
+	State: RUNNING
+	Name: bluez_output.00_1B_66_AA_BB_CC.1
+	Description: Headphones
+	Ports:
+		headphone-output: Headphone (type: Headphones, priority: 0, available)
+	Active Port: headphone-output
+`
+
+func TestParseSinks(t *testing.T) {
+	got := parseSinks(sinks, "bluez_output.00_1B_66_AA_BB_CC.1")
+	want := Output{
+		Sink:  "bluez_output.00_1B_66_AA_BB_CC.1",
+		Port:  "headphone-output",
+		Sinks: "alsa_output.pci-0000_00_1f.3.analog-stereo bluez_output.00_1B_66_AA_BB_CC.1",
+	}
+	if got != want || !got.Plugged() {
+		t.Errorf("parseSinks(bluez) = %+v, want %+v", got, want)
+	}
+	got = parseSinks(sinks, "alsa_output.pci-0000_00_1f.3.analog-stereo")
+	if got.Port != "analog-output-headphones" || got.Unplugged != "analog-output-headphones" || got.Plugged() {
+		t.Errorf("parseSinks(alsa) = %+v, want unplugged headphones", got)
+	}
+}
+
+func TestDisconnected(t *testing.T) {
+	bluetooth := Output{Sink: "bluez", Port: "headphone-output"}
+	speakers := Output{Sink: "alsa", Port: "analog-output-speaker", Unplugged: "analog-output-headphones"}
+	jack := Output{Sink: "alsa", Port: "analog-output-headphones"}
+	unplugged := Output{Sink: "alsa", Port: "analog-output-headphones", Unplugged: "analog-output-headphones"}
+	tests := []struct {
+		name          string
+		before, after Output
+		want          bool
+	}{
+		{"bluetooth dropped", bluetooth, speakers, true},
+		{"speakers chosen by hand", bluetooth, Output{Sink: "alsa", Port: "analog-output-speaker", Sinks: "alsa bluez"}, false},
+		{"jack unplugged", jack, unplugged, true},
+		{"unplugged and switched to speakers", jack, speakers, true},
+		{"plugged in", speakers, jack, false},
+		{"switched to speakers by hand", jack, Output{Sink: "alsa", Port: "analog-output-speaker"}, false},
+		{"nothing known before", Output{}, speakers, false},
+		{"unplugged port switched", unplugged, speakers, false},
+	}
+	for _, tt := range tests {
+		if got := Disconnected(tt.before, tt.after); got != tt.want {
+			t.Errorf("%s: Disconnected() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
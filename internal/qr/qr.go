@@ -0,0 +1,455 @@
+// Package qr encodes short texts, such as track links, as QR codes: byte
+// mode, error correction level M, versions 1 to 10. That holds up to 213
+// bytes, plenty for a URL, and keeps the encoder small.
+package qr
+
+import (
+	"errors"
+)
+
+// blockLayout is how a version splits its codewords at level M: count
+// blocks of short data codewords, then long blocks of short+1, each
+// followed by ec error correction codewords.
+type blockLayout struct {
+	ec, count, short, long int
+}
+
+// layouts is indexed by version.
+var layouts = [...]blockLayout{
+	1:  {10, 1, 16, 0},
+	2:  {16, 1, 28, 0},
+	3:  {26, 1, 44, 0},
+	4:  {18, 2, 32, 0},
+	5:  {24, 2, 43, 0},
+	6:  {16, 4, 27, 0},
+	7:  {18, 4, 31, 0},
+	8:  {22, 2, 38, 2},
+	9:  {22, 3, 36, 2},
+	10: {26, 4, 43, 1},
+}
+
+// alignments are the centre coordinates of the alignment patterns, indexed
+// by version.
+var alignments = [...][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// remainderBits pad the placed data out to the end of the matrix.
+var remainderBits = [...]int{1: 0, 2: 7, 3: 7, 4: 7, 5: 7, 6: 7, 7: 0, 8: 0, 9: 0, 10: 0}
+
+// ErrTooLong is returned for texts that don't fit version 10.
+var ErrTooLong = errors.New("qr: text too long")
+
+// Code is an encoded QR code: Modules[y][x] is true for dark modules. It
+// doesn't include the quiet zone.
+type Code struct {
+	Modules [][]bool
+
+	function [][]bool
+}
+
+// Size returns the width and height in modules.
+func (c *Code) Size() int {
+	return len(c.Modules)
+}
+
+// Encode returns text as the smallest QR code that holds it.
+func Encode(text string) (*Code, error) {
+	version := 0
+	for v := 1; v < len(layouts); v++ {
+		l := layouts[v]
+		capacity := l.count*l.short + l.long*(l.short+1)
+		if headerBits(v)+8*len(text) <= 8*capacity {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	c := newCode(version)
+	c.drawFunctionPatterns(version)
+	c.drawCodewords(codewords(version, text), remainderBits[version])
+
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask) // masks undo themselves
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	return c, nil
+}
+
+// headerBits is the length of the mode indicator and character count.
+func headerBits(version int) int {
+	if version < 10 {
+		return 4 + 8
+	}
+	return 4 + 16
+}
+
+// codewords returns the data and error correction codewords of text,
+// interleaved in the order they are placed.
+func codewords(version int, text string) []byte {
+	l := layouts[version]
+	capacity := l.count*l.short + l.long*(l.short+1)
+
+	var bits bitWriter
+	bits.write(0b0100, 4)
+	bits.write(len(text), headerBits(version)-4)
+	for i := 0; i < len(text); i++ {
+		bits.write(int(text[i]), 8)
+	}
+	// Terminator, then pad to a byte and fill with the alternating pad
+	// codewords.
+	bits.write(0, min(4, 8*capacity-bits.n))
+	bits.write(0, (8-bits.n%8)%8)
+	data := bits.bytes
+	for pad := byte(0xec); len(data) < capacity; pad ^= 0xec ^ 0x11 {
+		data = append(data, pad)
+	}
+
+	divisor := reedSolomonDivisor(l.ec)
+	var blocks, ecs [][]byte
+	for i := range l.count + l.long {
+		n := l.short
+		if i >= l.count {
+			n++
+		}
+		blocks = append(blocks, data[:n])
+		ecs = append(ecs, reedSolomonRemainder(data[:n], divisor))
+		data = data[n:]
+	}
+
+	var out []byte
+	for i := range l.short + 1 {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := range l.ec {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+type bitWriter struct {
+	bytes []byte
+	n     int
+}
+
+func (w *bitWriter) write(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+		if value>>i&1 == 1 {
+			w.bytes[w.n/8] |= 0x80 >> (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z <<= 1
+		z ^= carry * 0x1d
+		z ^= (y >> i & 1) * x
+	}
+	return z
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree,
+// highest coefficient first, without the leading 1.
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data.
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+func newCode(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{}
+	for range size {
+		c.Modules = append(c.Modules, make([]bool, size))
+		c.function = append(c.function, make([]bool, size))
+	}
+	return c
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.Modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(version int) {
+	size := c.Size()
+	for i := range size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	for _, corner := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= size || y < 0 || y >= size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				c.set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	positions := alignments[version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// The corners with finder patterns have none.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas; the mask loop fills them in.
+	c.drawFormatBits(0)
+
+	if version >= 7 {
+		rem := version
+		for range 12 {
+			rem = rem<<1 ^ (rem>>11)*0x1f25
+		}
+		bits := version<<12 | rem
+		for i := range 18 {
+			dark := bits>>i&1 == 1
+			a, b := size-11+i%3, i/3
+			c.set(a, b, dark)
+			c.set(b, a, dark)
+		}
+	}
+}
+
+// formatBits returns the 15 format bits for level M and mask.
+func formatBits(mask int) int {
+	const levelM = 0b00
+	data := levelM<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	size := c.Size()
+
+	for i := range 6 {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		c.set(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, size-15+i, bit(i))
+	}
+	c.set(8, size-8, true) // the dark module
+}
+
+// drawCodewords places data in the zigzag of column pairs from the bottom
+// right, skipping the function patterns.
+func (c *Code) drawCodewords(data []byte, remainder int) {
+	size := c.Size()
+	i, total := 0, 8*len(data)+remainder
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := range size {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y][x] || i >= total {
+					continue
+				}
+				if i < 8*len(data) {
+					c.Modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+				}
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y, row := range c.Modules {
+		for x := range row {
+			if c.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			row[x] = row[x] != invert
+		}
+	}
+}
+
+// penalty scores how hard the code is to scan, by the four rules of the
+// standard: long runs, 2×2 blocks, finder-like patterns and an unbalanced
+// share of dark modules.
+func (c *Code) penalty() int {
+	size := c.Size()
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return c.Modules[x][y]
+		}
+		return c.Modules[y][x]
+	}
+
+	penalty := 0
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, transpose := range []bool{false, true} {
+		for y := range size {
+			run := 1
+			for x := 1; x <= size; x++ {
+				if x < size && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+			for x := 0; x+7 <= size; x++ {
+				match := true
+				for k, dark := range finder {
+					if at(x+k, y, transpose) != dark {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				light := func(from, to int) bool {
+					for k := from; k < to; k++ {
+						if k >= 0 && k < size && at(k, y, transpose) {
+							return false
+						}
+					}
+					return true
+				}
+				if light(x-4, x) || light(x+7, x+11) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := range size {
+		for x := range size {
+			if c.Modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				v := c.Modules[y][x]
+				if c.Modules[y][x+1] == v && c.Modules[y+1][x] == v && c.Modules[y+1][x+1] == v {
+					penalty += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (size * size)
+	penalty += abs(percent-50) / 5 * 10
+	return penalty
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,126 @@
+package qr
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at 1-M, from the worked example in the standard's
+	// tutorials.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = %v, want %v", got, want)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	for mask, want := range []int{
+		0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011,
+		0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000,
+	} {
+		if got := formatBits(mask); got != want {
+			t.Errorf("formatBits(%d) = %015b, want %015b", mask, got, want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		text    string
+		version int
+	}{
+		{"hi", 1},
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", 4},
+		{"https://open.spotify.com/episode/4uLU6hMCjMI75M1A2tKUQC?si=0123456789abcdef", 5},
+		{string(bytes.Repeat([]byte("x"), 160)), 9},
+	} {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", tt.text, err)
+		}
+		if want := 17 + 4*tt.version; c.Size() != want {
+			t.Errorf("Encode(%q) is %d modules wide, want %d", tt.text, c.Size(), want)
+		}
+		if got, want := readBack(c, tt.version), codewords(tt.version, tt.text); !bytes.Equal(got, want) {
+			t.Errorf("Encode(%q) reads back as %v, want %v", tt.text, got, want)
+		}
+	}
+
+	if _, err := Encode(string(bytes.Repeat([]byte("x"), 214))); err != ErrTooLong {
+		t.Errorf("Encode of 214 bytes = %v, want ErrTooLong", err)
+	}
+}
+
+// readBack undoes Encode as a scanner would: it finds the mask in the
+// format bits, checks both copies agree, and collects the codewords.
+func readBack(c *Code, version int) []byte {
+	size := c.Size()
+	first, second := 0, 0
+	for i := range 15 {
+		var x, y int
+		switch {
+		case i < 6:
+			x, y = 8, i
+		case i < 8:
+			x, y = 8, i+1
+		case i == 8:
+			x, y = 7, 8
+		default:
+			x, y = 14-i, 8
+		}
+		if c.Modules[y][x] {
+			first |= 1 << i
+		}
+		if i < 8 {
+			x, y = size-1-i, 8
+		} else {
+			x, y = 8, size-15+i
+		}
+		if c.Modules[y][x] {
+			second |= 1 << i
+		}
+	}
+	if first != second {
+		return nil
+	}
+	mask := -1
+	for m := range 8 {
+		if formatBits(m) == first {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return nil
+	}
+
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+	l := layouts[version]
+	total := l.count*(l.short+l.ec) + l.long*(l.short+1+l.ec)
+	out := make([]byte, total)
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y][x] || i >= 8*total {
+					continue
+				}
+				if c.Modules[y][x] {
+					out[i/8] |= 0x80 >> (i % 8)
+				}
+				i++
+			}
+		}
+	}
+	return out
+}
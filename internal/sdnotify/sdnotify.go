@@ -0,0 +1,57 @@
+// Package sdnotify tells systemd how a Type=notify service is doing through
+// the socket in $NOTIFY_SOCKET: that it's ready, that it's still alive for
+// the watchdog and what it's up to, as sd_notify(3) does.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States to send with Notify.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status is the state that shows text in systemctl status.
+func Status(text string) string {
+	return "STATUS=" + text
+}
+
+// Notify sends state to systemd. It does nothing when the process wasn't
+// started by a unit that listens for notifications.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// An abstract socket.
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns how often to send Watchdog: half the unit's
+// WatchdogSec, so a late tick doesn't get the service killed. It is zero
+// when the unit has no watchdog or the watchdog is another process's.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
@@ -0,0 +1,137 @@
+package spectrum
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"sptsong/internal/guard"
+)
+
+// Levels is one frame of the level meter: each channel's loudness and the
+// peak it recently reached, from 0 to 1 over the bottom 60 dB.
+type Levels struct {
+	Left, Right         float64
+	LeftPeak, RightPeak float64
+}
+
+// meterFrames samples are measured per frame, about 23 ms.
+const meterFrames = 1024
+
+// Meter sends the levels of app's playback stream, found among the sound
+// server's sink inputs by application name or binary, until ctx is done.
+// While app plays nothing it looks for the stream again every few seconds.
+// The channel closes when pactl or parec are missing or the sound server
+// can't be reached.
+func Meter(ctx context.Context, app string) <-chan Levels {
+	levels := make(chan Levels)
+	go func() {
+		defer guard.Recover()
+		defer close(levels)
+		if _, err := exec.LookPath("parec"); err != nil {
+			return
+		}
+		for ctx.Err() == nil {
+			index, err := findStream(ctx, app)
+			if err != nil {
+				return
+			}
+			if index >= 0 {
+				measure(ctx, index, levels)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(3 * time.Second):
+			}
+		}
+	}()
+	return levels
+}
+
+// findStream returns the index of app's sink input, or -1 while it has
+// none.
+func findStream(ctx context.Context, app string) (int, error) {
+	cmd := exec.CommandContext(ctx, "pactl", "list", "sink-inputs")
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if err != nil {
+		return -1, err
+	}
+	return parseSinkInputs(string(out), app), nil
+}
+
+func parseSinkInputs(text, app string) int {
+	app = strings.ToLower(app)
+	for _, block := range strings.Split(text, "Sink Input #")[1:] {
+		index, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(block, "\n", 2)[0]))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(block, "\n") {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), " = ")
+			if !ok || (key != "application.name" && key != "application.process.binary") {
+				continue
+			}
+			if strings.Contains(strings.ToLower(strings.Trim(value, `"`)), app) {
+				return index
+			}
+		}
+	}
+	return -1
+}
+
+// measure records the sink input with parec and sends its levels until the
+// stream ends.
+func measure(ctx context.Context, index int, levels chan<- Levels) {
+	cmd := exec.CommandContext(ctx, "parec", "--monitor-stream="+strconv.Itoa(index), "--format=s16le",
+		"--rate="+strconv.Itoa(sampleRate), "--channels=2", "--latency-msec=20")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil || cmd.Start() != nil {
+		return
+	}
+	defer cmd.Wait()
+	var m meter
+	samples := make([]int16, 2*meterFrames)
+	for {
+		if err := binary.Read(stdout, binary.LittleEndian, samples); err != nil {
+			return
+		}
+		select {
+		case levels <- m.frame(samples):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// meter turns interleaved stereo samples into Levels, with peaks that
+// hold and then fall slowly.
+type meter struct {
+	last Levels
+}
+
+func (m *meter) frame(samples []int16) Levels {
+	var sum [2]float64
+	for i, s := range samples {
+		v := float64(s) / 32768
+		sum[i%2] += v * v
+	}
+	frames := float64(len(samples) / 2)
+	level := func(sum float64) float64 {
+		rms := math.Sqrt(sum / frames)
+		if rms <= 0 {
+			return 0
+		}
+		return min(max((20*math.Log10(rms)+60)/60, 0), 1)
+	}
+	l := Levels{Left: level(sum[0]), Right: level(sum[1])}
+	l.LeftPeak = max(l.Left, m.last.LeftPeak-0.01)
+	l.RightPeak = max(l.Right, m.last.RightPeak-0.01)
+	m.last = l
+	return l
+}
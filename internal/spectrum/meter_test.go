@@ -0,0 +1,48 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+)
+
+const sinkInputs = `Sink Input #12
+	Driver: protocol-native.c
+	Properties:
+		application.name = "Firefox"
+		application.process.binary = "firefox"
+
+Sink Input #57
+	Driver: protocol-native.c
+	Properties:
+		media.name = "Spotify"
+		application.name = "spotify"
+		application.process.binary = "spotify"
+`
+
+func TestParseSinkInputs(t *testing.T) {
+	if got := parseSinkInputs(sinkInputs, "Spotify"); got != 57 {
+		t.Errorf("parseSinkInputs(Spotify) = %d, want 57", got)
+	}
+	if got := parseSinkInputs(sinkInputs, "mpd"); got != -1 {
+		t.Errorf("parseSinkInputs(mpd) = %d, want -1", got)
+	}
+}
+
+func TestMeter(t *testing.T) {
+	// A full-scale sine on the left, silence on the right.
+	samples := make([]int16, 2*meterFrames)
+	for i := range meterFrames {
+		samples[2*i] = int16(32767 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+	}
+	var m meter
+	l := m.frame(samples)
+	// A sine's RMS is 3 dB under its peak: (60-3)/60.
+	if math.Abs(l.Left-0.95) > 0.01 || l.Right != 0 {
+		t.Errorf("frame() = %+v, want left 0.95 and right 0", l)
+	}
+
+	l = m.frame(make([]int16, 2*meterFrames))
+	if l.Left != 0 || math.Abs(l.LeftPeak-0.94) > 0.01 {
+		t.Errorf("after silence frame() = %+v, want the left peak falling from 0.95", l)
+	}
+}
@@ -0,0 +1,246 @@
+// Package spectrum produces levels for an audio visualizer, from cava's raw
+// output or from the sound server's monitor of what is playing, and for a
+// level meter of one player's stream.
+package spectrum
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"sptsong/internal/guard"
+)
+
+// ErrNoSource is returned when neither cava nor parec is installed.
+var ErrNoSource = errors.New("no visualizer source: install cava, or parec for PulseAudio and PipeWire")
+
+const (
+	sampleRate = 44100
+	// fftSize samples are analysed per frame: about 23 ms of audio, and a
+	// resolution of 43 Hz per bin.
+	fftSize = 1024
+	cavaMax = 1000
+)
+
+// Start runs source, "cava", "pulse" or "auto" for cava when it's
+// installed and the monitor otherwise, and sends levels from 0 to 1 for
+// bars bars, lowest frequencies first, until ctx is done. The channel
+// closes if the source stops.
+func Start(ctx context.Context, source string, bars int) (<-chan []float64, error) {
+	if source == "auto" {
+		source = "pulse"
+		if _, err := exec.LookPath("cava"); err == nil {
+			source = "cava"
+		}
+	}
+	switch source {
+	case "cava":
+		return startCava(ctx, bars)
+	case "pulse":
+		return startMonitor(ctx, bars)
+	}
+	return nil, fmt.Errorf("unknown visualizer %q: want cava, pulse or auto", source)
+}
+
+// startCava runs cava with a config of our own that writes frames as lines
+// of semicolon-separated levels.
+func startCava(ctx context.Context, bars int) (<-chan []float64, error) {
+	if _, err := exec.LookPath("cava"); err != nil {
+		return nil, ErrNoSource
+	}
+	dir, err := os.MkdirTemp("", "sptsong-cava")
+	if err != nil {
+		return nil, err
+	}
+	config := filepath.Join(dir, "config")
+	err = os.WriteFile(config, []byte(fmt.Sprintf(`[general]
+bars = %d
+framerate = 30
+
+[output]
+method = raw
+raw_target = /dev/stdout
+data_format = ascii
+ascii_max_range = %d
+bar_delimiter = 59
+frame_delimiter = 10
+`, bars, cavaMax)), 0o600)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "cava", "-p", config)
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	levels := make(chan []float64)
+	go func() {
+		defer guard.Recover()
+		defer close(levels)
+		defer os.RemoveAll(dir)
+		defer cmd.Wait()
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			frame := parseCava(scanner.Text())
+			select {
+			case levels <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return levels, nil
+}
+
+func parseCava(line string) []float64 {
+	var frame []float64
+	for _, field := range strings.Split(strings.TrimSuffix(line, ";"), ";") {
+		n, _ := strconv.Atoi(field)
+		frame = append(frame, min(float64(n)/cavaMax, 1))
+	}
+	return frame
+}
+
+// startMonitor records the default sink's monitor with parec, which works
+// with PulseAudio and PipeWire's PulseAudio server, and analyses it.
+func startMonitor(ctx context.Context, bars int) (<-chan []float64, error) {
+	if _, err := exec.LookPath("parec"); err != nil {
+		return nil, ErrNoSource
+	}
+	cmd := exec.CommandContext(ctx, "parec", "--device=@DEFAULT_MONITOR@", "--format=s16le",
+		"--rate="+strconv.Itoa(sampleRate), "--channels=1", "--latency-msec=20")
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	levels := make(chan []float64)
+	go func() {
+		defer guard.Recover()
+		defer close(levels)
+		defer cmd.Wait()
+		a := newAnalyser(bars)
+		samples := make([]int16, fftSize)
+		for {
+			// A broken stream closes the channel, as parec exiting does.
+			if err := binary.Read(stdout, binary.LittleEndian, samples); err != nil {
+				return
+			}
+			select {
+			case levels <- a.frame(samples):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return levels, nil
+}
+
+// analyser turns windows of samples into bar levels: a Hann-windowed FFT
+// summed into bands spaced evenly in pitch, scaled against a slowly
+// falling peak so quiet music still fills the bars, and smoothed so bars
+// fall gently.
+type analyser struct {
+	edges  []int
+	window []float64
+	peak   float64
+	last   []float64
+}
+
+func newAnalyser(bars int) *analyser {
+	a := &analyser{window: make([]float64, fftSize), last: make([]float64, bars), peak: 1}
+	for i := range a.window {
+		a.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/(fftSize-1))
+	}
+	// Bands from 50 Hz to 16 kHz, at least a bin wide each.
+	const low, high = 50.0, 16000.0
+	binWidth := float64(sampleRate) / fftSize
+	for i := 0; i <= bars; i++ {
+		f := low * math.Pow(high/low, float64(i)/float64(bars))
+		edge := int(f / binWidth)
+		if i > 0 && edge <= a.edges[i-1] {
+			edge = a.edges[i-1] + 1
+		}
+		a.edges = append(a.edges, min(edge, fftSize/2))
+	}
+	return a
+}
+
+func (a *analyser) frame(samples []int16) []float64 {
+	buf := make([]complex128, fftSize)
+	for i, s := range samples {
+		buf[i] = complex(float64(s)/32768*a.window[i], 0)
+	}
+	fft(buf)
+
+	levels := make([]float64, len(a.last))
+	loudest := 0.0
+	for b := range levels {
+		sum := 0.0
+		for k := a.edges[b]; k < max(a.edges[b+1], a.edges[b]+1) && k < fftSize/2; k++ {
+			sum += math.Hypot(real(buf[k]), imag(buf[k]))
+		}
+		levels[b] = math.Log1p(sum)
+		loudest = max(loudest, levels[b])
+	}
+	a.peak = max(a.peak*0.995, loudest, 0.5)
+	for b := range levels {
+		level := levels[b] / a.peak
+		// Rise at once, fall a little each frame.
+		levels[b] = max(level, a.last[b]*0.85)
+	}
+	copy(a.last, levels)
+	return levels
+}
+
+// fft transforms x in place; its length has to be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := range size / 2 {
+				w := complex(math.Cos(step*float64(k)), math.Sin(step*float64(k)))
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+			}
+		}
+	}
+}
+
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Bars draws levels as a row of eighth blocks.
+func Bars(levels []float64) string {
+	row := make([]rune, len(levels))
+	for i, level := range levels {
+		row[i] = blocks[int(min(max(level, 0), 1)*float64(len(blocks)-1)+0.5)]
+	}
+	return string(row)
+}
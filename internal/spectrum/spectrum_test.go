@@ -0,0 +1,57 @@
+package spectrum
+
+import (
+	"math"
+	"math/cmplx"
+	"reflect"
+	"testing"
+)
+
+func TestFFT(t *testing.T) {
+	// A cosine at bin 5 puts half its energy in bin 5 and half in its
+	// mirror image.
+	x := make([]complex128, 64)
+	for i := range x {
+		x[i] = complex(math.Cos(2*math.Pi*5*float64(i)/64), 0)
+	}
+	fft(x)
+	for k, v := range x {
+		want := 0.0
+		if k == 5 || k == 59 {
+			want = 32
+		}
+		if math.Abs(cmplx.Abs(v)-want) > 1e-9 {
+			t.Errorf("bin %d = %.3f, want %v", k, cmplx.Abs(v), want)
+		}
+	}
+}
+
+func TestAnalyserFindsTone(t *testing.T) {
+	a := newAnalyser(16)
+	samples := make([]int16, fftSize)
+	for i := range samples {
+		samples[i] = int16(20000 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate))
+	}
+	levels := a.frame(samples)
+	loudest := 0
+	for b := range levels {
+		if levels[b] > levels[loudest] {
+			loudest = b
+		}
+	}
+	// 1 kHz is in the band whose edges surround it.
+	binWidth := float64(sampleRate) / fftSize
+	if low, high := float64(a.edges[loudest])*binWidth, float64(a.edges[loudest+1])*binWidth; low > 1000 || high < 1000 {
+		t.Errorf("loudest band %d covers %.0f–%.0f Hz, want 1 kHz in it", loudest, low, high)
+	}
+}
+
+func TestParseCava(t *testing.T) {
+	got := parseCava("0;500;1000;")
+	if want := []float64{0, 0.5, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCava() = %v, want %v", got, want)
+	}
+	if bars := Bars(got); bars != " ▄█" {
+		t.Errorf("Bars() = %q", bars)
+	}
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// asciiGlyphs are the stand-ins for the symbols the display draws.
+var asciiGlyphs = map[rune]rune{
+	'─': '-', '━': '=', '═': '=', '│': '|', '┃': '|', '║': '|',
+	'╭': '+', '╮': '+', '╰': '+', '╯': '+', '┌': '+', '┐': '+', '└': '+', '┘': '+',
+	'┏': '+', '┓': '+', '┗': '+', '┛': '+', '╔': '+', '╗': '+', '╚': '+', '╝': '+',
+	'┼': '+', '╋': '+',
+	'█': '#', '▉': '#', '▊': '#', '▋': '#', '▌': '#', '▍': '#', '▎': '#', '▏': '#',
+	'▇': '#', '▆': '=', '▅': '=', '▄': '=', '▃': '_', '▂': '_', '▁': '_',
+	'▀': '"', '░': '.', '▒': ':', '▓': '#',
+	'♫': '*', '♪': '*', '▶': '>', '▸': '>', '⏸': '|', '■': '#', '⚠': '!', '☾': 'z',
+	'§': '#', '×': 'x', '…': '.', '–': '-', '—': '-', '·': '.', '↑': '^', '↓': 'v',
+	'←': '<', '→': '>', '‘': '\'', '’': '\'', '“': '"', '”': '"',
+}
+
+// ASCII replaces what a plain ASCII terminal can't show: the display's
+// symbols by look-alikes, accented letters by their base letters and any
+// other character by a question mark for each column it took, so the layout
+// stays in place.
+func ASCII(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case r < 0x80:
+			b.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// The accent of a decomposed letter.
+		default:
+			width := cells.RuneWidth(r)
+			if glyph, ok := asciiGlyphs[r]; ok && width > 0 {
+				b.WriteString(string(glyph) + strings.Repeat(" ", width-1))
+			} else {
+				b.WriteString(strings.Repeat("?", width))
+			}
+		}
+	}
+	return b.String()
+}
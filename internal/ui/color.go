@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ColorDepth is how many colors the terminal can show.
+type ColorDepth int32
+
+const (
+	Colors16 ColorDepth = iota
+	Colors256
+	ColorsFull
+)
+
+// ParseColorDepth reads a depth as written in the config: "16", "256" or
+// "full" (24-bit).
+func ParseColorDepth(s string) (ColorDepth, bool) {
+	switch s {
+	case "16":
+		return Colors16, true
+	case "256":
+		return Colors256, true
+	case "full", "truecolor", "24bit":
+		return ColorsFull, true
+	}
+	return Colors256, false
+}
+
+func (d ColorDepth) String() string {
+	switch d {
+	case Colors16:
+		return "16"
+	case ColorsFull:
+		return "full"
+	}
+	return "256"
+}
+
+// depth is the color depth Style.Render draws with. It is read from every
+// goroutine that renders, hence atomic.
+var depth atomic.Int32
+
+func init() {
+	depth.Store(int32(Colors256))
+}
+
+// SetColorDepth sets the depth styles are rendered with from then on.
+func SetColorDepth(d ColorDepth) {
+	depth.Store(int32(d))
+}
+
+// colorCode returns the SGR parameters setting hex as the foreground, or
+// the background with bg, at the current depth.
+func colorCode(hex string, bg bool) (string, bool) {
+	switch ColorDepth(depth.Load()) {
+	case ColorsFull:
+		r, g, b, ok := ParseHexColor(hex)
+		if !ok {
+			return "", false
+		}
+		if bg {
+			return fmt.Sprintf("48;2;%d;%d;%d", r, g, b), true
+		}
+		return fmt.Sprintf("38;2;%d;%d;%d", r, g, b), true
+	case Colors16:
+		n, ok := ansi16(hex)
+		if !ok {
+			return "", false
+		}
+		base := 30
+		if n >= 8 {
+			base, n = 90, n-8
+		}
+		if bg {
+			base += 10
+		}
+		return strconv.Itoa(base + n), true
+	}
+	n, ok := xterm256(hex)
+	if !ok {
+		return "", false
+	}
+	if bg {
+		return fmt.Sprintf("48;5;%d", n), true
+	}
+	return fmt.Sprintf("38;5;%d", n), true
+}
+
+// ansiPalette is xterm's default palette for the 16 basic colors.
+var ansiPalette = [16][3]int{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// ansi16 maps a "#rrggbb" color to the closest of the 16 basic colors.
+// Terminals have their own palettes, so this only keeps the hue and
+// brightness roughly right.
+func ansi16(hex string) (int, bool) {
+	r, g, b, ok := ParseHexColor(hex)
+	if !ok {
+		return 0, false
+	}
+	best, bestDist := 0, math.MaxInt
+	for i, c := range ansiPalette {
+		dist := (r-c[0])*(r-c[0]) + (g-c[1])*(g-c[1]) + (b-c[2])*(b-c[2])
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best, true
+}
+
+func ParseHexColor(hex string) (r, g, b int, ok bool) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// xterm256 maps a "#rrggbb" color to the closest entry of the xterm 256-color
+// palette, picking between the 6x6x6 cube and the grayscale ramp.
+func xterm256(hex string) (int, bool) {
+	r, g, b, ok := ParseHexColor(hex)
+	if !ok {
+		return 0, false
+	}
+
+	levels := []int{0, 95, 135, 175, 215, 255}
+	cubeIndex := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	ri, gi, bi := cubeIndex(r), cubeIndex(g), cubeIndex(b)
+	cr, cg, cb := levels[ri], levels[gi], levels[bi]
+
+	grayIndex := 23
+	if avg := (r + g + b) / 3; avg < 238 {
+		grayIndex = (avg - 3) / 10
+		if grayIndex < 0 {
+			grayIndex = 0
+		}
+	}
+	gray := 8 + grayIndex*10
+
+	dist := func(x, y, z int) int {
+		return (r-x)*(r-x) + (g-y)*(g-y) + (b-z)*(b-z)
+	}
+	if dist(gray, gray, gray) < dist(cr, cg, cb) {
+		return 232 + grayIndex, true
+	}
+	return 16 + 36*ri + 6*gi + bi, true
+}
+
+// BlendHex mixes two "#rrggbb" colors, t=0 giving a and t=1 giving b. It
+// returns "" if either color is unset.
+func BlendHex(a, b string, t float64) string {
+	ar, ag, ab, ok := ParseHexColor(a)
+	if !ok {
+		return ""
+	}
+	br, bg, bb, ok := ParseHexColor(b)
+	if !ok {
+		return ""
+	}
+	mix := func(x, y int) int {
+		return x + int(float64(y-x)*t)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", mix(ar, br), mix(ag, bg), mix(ab, bb))
+}
+
+// HSLHex converts hue (degrees), saturation and lightness (0-1) to
+// "#rrggbb".
+func HSLHex(h, s, l float64) string {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = c, x
+	case h < 120:
+		r, g = x, c
+	case h < 180:
+		g, b = c, x
+	case h < 240:
+		g, b = x, c
+	case h < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	return fmt.Sprintf("#%02x%02x%02x", int((r+m)*255+0.5), int((g+m)*255+0.5), int((b+m)*255+0.5))
+}
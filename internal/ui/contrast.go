@@ -0,0 +1,73 @@
+package ui
+
+import "math"
+
+// relativeLuminance follows the WCAG definition for an sRGB "#rrggbb" color.
+func relativeLuminance(hex string) (float64, bool) {
+	r, g, b, ok := ParseHexColor(hex)
+	if !ok {
+		return 0, false
+	}
+	linear := func(v int) float64 {
+		c := float64(v) / 255
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b), true
+}
+
+func contrastRatio(a, b string) float64 {
+	la, okA := relativeLuminance(a)
+	lb, okB := relativeLuminance(b)
+	if !okA || !okB {
+		return math.Inf(1)
+	}
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// IsLight reports whether dark text reads better on bg than light text.
+func IsLight(bg string) bool {
+	return contrastRatio("#000000", bg) > contrastRatio("#ffffff", bg)
+}
+
+// ensureContrast moves fg towards white or black, whichever the background
+// contrasts with more, until it reaches minRatio against bg.
+func ensureContrast(fg, bg string, minRatio float64) string {
+	if contrastRatio(fg, bg) >= minRatio {
+		return fg
+	}
+	target := "#ffffff"
+	if IsLight(bg) {
+		target = "#000000"
+	}
+	for t := 0.1; t < 1; t += 0.1 {
+		if c := BlendHex(fg, target, t); contrastRatio(c, bg) >= minRatio {
+			return c
+		}
+	}
+	return target
+}
+
+// WithContrast returns the theme with its text colors adjusted to at least
+// minRatio contrast against their own background, or against the terminal
+// background when they don't set one.
+func (t Theme) WithContrast(background string, minRatio float64) Theme {
+	if minRatio <= 1 {
+		return t
+	}
+	for _, style := range []*Style{&t.Accent, &t.Title, &t.Artist, &t.Time} {
+		bg := style.Bg
+		if bg == "" {
+			bg = background
+		}
+		if style.Fg != "" {
+			style.Fg = ensureContrast(style.Fg, bg, minRatio)
+		}
+	}
+	return t
+}
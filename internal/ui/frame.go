@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+type borderChars struct {
+	topLeft, topRight, bottomLeft, bottomRight, horizontal, vertical string
+}
+
+var BorderStyles = map[string]borderChars{
+	"rounded": {"╭", "╮", "╰", "╯", "─", "│"},
+	"square":  {"┌", "┐", "└", "┘", "─", "│"},
+	"heavy":   {"┏", "┓", "┗", "┛", "━", "┃"},
+	"double":  {"╔", "╗", "╚", "╝", "═", "║"},
+}
+
+// Frame returns the escape sequences drawing a border of the given style
+// around frame, with title set into the top edge. Unknown styles and frames
+// too small to hold a border draw nothing.
+func Frame(frame Rect, title, border string, style Style) string {
+	chars, ok := BorderStyles[border]
+	if !ok || frame.Width < 4 || frame.Height < 2 {
+		return ""
+	}
+
+	inner := frame.Width - 2
+
+	top := strings.Repeat(chars.horizontal, inner)
+	if title != "" {
+		label := Clip(fmt.Sprintf(" %s ", title), inner-1)
+		top = chars.horizontal + label + strings.Repeat(chars.horizontal, inner-1-Width(label))
+	}
+
+	var b strings.Builder
+	b.WriteString(MoveTo(frame.X, frame.Y) + style.Render(chars.topLeft+top+chars.topRight))
+	for row := 1; row < frame.Height-1; row++ {
+		b.WriteString(MoveTo(frame.X, frame.Y+row) + style.Render(chars.vertical))
+		b.WriteString(MoveTo(frame.X+frame.Width-1, frame.Y+row) + style.Render(chars.vertical))
+	}
+	bottom := chars.bottomLeft + strings.Repeat(chars.horizontal, inner) + chars.bottomRight
+	b.WriteString(MoveTo(frame.X, frame.Y+frame.Height-1) + style.Render(bottom))
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package ui
+
+import "fmt"
+
+const (
+	DefaultFontRatio = 0.5 // cell width / cell height
+
+	TextWidth  = 40
+	TextHeight = 6
+	LayoutGap  = 2
+)
+
+var LayoutNames = []string{"art-left", "art-right", "art-top", "no-art"}
+
+type Rect struct {
+	X, Y, Width, Height int
+}
+
+// Layout places the artwork and the text block inside the widget. Rects are
+// relative to the widget origin until At is called; Art is empty for layouts
+// without artwork.
+type Layout struct {
+	Name          string
+	Art           Rect
+	Text          Rect
+	Width, Height int
+}
+
+// NewLayout arranges the widget for a cover artWidth columns wide, in a
+// terminal whose cells are fontRatio as wide as they are tall.
+func NewLayout(name string, artWidth int, fontRatio float64) Layout {
+	artHeight := ArtRows(artWidth, fontRatio)
+	switch name {
+	case "art-right":
+		return Layout{
+			Name:   name,
+			Text:   Rect{0, 0, TextWidth, TextHeight},
+			Art:    Rect{TextWidth + LayoutGap, 0, artWidth, artHeight},
+			Width:  TextWidth + LayoutGap + artWidth,
+			Height: max(artHeight, TextHeight),
+		}
+	case "art-top":
+		width := max(TextWidth, artWidth)
+		return Layout{
+			Name:   name,
+			Art:    Rect{(width - artWidth) / 2, 0, artWidth, artHeight},
+			Text:   Rect{(width - TextWidth) / 2, artHeight + 1, TextWidth, TextHeight},
+			Width:  width,
+			Height: artHeight + 1 + TextHeight,
+		}
+	case "no-art":
+		return Layout{
+			Name:   name,
+			Text:   Rect{0, 0, TextWidth, TextHeight},
+			Width:  TextWidth,
+			Height: TextHeight,
+		}
+	}
+	return Layout{
+		Name:   "art-left",
+		Art:    Rect{0, 0, artWidth, artHeight},
+		Text:   Rect{artWidth + LayoutGap, 0, TextWidth, TextHeight},
+		Width:  artWidth + LayoutGap + TextWidth,
+		Height: max(artHeight, TextHeight),
+	}
+}
+
+// ArtRows returns how many rows a square cover artWidth columns wide takes.
+func ArtRows(artWidth int, fontRatio float64) int {
+	return max(int(float64(artWidth)*fontRatio+0.5), 1)
+}
+
+func NextLayout(name string) string {
+	for i, n := range LayoutNames {
+		if n == name {
+			return LayoutNames[(i+1)%len(LayoutNames)]
+		}
+	}
+	return LayoutNames[0]
+}
+
+// At translates the layout to absolute, zero-based terminal coordinates.
+func (l Layout) At(x, y int) Layout {
+	l.Art.X += x
+	l.Art.Y += y
+	l.Text.X += x
+	l.Text.Y += y
+	return l
+}
+
+// SpareRows returns how many rows of the widget are free under the text
+// block, where the artwork beside it is taller.
+func (l Layout) SpareRows() int {
+	if l.Name == "art-left" || l.Name == "art-right" {
+		return max(l.Height-TextHeight, 0)
+	}
+	return 0
+}
+
+func (l Layout) HasArt() bool {
+	return l.Art.Width > 0
+}
+
+// MoveTo returns the escape sequence placing the cursor at zero-based (x, y).
+func MoveTo(x, y int) string {
+	return fmt.Sprintf("\033[%d;%dH", y+1, x+1)
+}
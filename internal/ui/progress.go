@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+var partialBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// Bar draws a progress bar of width cells filled to fraction. The smooth
+// style uses eighth blocks for the last partially filled cell; gradient fades
+// the filled part from the theme's bar color to its bar end color. Marks,
+// fractions such as chapter starts, notch the bar where they fall.
+func Bar(theme Theme, smooth, gradient bool, fraction float64, width int, marks ...float64) string {
+	fraction = min(max(fraction, 0), 1)
+
+	var filled []rune
+	if !smooth {
+		filled = []rune(strings.Repeat("━", int(fraction*float64(width))))
+	} else {
+		eighths := int(fraction * float64(width*8))
+		filled = []rune(strings.Repeat("█", eighths/8) + partialBlocks[eighths%8])
+	}
+	empty := []rune(strings.Repeat("─", width-len(filled)))
+
+	for _, mark := range marks {
+		cell := int(mark * float64(width))
+		switch {
+		case cell <= 0 || cell >= width:
+		case cell >= len(filled):
+			empty[cell-len(filled)] = '┼'
+		case filled[cell] == '━':
+			filled[cell] = '╋'
+		case filled[cell] == '█':
+			filled[cell] = '▉'
+		}
+	}
+	return renderFilled(theme, gradient, string(filled), width) + theme.BarEmpty.Render(string(empty))
+}
+
+// renderFilled colors the filled part of the bar, either flat or as a
+// gradient running from the theme's bar color to its bar end color over the
+// whole bar width.
+func renderFilled(theme Theme, gradient bool, filled string, width int) string {
+	end := theme.BarEnd.Fg
+	if end == "" {
+		end = BlendHex(theme.BarFilled.Fg, "#ffffff", 0.5)
+	}
+	if !gradient || end == "" || width < 2 {
+		return theme.BarFilled.Render(filled)
+	}
+
+	var b strings.Builder
+	for i, cell := range []rune(filled) {
+		style := theme.BarFilled
+		style.Fg = BlendHex(theme.BarFilled.Fg, end, float64(i)/float64(width-1))
+		b.WriteString(style.Render(string(cell)))
+	}
+	return b.String()
+}
+
+// FormatDuration formats seconds as mm:ss, or h:mm:ss from an hour on, as
+// podcasts run.
+func FormatDuration(seconds int64) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// cells measures text as terminals lay it out: CJK and emoji take two
+// columns, combining marks none. Ambiguous characters are one column
+// whatever the locale, as the box drawing of the frame assumes.
+var cells = &runewidth.Condition{}
+
+// Width returns the number of terminal columns s takes.
+func Width(s string) int {
+	return cells.StringWidth(s)
+}
+
+// Truncate shortens s to at most width columns, ending it with "…" if it
+// had to cut.
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return cells.Truncate(s, width, "…")
+}
+
+// Clip shortens s to at most width columns without marking the cut.
+func Clip(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return cells.Truncate(s, width, "")
+}
+
+// Pad fills s with spaces up to width columns.
+func Pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-Width(s), 0))
+}
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Style is a foreground/background pair of "#rrggbb" colors. Empty values
+// leave the terminal default in place.
+type Style struct {
+	Fg    string `toml:"fg,omitempty"`
+	Bg    string `toml:"bg,omitempty"`
+	Faint bool   `toml:"-"`
+}
+
+type Theme struct {
+	Name      string `toml:"-"`
+	Accent    Style  `toml:"accent"`
+	Title     Style  `toml:"title"`
+	Artist    Style  `toml:"artist"`
+	BarFilled Style  `toml:"bar_filled"`
+	BarEnd    Style  `toml:"bar_end"`
+	BarEmpty  Style  `toml:"bar_empty"`
+	Time      Style  `toml:"time"`
+	Border    Style  `toml:"border"`
+}
+
+var builtinThemes = []Theme{
+	{Name: "default"},
+	{
+		Name:      "gruvbox",
+		Accent:    Style{Fg: "#fe8019"},
+		Title:     Style{Fg: "#fabd2f"},
+		Artist:    Style{Fg: "#ebdbb2"},
+		BarFilled: Style{Fg: "#b8bb26"},
+		BarEmpty:  Style{Fg: "#504945"},
+		Time:      Style{Fg: "#928374"},
+		Border:    Style{Fg: "#665c54"},
+	},
+	{
+		Name:      "nord",
+		Accent:    Style{Fg: "#88c0d0"},
+		Title:     Style{Fg: "#eceff4"},
+		Artist:    Style{Fg: "#d8dee9"},
+		BarFilled: Style{Fg: "#81a1c1"},
+		BarEmpty:  Style{Fg: "#4c566a"},
+		Time:      Style{Fg: "#e5e9f0"},
+		Border:    Style{Fg: "#5e81ac"},
+	},
+	{
+		// For light terminal backgrounds, after gruvbox's light variant.
+		Name:      "light",
+		Accent:    Style{Fg: "#af3a03"},
+		Title:     Style{Fg: "#b57614"},
+		Artist:    Style{Fg: "#3c3836"},
+		BarFilled: Style{Fg: "#79740e"},
+		BarEmpty:  Style{Fg: "#d5c4a1"},
+		Time:      Style{Fg: "#7c6f64"},
+		Border:    Style{Fg: "#bdae93"},
+	},
+	{
+		Name:      "dracula",
+		Accent:    Style{Fg: "#ff79c6"},
+		Title:     Style{Fg: "#f8f8f2"},
+		Artist:    Style{Fg: "#8be9fd"},
+		BarFilled: Style{Fg: "#bd93f9"},
+		BarEmpty:  Style{Fg: "#44475a"},
+		Time:      Style{Fg: "#6272a4"},
+		Border:    Style{Fg: "#6272a4"},
+	},
+}
+
+// loadThemeFiles reads every theme file in dir. Unreadable files are skipped.
+func LoadThemeFiles(dir string) map[string]Theme {
+	themes := make(map[string]Theme)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.toml"))
+	for _, path := range paths {
+		var t Theme
+		if _, err := toml.DecodeFile(path, &t); err == nil {
+			themes[strings.TrimSuffix(filepath.Base(path), ".toml")] = t
+		}
+	}
+	return themes
+}
+
+// LoadThemes returns the bundled themes followed by the user's themes from
+// the files in dir and the config, sorted by name. A user theme replaces a
+// bundled one of the same name, and a config theme replaces a file.
+func LoadThemes(configThemes map[string]Theme, dir string) []Theme {
+	userThemes := LoadThemeFiles(dir)
+	for name, t := range configThemes {
+		userThemes[name] = t
+	}
+
+	themes := make([]Theme, 0, len(builtinThemes)+len(userThemes))
+	for _, t := range builtinThemes {
+		if _, ok := userThemes[t.Name]; !ok {
+			themes = append(themes, t)
+		}
+	}
+
+	names := make([]string, 0, len(userThemes))
+	for name := range userThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := userThemes[name]
+		t.Name = name
+		themes = append(themes, t)
+	}
+	return themes
+}
+
+func FindTheme(themes []Theme, name string) int {
+	for i, t := range themes {
+		if t.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// Tinted returns a copy of the theme with the accent, progress bar and border
+// recolored, used to match the UI to the current album artwork.
+func (t Theme) Tinted(color string) Theme {
+	t.Accent.Fg = color
+	t.BarFilled.Fg = color
+	t.Border.Fg = color
+	return t
+}
+
+// Dimmed returns the theme faded halfway into the background, used while
+// playback is paused. Elements without a color of their own are drawn faint.
+func (t Theme) Dimmed(background string) Theme {
+	for _, style := range []*Style{&t.Accent, &t.Title, &t.Artist, &t.BarFilled, &t.BarEnd, &t.BarEmpty, &t.Time, &t.Border} {
+		if faded := BlendHex(style.Fg, background, 0.5); faded != "" {
+			style.Fg = faded
+		} else {
+			style.Faint = true
+		}
+	}
+	return t
+}
+
+func (s Style) Render(text string) string {
+	seq := s.sequence()
+	if seq == "" {
+		return text
+	}
+	return seq + text + "\033[0m"
+}
+
+func (s Style) sequence() string {
+	var codes []string
+	if s.Faint {
+		codes = append(codes, "2")
+	}
+	if code, ok := colorCode(s.Fg, false); ok {
+		codes = append(codes, code)
+	}
+	if code, ok := colorCode(s.Bg, true); ok {
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(codes, ";") + "m"
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// WalTheme is the theme named "wal", made from the palette pywal (and wal
+// compatible tools such as wallust) derive from the wallpaper.
+const WalTheme = "wal"
+
+// LoadWalTheme reads a wal colors.json and maps its palette onto a theme:
+// the wallpaper's own hues, color1 to color6, for the accent, bar and border,
+// the foreground for the title, and the dim color8 for what sits behind.
+func LoadWalTheme(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	var wal struct {
+		Special struct {
+			Foreground string `json:"foreground"`
+		} `json:"special"`
+		Colors map[string]string `json:"colors"`
+	}
+	if err := json.Unmarshal(data, &wal); err != nil {
+		return Theme{}, err
+	}
+	color := func(name string) Style { return Style{Fg: wal.Colors[name]} }
+	return Theme{
+		Name:      WalTheme,
+		Accent:    color("color4"),
+		Title:     Style{Fg: wal.Special.Foreground},
+		Artist:    color("color7"),
+		BarFilled: color("color2"),
+		BarEnd:    color("color6"),
+		BarEmpty:  color("color8"),
+		Time:      color("color8"),
+		Border:    color("color4"),
+	}, nil
+}
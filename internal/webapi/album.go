@@ -0,0 +1,81 @@
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AlbumPosition is where a track sits in its album: its number in album
+// order, counting across discs, the album's track count, and the running
+// time of the tracks before it and of the whole album.
+type AlbumPosition struct {
+	Track  int           `json:"track"`
+	Tracks int           `json:"tracks"`
+	Before time.Duration `json:"before"`
+	Length time.Duration `json:"length"`
+}
+
+// Fraction returns how much of the album has been played once position
+// into the track has.
+func (a AlbumPosition) Fraction(position time.Duration) float64 {
+	if a.Length <= 0 {
+		return 0
+	}
+	return min(float64(a.Before+position)/float64(a.Length), 1)
+}
+
+// AlbumPosition looks up the album of the track with the given Spotify id
+// and where the track sits in it.
+func (c *Client) AlbumPosition(ctx context.Context, trackID string) (AlbumPosition, error) {
+	var track struct {
+		DiscNumber  int `json:"disc_number"`
+		TrackNumber int `json:"track_number"`
+		Album       struct {
+			ID string `json:"id"`
+		} `json:"album"`
+	}
+	if err := c.Do(ctx, "GET", "/tracks/"+url.PathEscape(trackID), nil, nil, &track); err != nil {
+		return AlbumPosition{}, err
+	}
+
+	// Album tracks come in disc and track order, 50 at a time at most.
+	var pos AlbumPosition
+	found := false
+	for offset := 0; ; {
+		var page struct {
+			Items []struct {
+				DiscNumber  int   `json:"disc_number"`
+				TrackNumber int   `json:"track_number"`
+				DurationMS  int64 `json:"duration_ms"`
+			} `json:"items"`
+			Next string `json:"next"`
+		}
+		query := url.Values{"limit": {"50"}, "offset": {strconv.Itoa(offset)}}
+		if err := c.Do(ctx, "GET", "/albums/"+url.PathEscape(track.Album.ID)+"/tracks", query, nil, &page); err != nil {
+			return AlbumPosition{}, err
+		}
+		for _, item := range page.Items {
+			duration := time.Duration(item.DurationMS) * time.Millisecond
+			pos.Tracks++
+			pos.Length += duration
+			// Matching on numbers rather than ids survives track relinking.
+			if item.DiscNumber == track.DiscNumber && item.TrackNumber == track.TrackNumber {
+				pos.Track, found = pos.Tracks, true
+			}
+			if !found {
+				pos.Before += duration
+			}
+		}
+		offset += len(page.Items)
+		if page.Next == "" || len(page.Items) == 0 {
+			break
+		}
+	}
+	if !found {
+		return AlbumPosition{}, fmt.Errorf("webapi: track %s is not on its album", trackID)
+	}
+	return pos, nil
+}
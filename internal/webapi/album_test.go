@@ -0,0 +1,49 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAlbumPosition(t *testing.T) {
+	pages := map[string]string{
+		"/v1/tracks/t2": `{"disc_number": 2, "track_number": 1, "album": {"id": "a"}}`,
+		"/v1/albums/a/tracks?limit=50&offset=0": `{
+			"items": [
+				{"disc_number": 1, "track_number": 1, "duration_ms": 60000},
+				{"disc_number": 1, "track_number": 2, "duration_ms": 120000}
+			],
+			"next": "https://api.spotify.com/v1/albums/a/tracks?offset=2&limit=50"
+		}`,
+		"/v1/albums/a/tracks?limit=50&offset=2": `{
+			"items": [
+				{"disc_number": 2, "track_number": 1, "duration_ms": 180000},
+				{"disc_number": 2, "track_number": 2, "duration_ms": 240000}
+			]
+		}`,
+	}
+	client, _ := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		key := r.URL.Path
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		if body, ok := pages[key]; ok {
+			return http.StatusOK, body
+		}
+		return http.StatusNotFound, `{"error": {"status": 404, "message": "not found"}}`
+	})
+
+	got, err := client.AlbumPosition(context.Background(), "t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AlbumPosition{Track: 3, Tracks: 4, Before: 3 * time.Minute, Length: 10 * time.Minute}
+	if got != want {
+		t.Errorf("AlbumPosition() = %+v, want %+v", got, want)
+	}
+	if f := got.Fraction(2 * time.Minute); f != 0.5 {
+		t.Errorf("Fraction(2m) = %v, want 0.5", f)
+	}
+}
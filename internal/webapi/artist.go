@@ -0,0 +1,39 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// Artist is what the Web API knows about an artist.
+type Artist struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Genres     []string `json:"genres"`
+	Popularity int      `json:"popularity"`
+	Followers  struct {
+		Total int `json:"total"`
+	} `json:"followers"`
+}
+
+// TrackArtist looks up the first artist of the track with the given Spotify
+// id.
+func (c *Client) TrackArtist(ctx context.Context, trackID string) (Artist, error) {
+	var track struct {
+		Artists []struct {
+			ID string `json:"id"`
+		} `json:"artists"`
+	}
+	if err := c.Do(ctx, "GET", "/tracks/"+url.PathEscape(trackID), nil, nil, &track); err != nil {
+		return Artist{}, err
+	}
+	if len(track.Artists) == 0 || track.Artists[0].ID == "" {
+		return Artist{}, errors.New("webapi: track " + trackID + " has no artist")
+	}
+	var artist Artist
+	if err := c.Do(ctx, "GET", "/artists/"+url.PathEscape(track.Artists[0].ID), nil, nil, &artist); err != nil {
+		return Artist{}, err
+	}
+	return artist, nil
+}
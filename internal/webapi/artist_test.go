@@ -0,0 +1,33 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTrackArtist(t *testing.T) {
+	pages := map[string]string{
+		"/v1/tracks/t1":   `{"artists": [{"id": "ar1"}, {"id": "ar2"}]}`,
+		"/v1/artists/ar1": `{"id": "ar1", "name": "Band", "genres": ["indie rock", "shoegaze"], "popularity": 61, "followers": {"total": 120345}}`,
+	}
+	client, requests := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		if body, ok := pages[r.URL.Path]; ok {
+			return http.StatusOK, body
+		}
+		return http.StatusNotFound, `{"error": {"status": 404, "message": "not found"}}`
+	})
+
+	got, err := client.TrackArtist(context.Background(), "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Band" || !reflect.DeepEqual(got.Genres, []string{"indie rock", "shoegaze"}) ||
+		got.Popularity != 61 || got.Followers.Total != 120345 {
+		t.Errorf("TrackArtist() = %+v", got)
+	}
+	if len(*requests) != 2 || (*requests)[1] != "GET /v1/artists/ar1?" {
+		t.Errorf("requested %q", *requests)
+	}
+}
@@ -0,0 +1,113 @@
+package webapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Scopes are the permissions requested at login.
+var Scopes = []string{
+	"user-read-playback-state",
+	"user-modify-playback-state",
+	"user-read-currently-playing",
+	"playlist-read-private",
+	"playlist-read-collaborative",
+	"playlist-modify-public",
+	"playlist-modify-private",
+	"user-library-read",
+}
+
+// Login runs the authorization code flow with PKCE, which needs no client
+// secret: it serves redirectURI on the loopback interface, hands the
+// authorization URL to open, waits for Spotify to redirect back and saves the
+// resulting token.
+func (c *Client) Login(ctx context.Context, redirectURI string, open func(authURL string)) error {
+	if c.ClientID == "" {
+		return ErrNoClientID
+	}
+	redirect, err := url.Parse(redirectURI)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return fmt.Errorf("listening for the login redirect: %w", err)
+	}
+	defer listener.Close()
+
+	verifier := randomString(64)
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomString(16)
+
+	authURL := "https://accounts.spotify.com/authorize?" + url.Values{
+		"client_id":             {c.ClientID},
+		"response_type":         {"code"},
+		"redirect_uri":          {redirectURI},
+		"code_challenge_method": {"S256"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"scope":                 {strings.Join(Scopes, " ")},
+		"state":                 {state},
+	}.Encode()
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirect.Path {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case query.Get("state") != state:
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			fmt.Fprintln(w, "Login failed, you can close this tab.")
+			errs <- errors.New("spotify login: " + query.Get("error"))
+			return
+		}
+		fmt.Fprintln(w, "Logged in to sptsong, you can close this tab.")
+		codes <- query.Get("code")
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	open(authURL)
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	token, err := c.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveToken(token)
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)[:n]
+}
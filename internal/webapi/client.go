@@ -0,0 +1,221 @@
+// Package webapi is a small client for the Spotify Web API, for the things
+// MPRIS can't do such as queueing tracks.
+package webapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiBase = "https://api.spotify.com/v1"
+
+var (
+	ErrNoClientID  = errors.New("no Spotify client id configured; set [spotify] client_id in config.toml")
+	ErrNotLoggedIn = errors.New("not logged in to Spotify; run `sptsong login`")
+)
+
+// APIError is an error response from the Web API.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("spotify: %s (%d)", e.Message, e.Status)
+}
+
+// Token is the OAuth token pair stored between runs.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// Client calls the Web API on behalf of the logged in user, refreshing the
+// access token as needed and saving it to TokenPath.
+type Client struct {
+	ClientID  string
+	TokenPath string
+	HTTP      *http.Client
+
+	mu    sync.Mutex
+	token *Token
+}
+
+func New(clientID, tokenPath string, httpClient *http.Client) *Client {
+	return &Client{ClientID: clientID, TokenPath: tokenPath, HTTP: httpClient}
+}
+
+// accessToken returns a valid access token, loading the saved token on first
+// use and refreshing it shortly before it expires.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ClientID == "" {
+		return "", ErrNoClientID
+	}
+	if c.token == nil {
+		data, err := os.ReadFile(c.TokenPath)
+		if err != nil {
+			return "", ErrNotLoggedIn
+		}
+		var token Token
+		if err := json.Unmarshal(data, &token); err != nil || token.RefreshToken == "" {
+			return "", ErrNotLoggedIn
+		}
+		c.token = &token
+	}
+	if time.Until(c.token.Expiry) < time.Minute {
+		token, err := c.requestToken(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {c.token.RefreshToken},
+		})
+		if err != nil {
+			return "", err
+		}
+		if token.RefreshToken == "" {
+			token.RefreshToken = c.token.RefreshToken
+		}
+		if err := c.saveToken(token); err != nil {
+			return "", err
+		}
+	}
+	return c.token.AccessToken, nil
+}
+
+// requestToken posts to the accounts service's token endpoint.
+func (c *Client) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.ClientID)
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://accounts.spotify.com/api/token", bytes.NewBufferString(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Error        string `json:"error"`
+		Description  string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("spotify token: %s", resp.Status)
+	}
+	if body.Error == "invalid_grant" {
+		// The refresh token was revoked or has expired.
+		return nil, ErrNotLoggedIn
+	}
+	if body.Error != "" || body.AccessToken == "" {
+		return nil, fmt.Errorf("spotify token: %s %s", body.Error, body.Description)
+	}
+	return &Token{
+		AccessToken:  body.AccessToken,
+		RefreshToken: body.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// saveToken makes token current and writes it to TokenPath, readable only by
+// the user. c.mu must be held.
+func (c *Client) saveToken(token *Token) error {
+	c.token = token
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.TokenPath), 0o700); err != nil {
+		return err
+	}
+	tmp := c.TokenPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.TokenPath)
+}
+
+// Do calls the Web API endpoint at path (relative to /v1), sending body as
+// JSON if it isn't nil and decoding the response into out if it isn't nil.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	endpoint := apiBase + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("%w: %s", ErrNotLoggedIn, apiErr.Error.Message)
+		}
+		message := apiErr.Error.Message
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{Status: resp.StatusCode, Message: message}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Play starts a track, episode, album, playlist or other context on the
+// active device.
+func (c *Client) Play(ctx context.Context, uri string) error {
+	body := map[string]any{"context_uri": uri}
+	if strings.HasPrefix(uri, "spotify:track:") || strings.HasPrefix(uri, "spotify:episode:") {
+		body = map[string]any{"uris": []string{uri}}
+	}
+	return c.Do(ctx, "PUT", "/me/player/play", nil, body, nil)
+}
+
+// Queue adds a track or episode to the end of the user's playback queue.
+func (c *Client) Queue(ctx context.Context, uri string) error {
+	return c.Do(ctx, "POST", "/me/player/queue", url.Values{"uri": {uri}}, nil, nil)
+}
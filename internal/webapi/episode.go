@@ -0,0 +1,82 @@
+package webapi
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter is a section of a podcast episode.
+type Chapter struct {
+	Start time.Duration `json:"start"`
+	Title string        `json:"title"`
+}
+
+// EpisodeChapters returns the chapters of the episode with the given Spotify
+// id. The Web API has no chapter field for episodes, so they come from the
+// timestamps shows put in their descriptions ("12:34 Topic"); an episode
+// without them has no chapters and no error.
+func (c *Client) EpisodeChapters(ctx context.Context, episodeID string) ([]Chapter, error) {
+	var episode struct {
+		Description     string `json:"description"`
+		HTMLDescription string `json:"html_description"`
+	}
+	query := url.Values{"market": {"from_token"}}
+	if err := c.Do(ctx, "GET", "/episodes/"+url.PathEscape(episodeID), query, nil, &episode); err != nil {
+		return nil, err
+	}
+	// The plain description loses the line breaks the timestamps sit on.
+	text := episode.Description
+	if episode.HTMLDescription != "" {
+		text = htmlText(episode.HTMLDescription)
+	}
+	return ParseChapters(text), nil
+}
+
+var (
+	lineBreak = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>`)
+	tag       = regexp.MustCompile(`<[^>]*>`)
+	// timestamp matches a line starting with a time such as 1:02:03, 12:34
+	// or (4:05), then the chapter title after an optional separator.
+	timestamp = regexp.MustCompile(`^[\s(\[]*((?:\d{1,2}:)?\d{1,2}:\d{2})[)\]]?\s*[-–—:|]?\s*(.*)$`)
+)
+
+func htmlText(s string) string {
+	s = lineBreak.ReplaceAllString(s, "\n")
+	return html.UnescapeString(tag.ReplaceAllString(s, ""))
+}
+
+// ParseChapters finds the chapter list in an episode description: lines
+// starting with a timestamp, in increasing order. Fewer than two of them
+// aren't a chapter list.
+func ParseChapters(description string) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		m := timestamp.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		start := parseTimestamp(m[1])
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			continue
+		}
+		chapters = append(chapters, Chapter{Start: start, Title: strings.TrimSpace(m[2])})
+	}
+	if len(chapters) < 2 {
+		return nil
+	}
+	return chapters
+}
+
+func parseTimestamp(s string) time.Duration {
+	var d time.Duration
+	for _, part := range strings.Split(s, ":") {
+		n, _ := strconv.Atoi(part)
+		d = d*60 + time.Duration(n)
+	}
+	return d * time.Second
+}
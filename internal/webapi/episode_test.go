@@ -0,0 +1,50 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChapters(t *testing.T) {
+	got := ParseChapters("Our guest talks about compilers.\n" +
+		"00:00 Intro\n" +
+		"(4:05) Why Go – and why now\n" +
+		"1:02:03 - Listener questions\n" +
+		"Music at 0:30 doesn't count\n")
+	want := []Chapter{
+		{0, "Intro"},
+		{4*time.Minute + 5*time.Second, "Why Go – and why now"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "Listener questions"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseChapters() = %+v, want %+v", got, want)
+	}
+
+	if got := ParseChapters("Recorded at 10:30 on Friday."); got != nil {
+		t.Errorf("a single timestamp gave chapters %+v", got)
+	}
+}
+
+func TestEpisodeChapters(t *testing.T) {
+	client, requests := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		return http.StatusOK, `{
+			"description": "Show notes 00:00 Hello 10:00 Goodbye",
+			"html_description": "<p>Show notes</p><p>00:00 Hello<br/>10:00 Good&amp;bye</p>"
+		}`
+	})
+
+	got, err := client.EpisodeChapters(context.Background(), "ep1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Chapter{{0, "Hello"}, {10 * time.Minute, "Good&bye"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EpisodeChapters() = %+v, want %+v", got, want)
+	}
+	if (*requests)[0] != "GET /v1/episodes/ep1?market=from_token" {
+		t.Errorf("requested %q", (*requests)[0])
+	}
+}
@@ -0,0 +1,40 @@
+package webapi
+
+import (
+	"context"
+	"net/url"
+)
+
+// AudioFeatures is Spotify's analysis of a track.
+type AudioFeatures struct {
+	Tempo        float64 `json:"tempo"`
+	Key          int     `json:"key"`  // pitch class, -1 if not detected
+	Mode         int     `json:"mode"` // 1 major, 0 minor
+	Energy       float64 `json:"energy"`
+	Danceability float64 `json:"danceability"`
+}
+
+var pitchClasses = []string{"C", "C♯", "D", "D♯", "E", "F", "F♯", "G", "G♯", "A", "A♯", "B"}
+
+// KeyName spells the track's key, such as "F♯ minor", or returns "" when
+// none was detected.
+func (f AudioFeatures) KeyName() string {
+	if f.Key < 0 || f.Key >= len(pitchClasses) {
+		return ""
+	}
+	if f.Mode == 1 {
+		return pitchClasses[f.Key] + " major"
+	}
+	return pitchClasses[f.Key] + " minor"
+}
+
+// AudioFeatures looks up the audio features of the track with the given
+// Spotify id. Apps registered since late 2024 aren't allowed the endpoint
+// and get an *APIError with status 403.
+func (c *Client) AudioFeatures(ctx context.Context, trackID string) (AudioFeatures, error) {
+	var features AudioFeatures
+	if err := c.Do(ctx, "GET", "/audio-features/"+url.PathEscape(trackID), nil, nil, &features); err != nil {
+		return AudioFeatures{}, err
+	}
+	return features, nil
+}
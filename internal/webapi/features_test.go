@@ -0,0 +1,41 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAudioFeatures(t *testing.T) {
+	client, requests := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		if r.URL.Path == "/v1/audio-features/t1" {
+			return http.StatusOK, `{"tempo": 127.9, "key": 6, "mode": 0, "energy": 0.8, "danceability": 0.65}`
+		}
+		return http.StatusForbidden, `{"error": {"status": 403, "message": "Forbidden"}}`
+	})
+
+	got, err := client.AudioFeatures(context.Background(), "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AudioFeatures{Tempo: 127.9, Key: 6, Mode: 0, Energy: 0.8, Danceability: 0.65}
+	if got != want {
+		t.Errorf("AudioFeatures() = %+v, want %+v", got, want)
+	}
+	if got.KeyName() != "F♯ minor" {
+		t.Errorf("KeyName() = %q", got.KeyName())
+	}
+	if (*requests)[0] != "GET /v1/audio-features/t1?" {
+		t.Errorf("requested %q", (*requests)[0])
+	}
+
+	_, err = client.AudioFeatures(context.Background(), "t2")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden {
+		t.Errorf("forbidden lookup gave %v", err)
+	}
+	if (AudioFeatures{Key: -1}).KeyName() != "" {
+		t.Error("an undetected key has a name")
+	}
+}
@@ -0,0 +1,245 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+// ErrNoActiveDevice means the account has no Connect device playing or
+// paused, so there is nothing to show or control.
+var ErrNoActiveDevice = errors.New("no active Spotify device")
+
+// PollInterval is how often Player asks the Web API for the playback state.
+// In between, the position is extrapolated, which keeps fast display refresh
+// rates well inside the API's rate limits.
+const PollInterval = time.Second
+
+// Player implements mpris.Player on the Web API's playback state, following
+// whatever Connect device is active. It needs no local Spotify or D-Bus.
+type Player struct {
+	client *Client
+
+	mu      sync.Mutex
+	state   *playbackState
+	fetched time.Time
+}
+
+func NewPlayer(client *Client) *Player {
+	return &Player{client: client}
+}
+
+type image struct {
+	URL string `json:"url"`
+}
+
+// playbackState is the subset of GET /me/player the display needs. Item is a
+// track or, with additional_types=episode, a podcast episode.
+type playbackState struct {
+	Device struct {
+		Name          string `json:"name"`
+		VolumePercent *int   `json:"volume_percent"`
+	} `json:"device"`
+	ShuffleState bool   `json:"shuffle_state"`
+	RepeatState  string `json:"repeat_state"`
+	ProgressMS   int64  `json:"progress_ms"`
+	IsPlaying    bool   `json:"is_playing"`
+	Item         *struct {
+		ID         string `json:"id"`
+		URI        string `json:"uri"`
+		Name       string `json:"name"`
+		DurationMS int64  `json:"duration_ms"`
+		Artists    []struct {
+			Name string `json:"name"`
+		} `json:"artists"`
+		Album struct {
+			Name   string  `json:"name"`
+			Images []image `json:"images"`
+		} `json:"album"`
+		Show struct {
+			Name      string  `json:"name"`
+			Publisher string  `json:"publisher"`
+			Images    []image `json:"images"`
+		} `json:"show"`
+		Images []image `json:"images"`
+	} `json:"item"`
+}
+
+// fetch returns the playback state, from the API at most once per
+// PollInterval unless force is set. p.mu must be held.
+func (p *Player) fetch(force bool) (*playbackState, error) {
+	if !force && p.state != nil && time.Since(p.fetched) < PollInterval {
+		return p.state, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A 204 with no body leaves state empty: nothing is active.
+	var state playbackState
+	query := url.Values{"additional_types": {"episode"}}
+	if err := p.client.Do(ctx, "GET", "/me/player", query, nil, &state); err != nil {
+		return nil, err
+	}
+	p.state, p.fetched = &state, time.Now()
+	return p.state, nil
+}
+
+// Metadata maps the playback state onto the MPRIS snapshot. Track ids take
+// the same form as the desktop client's, so caches are shared between
+// backends.
+func (p *Player) Metadata() (*mpris.Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, err := p.fetch(false)
+	if err != nil {
+		return nil, err
+	}
+	item := state.Item
+	if item == nil {
+		return nil, ErrNoActiveDevice
+	}
+
+	position := state.ProgressMS
+	if state.IsPlaying {
+		position += time.Since(p.fetched).Milliseconds()
+	}
+	position = min(position, item.DurationMS)
+	metadata := &mpris.Metadata{
+		Title:    item.Name,
+		Album:    item.Album.Name,
+		Length:   item.DurationMS / 1000,
+		Position: position / 1000,
+		Duration: time.Duration(item.DurationMS) * time.Millisecond,
+		Elapsed:  time.Duration(position) * time.Millisecond,
+		Status:   "Paused",
+		Shuffle:  state.ShuffleState,
+		Loop:     "None",
+	}
+	if kind, id, ok := strings.Cut(strings.TrimPrefix(item.URI, "spotify:"), ":"); ok {
+		metadata.TrackID = "/com/spotify/" + kind + "/" + id
+	}
+	if len(item.Artists) > 0 {
+		metadata.Artist = item.Artists[0].Name
+	}
+
+	// Episodes have a show in place of artists and album.
+	images := item.Album.Images
+	if item.Show.Name != "" {
+		metadata.Artist, metadata.Album = item.Show.Publisher, item.Show.Name
+		images = item.Images
+		if len(images) == 0 {
+			images = item.Show.Images
+		}
+	}
+	if metadata.Artist == "" {
+		metadata.Artist = "Unknown Artist"
+	}
+	// Images come largest first.
+	if len(images) > 0 {
+		metadata.ArtURL = images[0].URL
+	}
+
+	if state.IsPlaying {
+		metadata.Status = "Playing"
+	}
+	switch state.RepeatState {
+	case "track":
+		metadata.Loop = "Track"
+	case "context":
+		metadata.Loop = "Playlist"
+	}
+	if v := state.Device.VolumePercent; v != nil {
+		metadata.Volume = float64(*v) / 100
+	}
+	return metadata, nil
+}
+
+// Identity names the active Connect device once one has been seen.
+func (p *Player) Identity() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != nil && p.state.Device.Name != "" {
+		return "Spotify on " + p.state.Device.Name
+	}
+	return "Spotify Connect"
+}
+
+func (p *Player) SetVolume(volume float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer func() { p.state = nil }()
+
+	percent := int(min(max(volume, 0), 1)*100 + 0.5)
+	query := url.Values{"volume_percent": {strconv.Itoa(percent)}}
+	return p.client.Do(ctx, "PUT", "/me/player/volume", query, nil, nil)
+}
+
+// Call maps MPRIS Player methods onto the Web API's player endpoints. Seek
+// and SetPosition take microseconds, as in MPRIS. The cached state is
+// dropped afterwards so the next Metadata shows the effect.
+func (p *Player) Call(method string, args ...any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer func() { p.state = nil }()
+
+	switch method {
+	case "Play":
+		return p.client.Do(ctx, "PUT", "/me/player/play", nil, nil, nil)
+	case "Pause", "Stop":
+		return p.client.Do(ctx, "PUT", "/me/player/pause", nil, nil, nil)
+	case "PlayPause":
+		state, err := p.fetch(true)
+		if err != nil {
+			return err
+		}
+		if state.IsPlaying {
+			return p.client.Do(ctx, "PUT", "/me/player/pause", nil, nil, nil)
+		}
+		return p.client.Do(ctx, "PUT", "/me/player/play", nil, nil, nil)
+	case "Next":
+		return p.client.Do(ctx, "POST", "/me/player/next", nil, nil, nil)
+	case "Previous":
+		return p.client.Do(ctx, "POST", "/me/player/previous", nil, nil, nil)
+	case "Seek", "SetPosition":
+		if len(args) != 1 {
+			return fmt.Errorf("webapi: %s takes one argument", method)
+		}
+		us, ok := args[0].(int64)
+		if !ok {
+			return fmt.Errorf("webapi: %s takes microseconds as int64", method)
+		}
+		ms := us / 1000
+		if method == "Seek" {
+			state, err := p.fetch(true)
+			if err != nil {
+				return err
+			}
+			ms = max(state.ProgressMS+ms, 0)
+		}
+		query := url.Values{"position_ms": {strconv.FormatInt(ms, 10)}}
+		return p.client.Do(ctx, "PUT", "/me/player/seek", query, nil, nil)
+	case "OpenUri":
+		if len(args) != 1 {
+			return fmt.Errorf("webapi: OpenUri takes one argument")
+		}
+		uri, err := ParseURI(fmt.Sprint(args[0]))
+		if err != nil {
+			return err
+		}
+		return p.client.Play(ctx, uri)
+	}
+	return fmt.Errorf("webapi: %s is not supported", method)
+}
@@ -0,0 +1,133 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// fakeAPI returns a client that is logged in and answers every request with
+// status and body, recording the requests it was sent.
+func fakeAPI(t *testing.T, status int, body string) (*Client, *[]string) {
+	t.Helper()
+	return fakeAPIFunc(t, func(*http.Request) (int, string) { return status, body })
+}
+
+// fakeAPIFunc is fakeAPI with the answer to each request chosen by respond.
+func fakeAPIFunc(t *testing.T, respond func(*http.Request) (int, string)) (*Client, *[]string) {
+	t.Helper()
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	token, _ := json.Marshal(Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)})
+	if err := os.WriteFile(tokenPath, token, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		status, body := respond(r)
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Request: r}, nil
+	})
+	return New("id", tokenPath, &http.Client{Transport: transport}), &requests
+}
+
+func TestPlayerMetadata(t *testing.T) {
+	client, _ := fakeAPI(t, http.StatusOK, `{
+		"device": {"name": "Kitchen", "volume_percent": 40},
+		"shuffle_state": true, "repeat_state": "context",
+		"progress_ms": 83000, "is_playing": false,
+		"item": {
+			"id": "abc", "uri": "spotify:track:abc", "name": "Song", "duration_ms": 215000,
+			"artists": [{"name": "First"}, {"name": "Second"}],
+			"album": {"name": "Album", "images": [{"url": "https://i.scdn.co/image/large"}, {"url": "https://i.scdn.co/image/small"}]}
+		}
+	}`)
+	player := NewPlayer(client)
+
+	got, err := player.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mpris.Metadata{
+		TrackID:  "/com/spotify/track/abc",
+		Title:    "Song",
+		Artist:   "First",
+		Album:    "Album",
+		Length:   215,
+		Position: 83,
+		Duration: 215 * time.Second,
+		Elapsed:  83 * time.Second,
+		ArtURL:   "https://i.scdn.co/image/large",
+		Status:   "Paused",
+		Shuffle:  true,
+		Loop:     "Playlist",
+		Volume:   0.4,
+	}
+	if *got != want {
+		t.Errorf("Metadata() = %+v, want %+v", *got, want)
+	}
+	if got := player.Identity(); got != "Spotify on Kitchen" {
+		t.Errorf("Identity() = %q", got)
+	}
+}
+
+func TestPlayerMetadataEpisode(t *testing.T) {
+	client, _ := fakeAPI(t, http.StatusOK, `{
+		"is_playing": true,
+		"item": {
+			"uri": "spotify:episode:xyz", "name": "Episode", "duration_ms": 3600000,
+			"images": [{"url": "https://i.scdn.co/image/ep"}],
+			"show": {"name": "Show", "publisher": "Host"}
+		}
+	}`)
+	got, err := NewPlayer(client).Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TrackID != "/com/spotify/episode/xyz" || got.Artist != "Host" || got.Album != "Show" ||
+		got.ArtURL != "https://i.scdn.co/image/ep" || got.Status != "Playing" {
+		t.Errorf("Metadata() = %+v", *got)
+	}
+}
+
+func TestPlayerNoActiveDevice(t *testing.T) {
+	client, _ := fakeAPI(t, http.StatusNoContent, "")
+	if _, err := NewPlayer(client).Metadata(); !errors.Is(err, ErrNoActiveDevice) {
+		t.Errorf("Metadata() error = %v, want ErrNoActiveDevice", err)
+	}
+}
+
+func TestPlayerCall(t *testing.T) {
+	tests := []struct {
+		method string
+		args   []any
+		want   string
+	}{
+		{"Next", nil, "POST /v1/me/player/next?"},
+		{"Pause", nil, "PUT /v1/me/player/pause?"},
+		{"SetPosition", []any{int64(30_000_000)}, "PUT /v1/me/player/seek?position_ms=30000"},
+		{"Seek", []any{int64(-5_000_000)}, "PUT /v1/me/player/seek?position_ms=5000"},
+	}
+	for _, tt := range tests {
+		client, requests := fakeAPI(t, http.StatusOK, `{"progress_ms": 10000}`)
+		if err := NewPlayer(client).Call(tt.method, tt.args...); err != nil {
+			t.Errorf("Call(%s): %v", tt.method, err)
+			continue
+		}
+		if last := (*requests)[len(*requests)-1]; last != tt.want {
+			t.Errorf("Call(%s) sent %q, want %q", tt.method, last, tt.want)
+		}
+	}
+}
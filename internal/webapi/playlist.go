@@ -0,0 +1,192 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// Playlist is one of the playlists in the user's library.
+type Playlist struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	URI           string `json:"uri"`
+	Collaborative bool   `json:"collaborative"`
+	Owner         struct {
+		ID string `json:"id"`
+	} `json:"owner"`
+	// Editable is set for playlists the user can add tracks to: their own
+	// and collaborative ones.
+	Editable bool `json:"-"`
+}
+
+// Album is an album saved in the user's library.
+type Album struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	URI     string `json:"uri"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+}
+
+// Playlists returns the playlists in the user's library, in its order.
+func (c *Client) Playlists(ctx context.Context) ([]Playlist, error) {
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := c.Do(ctx, "GET", "/me", nil, nil, &me); err != nil {
+		return nil, err
+	}
+
+	var playlists []Playlist
+	err := c.pages(ctx, "/me/playlists", func(item json.RawMessage) error {
+		var p Playlist
+		if err := json.Unmarshal(item, &p); err != nil {
+			return err
+		}
+		p.Editable = p.Owner.ID == me.ID || p.Collaborative
+		playlists = append(playlists, p)
+		return nil
+	})
+	return playlists, err
+}
+
+// SavedAlbums returns the albums in the user's library, most recently
+// saved first.
+func (c *Client) SavedAlbums(ctx context.Context) ([]Album, error) {
+	var albums []Album
+	err := c.pages(ctx, "/me/albums", func(item json.RawMessage) error {
+		var saved struct {
+			Album Album `json:"album"`
+		}
+		if err := json.Unmarshal(item, &saved); err != nil {
+			return err
+		}
+		albums = append(albums, saved.Album)
+		return nil
+	})
+	return albums, err
+}
+
+// Liked reports whether the track with the given Spotify id is in the
+// user's Liked Songs.
+func (c *Client) Liked(ctx context.Context, trackID string) (bool, error) {
+	var contains []bool
+	query := url.Values{"ids": {trackID}}
+	if err := c.Do(ctx, "GET", "/me/tracks/contains", query, nil, &contains); err != nil {
+		return false, err
+	}
+	return len(contains) == 1 && contains[0], nil
+}
+
+// pages walks a paged listing 50 items at a time, handing each item to add.
+func (c *Client) pages(ctx context.Context, path string, add func(item json.RawMessage) error) error {
+	for offset := 0; ; {
+		var page struct {
+			Items []json.RawMessage `json:"items"`
+			Next  string            `json:"next"`
+		}
+		query := url.Values{"limit": {"50"}, "offset": {strconv.Itoa(offset)}}
+		if err := c.Do(ctx, "GET", path, query, nil, &page); err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if err := add(item); err != nil {
+				return err
+			}
+		}
+		offset += len(page.Items)
+		if page.Next == "" || len(page.Items) == 0 {
+			return nil
+		}
+	}
+}
+
+// AddToPlaylist appends a track or episode to the end of a playlist.
+func (c *Client) AddToPlaylist(ctx context.Context, playlistID, uri string) error {
+	body := map[string][]string{"uris": {uri}}
+	return c.Do(ctx, "POST", "/playlists/"+url.PathEscape(playlistID)+"/tracks", nil, body, nil)
+}
+
+// PlaylistItem is a track or episode in a playlist. Tracks Spotify can't
+// play here, such as local files, keep their place with what the playlist
+// knows of them.
+type PlaylistItem struct {
+	URI     string `json:"uri"`
+	Name    string `json:"name"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+}
+
+// PlaylistItems returns the tracks of a playlist in order, with the
+// snapshot id of the version they come from.
+func (c *Client) PlaylistItems(ctx context.Context, playlistID string) (items []PlaylistItem, snapshot string, err error) {
+	path := "/playlists/" + url.PathEscape(playlistID)
+	var playlist struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := c.Do(ctx, "GET", path, url.Values{"fields": {"snapshot_id"}}, nil, &playlist); err != nil {
+		return nil, "", err
+	}
+	err = c.pages(ctx, path+"/tracks", func(item json.RawMessage) error {
+		var entry struct {
+			Track *PlaylistItem `json:"track"`
+		}
+		if err := json.Unmarshal(item, &entry); err != nil {
+			return err
+		}
+		if entry.Track == nil {
+			// A track removed from Spotify still takes up its position.
+			entry.Track = &PlaylistItem{Name: "(unavailable)"}
+		}
+		items = append(items, *entry.Track)
+		return nil
+	})
+	return items, playlist.SnapshotID, err
+}
+
+// MovePlaylistItem moves the track at position from so that it ends up at
+// position to, in the playlist version snapshot, and returns the new
+// version's snapshot id.
+func (c *Client) MovePlaylistItem(ctx context.Context, playlistID string, from, to int, snapshot string) (string, error) {
+	before := to
+	if to > from {
+		// insert_before counts positions before the track is taken out.
+		before++
+	}
+	body := map[string]any{"range_start": from, "insert_before": before, "range_length": 1}
+	if snapshot != "" {
+		body["snapshot_id"] = snapshot
+	}
+	return c.editPlaylist(ctx, "PUT", playlistID, body)
+}
+
+// RemoveFromPlaylist removes every occurrence of a track from a playlist,
+// in the playlist version snapshot, and returns the new snapshot id.
+func (c *Client) RemoveFromPlaylist(ctx context.Context, playlistID, uri, snapshot string) (string, error) {
+	body := map[string]any{"tracks": []map[string]string{{"uri": uri}}}
+	if snapshot != "" {
+		body["snapshot_id"] = snapshot
+	}
+	return c.editPlaylist(ctx, "DELETE", playlistID, body)
+}
+
+// InsertIntoPlaylist puts a track back at position and returns the new
+// snapshot id.
+func (c *Client) InsertIntoPlaylist(ctx context.Context, playlistID, uri string, position int) (string, error) {
+	body := map[string]any{"uris": []string{uri}, "position": position}
+	return c.editPlaylist(ctx, "POST", playlistID, body)
+}
+
+// editPlaylist sends a change to a playlist's tracks and returns the
+// snapshot id Spotify answers with.
+func (c *Client) editPlaylist(ctx context.Context, method, playlistID string, body any) (string, error) {
+	var result struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	err := c.Do(ctx, method, "/playlists/"+url.PathEscape(playlistID)+"/tracks", nil, body, &result)
+	return result.SnapshotID, err
+}
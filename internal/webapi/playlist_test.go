@@ -0,0 +1,163 @@
+package webapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestPlaylists(t *testing.T) {
+	pages := map[string]string{
+		"/v1/me": `{"id": "me"}`,
+		"/v1/me/playlists?limit=50&offset=0": `{
+			"items": [
+				{"id": "p1", "name": "Mine", "owner": {"id": "me"}},
+				{"id": "p2", "name": "Followed", "owner": {"id": "someone"}}
+			],
+			"next": "https://api.spotify.com/v1/me/playlists?offset=2&limit=50"
+		}`,
+		"/v1/me/playlists?limit=50&offset=2": `{
+			"items": [{"id": "p3", "name": "Shared", "collaborative": true, "owner": {"id": "friend"}}]
+		}`,
+	}
+	client, _ := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		key := r.URL.Path
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		if body, ok := pages[key]; ok {
+			return http.StatusOK, body
+		}
+		return http.StatusNotFound, `{"error": {"status": 404, "message": "not found"}}`
+	})
+
+	got, err := client.Playlists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "Mine" || got[2].Name != "Shared" {
+		t.Fatalf("Playlists() = %+v", got)
+	}
+	if !got[0].Editable || got[1].Editable || !got[2].Editable {
+		t.Errorf("Editable = %v %v %v, want true false true", got[0].Editable, got[1].Editable, got[2].Editable)
+	}
+}
+
+func TestSavedAlbums(t *testing.T) {
+	client, _ := fakeAPI(t, http.StatusOK, `{"items": [
+		{"added_at": "2024-01-01T00:00:00Z", "album": {"id": "a1", "name": "Souvlaki", "uri": "spotify:album:a1", "artists": [{"name": "Slowdive"}]}}
+	]}`)
+	got, err := client.SavedAlbums(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].URI != "spotify:album:a1" || got[0].Artists[0].Name != "Slowdive" {
+		t.Errorf("SavedAlbums() = %+v", got)
+	}
+}
+
+func TestLiked(t *testing.T) {
+	client, requests := fakeAPI(t, http.StatusOK, `[true]`)
+	liked, err := client.Liked(context.Background(), "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !liked || (*requests)[0] != "GET /v1/me/tracks/contains?ids=t1" {
+		t.Errorf("Liked() = %v after %q", liked, (*requests)[0])
+	}
+}
+
+func TestAddToPlaylist(t *testing.T) {
+	var body string
+	client, requests := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		return http.StatusCreated, `{"snapshot_id": "s"}`
+	})
+	if err := client.AddToPlaylist(context.Background(), "p1", "spotify:track:t1"); err != nil {
+		t.Fatal(err)
+	}
+	if (*requests)[0] != "POST /v1/playlists/p1/tracks?" || body != `{"uris":["spotify:track:t1"]}` {
+		t.Errorf("sent %q with %s", (*requests)[0], body)
+	}
+}
+
+func TestPlaylistItems(t *testing.T) {
+	pages := map[string]string{
+		"/v1/playlists/p1?fields=snapshot_id": `{"snapshot_id": "s1"}`,
+		"/v1/playlists/p1/tracks?limit=50&offset=0": `{"items": [
+			{"track": {"uri": "spotify:track:a", "name": "A", "artists": [{"name": "X"}]}},
+			{"track": null},
+			{"track": {"uri": "spotify:local:::B:180", "name": "B"}}
+		]}`,
+	}
+	client, _ := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		if body, ok := pages[r.URL.Path+"?"+r.URL.RawQuery]; ok {
+			return http.StatusOK, body
+		}
+		return http.StatusNotFound, `{"error": {"status": 404, "message": "not found"}}`
+	})
+	items, snapshot, err := client.PlaylistItems(context.Background(), "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != "s1" || len(items) != 3 || items[0].Artists[0].Name != "X" || items[1].URI != "" || items[2].Name != "B" {
+		t.Errorf("PlaylistItems() = %+v, %q", items, snapshot)
+	}
+}
+
+func TestEditPlaylist(t *testing.T) {
+	tests := []struct {
+		name        string
+		edit        func(*Client) (string, error)
+		wantRequest string
+		wantBody    string
+	}{
+		{
+			"move down",
+			func(c *Client) (string, error) { return c.MovePlaylistItem(context.Background(), "p1", 2, 3, "s1") },
+			"PUT /v1/playlists/p1/tracks?",
+			`{"insert_before":4,"range_length":1,"range_start":2,"snapshot_id":"s1"}`,
+		},
+		{
+			"move up",
+			func(c *Client) (string, error) { return c.MovePlaylistItem(context.Background(), "p1", 2, 1, "") },
+			"PUT /v1/playlists/p1/tracks?",
+			`{"insert_before":1,"range_length":1,"range_start":2}`,
+		},
+		{
+			"remove",
+			func(c *Client) (string, error) {
+				return c.RemoveFromPlaylist(context.Background(), "p1", "spotify:track:a", "s1")
+			},
+			"DELETE /v1/playlists/p1/tracks?",
+			`{"snapshot_id":"s1","tracks":[{"uri":"spotify:track:a"}]}`,
+		},
+		{
+			"insert",
+			func(c *Client) (string, error) {
+				return c.InsertIntoPlaylist(context.Background(), "p1", "spotify:track:a", 4)
+			},
+			"POST /v1/playlists/p1/tracks?",
+			`{"position":4,"uris":["spotify:track:a"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			client, requests := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				return http.StatusOK, `{"snapshot_id": "s2"}`
+			})
+			snapshot, err := tt.edit(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snapshot != "s2" || (*requests)[0] != tt.wantRequest || body != tt.wantBody {
+				t.Errorf("sent %q with %s, got snapshot %q", (*requests)[0], body, snapshot)
+			}
+		})
+	}
+}
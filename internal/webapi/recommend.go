@@ -0,0 +1,40 @@
+package webapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Track is a track as the Web API lists it.
+type Track struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	URI     string `json:"uri"`
+	Artists []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artists"`
+}
+
+// Recommendations returns up to limit tracks like the one with the given
+// Spotify id, seeded by the track and its first artist. Apps registered
+// since late 2024 aren't allowed the endpoint and get an *APIError with
+// status 403.
+func (c *Client) Recommendations(ctx context.Context, trackID string, limit int) ([]Track, error) {
+	var seed Track
+	if err := c.Do(ctx, "GET", "/tracks/"+url.PathEscape(trackID), nil, nil, &seed); err != nil {
+		return nil, err
+	}
+	query := url.Values{"seed_tracks": {trackID}, "limit": {strconv.Itoa(limit)}}
+	if len(seed.Artists) > 0 {
+		query.Set("seed_artists", seed.Artists[0].ID)
+	}
+	var body struct {
+		Tracks []Track `json:"tracks"`
+	}
+	if err := c.Do(ctx, "GET", "/recommendations", query, nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Tracks, nil
+}
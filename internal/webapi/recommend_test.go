@@ -0,0 +1,27 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRecommendations(t *testing.T) {
+	client, requests := fakeAPIFunc(t, func(r *http.Request) (int, string) {
+		if r.URL.Path == "/v1/tracks/t1" {
+			return http.StatusOK, `{"id": "t1", "artists": [{"id": "ar1", "name": "Band"}]}`
+		}
+		return http.StatusOK, `{"tracks": [{"id": "t2", "name": "Other", "uri": "spotify:track:t2", "artists": [{"name": "Band"}]}]}`
+	})
+
+	got, err := client.Recommendations(context.Background(), "t1", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].URI != "spotify:track:t2" || got[0].Artists[0].Name != "Band" {
+		t.Errorf("Recommendations() = %+v", got)
+	}
+	if (*requests)[1] != "GET /v1/recommendations?limit=20&seed_artists=ar1&seed_tracks=t1" {
+		t.Errorf("requested %q", (*requests)[1])
+	}
+}
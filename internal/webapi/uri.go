@@ -0,0 +1,34 @@
+package webapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseURI accepts a Spotify URI (spotify:track:<id>) or an
+// open.spotify.com link, with or without a locale prefix or tracking
+// parameters, and returns the URI.
+func ParseURI(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "spotify:") {
+		parts := strings.Split(s, ":")
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return "", fmt.Errorf("not a Spotify URI: %q", s)
+		}
+		return s, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host != "open.spotify.com" {
+		return "", fmt.Errorf("not a Spotify URI or open.spotify.com link: %q", s)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "intl-") {
+		parts = parts[1:]
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("not a link to a single item: %q", s)
+	}
+	return "spotify:" + parts[0] + ":" + parts[1], nil
+}
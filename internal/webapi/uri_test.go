@@ -0,0 +1,33 @@
+package webapi
+
+import "testing"
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"spotify:track:4uLU6hMCjMI75M1A2tKUQC", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"},
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"},
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=abc123", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"},
+		{"https://open.spotify.com/intl-de/track/4uLU6hMCjMI75M1A2tKUQC", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"},
+		{"  https://open.spotify.com/episode/512ojhOuo1ktJprKbVcKyQ\n", "spotify:episode:512ojhOuo1ktJprKbVcKyQ"},
+	}
+	for _, tt := range tests {
+		got, err := ParseURI(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseURI(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		"spotify:track",
+		"https://example.com/track/4uLU6hMCjMI75M1A2tKUQC",
+		"https://open.spotify.com/",
+		"https://open.spotify.com/user/someone/playlist/37i9dQZF1DX",
+	} {
+		if got, err := ParseURI(in); err == nil {
+			t.Errorf("ParseURI(%q) = %q, want an error", in, got)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/ui"
+)
+
+// binding is one key of the display. The table below drives both
+// handleKeyboard and the help overlay, so the help can't go stale.
+type binding struct {
+	// name is what [keys] in the config calls the action.
+	name   string
+	key    tcell.Key
+	ch     rune
+	mod    tcell.ModMask
+	label  string
+	action string
+	run    func(sd *SpotifyDisplay)
+	// light bindings only change the player, so the screen isn't cleared
+	// and the artwork stays where it is.
+	light bool
+}
+
+// bindings lists the display's keys in the order the help shows them. Keys
+// without run are handled by the run loop itself.
+var bindings = []binding{
+	{name: "move_up", key: tcell.KeyUp, label: "↑", action: "move to the top (manual: 1 cell)", run: func(sd *SpotifyDisplay) { sd.move(0, -1, "", "top") }},
+	{name: "move_down", key: tcell.KeyDown, label: "↓", action: "move to the bottom (manual: 1 cell)", run: func(sd *SpotifyDisplay) { sd.move(0, 1, "", "bottom") }},
+	{name: "move_left", key: tcell.KeyLeft, label: "←", action: "move to the left (manual: 1 cell)", run: func(sd *SpotifyDisplay) { sd.move(-1, 0, "left", "") }},
+	{name: "move_right", key: tcell.KeyRight, label: "→", action: "move to the right (manual: 1 cell)", run: func(sd *SpotifyDisplay) { sd.move(1, 0, "right", "") }},
+	{name: "nudge_up", key: tcell.KeyUp, mod: tcell.ModShift, label: "⇧↑", action: "5 cells up", run: func(sd *SpotifyDisplay) { sd.nudge(0, -5) }},
+	{name: "nudge_down", key: tcell.KeyDown, mod: tcell.ModShift, label: "⇧↓", action: "5 cells down", run: func(sd *SpotifyDisplay) { sd.nudge(0, 5) }},
+	{name: "nudge_left", key: tcell.KeyLeft, mod: tcell.ModShift, label: "⇧←", action: "5 cells left", run: func(sd *SpotifyDisplay) { sd.nudge(-5, 0) }},
+	{name: "nudge_right", key: tcell.KeyRight, mod: tcell.ModShift, label: "⇧→", action: "5 cells right", run: func(sd *SpotifyDisplay) { sd.nudge(5, 0) }},
+	{name: "seek_back", ch: '[', label: "[", action: "seek back 5s", light: true, run: func(sd *SpotifyDisplay) { sd.seekBy(-seekStep) }},
+	{name: "seek_forward", ch: ']', label: "]", action: "seek forward 5s", light: true, run: func(sd *SpotifyDisplay) { sd.seekBy(seekStep) }},
+	{name: "volume_down", ch: '-', label: "-", action: "volume down", light: true, run: func(sd *SpotifyDisplay) { sd.changeVolume(-volumeStep) }},
+	{name: "volume_up", ch: '+', label: "+", action: "volume up", light: true, run: func(sd *SpotifyDisplay) { sd.changeVolume(volumeStep) }},
+	{name: "slower", ch: '<', label: "<", action: "slower playback", light: true, run: func(sd *SpotifyDisplay) { sd.stepRate(false) }},
+	{name: "faster", ch: '>', label: ">", action: "faster playback", light: true, run: func(sd *SpotifyDisplay) { sd.stepRate(true) }},
+	{name: "settings", ch: 'o', label: "o", action: "playback settings: crossfade, MixRamp (mpd)", run: (*SpotifyDisplay).openSettings},
+	{name: "sleep_extend", ch: 'z', label: "z", action: "sleep timer: pause in 15 more minutes", light: true, run: (*SpotifyDisplay).extendSleep},
+	{name: "sleep_cancel", ch: 'Z', label: "Z", action: "cancel the sleep timer", light: true, run: (*SpotifyDisplay).cancelSleep},
+	{name: "copy_link", ch: 'y', label: "y", action: "copy the track's Spotify link", light: true, run: func(sd *SpotifyDisplay) { sd.copyTrack(false) }},
+	{name: "copy_name", ch: 'Y', label: "Y", action: "copy \"artist – title\"", light: true, run: func(sd *SpotifyDisplay) { sd.copyTrack(true) }},
+	{name: "add_to_playlist", ch: 'A', label: "A", action: "add the track to a playlist", run: (*SpotifyDisplay).openPlaylistPicker},
+	{name: "edit_playlist", ch: 'p', label: "p", action: "edit a playlist: reorder and remove tracks", run: (*SpotifyDisplay).openPlaylistEditor},
+	{name: "browse_files", ch: 'F', label: "F", action: "play music files through MPD", run: (*SpotifyDisplay).openBrowser},
+	{name: "library", ch: 'b', label: "b", action: "play from your library", run: (*SpotifyDisplay).openLibrary},
+	{name: "recommendations", ch: 'r', label: "r", action: "more like this (recommendations)", run: (*SpotifyDisplay).openRecommendations},
+	{name: "qr", ch: 'l', label: "l", action: "QR code of the track's link (Esc closes)", run: func(sd *SpotifyDisplay) { sd.showQR = !sd.showQR }},
+	{name: "features", ch: 'a', label: "a", action: "audio features of the track", run: func(sd *SpotifyDisplay) { sd.toggleFeatures() }},
+	{name: "artist", ch: 'i', label: "i", action: "about the artist", run: func(sd *SpotifyDisplay) { sd.toggleArtist() }},
+	{name: "history", ch: 'h', label: "h", action: "history of tracks played", run: func(sd *SpotifyDisplay) { sd.history.visible, sd.history.scroll = !sd.history.visible, 0 }},
+	{name: "history_times", ch: 'H', label: "H", action: "history times: how long ago or when", light: true, run: func(sd *SpotifyDisplay) { sd.history.relative = !sd.history.relative }},
+	{name: "history_down", ch: 'j', label: "j", action: "scroll the history down", light: true, run: func(sd *SpotifyDisplay) { sd.history.scrollBy(1) }},
+	{name: "history_up", ch: 'k', label: "k", action: "scroll the history up", light: true, run: func(sd *SpotifyDisplay) { sd.history.scrollBy(-1) }},
+	{name: "manual", ch: 'm', label: "m", action: "manual positioning on/off", run: (*SpotifyDisplay).toggleManual},
+	{name: "center", ch: 'c', label: "c", action: "center", run: func(sd *SpotifyDisplay) {
+		sd.HorizontalAlign = "center"
+		sd.VerticalAlign = "center"
+		sd.savePosition()
+	}},
+	{name: "layout", key: tcell.KeyTab, label: "Tab", action: "cycle layout", run: func(sd *SpotifyDisplay) { sd.Layout = ui.NextLayout(sd.Layout) }},
+	{name: "theme", ch: 't', label: "t", action: "cycle theme", run: (*SpotifyDisplay).cycleTheme},
+	{name: "fullscreen", ch: 'f', label: "f", action: "full-screen artwork", run: func(sd *SpotifyDisplay) { sd.fullscreen = !sd.fullscreen }},
+	{name: "theme_editor", ch: 'e', label: "e", action: "theme editor", run: func(sd *SpotifyDisplay) { sd.editor = newThemeEditor(sd.themes[sd.themeIndex]) }},
+	{name: "debug", ch: 'D', label: "D", action: "debug overlay", run: func(sd *SpotifyDisplay) { sd.debug = !sd.debug }},
+	{name: "help", ch: '?', label: "?", action: "this help (↑↓ scroll, Esc closes)", run: func(sd *SpotifyDisplay) { sd.help = !sd.help }},
+	{name: "detach", ch: 'd', label: "d", action: "detach"},
+	{name: "quit", ch: 'q', label: "q", action: "quit"},
+}
+
+// keyBindings returns the binding table with the keys remapped by [keys],
+// which maps action names to keys: a single character, or a special key as
+// tcell names it ("F5", "PgUp", "Ctrl-N"), with "Shift+" for the arrows.
+// Two actions left on the same key are an error, since only one could run.
+func keyBindings(remap map[string]string) ([]binding, error) {
+	keys := append([]binding(nil), bindings...)
+	for name, spec := range remap {
+		i := slices.IndexFunc(keys, func(b binding) bool { return b.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("keys: unknown action %q", name)
+		}
+		key, ch, mod, label, err := parseKey(spec)
+		if err != nil {
+			return nil, fmt.Errorf("keys: %s: %v", name, err)
+		}
+		keys[i].key, keys[i].ch, keys[i].mod, keys[i].label = key, ch, mod, label
+	}
+	for i, a := range keys {
+		for _, b := range keys[i+1:] {
+			if a.key == b.key && a.ch == b.ch && a.mod == b.mod {
+				return nil, fmt.Errorf("keys: %s and %s are both on %s", a.name, b.name, a.label)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// parseKey reads one key of [keys].
+func parseKey(spec string) (key tcell.Key, ch rune, mod tcell.ModMask, label string, err error) {
+	if r := []rune(spec); len(r) == 1 {
+		if r[0] <= ' ' {
+			return 0, 0, 0, "", fmt.Errorf("%q can't be bound", spec)
+		}
+		return 0, r[0], 0, spec, nil
+	}
+	name := spec
+	if rest, ok := cutPrefixFold(name, "shift+"); ok {
+		name, mod = rest, tcell.ModShift
+	}
+	for k, n := range tcell.KeyNames {
+		if !strings.EqualFold(n, name) || k == tcell.KeyEsc {
+			continue
+		}
+		if mod != 0 && k != tcell.KeyUp && k != tcell.KeyDown && k != tcell.KeyLeft && k != tcell.KeyRight {
+			return 0, 0, 0, "", fmt.Errorf("shift only goes with the arrows, not %s", n)
+		}
+		label = n
+		if mod != 0 {
+			label = "⇧" + n
+		}
+		return k, 0, mod, label, nil
+	}
+	return 0, 0, 0, "", fmt.Errorf("unknown key %q", spec)
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring case.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// keyTable is the display's bindings, remapped by [keys].
+func (sd *SpotifyDisplay) keyTable() []binding {
+	if sd.keys == nil {
+		return bindings
+	}
+	return sd.keys
+}
+
+// boundTo returns the binding for event, or nil for a key bound to nothing.
+func (sd *SpotifyDisplay) boundTo(event *tcell.EventKey) *binding {
+	ch := keyRune(event)
+	keys := sd.keyTable()
+	for i, b := range keys {
+		if (ch == 0 && b.ch == 0 && event.Key() == b.key && event.Modifiers()&tcell.ModShift == b.mod) || (ch != 0 && ch == b.ch) {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// handleKeyboard runs the binding for event and reports whether the screen
+// needs a full repaint.
+func (sd *SpotifyDisplay) handleKeyboard(event *tcell.EventKey) bool {
+	if event.Key() == tcell.KeyEsc && sd.help {
+		sd.help = false
+		return true
+	}
+	if (event.Key() == tcell.KeyUp || event.Key() == tcell.KeyDown) && sd.help {
+		// The arrows scroll the help while it's open; drawHelp keeps
+		// the top in range.
+		if event.Key() == tcell.KeyUp {
+			sd.helpTop--
+		} else {
+			sd.helpTop++
+		}
+		sd.drawHelp(sd.getTerminalSize())
+		return false
+	}
+	if event.Key() == tcell.KeyEsc && sd.showQR {
+		sd.showQR = false
+		return true
+	}
+	if event.Key() == tcell.KeyEsc && sd.issues != nil {
+		sd.issues = nil
+		return true
+	}
+	if event.Key() == tcell.KeyEsc && sd.toastText != "" {
+		sd.toastText = ""
+		return true
+	}
+	if b := sd.boundTo(event); b != nil && b.run != nil {
+		b.run(sd)
+		return !b.light
+	}
+	return false
+}
+
+// keyRune returns the character typed for event, or 0 for a special key.
+func keyRune(event *tcell.EventKey) rune {
+	if event.Key() != tcell.KeyRune {
+		return 0
+	}
+	return event.Rune()
+}
+
+// drawHelp draws the key list in a box in the middle of the screen. On a
+// terminal too short for all of it, the list scrolls from sd.helpTop.
+func (sd *SpotifyDisplay) drawHelp(term TerminalSize) {
+	bindings := sd.keyTable()
+	labelWidth, actionWidth := 0, 0
+	for _, b := range bindings {
+		labelWidth = max(labelWidth, ui.Width(b.label))
+		actionWidth = max(actionWidth, ui.Width(b.action))
+	}
+	actionWidth = min(actionWidth, term.width-labelWidth-7)
+	rows := min(len(bindings), term.height-2)
+	if actionWidth < 1 || rows < 1 {
+		return
+	}
+	sd.helpTop = max(min(sd.helpTop, len(bindings)-rows), 0)
+	width := labelWidth + actionWidth + 7
+	height := rows + 2
+	box := ui.Rect{X: (term.width - width) / 2, Y: (term.height - height) / 2, Width: width, Height: height}
+	title := "keys"
+	if rows < len(bindings) {
+		title = fmt.Sprintf("keys %d–%d of %d", sd.helpTop+1, sd.helpTop+rows, len(bindings))
+	}
+
+	theme := sd.theme()
+	border := sd.Border
+	if _, ok := ui.BorderStyles[border]; !ok {
+		border = "rounded"
+	}
+	fmt.Fprint(sd.out, ui.Frame(box, title, border, theme.Border))
+	for i, b := range bindings[sd.helpTop : sd.helpTop+rows] {
+		action := ui.Pad(ui.Truncate(b.action, actionWidth), actionWidth)
+		fmt.Fprint(sd.out, ui.MoveTo(box.X+1, box.Y+1+i)+" "+theme.Accent.Render(ui.Pad(b.label, labelWidth))+"   "+theme.Artist.Render(action)+" ")
+	}
+}
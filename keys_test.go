@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestKeyBindingsOverride(t *testing.T) {
+	keys, err := keyBindings(map[string]string{"quit": "x", "layout": "F2", "nudge_up": "shift+up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := &SpotifyDisplay{keys: keys}
+	tests := []struct {
+		event *tcell.EventKey
+		want  string
+	}{
+		{tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), "quit"},
+		{tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone), ""},
+		{tcell.NewEventKey(tcell.KeyF2, 0, tcell.ModNone), "layout"},
+		{tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone), ""},
+		{tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModShift), "nudge_up"},
+		{tcell.NewEventKey(tcell.KeyRune, 'h', tcell.ModNone), "history"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if b := sd.boundTo(tt.event); b != nil {
+			got = b.name
+		}
+		if got != tt.want {
+			t.Errorf("%s is bound to %q, want %q", tt.event.Name(), got, tt.want)
+		}
+	}
+	for _, b := range keys {
+		if b.name == "layout" && b.label != "F2" {
+			t.Errorf("help shows layout on %q, want F2", b.label)
+		}
+	}
+}
+
+func TestKeyBindingsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		remap map[string]string
+		want  string
+	}{
+		{"conflict with a default", map[string]string{"quit": "h"}, "both on h"},
+		{"two remaps on one key", map[string]string{"quit": "x", "detach": "x"}, "both on x"},
+		{"unknown action", map[string]string{"explode": "x"}, `unknown action "explode"`},
+		{"unknown key", map[string]string{"quit": "Hyper-Q"}, `unknown key "Hyper-Q"`},
+		{"shift on a function key", map[string]string{"quit": "shift+F1"}, "shift only goes with the arrows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keyBindings(tt.remap)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("keyBindings(%v) = %v, want an error containing %q", tt.remap, err, tt.want)
+			}
+		})
+	}
+}
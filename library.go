@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+
+	"sptsong/internal/webapi"
+)
+
+// openLibrary lists the user's playlists and saved albums; picking one
+// starts it on the active Spotify device, for using sptsong as a small
+// controller.
+func (sd *SpotifyDisplay) openLibrary() {
+	if sd.Spotify.ClientID == "" {
+		sd.showNotice(webapi.ErrNoClientID.Error())
+		return
+	}
+	p := &picker{
+		heading: "library",
+		caption: "playlists and saved albums",
+		cache:   &sd.library,
+		pick: func(sd *SpotifyDisplay, item pickerItem) {
+			sd.runWebAPI("playing "+item.name, func(ctx context.Context, client *webapi.Client) error {
+				return client.Play(ctx, item.uri)
+			})
+		},
+	}
+	sd.loadPicker(p, func(ctx context.Context, client *webapi.Client) ([]pickerItem, error) {
+		playlists, err := client.Playlists(ctx)
+		if err != nil {
+			return nil, err
+		}
+		albums, err := client.SavedAlbums(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var items []pickerItem
+		for _, p := range playlists {
+			items = append(items, pickerItem{name: p.Name, uri: p.URI, id: p.ID})
+		}
+		for _, a := range albums {
+			name := a.Name
+			if len(a.Artists) > 0 {
+				name += " – " + a.Artists[0].Name
+			}
+			items = append(items, pickerItem{name: name, uri: a.URI, id: a.ID})
+		}
+		return items, nil
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	login1Name    = "org.freedesktop.login1"
+	login1Session = "org.freedesktop.login1.Session"
+)
+
+// watchLock subscribes to our logind session's Lock and Unlock signals and
+// its LockedHint property on the system bus, when [lock] asks to pause. Some
+// desktops lock through logind and send the signals, others only set the
+// hint, so both count. It returns nil without logind, as in containers or
+// on macOS.
+func (sd *SpotifyDisplay) watchLock() (chan *dbus.Signal, func()) {
+	if !sd.Lock.Pause || sd.companion {
+		return nil, func() {}
+	}
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, func() {}
+	}
+	path, err := sessionPath(conn)
+	if err != nil {
+		conn.Close()
+		return nil, func() {}
+	}
+	conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(login1Session),
+	)
+	conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchArg(0, login1Session),
+	)
+	signals := make(chan *dbus.Signal, 4)
+	conn.Signal(signals)
+	return signals, func() { conn.Close() }
+}
+
+// sessionPath finds our logind session: the one named by XDG_SESSION_ID,
+// else the one our process belongs to, else the user's display session.
+func sessionPath(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	manager := conn.Object(login1Name, "/org/freedesktop/login1")
+	var path dbus.ObjectPath
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		if err := manager.Call(login1Name+".Manager.GetSession", 0, id).Store(&path); err == nil {
+			return path, nil
+		}
+	}
+	if err := manager.Call(login1Name+".Manager.GetSessionByPID", 0, uint32(os.Getpid())).Store(&path); err == nil {
+		return path, nil
+	}
+	err := manager.Call(login1Name+".Manager.GetSession", 0, "auto").Store(&path)
+	return path, err
+}
+
+// lockChanged reports whether signal locked (true) or unlocked the
+// session, and false for ok when it's about something else.
+func lockChanged(signal *dbus.Signal) (locked, ok bool) {
+	switch signal.Name {
+	case login1Session + ".Lock":
+		return true, true
+	case login1Session + ".Unlock":
+		return false, true
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		if len(signal.Body) < 2 {
+			return false, false
+		}
+		changed, _ := signal.Body[1].(map[string]dbus.Variant)
+		hint, present := changed["LockedHint"]
+		if !present {
+			return false, false
+		}
+		locked, ok = hint.Value().(bool)
+		return locked, ok
+	}
+	return false, false
+}
+
+// lockSignal handles a signal from watchLock's channel, and reports
+// whether to keep reading it. ok is false once godbus has closed the
+// channel because the system bus connection dropped, which only turns off
+// pausing on lock.
+func (sd *SpotifyDisplay) lockSignal(signal *dbus.Signal, ok bool) (listening bool) {
+	if !ok {
+		slog.Warn("lost the system bus; no longer pausing on screen lock")
+		return false
+	}
+	if locked, ok := lockChanged(signal); ok {
+		sd.screenLocked(locked)
+	}
+	return true
+}
+
+// screenLocked pauses playback when the session locks, remembering that it
+// did so unlocking can resume only what locking paused. A player that is
+// paused or stopped already is left alone.
+func (sd *SpotifyDisplay) screenLocked(locked bool) {
+	if locked == sd.locked {
+		// The signal and the hint both arrive for one lock.
+		return
+	}
+	sd.locked = locked
+	slog.Debug("screen lock", "locked", locked)
+	switch {
+	case locked && sd.Lock.Pause:
+		if metadata, err := sd.player.Metadata(); err != nil || metadata.Status != "Playing" {
+			return
+		}
+		if sd.player.Call("Pause") == nil {
+			sd.lockPaused = true
+		}
+	case !locked && sd.lockPaused:
+		sd.lockPaused = false
+		if sd.Lock.Resume {
+			sd.player.Call("Play")
+		}
+	}
+}
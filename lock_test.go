@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+)
+
+func lockSignal(locked bool) *dbus.Signal {
+	name := login1Session + ".Unlock"
+	if locked {
+		name = login1Session + ".Lock"
+	}
+	return &dbus.Signal{Name: name}
+}
+
+func TestScreenLockPausesOnlyPlayback(t *testing.T) {
+	for _, tt := range []struct {
+		status string
+		want   []string
+	}{
+		{"Playing", []string{"Pause[]", "Play[]"}},
+		{"Paused", nil},
+		{"Stopped", nil},
+	} {
+		player := &fakePlayer{metadata: mpris.Metadata{Status: tt.status}}
+		sd := &SpotifyDisplay{player: player, Config: config.Config{Lock: config.LockConfig{Pause: true, Resume: true}}}
+		sd.lockSignal(lockSignal(true), true)
+		sd.lockSignal(lockSignal(false), true)
+		if !reflect.DeepEqual(player.calls, tt.want) {
+			t.Errorf("%s: lock and unlock called %v, want %v", tt.status, player.calls, tt.want)
+		}
+	}
+}
+
+func TestClosedLockSignals(t *testing.T) {
+	locks := make(chan *dbus.Signal)
+	close(locks)
+
+	sd := &SpotifyDisplay{player: &fakePlayer{}}
+	signal, ok := <-locks
+	if sd.lockSignal(signal, ok) {
+		t.Error("still listening on a closed channel")
+	}
+}
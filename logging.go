@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+
+	"sptsong/internal/config"
+	"sptsong/internal/logging"
+)
+
+// maxLogSize is how large sptsong.log grows before it is moved aside to
+// sptsong.log.1.
+const maxLogSize = 1 << 20
+
+// logLevel is the level of the default logger, changed by config reloads.
+var logLevel slog.LevelVar
+
+// startLogging sends the default logger to sptsong.log in the state
+// directory, at cfg's level. Without a writable state directory there is no
+// log, which is no reason not to run.
+func startLogging(cfg config.Config) (stop func(), err error) {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: log_level: %v", errConfig, err)
+	}
+	logLevel.Set(level)
+	file, err := logging.Open(filepath.Join(config.StateDir(), "sptsong.log"), maxLogSize)
+	if err != nil {
+		// slog's own default would write over the display.
+		slog.SetDefault(logging.New(io.Discard, &logLevel))
+		return func() {}, nil
+	}
+	slog.SetDefault(logging.New(file, &logLevel))
+	return func() { file.Close() }, nil
+}
+
+// setLogLevel applies a reloaded config's log_level.
+func setLogLevel(cfg config.Config) {
+	if level, err := logging.ParseLevel(cfg.LogLevel); err == nil {
+		logLevel.Set(level)
+	}
+}
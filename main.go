@@ -1,314 +1,1045 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
+	"net"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/godbus/dbus/v5"
-	"github.com/nsf/termbox-go"
-)
 
-type Config struct {
-	minWidth        int
-	contentHeight   int
-	margin          int
-	horizontalAlign string
-	verticalAlign   string
-}
+	"sptsong/internal/artistinfo"
+	"sptsong/internal/config"
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+	"sptsong/internal/notify"
+	"sptsong/internal/plugin"
+	"sptsong/internal/pulse"
+	"sptsong/internal/sdnotify"
+	"sptsong/internal/ui"
+	"sptsong/internal/webapi"
+
+	"sptsong/internal/artwork"
+)
 
 type SpotifyDisplay struct {
 	bus           *dbus.Conn
-	spotifyObject dbus.BusObject
+	player        mpris.Player
 	cacheDir      string
+	covers        artwork.Cache
 	currentArtURL string
-	Config
-}
-
-type Metadata struct {
-	Title    string
-	Artist   string
-	Length   int64
-	Position int64
-	ArtURL   string
+	playerName    string
+	artAccent     string
+	tracks        *trackCache
+	renders       *artwork.Renderer
+	artReady      chan artResult
+	artGeneration int
+	cancelArt     context.CancelFunc
+	trackSettle   settler
+	clock         Clock
+	debug         bool
+	paused        bool
+	started       time.Time
+	fullscreen    bool
+	wasCompact    bool
+	wasSmall      bool
+	artRetry      time.Time
+	playerFailed  bool
+	toastText     string
+	toastAt       time.Time
+	termBg        string
+	watchdog      watchdog
+	gaps          gapMeter
+	stuck         bool
+	out           io.Writer
+	redraw        chan struct{}
+	poll          *poller
+	screenSize    func() (width, height int, fontRatio float64)
+	themes        []ui.Theme
+	themeIndex    int
+	export        *mpris.Exporter
+	editor        *themeEditor
+	help          bool
+	helpTop       int
+	showQR        bool
+	drag          dragState
+	volume        float64
+	pending       heldInput
+	notifier      *notify.Dispatcher
+	keys          []binding
+	events        eventWatcher
+	args          []string
+	loaded        config.Config
+	configErr     error
+	issues        []healthIssue
+	cached        mpris.Metadata
+	albumTrack    string
+	album         *webapi.AlbumPosition
+	albumReady    chan albumResult
+	chapterTrack  string
+	chapters      []webapi.Chapter
+	chapterDone   chan chapterResult
+	featureTrack  string
+	features      *webapi.AudioFeatures
+	featureErr    error
+	liked         *bool
+	featureDone   chan featureResult
+	showFeatures  bool
+	artistName    string
+	artist        *artistinfo.Info
+	artistErr     error
+	artistDone    chan artistResult
+	showArtist    bool
+	picker        *picker
+	settings      *settingsPanel
+	timeLocale    timeLocale
+	// prefetched is the track the next one's details were looked up for.
+	prefetched string
+	// playlistEditor is the playlist last opened for editing; it keeps
+	// sending its edits after it closes.
+	playlistEditor *playlistEditor
+	playlists      []pickerItem
+	library        []pickerItem
+	similarTrack   string
+	similar        []pickerItem
+	pickerDone     chan pickerResult
+	playlistDone   chan playlistResult
+	takeover       chan net.Conn
+	taker          net.Conn
+	service        bool
+	plugins        *plugin.Host
+	pluginLines    map[string][]string
+	companion      bool
+	published      published
+	cachedAt       time.Time
+	current        mpris.Metadata
+	notice         string
+	termState      terminalState
+	history        *trackHistory
+	queue          upNext
+	noticeAt       time.Time
+	sleepAt        time.Time
+	locked         bool
+	lockPaused     bool
+	output         pulse.Output
+	visualizing    bool
+	vizRow         ui.Rect
+	metering       bool
+	meterRow       ui.Rect
+	config.Config
 }
 
 type TerminalSize struct {
 	width, height, startX, startY int
+	fontRatio                     float64
+	layout                        ui.Layout
+	frame                         ui.Rect
 }
 
-func NewSpotifyDisplay() (*SpotifyDisplay, error) {
+func NewSpotifyDisplay(cfg config.Config) (*SpotifyDisplay, error) {
 	homeDir, _ := os.UserHomeDir()
 	cacheDir := filepath.Join(homeDir, ".cache", "spotify-display")
-	os.MkdirAll(cacheDir, 0o755)
+	os.MkdirAll(filepath.Join(cacheDir, "art"), 0o755)
+
+	if err := artwork.ConfigureHTTP(cfg.Network.Timeout, cfg.Network.CABundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	}
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	}
+
+	keys, err := keyBindings(cfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	}
 
-	conn, err := dbus.SessionBus()
+	player, conn, err := openPlayer(cfg)
 	if err != nil {
 		return nil, err
 	}
+	if player, err = withOverrides(player, cfg.Overrides); err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	}
+
+	themes := loadThemes(cfg)
+	ui.SetColorDepth(colorDepth(cfg.Terminal))
+	store, err := openHistoryStore(cfg.History, cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	}
+	clock := newClock()
+	history := newTrackHistory(cfg.History.Size, store)
+	history.relative = cfg.History.Relative
 
 	return &SpotifyDisplay{
-		bus:           conn,
-		spotifyObject: conn.Object("org.mpris.MediaPlayer2.spotify", "/org/mpris/MediaPlayer2"),
-		cacheDir:      cacheDir,
-		Config: Config{
-			minWidth:        60,
-			contentHeight:   9,
-			margin:          2,
-			horizontalAlign: "center",
-			verticalAlign:   "bottom",
-		},
+		bus:          conn,
+		player:       player,
+		cacheDir:     cacheDir,
+		covers:       newCoverCache(cacheDir, cfg),
+		tracks:       newTrackCache(filepath.Join(cacheDir, "tracks.json"), cfg.TrackCacheTTL, clock),
+		history:      history,
+		timeLocale:   userTimeLocale(),
+		renders:      artwork.NewRenderer(),
+		artReady:     make(chan artResult),
+		albumReady:   make(chan albumResult, 1),
+		chapterDone:  make(chan chapterResult, 1),
+		featureDone:  make(chan featureResult, 1),
+		artistDone:   make(chan artistResult, 1),
+		pickerDone:   make(chan pickerResult, 1),
+		playlistDone: make(chan playlistResult, 1),
+		trackSettle:  settler{delay: cfg.SettleDelay},
+		clock:        clock,
+		started:      clock.Now(),
+		events:       eventWatcher{settle: settler{delay: cfg.SettleDelay}},
+		notifier:     notifier,
+		keys:         keys,
+		themes:       themes,
+		themeIndex:   ui.FindTheme(themes, cfg.Theme),
+		out:          os.Stdout,
+		redraw:       make(chan struct{}, 1),
+		screenSize:   localScreenSize,
+		loaded:       cfg,
+		Config:       cfg,
 	}, nil
 }
 
-func (sd *SpotifyDisplay) getTerminalSize() TerminalSize {
-	width, height := termbox.Size()
-	startX := (width - sd.minWidth) / 2
-	startY := height - sd.contentHeight - sd.margin
+// localScreenSize measures the terminal the process is attached to.
+func localScreenSize() (width, height int, ratio float64) {
+	width, height = screenSize()
+	return width, height, fontRatio()
+}
 
-	if sd.horizontalAlign == "left" {
-		startX = sd.margin
-	} else if sd.horizontalAlign == "right" {
-		startX = width - sd.minWidth - sd.margin
+// invalidate clears the screen so that everything, the cover included, is
+// drawn again on the next poll, which it brings forward so a paused or idle
+// display isn't left blank until its slow interval is up.
+func (sd *SpotifyDisplay) invalidate() {
+	fmt.Fprint(sd.out, "\033[2J\033[H")
+	sd.currentArtURL = ""
+	sd.poll.hurry()
+}
+
+// requestRedraw asks the run loop to clear the screen and repaint everything,
+// including the artwork. It is safe to call from any goroutine.
+func (sd *SpotifyDisplay) requestRedraw() {
+	select {
+	case sd.redraw <- struct{}{}:
+	default:
 	}
+}
 
-	if sd.verticalAlign == "top" {
-		startY = sd.margin
-	} else if sd.verticalAlign == "center" {
-		startY = (height - sd.contentHeight) / 2
+func (sd *SpotifyDisplay) theme() ui.Theme {
+	theme := sd.themes[sd.themeIndex]
+	if sd.editor != nil {
+		// Show the edited colors as they are, untinted by the artwork.
+		return sd.editor.theme
+	}
+	if sd.ArtAccent && sd.artAccent != "" {
+		theme = theme.Tinted(sd.artAccent)
+	}
+	theme = theme.WithContrast(sd.background(), sd.MinContrast)
+	if sd.paused {
+		theme = theme.Dimmed(sd.background())
+	}
+	if sd.noColor() {
+		return ui.Theme{Name: theme.Name}
 	}
+	return theme
+}
 
-	return TerminalSize{width, height, startX, startY}
+// noColor reports whether the display is to be drawn in the terminal's own
+// colors, by --no-color or the NO_COLOR convention.
+func (sd *SpotifyDisplay) noColor() bool {
+	return sd.NoColor || os.Getenv("NO_COLOR") != ""
 }
 
-func (sd *SpotifyDisplay) getMetadata() (*Metadata, error) {
-	variant, err := sd.spotifyObject.GetProperty("org.mpris.MediaPlayer2.Player.Metadata")
-	if err != nil {
-		return nil, err
+func (sd *SpotifyDisplay) cycleTheme() {
+	sd.themeIndex = (sd.themeIndex + 1) % len(sd.themes)
+}
+
+func (sd *SpotifyDisplay) getTerminalSize() TerminalSize {
+	width, height, ratio := sd.screenSize()
+	layout := ui.NewLayout(sd.Layout, sd.Art.Size, ratio)
+
+	// The border adds a line above and below and a column of padding on
+	// either side of the content.
+	padX, padY := 0, 0
+	if _, ok := ui.BorderStyles[sd.Border]; ok {
+		padX, padY = 2, 1
 	}
+	frameWidth := layout.Width + 2*padX
+	frameHeight := layout.Height + 2*padY
 
-	metadata := variant.Value().(map[string]dbus.Variant)
-	position, _ := sd.spotifyObject.GetProperty("org.mpris.MediaPlayer2.Player.Position")
+	startX := (width - frameWidth) / 2
+	startY := height - frameHeight - sd.Margin
 
-	artists := metadata["xesam:artist"].Value().([]string)
-	artist := "Unknown Artist"
-	if len(artists) > 0 {
-		artist = artists[0]
+	if sd.HorizontalAlign == "left" {
+		startX = sd.Margin
+	} else if sd.HorizontalAlign == "right" {
+		startX = width - frameWidth - sd.Margin
 	}
 
-	rawURL := strings.Trim(metadata["mpris:artUrl"].String(), "\"")
-	artURL := ""
-	if strings.HasPrefix(rawURL, "https://i.scdn.co/image/") {
-		artURL = rawURL
-	} else if strings.HasPrefix(rawURL, "file://") {
-		artURL = strings.TrimPrefix(rawURL, "file://")
+	if sd.VerticalAlign == "top" {
+		startY = sd.Margin
+	} else if sd.VerticalAlign == "center" {
+		startY = (height - frameHeight) / 2
 	}
 
-	var length int64
-	switch v := metadata["mpris:length"].Value().(type) {
-	case int64:
-		length = v
-	case uint64:
-		length = int64(v)
+	// Manual positions stay on screen when the terminal shrinks.
+	if sd.HorizontalAlign == "manual" {
+		startX = max(min(sd.X, width-frameWidth), 0)
+	}
+	if sd.VerticalAlign == "manual" {
+		startY = max(min(sd.Y, height-frameHeight), 0)
 	}
 
-	var pos int64
-	switch v := position.Value().(type) {
-	case int64:
-		pos = v
-	case uint64:
-		pos = int64(v)
+	return TerminalSize{
+		width:     width,
+		height:    height,
+		startX:    startX,
+		startY:    startY,
+		fontRatio: ratio,
+		layout:    layout.At(startX+padX, startY+padY),
+		frame:     ui.Rect{X: startX, Y: startY, Width: frameWidth, Height: frameHeight},
 	}
+}
 
-	return &Metadata{
-		Title:    metadata["xesam:title"].String(),
-		Artist:   artist,
-		Length:   length / 1000000,
-		Position: pos / 1000000,
-		ArtURL:   artURL,
-	}, nil
+func (sd *SpotifyDisplay) drawProgressBar(metadata *mpris.Metadata, text ui.Rect) {
+	width := sd.Progress.Width.Resolve(text.Width)
+	bar := sd.renderBar(progressFraction(metadata), width, sd.chapterMarks(metadata)...)
+	timeText := sd.timeText(metadata)
+
+	blank := strings.Repeat(" ", text.Width)
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+4)+blank)
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+5)+blank)
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+4)+bar)
+	fmt.Fprint(sd.out, ui.MoveTo(text.X+(width-ui.Width(timeText))/2, text.Y+5)+sd.theme().Time.Render(timeText))
 }
 
-func (sd *SpotifyDisplay) downloadArtwork(artURL string) (string, error) {
-	if artURL == "" {
-		return "", nil
+// fullscreenArt returns the largest square, in cells, that fits above the
+// overlay line, taking the terminal's cell proportions into account.
+func fullscreenArt(term TerminalSize) (x, y, width, height int) {
+	height = term.height - 1
+	width = int(float64(height) / term.fontRatio)
+	if width > term.width {
+		width = term.width
+		height = ui.ArtRows(width, term.fontRatio)
 	}
+	return (term.width - width) / 2, (term.height - 1 - height) / 2, width, height
+}
 
-	imagePath := filepath.Join(sd.cacheDir, "current_artwork.png")
-	var input io.ReadCloser
-	var err error
+// drawFullscreenOverlay writes the one-line title/artist/time overlay on the
+// bottom row of the full-screen art mode.
+func (sd *SpotifyDisplay) drawFullscreenOverlay(metadata *mpris.Metadata, term TerminalSize) {
+	theme := sd.theme()
+	timeText := sd.timeText(metadata)
+	textWidth := ui.Width(metadata.Title + " — " + metadata.Artist + "  " + timeText)
+	x := max((term.width-textWidth)/2, 0)
 
-	if strings.HasPrefix(artURL, "/") {
-		input, err = os.Open(artURL)
-	} else {
-		req, _ := http.NewRequest("GET", artURL, nil)
-		req.Header.Set("User-Agent", "spotify-display/1.0")
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return "", err
-		}
-		input = resp.Body
+	fmt.Fprintf(sd.out, "\033[%d;1H\033[2K", term.height)
+	fmt.Fprintf(sd.out, "\033[%d;%dH%s — %s  %s", term.height, x+1,
+		theme.Title.Render(sd.visual(metadata.Title)),
+		theme.Artist.Render(sd.visual(metadata.Artist)),
+		theme.Time.Render(timeText))
+}
+
+func (sd *SpotifyDisplay) drawNowPlaying(metadata *mpris.Metadata, term TerminalSize) {
+	text := term.layout.Text
+	blank := strings.Repeat(" ", text.Width)
+
+	// Clear previous lines before writing new text
+	for row := 0; row < 4; row++ {
+		fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+row)+blank)
 	}
-	defer input.Close()
 
-	output, err := os.Create(imagePath)
-	if err != nil {
-		return "", err
+	fmt.Fprint(sd.out, ui.Frame(term.frame, sd.playerName, sd.Border, sd.theme().Border))
+
+	// Write new text
+	theme := sd.theme()
+	header := theme.Accent.Render("♫ Now Playing") + " via " + sd.playerName
+	if sd.paused {
+		header += " " + theme.Accent.Render("⏸")
 	}
-	defer output.Close()
+	if rate := rateLabel(metadata.Rate); rate != "" {
+		header += " " + theme.Accent.Render(rate)
+	}
+	if sleep := sd.sleepLabel(); sleep != "" {
+		header += " " + theme.Time.Render(sleep)
+	}
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y)+header)
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+1)+theme.Title.Render(sd.visual(ui.Truncate(metadata.Title, text.Width))))
+	byline := "by " + metadata.Artist
+	if isEpisode(metadata) {
+		byline = "from " + showName(metadata)
+	}
+	fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+2)+theme.Artist.Render(sd.visual(ui.Truncate(byline, text.Width))))
+	if sd.stuck {
+		warning := ui.Truncate("⚠ player appears stuck", text.Width)
+		fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+3)+theme.Accent.Render(warning))
+	} else if notice := sd.activeNotice(); notice != "" {
+		fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+3)+theme.Accent.Render(ui.Truncate(notice, text.Width)))
+	} else if sd.configErr != nil {
+		warning := ui.Truncate("⚠ config not reloaded: "+sd.configErr.Error(), text.Width)
+		fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+3)+theme.Accent.Render(warning))
+	} else if chapter := sd.currentChapter(metadata); chapter != "" {
+		line := ui.Truncate("§ "+chapter, text.Width)
+		fmt.Fprint(sd.out, ui.MoveTo(text.X, text.Y+3)+theme.Time.Render(line))
+	} else if sd.album != nil && sd.albumTrack == metadata.TrackID {
+		sd.drawAlbumLine(metadata, text)
+	}
+	sd.drawProgressBar(metadata, text)
+	for i, place := range sd.liveRows()[:sd.liveRowsUnderBar(term)] {
+		place(text.X, text.Y+ui.TextHeight+i, text.Width)
+	}
+	sd.drawUpNext(metadata, term)
+}
 
-	_, err = io.Copy(output, input)
-	return imagePath, err
+// compact reports whether the one-line layout should be used, either because
+// it was requested or because the terminal is too short for the full one.
+func (sd *SpotifyDisplay) compact(term TerminalSize) bool {
+	return sd.Compact || term.height < term.frame.Height
 }
 
-func (sd *SpotifyDisplay) displayImage(imagePath string, startX, startY int) error {
-	chafaPath, err := exec.LookPath("chafa")
-	if err != nil {
-		return err
+func statusGlyph(status string) string {
+	switch status {
+	case "Playing":
+		return "▶"
+	case "Paused":
+		return "⏸"
 	}
+	return "■"
+}
 
-	fmt.Print("\0337")
-	fmt.Printf("\033[%d;%dH", startY+1, startX+1)
+// drawCompact renders the status, "artist – title" and a mini progress bar on
+// a single line, placed according to the vertical alignment.
+func (sd *SpotifyDisplay) drawCompact(metadata *mpris.Metadata, term TerminalSize) {
+	barWidth := sd.compactBarWidth()
 
-	cmd := exec.Command(chafaPath, "--size=18x18", "--symbols=block", "--colors=256", imagePath)
-	cmd.Stdout = os.Stdout
-	cmd.Run()
+	row := term.height
+	if sd.VerticalAlign == "top" {
+		row = 1
+	} else if sd.VerticalAlign == "center" {
+		row = (term.height + 1) / 2
+	}
 
-	fmt.Print("\0338")
-	return nil
+	timeText := sd.timeText(metadata)
+
+	room := term.width - barWidth - ui.Width(timeText) - 5
+	text := ui.Truncate(metadata.Artist+" – "+metadata.Title, room)
+
+	theme := sd.theme()
+	fmt.Fprintf(sd.out, "\033[%d;1H\033[2K", row)
+	fmt.Fprintf(sd.out, "\033[%d;1H%s %s %s %s", row,
+		theme.Accent.Render(statusGlyph(metadata.Status)),
+		theme.Title.Render(sd.visual(text)),
+		sd.renderBar(progressFraction(metadata), barWidth),
+		theme.Time.Render(timeText))
 }
 
-func (sd *SpotifyDisplay) drawProgressBar(metadata *Metadata, term TerminalSize) {
-	width := 40
-	progress := int(float64(metadata.Position) / float64(metadata.Length) * float64(width))
-	if progress < 0 {
-		progress = 0
-	} else if progress > width {
-		progress = width
+func (sd *SpotifyDisplay) Run() error {
+	if err := takeScreen(); err != nil {
+		return err
 	}
+	defer restoreScreen()
+	defer guard.Recover()
+	queryBackground(sd.Config)
+	width, height := screenSize()
+	slog.Debug("terminal", "term", os.Getenv("TERM"), "colors", colorDepth(sd.Terminal), "width", width, "height", height, "font_ratio", fontRatio())
 
-	bar := strings.Repeat("━", progress) + strings.Repeat("─", width-progress)
-	timeText := fmt.Sprintf("%02d:%02d/%02d:%02d",
-		metadata.Position/60, metadata.Position%60,
-		metadata.Length/60, metadata.Length%60)
+	// The poller stops once Run returns; interruptScreen wakes it from
+	// readEvent so it doesn't outlive the display.
+	eventQueue := make(chan tcell.Event)
+	done := make(chan struct{})
+	defer close(done)
+	defer interruptScreen()
+	go func() {
+		defer guard.Recover()
+		for {
+			event := readEvent()
+			if _, ok := event.(*tcell.EventInterrupt); ok {
+				return
+			}
+			select {
+			case eventQueue <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
 
-	fmt.Printf("\033[%d;%dH%s", term.startY+5, term.startX+20, strings.Repeat(" ", 60))
-	fmt.Printf("\033[%d;%dH%s", term.startY+6, term.startX+20, strings.Repeat(" ", 60))
-	fmt.Printf("\033[%d;%dH%s", term.startY+5, term.startX+20, bar)
-	fmt.Printf("\033[%d;%dH%s", term.startY+6, term.startX+20+(width-len(timeText))/2, timeText)
+	detached := false
+	err := sd.loop(eventQueue, func() bool {
+		detached = true
+		return true
+	}, func() bool {
+		// A hangup from a terminal that is gone means detach; one sent
+		// while it's still there asks for a config reload.
+		return !terminalAlive()
+	})
+	if err == nil && detached {
+		err = errDetached
+	}
+	return err
 }
 
-func (sd *SpotifyDisplay) handleKeyboard(event termbox.Event) bool {
-	switch event.Key {
-	case termbox.KeyArrowUp:
-		sd.verticalAlign = "top"
-	case termbox.KeyArrowDown:
-		sd.verticalAlign = "bottom"
-	case termbox.KeyArrowLeft:
-		sd.horizontalAlign = "left"
-	case termbox.KeyArrowRight:
-		sd.horizontalAlign = "right"
-	default:
-		if event.Ch == 'c' {
-			sd.horizontalAlign = "center"
-			sd.verticalAlign = "center"
-		} else {
-			return false
+// loop runs the display until it is quit, reading keys from events. On the
+// detach key, or a hangup for which hungUp reports true, it calls detach, and
+// returns if detach says so. Other hangups reload the config, as does saving
+// config.toml or a theme file.
+func (sd *SpotifyDisplay) loop(events <-chan tcell.Event, detach, hungUp func() bool) error {
+	// Each pass of the loop below draws into buffered, which is flushed
+	// before the next one waits.
+	buffered := newFrameBuffer(sd.out)
+	sd.out = buffered
+	defer func() {
+		buffered.Flush()
+		sd.out = buffered.out
+	}()
+
+	if sd.ExportMPRIS && sd.export == nil {
+		export, err := mpris.Export(sd.bus, sd.player)
+		if err != nil {
+			return fmt.Errorf("exporting MPRIS: %w", err)
 		}
+		sd.export = export
 	}
-	return true
-}
 
-func (sd *SpotifyDisplay) Run() error {
-	if err := termbox.Init(); err != nil {
-		return err
+	sd.poll = newPoller(sd.clock, sd.Refresh)
+	defer sd.poll.Stop()
+	signals := sd.watchPlayer()
+	if signals != nil {
+		defer sd.bus.RemoveSignal(signals)
 	}
-	defer termbox.Close()
-
-	fmt.Print("\033[?25l")
-	defer fmt.Print("\033[?25h")
-	fmt.Print("\033[2J\033[H")
+	locks, unwatchLock := sd.watchLock()
+	defer unwatchLock()
+	stopPlugins := sd.startPlugins()
+	defer stopPlugins()
+	outputCtx, stopOutput := context.WithCancel(context.Background())
+	defer stopOutput()
+	outputs := sd.watchOutput(outputCtx)
+	levels := sd.startVisualizer(outputCtx)
+	meter := sd.startMeter(outputCtx)
 
-	eventQueue := make(chan termbox.Event)
-	go func() {
-		for {
-			eventQueue <- termbox.PollEvent()
+	// The frame ticker only runs while seek or volume input is pending.
+	var frame Ticker
+	frameC := func() <-chan time.Time {
+		if frame == nil {
+			return nil
+		}
+		return frame.C()
+	}
+	defer func() {
+		if frame != nil {
+			frame.Stop()
 		}
 	}()
 
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+	calls := sd.exportService()
+	defer sd.restoreTerminal()
+
+	// A service pings systemd's watchdog from here, so a stuck loop gets
+	// it restarted.
+	var aliveC <-chan time.Time
+	if sd.service {
+		sdnotify.Notify(sdnotify.Ready + "\n" + sdnotify.Status("showing the player"))
+		if every := sdnotify.WatchdogInterval(); every > 0 {
+			alive := sd.clock.NewTicker(every)
+			defer alive.Stop()
+			aliveC = alive.C()
+		}
+	}
+
+	health := make(chan []healthIssue, 1)
+	go func(cfg config.Config) {
+		defer guard.Recover()
+		health <- checkHealth(cfg)
+	}(sd.Config)
+
+	watching := make(chan struct{})
+	defer close(watching)
+	configChanged, err := watchConfig(watching)
+	if err != nil {
+		slog.Warn("config changes are only read on SIGHUP", "err", err)
+	}
+	stamp := configStamp()
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
 
 	for {
+		buffered.ascii = sd.ASCII
+		buffered.Flush()
 		select {
-		case event := <-eventQueue:
-			if event.Type == termbox.EventKey {
-				if event.Ch == 'q' {
+		case event := <-events:
+			key, _ := event.(*tcell.EventKey)
+			if _, ok := event.(*tcell.EventResize); ok {
+				// Lay out the frame and the cover for the new size now,
+				// not on the next track.
+				syncScreen()
+				sd.invalidate()
+			} else if mouse, ok := event.(*tcell.EventMouse); ok {
+				if sd.handleMouse(mouse) {
+					sd.invalidate()
+				}
+			} else if key != nil && sd.editor != nil {
+				if sd.handleEditorKey(key) {
+					sd.invalidate()
+				}
+			} else if key != nil && sd.picker != nil {
+				if sd.handlePickerKey(key) {
+					sd.invalidate()
+				}
+			} else if key != nil && sd.settings != nil {
+				if sd.handleSettingsKey(key) {
+					sd.invalidate()
+				}
+			} else if key != nil && sd.editingPlaylist() {
+				if sd.handlePlaylistKey(key) {
+					sd.invalidate()
+				}
+			} else if key != nil {
+				action := ""
+				if b := sd.boundTo(key); b != nil {
+					action = b.name
+				}
+				if sd.service && (action == "quit" || action == "detach") {
+					// Only systemctl stops a service.
+					continue
+				}
+				if action == "quit" {
 					return nil
 				}
-				if sd.handleKeyboard(event) {
-					fmt.Print("\033[2J\033[H")
-					sd.currentArtURL = ""
+				if action == "detach" && detach() {
+					return nil
+				}
+				if sd.handleKeyboard(key) {
+					sd.invalidate()
 				}
 			}
+			if frame == nil && !sd.pending.empty() {
+				frame = sd.clock.NewTicker(frameInterval)
+			}
 
-		case <-ticker.C:
+		case <-frameC():
+			frame.Stop()
+			frame = nil
+			sd.flushInput()
+			// Show the effect without waiting out a paused poll interval.
+			sd.poll.hurry()
+
+		case <-sd.redraw:
+			sd.invalidate()
+
+		case call := <-calls:
+			call.done <- call.run(sd)
+			sd.invalidate()
+
+		case signal, ok := <-signals:
+			listening, err := sd.playerSignal(signal, ok)
+			if err != nil {
+				return err
+			}
+			if !listening {
+				signals = nil
+			}
+
+		case signal, ok := <-locks:
+			if !sd.lockSignal(signal, ok) {
+				locks = nil
+			}
+
+		case frame, ok := <-levels:
+			if !ok {
+				levels, sd.visualizing = nil, false
+				continue
+			}
+			sd.drawVisualizer(frame)
+
+		case frame, ok := <-meter:
+			if !ok {
+				// No sound server: the meter turns itself off.
+				meter, sd.metering = nil, false
+				continue
+			}
+			sd.drawMeter(frame)
+
+		case out, ok := <-outputs:
+			if !ok {
+				outputs = nil
+				continue
+			}
+			sd.outputChanged(out)
+
+		case m := <-sd.plugins.Messages():
+			if sd.pluginMessage(m) {
+				sd.invalidate()
+			}
+
+		case <-aliveC:
+			sdnotify.Notify(sdnotify.Watchdog)
+
+		case <-sd.poll.C():
+			if sd.service && sd.bus != nil && !sd.bus.Connected() {
+				return errBusLost
+			}
+			sd.checkSleep()
 			term := sd.getTerminalSize()
-			metadata, err := sd.getMetadata()
+			metadata, err := sd.player.Metadata()
+			sd.poll.observe(metadata, err)
 			if err != nil {
+				if !sd.playerFailed {
+					sd.playerFailed = true
+					sd.toast("can't read the player: " + err.Error())
+				}
+				sd.drawToast(term)
 				continue
 			}
+			cleanMetadata(metadata)
+			sd.playerFailed = false
 
-			// Clear previous lines before writing new text
-			fmt.Printf("\033[%d;%dH%s", term.startY+1, term.startX+20, strings.Repeat(" ", 60))
-			fmt.Printf("\033[%d;%dH%s", term.startY+2, term.startX+20, strings.Repeat(" ", 60))
-			fmt.Printf("\033[%d;%dH%s", term.startY+3, term.startX+20, strings.Repeat(" ", 60))
+			if sd.playerName == "" {
+				sd.playerName = sd.player.Identity()
+			}
+			sd.current = *metadata
+			sd.paused = metadata.Status == "Paused"
+			if !sd.pending.volume {
+				sd.volume = metadata.Volume
+			}
+			if sd.export != nil {
+				sd.export.Update(metadata)
+			}
 
-			// Write new text
-			fmt.Printf("\033[%d;%dH♫ Now Playing", term.startY+1, term.startX+20)
-			fmt.Printf("\033[%d;%dH%s", term.startY+2, term.startX+20, metadata.Title)
-			fmt.Printf("\033[%d;%dHby %s", term.startY+3, term.startX+20, metadata.Artist)
-			sd.drawProgressBar(metadata, term)
+			sd.gaps.observe(metadata, sd.clock.Now())
+			sd.stuck = sd.StuckTimeout > 0 && sd.watchdog.stuck(metadata, sd.StuckTimeout, sd.clock.Now())
+			if sd.stuck && sd.StuckNudge && !sd.watchdog.nudged {
+				sd.watchdog.nudged = true
+				slog.Warn("player appears stuck; nudging it", "track", metadata.TrackID)
+				if err := sd.nudgePlayer(); err != nil {
+					slog.Error("nudging the player", "err", err)
+				}
+			}
+
+			sd.publishEvents(metadata)
+			sd.writeStatusCache(metadata)
+			sd.updateNowPlaying(metadata)
+			sd.updateTerminal(metadata)
 
-			if metadata.ArtURL != sd.currentArtURL && metadata.ArtURL != "" {
-				sd.currentArtURL = metadata.ArtURL
-				if imagePath, err := sd.downloadArtwork(metadata.ArtURL); err == nil {
-					sd.displayImage(imagePath, term.startX, term.startY)
+			compact := sd.compact(term)
+			if compact != sd.wasCompact {
+				sd.wasCompact = compact
+				sd.invalidate()
+			}
+
+			if sd.debug {
+				sd.drawDebugOverlay(term)
+			}
+
+			sd.vizRow, sd.meterRow = ui.Rect{}, ui.Rect{}
+			small := sd.tooSmall(metadata, term)
+			if small != sd.wasSmall {
+				// Growing back redraws everything, the cover included.
+				sd.wasSmall = small
+				sd.invalidate()
+			}
+			if small {
+				sd.drawTooSmall(metadata, term)
+				continue
+			}
+			if compact {
+				sd.drawCompact(metadata, term)
+				sd.drawHealthBanner(term)
+				sd.drawToast(term)
+				continue
+			} else if sd.fullscreen {
+				sd.drawFullscreenOverlay(metadata, term)
+			} else {
+				sd.drawNowPlaying(metadata, term)
+			}
+			if sd.editor != nil {
+				sd.drawThemeEditor()
+			}
+			sd.drawPanels(term)
+			sd.drawHealthBanner(term)
+			if sd.help {
+				sd.drawHelp(term)
+			}
+			if sd.showQR {
+				sd.drawQR(term)
+			}
+			if sd.editingPlaylist() {
+				sd.drawPlaylistEditor(term)
+			}
+			if sd.settings != nil {
+				sd.drawSettings(term)
+			}
+			if sd.picker != nil {
+				sd.drawPicker(term)
+			}
+			if sd.help || sd.showQR || sd.picker != nil || sd.editor != nil || sd.editingPlaylist() || sd.settings != nil {
+				// Keep the live rows from drawing over the overlay.
+				sd.vizRow, sd.meterRow = ui.Rect{}, ui.Rect{}
+			}
+			sd.drawToast(term)
+
+			// Tracks without art from the player are keyed by album so a
+			// looked-up cover is fetched once per album.
+			artKey := metadata.ArtURL
+			if artKey == "" && sd.ArtLookup && metadata.Album != "" {
+				artKey = "lookup:" + metadata.Artist + "\x00" + metadata.Album
+			}
+
+			if !sd.artRetry.IsZero() && !sd.clock.Now().Before(sd.artRetry) {
+				sd.currentArtURL = ""
+			}
+
+			settled := sd.trackSettle.update(metadata.TrackID, sd.clock.Now())
+			if settled {
+				sd.updateAlbum(metadata.TrackID)
+				sd.updateChapters(metadata.TrackID)
+				sd.updateFeatures(metadata.TrackID)
+				sd.updateArtist(metadata)
+				sd.updateUpNext(metadata.TrackID, term)
+			}
+			sd.prefetchNext(metadata)
+			if settled && artKey != sd.currentArtURL && artKey != "" && !sd.drag.active {
+				sd.currentArtURL = artKey
+				job := sd.newArtJob(metadata, term.fontRatio)
+				if sd.fullscreen {
+					job.x, job.y, job.width, job.height = fullscreenArt(term)
+				} else if art := term.layout.Art; term.layout.HasArt() {
+					job.x, job.y, job.width, job.height = art.X, art.Y, art.Width, art.Height
 				}
+				sd.startArtwork(job)
 			}
 
-		case <-sigChan:
-			return nil
+		case conn := <-sd.takeover:
+			sd.handOver(conn)
+			return errTakenOver
+
+		case result := <-sd.chapterDone:
+			sd.setChapters(result)
+
+		case result := <-sd.featureDone:
+			sd.setFeatures(result)
+
+		case result := <-sd.artistDone:
+			sd.setArtist(result)
+
+		case result := <-sd.pickerDone:
+			sd.setPicker(result)
+
+		case result := <-sd.playlistDone:
+			sd.setPlaylist(result)
+
+		case result := <-sd.albumReady:
+			sd.setAlbum(result)
+
+		case result := <-sd.artReady:
+			if result.generation != sd.artGeneration || sd.wasSmall {
+				continue
+			}
+			if result.err != nil {
+				sd.artFailed(result.err)
+				continue
+			}
+			sd.drawImage(result.lines, result.x, result.y)
+			sd.writeNowPlayingArt(result.imagePath)
+			if sd.ArtAccent {
+				sd.artAccent = result.accent
+			}
+
+		case reply := <-backgroundReplies:
+			if color, ok := parseColorReply(reply); ok {
+				sd.setBackground(color)
+			}
+
+		case sd.issues = <-health:
+			for _, issue := range sd.issues {
+				slog.Warn(issue.problem, "fix", issue.fix)
+			}
+			sd.requestRedraw()
+
+		case <-configChanged:
+			if next := configStamp(); next != stamp {
+				stamp = next
+				sd.reloadConfig()
+			}
+
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP && !hungUp() {
+				stamp = configStamp()
+				sd.reloadConfig()
+				continue
+			}
+			if sig != syscall.SIGHUP || detach() {
+				return nil
+			}
 		}
 	}
 }
 
 func main() {
-	if err := exec.Command("pgrep", "spotify").Run(); err != nil {
-		fmt.Println("Spotify is not running. Please start Spotify first.")
-		return
+	args, jsonErrors := extractJSONErrors(os.Args[1:])
+	if err := run(args); err != nil {
+		exitWithError(err, jsonErrors)
+	}
+}
+
+func run(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errConfig, err)
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "mirror":
+			return runMirror(cfg, args[1:])
+		case "wallpaper":
+			return runWallpaper(cfg, args[1:])
+		case "login":
+			return runLogin(cfg, args[1:])
+		case "queue":
+			return runQueue(cfg, args[1:])
+		case "attach":
+			return runAttach(cfg, args[1:])
+		case "session":
+			return runSession(cfg, args[1:])
+		case "once":
+			return runOnce(cfg, args[1:])
+		case "prompt":
+			return runPrompt(cfg, args[1:])
+		case "tmux":
+			return runTmux(cfg, args[1:])
+		case "status":
+			return runStatus(cfg, args[1:])
+		case "play", "pause", "toggle", "next", "prev":
+			return runControl(cfg, args[0], args[1:])
+		}
 	}
 
-	display, err := NewSpotifyDisplay()
+	flags := displayFlags("sptsong", &cfg)
+	role := flags.String("role", "", "if sptsong is already running: mirror it, takeover, or open a companion view")
+	takeover := flags.Bool("takeover", false, "if sptsong is already running, replace it and keep its view (--role takeover)")
+	sleep := flags.Duration("sleep", 0, "pause playback after this long, e.g. 30m")
+	service := flags.Bool("service", false, "run as a systemd --user service: wait for the player, reconnect, ignore q and d")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	stopLogging, err := startLogging(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer stopLogging()
+	slog.Info("starting", "backend", cfg.Backend, "layout", cfg.Layout)
 
-	if err := display.Run(); err != nil {
-		log.Fatal(err)
+	companion := false
+	var handed *viewState
+	if other := findInstance(); other != nil {
+		// A service has nobody to ask, and replaces what it finds.
+		role, err := chooseRole(other, *role, *takeover || *service)
+		if err != nil {
+			return err
+		}
+		switch role {
+		case "":
+			return nil
+		case "mirror":
+			return mirrorInstance()
+		case "takeover":
+			if handed, err = takeOver(); err != nil {
+				return err
+			}
+		case "companion":
+			companion = true
+			cfg.ExportMPRIS, cfg.Notify, cfg.EventLog = false, nil, ""
+		}
+	}
+
+	if *service {
+		defer sdnotify.Notify(sdnotify.Stopping)
+	}
+	display, err := openDisplay(cfg, *service)
+	if display == nil || err != nil {
+		return err
+	}
+	if handed != nil {
+		display.restoreView(*handed)
+	}
+	if *sleep > 0 {
+		display.setSleep(*sleep)
 	}
+	for {
+		display.args, display.service = args, *service
+		var listener net.Listener
+		if companion {
+			display.beCompanion()
+		} else if listener, err = claimInstance(); err == nil {
+			display.serveInstances(listener, "display")
+		}
+
+		err = display.Run()
+		slog.Info("exiting", "err", err)
+		if listener != nil {
+			// Free the socket for the session or the display taking over.
+			listener.Close()
+		}
+		if !errors.Is(err, errBusLost) {
+			break
+		}
+		// A service connects again and carries on with the same view.
+		view := display.view()
+		if display, err = waitForDisplay(cfg); display == nil || err != nil {
+			return err
+		}
+		display.restoreView(view)
+	}
+	if errors.Is(err, errTakenOver) {
+		fmt.Println("sptsong: another sptsong took over")
+		return nil
+	}
+	if errors.Is(err, errDetached) {
+		if display.bus != nil {
+			// Let the session claim our exported bus name.
+			display.bus.Close()
+		}
+		if err := startSession(display.view(), args); err != nil {
+			return fmt.Errorf("detaching: %w", err)
+		}
+		fmt.Println("sptsong: detached; run `sptsong attach` to get it back")
+		return nil
+	}
+	return err
+}
 
-	fmt.Print("\033[2J\033[H")
-	fmt.Print("\033[?25h")
+// displayFlags returns the flags shared by the display and its detached
+// session, bound to cfg.
+func displayFlags(name string, cfg *config.Config) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.BoolVar(&cfg.Compact, "compact", cfg.Compact, "use the one-line layout without artwork")
+	flags.IntVar(&cfg.Art.Size, "art-size", cfg.Art.Size, "artwork width in cells")
+	flags.StringVar(&cfg.Art.Symbols, "art-symbols", cfg.Art.Symbols, "chafa symbol set, e.g. block, half, braille, all")
+	flags.StringVar(&cfg.Art.Dither, "art-dither", cfg.Art.Dither, "chafa dithering: none, ordered or diffusion")
+	flags.IntVar(&cfg.Art.Work, "art-work", cfg.Art.Work, "chafa work factor, 1-9")
+	flags.StringVar(&cfg.Art.Colors, "art-colors", cfg.Art.Colors, "artwork color depth: 16, 256, full or auto")
+	flags.BoolVar(&cfg.NoColor, "no-color", cfg.NoColor, "draw without colors (also set by NO_COLOR)")
+	flags.BoolVar(&cfg.ASCII, "ascii", cfg.ASCII, "draw with plain ASCII only, for dumb terminals and serial consoles")
+	flags.BoolFunc("debug", "log in detail (log_level = \"debug\")", func(string) error {
+		cfg.LogLevel = "debug"
+		return nil
+	})
+	flags.StringVar(&cfg.EventLog, "event-log", cfg.EventLog, "append every player event to this file as a line of JSON")
+	playerFlags(flags, cfg)
+	return flags
 }
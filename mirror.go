@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"sptsong/internal/config"
+)
+
+// mirrorTokenEnv holds the token for `mirror` when it isn't given with
+// --token, where other users could read it in the process list.
+const mirrorTokenEnv = "SPTSONG_MIRROR_TOKEN"
+
+// mirror is an io.Writer that copies every frame written to the local
+// terminal to all connected TCP clients. Slow clients are dropped rather than
+// allowed to stall the display.
+type mirror struct {
+	mu      sync.Mutex
+	out     io.Writer
+	clients map[net.Conn]chan []byte
+	onJoin  func()
+}
+
+func newMirror(out io.Writer, onJoin func()) *mirror {
+	return &mirror{
+		out:     out,
+		clients: make(map[net.Conn]chan []byte),
+		onJoin:  onJoin,
+	}
+}
+
+func (m *mirror) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for conn, frames := range m.clients {
+		select {
+		case frames <- append([]byte(nil), p...):
+		default:
+			m.drop(conn)
+		}
+	}
+	return m.out.Write(p)
+}
+
+// drop disconnects a client. m.mu must be held.
+func (m *mirror) drop(conn net.Conn) {
+	if frames, ok := m.clients[conn]; ok {
+		close(frames)
+		delete(m.clients, conn)
+		conn.Close()
+	}
+}
+
+// serve mirrors to every viewer that connects to listener and, if token is
+// set, sends it first.
+func (m *mirror) serve(listener net.Listener, token string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		if token == "" {
+			m.add(conn)
+			continue
+		}
+		go func() {
+			if !checkToken(conn, token) {
+				conn.Close()
+				return
+			}
+			m.add(conn)
+		}()
+	}
+}
+
+// checkToken reads the line a viewer sends first and reports whether it is
+// the token.
+func checkToken(conn net.Conn, token string) bool {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	line, err := bufio.NewReader(io.LimitReader(conn, 1024)).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSuffix(line, "\n")), []byte(token)) == 1
+}
+
+// listenMirror opens the listener for `mirror --listen`. An address with a
+// slash is a unix socket only its owner may connect to, and one without a
+// host listens on the loopback interface. Any other host is open to the
+// network, so it takes a token for viewers to send.
+func listenMirror(addr, token string) (net.Listener, error) {
+	if strings.Contains(addr, "/") {
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(addr)
+		}
+		listener, err := net.Listen("unix", addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(addr, 0o600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: --listen %s: %v", errUsage, addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) && token == "" {
+		return nil, fmt.Errorf("%w: --listen %s is open to the network; set a --token (or %s) for viewers to send", errUsage, addr, mirrorTokenEnv)
+	}
+	return net.Listen("tcp", net.JoinHostPort(host, port))
+}
+
+// add starts streaming frames to conn.
+func (m *mirror) add(conn net.Conn) {
+	frames := make(chan []byte, 256)
+	m.mu.Lock()
+	m.clients[conn] = frames
+	m.mu.Unlock()
+
+	go func() {
+		for frame := range frames {
+			if _, err := conn.Write(frame); err != nil {
+				m.mu.Lock()
+				m.drop(conn)
+				m.mu.Unlock()
+				return
+			}
+		}
+	}()
+
+	// New viewers need a full frame, not just the next partial update.
+	m.onJoin()
+}
+
+// runMirrorClient renders the frames streamed by a `mirror --listen`
+// instance until the connection closes, sending token first if it is set.
+func runMirrorClient(addr, token string) error {
+	network := "tcp"
+	if strings.Contains(addr, "/") {
+		network = "unix"
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		if _, err := fmt.Fprintln(conn, token); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	return showFrames(conn, conn)
+}
+
+// showFrames copies frames from r to the terminal until conn closes or the
+// user interrupts.
+func showFrames(conn net.Conn, r io.Reader) error {
+	defer conn.Close()
+
+	fmt.Print(enterAltScreen)
+	defer fmt.Print(leaveAltScreen)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		conn.Close()
+	}()
+
+	_, err := io.Copy(os.Stdout, r)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+func runMirror(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	listen := flags.String("listen", "", "serve frames on this address: :7070 for this machine only, 0.0.0.0:7070 with a --token for the network, or a unix socket path")
+	connect := flags.String("connect", "", "render frames from a listening instance, e.g. host:7070")
+	token := flags.String("token", "", "secret viewers must send, needed to listen beyond this machine (default $"+mirrorTokenEnv+")")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *token == "" {
+		*token = os.Getenv(mirrorTokenEnv)
+	}
+
+	if *connect != "" {
+		return runMirrorClient(*connect, *token)
+	}
+	if *listen == "" {
+		return fmt.Errorf("%w: mirror needs --listen or --connect", errUsage)
+	}
+
+	listener, err := listenMirror(*listen, *token)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	display, err := NewSpotifyDisplay(cfg)
+	if err != nil {
+		return err
+	}
+
+	m := newMirror(display.out, display.requestRedraw)
+	display.out = m
+	go m.serve(listener, *token)
+
+	return display.Run()
+}
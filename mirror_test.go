@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenMirror(t *testing.T) {
+	if _, err := listenMirror("0.0.0.0:0", ""); !errors.Is(err, errUsage) {
+		t.Errorf("listening on every interface without a token: %v, want a usage error", err)
+	}
+
+	listener, err := listenMirror(":0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	if ip := listener.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+		t.Errorf("listened on %v, want the loopback interface", ip)
+	}
+
+	path := filepath.Join(t.TempDir(), "mirror.sock")
+	listener, err = listenMirror(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, %v; want 0600", info.Mode(), err)
+	}
+}
+
+func TestMirrorToken(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	joined := make(chan struct{}, 2)
+	m := newMirror(io.Discard, func() { joined <- struct{}{} })
+	go m.serve(listener, "s3cret")
+
+	view := func(token string) net.Conn {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(conn, token)
+		return conn
+	}
+
+	stranger := view("guess")
+	defer stranger.Close()
+	if n, err := stranger.Read(make([]byte, 1)); err == nil {
+		t.Errorf("viewer with the wrong token read %d bytes", n)
+	}
+
+	viewer := view("s3cret")
+	defer viewer.Close()
+	<-joined
+	m.Write([]byte("frame"))
+	frame := make([]byte, 5)
+	if _, err := io.ReadFull(viewer, frame); err != nil || string(frame) != "frame" {
+		t.Errorf("viewer with the token read %q, %v", frame, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+// volumeStep is how much one notch of the scroll wheel, or one press of a
+// volume key, changes the volume.
+const volumeStep = 0.05
+
+// dragState tracks the widget being dragged with the left mouse button.
+// held tells a press from the motion events that follow it while the button
+// stays down, so a drag that started outside the widget doesn't pick it up.
+type dragState struct {
+	active, held     bool
+	offsetX, offsetY int
+}
+
+// handleMouse drags the widget around and turns the scroll wheel over it
+// into volume changes. It reports whether the screen needs a full repaint.
+func (sd *SpotifyDisplay) handleMouse(event *tcell.EventMouse) bool {
+	term := sd.getTerminalSize()
+	frame := term.frame
+	mouseX, mouseY := event.Position()
+	inside := mouseX >= frame.X && mouseX < frame.X+frame.Width &&
+		mouseY >= frame.Y && mouseY < frame.Y+frame.Height
+
+	buttons := event.Buttons()
+	switch {
+	case buttons&tcell.Button1 != 0:
+		pressed := !sd.drag.held
+		sd.drag.held = true
+		if !sd.drag.active {
+			if !inside || !pressed {
+				return false
+			}
+			sd.drag = dragState{active: true, held: true, offsetX: mouseX - frame.X, offsetY: mouseY - frame.Y}
+			return false
+		}
+		x := max(min(mouseX-sd.drag.offsetX, term.width-frame.Width), 0)
+		y := max(min(mouseY-sd.drag.offsetY, term.height-frame.Height), 0)
+		if x == frame.X && y == frame.Y {
+			return false
+		}
+		sd.HorizontalAlign, sd.VerticalAlign = "manual", "manual"
+		sd.X, sd.Y = x, y
+		return true
+
+	case buttons == tcell.ButtonNone:
+		sd.drag.held = false
+		if !sd.drag.active {
+			return false
+		}
+		sd.drag.active = false
+		if sd.manual() {
+			sd.savePosition()
+		}
+		// Draw the artwork again, which is held back while dragging.
+		return true
+
+	case buttons&(tcell.WheelUp|tcell.WheelDown) != 0:
+		if !inside {
+			return false
+		}
+		step := volumeStep
+		if buttons&tcell.WheelDown != 0 {
+			step = -volumeStep
+		}
+		sd.changeVolume(step)
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"time"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/notify"
+)
+
+// seekThreshold is how far the position may stray from where steady
+// playback would have taken it before the difference counts as a seek.
+const seekThreshold = 3 * time.Second
+
+// eventWatcher turns successive metadata snapshots into notification events:
+// a track change once the new track has settled, pause and resume, seeks,
+// and the player getting stuck. The first snapshot only sets the baseline.
+type eventWatcher struct {
+	settle   settler
+	started  bool
+	track    string
+	status   string
+	stuck    bool
+	position int64
+	seen     time.Time
+	trackNow string
+}
+
+func (w *eventWatcher) observe(m *mpris.Metadata, stuck bool, now time.Time) []notify.Kind {
+	settled := w.settle.update(m.TrackID, now)
+	if !w.started {
+		w.started = true
+		w.track, w.status, w.stuck = m.TrackID, m.Status, stuck
+		w.trackNow, w.position, w.seen = m.TrackID, m.Position, now
+		return nil
+	}
+
+	var kinds []notify.Kind
+	if m.TrackID == w.trackNow {
+		expected := time.Duration(w.position) * time.Second
+		if w.status == "Playing" {
+			expected += now.Sub(w.seen)
+		}
+		if drift := time.Duration(m.Position)*time.Second - expected; drift > seekThreshold || drift < -seekThreshold {
+			kinds = append(kinds, notify.Seeked)
+		}
+	}
+	w.trackNow, w.position, w.seen = m.TrackID, m.Position, now
+
+	if settled && m.TrackID != w.track && m.TrackID != "" {
+		kinds = append(kinds, notify.TrackChanged)
+		w.track = m.TrackID
+	}
+	if m.Status != w.status {
+		switch {
+		case m.Status == "Paused" && w.status == "Playing":
+			kinds = append(kinds, notify.Paused)
+		case m.Status == "Playing" && w.status == "Paused":
+			kinds = append(kinds, notify.Resumed)
+		}
+		w.status = m.Status
+	}
+	if stuck && !w.stuck {
+		kinds = append(kinds, notify.Stuck)
+	}
+	w.stuck = stuck
+	return kinds
+}
+
+// publishEvents sends the events since the last snapshot to the sinks, and
+// adds new tracks to the history.
+func (sd *SpotifyDisplay) publishEvents(m *mpris.Metadata) {
+	now := sd.clock.Now()
+	if !sd.events.started {
+		// The first track raises no event, but it is being played, and
+		// plugins need to know what it is.
+		sd.history.add(m, now)
+		sd.plugins.Publish(notify.Event{Kind: notify.TrackChanged, Time: now, Player: sd.playerName, Track: *m})
+	}
+	for _, kind := range sd.events.observe(m, sd.stuck, now) {
+		if kind == notify.TrackChanged {
+			sd.history.add(m, now)
+		}
+		e := notify.Event{Kind: kind, Time: now, Player: sd.playerName, Track: *m}
+		sd.notifier.Publish(e)
+		sd.plugins.Publish(e)
+	}
+}
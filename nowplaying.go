@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+
+	"sptsong/internal/mpris"
+)
+
+// published is what was last written to the [now_playing] files.
+type published struct {
+	text, track string
+}
+
+// writeFileAtomic replaces path with data through a rename, so a reader
+// polling the file, like OBS, never sees it half written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// updateNowPlaying rewrites [now_playing] text_file whenever the formatted
+// text changes, and removes art_file when the track does, until its cover
+// is ready.
+func (sd *SpotifyDisplay) updateNowPlaying(metadata *mpris.Metadata) {
+	if sd.companion {
+		return
+	}
+	if path := sd.NowPlaying.TextFile; path != "" {
+		text := formatStatus(sd.NowPlaying.Format, metadata)
+		if text != sd.published.text && writeFileAtomic(path, []byte(text+"\n")) == nil {
+			sd.published.text = text
+		}
+	}
+	if metadata.TrackID != sd.published.track {
+		sd.published.track = metadata.TrackID
+		if sd.NowPlaying.ArtFile != "" {
+			os.Remove(sd.NowPlaying.ArtFile)
+		}
+	}
+}
+
+// writeNowPlayingArt copies the current cover to [now_playing] art_file.
+func (sd *SpotifyDisplay) writeNowPlayingArt(imagePath string) {
+	if sd.companion || sd.NowPlaying.ArtFile == "" || imagePath == "" {
+		return
+	}
+	if data, err := os.ReadFile(imagePath); err == nil {
+		writeFileAtomic(sd.NowPlaying.ArtFile, data)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sptsong/internal/config"
+)
+
+// snapshot is an io.Writer standing in for the terminal: it understands the
+// cursor moves, clears and colors the display draws with, and keeps the
+// resulting cells so they can be printed as plain lines.
+type snapshot struct {
+	width, height int
+	cells         [][]cell
+	x, y          int
+	savedX        int
+	savedY        int
+	style         string
+}
+
+type cell struct {
+	ch    rune
+	style string
+}
+
+func newSnapshot(width, height int) *snapshot {
+	s := &snapshot{width: width, height: height, cells: make([][]cell, height)}
+	for i := range s.cells {
+		s.cells[i] = make([]cell, width)
+	}
+	s.clear()
+	return s
+}
+
+func (s *snapshot) clear() {
+	for _, row := range s.cells {
+		for i := range row {
+			row[i] = cell{ch: ' '}
+		}
+	}
+}
+
+func (s *snapshot) Write(p []byte) (int, error) {
+	text := []rune(string(p))
+	for i := 0; i < len(text); i++ {
+		switch r := text[i]; {
+		case r == '\033' && i+1 < len(text) && text[i+1] == '[':
+			end := i + 2
+			for end < len(text) && (text[end] < 0x40 || text[end] > 0x7e) {
+				end++
+			}
+			if end == len(text) {
+				return len(p), nil
+			}
+			s.control(string(text[i+2:end]), text[end])
+			i = end
+		case r == '\033' && i+1 < len(text):
+			switch text[i+1] {
+			case '7':
+				s.savedX, s.savedY = s.x, s.y
+			case '8':
+				s.x, s.y = s.savedX, s.savedY
+			}
+			i++
+		case r == '\n':
+			s.x, s.y = 0, s.y+1
+		case r == '\r':
+			s.x = 0
+		case r >= ' ':
+			if s.x >= 0 && s.x < s.width && s.y >= 0 && s.y < s.height {
+				s.cells[s.y][s.x] = cell{ch: r, style: s.style}
+			}
+			s.x++
+		}
+	}
+	return len(p), nil
+}
+
+// control applies one CSI sequence; anything it doesn't draw with, like
+// showing or hiding the cursor, is ignored.
+func (s *snapshot) control(params string, final rune) {
+	number := func(text string, fallback int) int {
+		if n, err := strconv.Atoi(text); err == nil {
+			return n
+		}
+		return fallback
+	}
+	switch final {
+	case 'H':
+		row, col, _ := strings.Cut(params, ";")
+		s.y, s.x = number(row, 1)-1, number(col, 1)-1
+	case 'C':
+		s.x += number(params, 1)
+	case 'J':
+		if params == "2" {
+			s.clear()
+		}
+	case 'K':
+		if params == "2" && s.y >= 0 && s.y < s.height {
+			for i := range s.cells[s.y] {
+				s.cells[s.y][i] = cell{ch: ' '}
+			}
+		}
+	case 'm':
+		if params == "" || params == "0" {
+			s.style = ""
+		} else if rest, ok := strings.CutPrefix(params, "0;"); ok {
+			s.style = "\033[" + rest + "m"
+		} else {
+			s.style += "\033[" + params + "m"
+		}
+	}
+}
+
+// String returns the cells as lines, without trailing blanks, with the colors
+// reset at the end of each line.
+func (s *snapshot) String() string {
+	var b strings.Builder
+	for _, row := range s.cells {
+		end := len(row)
+		for end > 0 && row[end-1] == (cell{ch: ' '}) {
+			end--
+		}
+		style := ""
+		for _, c := range row[:end] {
+			if c.style != style {
+				if style != "" {
+					b.WriteString("\033[0m")
+				}
+				b.WriteString(c.style)
+				style = c.style
+			}
+			b.WriteRune(c.ch)
+		}
+		if style != "" {
+			b.WriteString("\033[0m")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// runOnce draws a single frame of the display, artwork included, to stdout
+// and exits, for motd screens, cron jobs and pipes.
+func runOnce(cfg config.Config, args []string) error {
+	flags := displayFlags("sptsong once", &cfg)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w: once takes no arguments", errUsage)
+	}
+	// Nothing but the frame: no exported player, notifications or event log.
+	cfg.ExportMPRIS, cfg.Notify, cfg.EventLog = false, nil, ""
+	cfg.HorizontalAlign, cfg.VerticalAlign, cfg.Margin = "left", "top", 0
+
+	sd, err := NewSpotifyDisplay(cfg)
+	if err != nil {
+		return err
+	}
+	defer sd.notifier.Close()
+	metadata, err := sd.player.Metadata()
+	if err != nil {
+		return err
+	}
+	cleanMetadata(metadata)
+	sd.playerName = sd.player.Identity()
+	sd.paused = metadata.Status == "Paused"
+
+	// The screen is exactly the size of the widget.
+	ratio := fontRatio()
+	sd.screenSize = func() (int, int, float64) { return 0, 0, ratio }
+	frame := sd.getTerminalSize().frame
+	width, height := frame.Width, frame.Height
+	if sd.Compact {
+		height = 1
+	}
+	sd.screenSize = func() (int, int, float64) { return width, height, ratio }
+	term := sd.getTerminalSize()
+	screen := newSnapshot(width, height)
+	sd.out = screen
+
+	if sd.compact(term) {
+		sd.drawCompact(metadata, term)
+		_, err = fmt.Print(screen)
+		return err
+	}
+
+	// The cover comes first so its accent can tint the text.
+	var cover artResult
+	if art := term.layout.Art; term.layout.HasArt() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		job := sd.newArtJob(metadata, ratio)
+		job.x, job.y, job.width, job.height = art.X, art.Y, art.Width, art.Height
+		result, err := sd.loadArtwork(ctx, job)
+		if err == nil {
+			cover = result
+			sd.artAccent = result.accent
+		}
+	}
+	sd.drawNowPlaying(metadata, term)
+	sd.drawImage(cover.lines, cover.x, cover.y)
+	_, err = fmt.Print(screen)
+	return err
+}
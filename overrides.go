@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+)
+
+// overridePlayer applies the configured metadata corrections to everything
+// the player reports, so the display, notifications, the MPRIS export and
+// the mirror all see the same names.
+type overridePlayer struct {
+	mpris.Player
+	rules []config.Override
+}
+
+func withOverrides(player mpris.Player, rules []config.Override) (mpris.Player, error) {
+	if len(rules) == 0 {
+		return player, nil
+	}
+	for i, r := range rules {
+		if r.TrackID == "" && r.Title == "" && r.Artist == "" && r.Album == "" {
+			return nil, fmt.Errorf("override #%d matches every track; set track_id, title, artist or album", i+1)
+		}
+		if r.Set == (config.OverrideFields{}) {
+			return nil, fmt.Errorf("override #%d has nothing to set", i+1)
+		}
+	}
+	return &overridePlayer{Player: player, rules: rules}, nil
+}
+
+func (p *overridePlayer) Metadata() (*mpris.Metadata, error) {
+	m, err := p.Player.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	p.apply(m)
+	return m, nil
+}
+
+// SetRate passes rate changes on, for players that take them.
+func (p *overridePlayer) SetRate(rate float64) error {
+	setter, ok := p.Player.(mpris.RateSetter)
+	if !ok {
+		return errFixedRate
+	}
+	return setter.SetRate(rate)
+}
+
+// PlayFile and QueueFile pass local files on, for players that play them.
+func (p *overridePlayer) PlayFile(path string) error {
+	player, ok := p.Player.(mpris.FilePlayer)
+	if !ok {
+		return errNoFiles
+	}
+	return player.PlayFile(path)
+}
+
+func (p *overridePlayer) QueueFile(path string) error {
+	player, ok := p.Player.(mpris.FilePlayer)
+	if !ok {
+		return errNoFiles
+	}
+	return player.QueueFile(path)
+}
+
+// Settings passes the playback settings on, for players that have them.
+func (p *overridePlayer) Settings() ([]mpris.Setting, error) {
+	player, ok := p.Player.(mpris.PlaybackSettings)
+	if !ok {
+		return nil, errNoSettings
+	}
+	return player.Settings()
+}
+
+func (p *overridePlayer) ChangeSetting(name string, value float64) error {
+	player, ok := p.Player.(mpris.PlaybackSettings)
+	if !ok {
+		return errNoSettings
+	}
+	return player.ChangeSetting(name, value)
+}
+
+// UpNext corrects the queued tracks too, for players that share them.
+func (p *overridePlayer) UpNext(n int) ([]mpris.Metadata, error) {
+	lister, ok := p.Player.(mpris.TrackLister)
+	if !ok {
+		return nil, mpris.ErrNoTrackList
+	}
+	queue, err := lister.UpNext(n)
+	for i := range queue {
+		p.apply(&queue[i])
+	}
+	return queue, err
+}
+
+func (p *overridePlayer) apply(m *mpris.Metadata) {
+	// Rules match the player's own values, so they don't depend on each
+	// other's order; when several set a field the last one wins.
+	original := *m
+	for _, r := range p.rules {
+		if !matchesOverride(r, &original) {
+			continue
+		}
+		if r.Set.Title != "" {
+			m.Title = r.Set.Title
+		}
+		if r.Set.Artist != "" {
+			m.Artist = r.Set.Artist
+		}
+		if r.Set.Album != "" {
+			m.Album = r.Set.Album
+		}
+	}
+}
+
+func matchesOverride(r config.Override, m *mpris.Metadata) bool {
+	field := func(want, got string) bool {
+		return want == "" || strings.EqualFold(want, got)
+	}
+	trackID := r.TrackID
+	if kind, id, ok := strings.Cut(strings.TrimPrefix(trackID, "spotify:"), ":"); ok {
+		trackID = "/com/spotify/" + kind + "/" + id
+	}
+	return field(trackID, m.TrackID) && field(r.Title, m.Title) && field(r.Artist, m.Artist) && field(r.Album, m.Album)
+}
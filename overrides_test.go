@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+)
+
+// fakePlayer reports fixed metadata and queue, and records the calls made
+// to it.
+type fakePlayer struct {
+	metadata mpris.Metadata
+	queue    []mpris.Metadata
+	calls    []string
+	volume   float64
+}
+
+func (p *fakePlayer) Metadata() (*mpris.Metadata, error) {
+	m := p.metadata
+	return &m, nil
+}
+
+func (p *fakePlayer) Identity() string { return "fake" }
+
+func (p *fakePlayer) Call(method string, args ...any) error {
+	p.calls = append(p.calls, fmt.Sprintf("%s%v", method, args))
+	return nil
+}
+
+func (p *fakePlayer) SetVolume(volume float64) error {
+	p.calls = append(p.calls, "SetVolume")
+	p.volume = volume
+	return nil
+}
+
+func (p *fakePlayer) UpNext(n int) ([]mpris.Metadata, error) {
+	return append([]mpris.Metadata(nil), p.queue[:min(n, len(p.queue))]...), nil
+}
+
+// loadOverrides reads the override rules out of a config.toml holding text.
+func loadOverrides(t *testing.T, text string) ([]config.Override, error) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "sptsong"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sptsong", "config.toml"), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	return cfg.Overrides, err
+}
+
+func TestOverrideParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		toml    string
+		want    []config.Override
+		wantErr string
+	}{
+		{
+			name: "one rule",
+			toml: `
+[[override]]
+artist = "Sigur Ros"
+set.artist = "Sigur Rós"
+`,
+			want: []config.Override{{Artist: "Sigur Ros", Set: config.OverrideFields{Artist: "Sigur Rós"}}},
+		},
+		{
+			name: "several rules, every field",
+			toml: `
+[[override]]
+track_id = "spotify:track:abc"
+set = { title = "Title", album = "Album" }
+
+[[override]]
+title = "t"
+album = "a"
+set.artist = "A"
+`,
+			want: []config.Override{
+				{TrackID: "spotify:track:abc", Set: config.OverrideFields{Title: "Title", Album: "Album"}},
+				{Title: "t", Album: "a", Set: config.OverrideFields{Artist: "A"}},
+			},
+		},
+		{
+			name: "no rules",
+			toml: `layout = "no-art"`,
+		},
+		{
+			name: "set isn't a table",
+			toml: `
+[[override]]
+artist = "a"
+set = "b"
+`,
+			wantErr: "set",
+		},
+		{
+			name:    "override isn't an array of tables",
+			toml:    `override = "x"`,
+			wantErr: "override",
+		},
+		{
+			// Left with nothing to match, which withOverrides rejects.
+			name: "unknown match field",
+			toml: `
+[[override]]
+genre = "rock"
+set.artist = "a"
+`,
+			want: []config.Override{{Set: config.OverrideFields{Artist: "a"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadOverrides(t, tt.toml)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("overrides = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidOverrides(t *testing.T) {
+	set := config.OverrideFields{Artist: "A"}
+	tests := []struct {
+		name    string
+		rules   []config.Override
+		wantErr string
+	}{
+		{"matches every track", []config.Override{{Set: set}}, "override #1 matches every track"},
+		{"sets nothing", []config.Override{{Artist: "a"}}, "override #1 has nothing to set"},
+		{"second rule", []config.Override{{Artist: "a", Set: set}, {Title: "t"}}, "override #2 has nothing to set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, err := withOverrides(&fakePlayer{}, tt.rules)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			if player != nil {
+				t.Errorf("player = %v, want nil", player)
+			}
+		})
+	}
+}
+
+func TestWithoutOverridesKeepsPlayer(t *testing.T) {
+	base := &fakePlayer{}
+	player, err := withOverrides(base, nil)
+	if err != nil || player != base {
+		t.Errorf("withOverrides(nil) = %v, %v; want the player itself", player, err)
+	}
+}
+
+func TestOverrideMatching(t *testing.T) {
+	song := mpris.Metadata{
+		TrackID: "/com/spotify/track/abc",
+		Title:   "Hoppípolla",
+		Artist:  "Sigur Ros",
+		Album:   "Takk...",
+	}
+	tests := []struct {
+		name  string
+		rules []config.Override
+		want  mpris.Metadata
+	}{
+		{
+			name:  "artist, ignoring case",
+			rules: []config.Override{{Artist: "sigur ros", Set: config.OverrideFields{Artist: "Sigur Rós"}}},
+			want:  mpris.Metadata{TrackID: song.TrackID, Title: song.Title, Artist: "Sigur Rós", Album: song.Album},
+		},
+		{
+			name:  "spotify URI",
+			rules: []config.Override{{TrackID: "spotify:track:abc", Set: config.OverrideFields{Title: "Hoppipolla"}}},
+			want:  mpris.Metadata{TrackID: song.TrackID, Title: "Hoppipolla", Artist: song.Artist, Album: song.Album},
+		},
+		{
+			name:  "object path",
+			rules: []config.Override{{TrackID: "/com/spotify/track/abc", Set: config.OverrideFields{Album: "Takk"}}},
+			want:  mpris.Metadata{TrackID: song.TrackID, Title: song.Title, Artist: song.Artist, Album: "Takk"},
+		},
+		{
+			name:  "every field must match",
+			rules: []config.Override{{Artist: "Sigur Ros", Album: "()", Set: config.OverrideFields{Artist: "X"}}},
+			want:  song,
+		},
+		{
+			name:  "other track",
+			rules: []config.Override{{TrackID: "spotify:track:xyz", Set: config.OverrideFields{Title: "X"}}},
+			want:  song,
+		},
+		{
+			name: "rules match the player's values, not each other's",
+			rules: []config.Override{
+				{Artist: "Sigur Ros", Set: config.OverrideFields{Artist: "Sigur Rós"}},
+				{Artist: "Sigur Rós", Set: config.OverrideFields{Album: "X"}},
+			},
+			want: mpris.Metadata{TrackID: song.TrackID, Title: song.Title, Artist: "Sigur Rós", Album: song.Album},
+		},
+		{
+			name: "the last rule setting a field wins",
+			rules: []config.Override{
+				{Artist: "Sigur Ros", Set: config.OverrideFields{Title: "First"}},
+				{Title: "Hoppípolla", Set: config.OverrideFields{Title: "Second"}},
+			},
+			want: mpris.Metadata{TrackID: song.TrackID, Title: "Second", Artist: song.Artist, Album: song.Album},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, err := withOverrides(&fakePlayer{metadata: song, queue: []mpris.Metadata{song}}, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := player.Metadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("Metadata() = %+v, want %+v", *got, tt.want)
+			}
+			queue, err := player.(mpris.TrackLister).UpNext(1)
+			if err != nil || len(queue) != 1 || queue[0] != tt.want {
+				t.Errorf("UpNext() = %+v, %v; want [%+v]", queue, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverrideUpNextWithoutTrackList(t *testing.T) {
+	player, err := withOverrides(struct{ mpris.Player }{&fakePlayer{}}, []config.Override{{Artist: "a", Set: config.OverrideFields{Artist: "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := player.(mpris.TrackLister).UpNext(3); !errors.Is(err, mpris.ErrNoTrackList) {
+		t.Errorf("UpNext() error = %v, want ErrNoTrackList", err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import "sptsong/internal/ui"
+
+// sidePanel is a block of rows drawn next to the now-playing frame, such as
+// the audio features.
+type sidePanel struct {
+	rows int
+	draw func(term TerminalSize, top int)
+}
+
+// sidePanels are the open panels, in the order they stack away from the
+// frame.
+func (sd *SpotifyDisplay) sidePanels(term TerminalSize) []sidePanel {
+	if sd.fullscreen {
+		return nil
+	}
+	var panels []sidePanel
+	for _, place := range sd.liveRows()[sd.liveRowsUnderBar(term):] {
+		panels = append(panels, sidePanel{1, func(term TerminalSize, top int) {
+			frame := term.frame
+			place(frame.X+max(frame.Width-ui.TextWidth, 0)/2, top, frame.Width)
+		}})
+	}
+	if sd.showFeatures {
+		panels = append(panels, sidePanel{featureRows, sd.drawFeatures})
+	}
+	if sd.showArtist {
+		panels = append(panels, sidePanel{artistRows, sd.drawArtist})
+	}
+	return append(panels, sd.pluginPanels()...)
+}
+
+// drawPanels places the open panels under the frame while they fit and
+// above it after that, skipping any that fit neither, then gives the
+// history whatever is left: the space under them, or above the frame when
+// the display sits at the bottom of the screen.
+func (sd *SpotifyDisplay) drawPanels(term TerminalSize) {
+	frame := term.frame
+	below, belowRows := frame.Y+frame.Height, term.height-(frame.Y+frame.Height)
+	aboveRows := frame.Y
+	for _, p := range sd.sidePanels(term) {
+		switch {
+		case p.rows <= belowRows:
+			p.draw(term, below)
+			below, belowRows = below+p.rows, belowRows-p.rows
+		case p.rows <= aboveRows:
+			aboveRows -= p.rows
+			p.draw(term, aboveRows)
+		}
+	}
+
+	if !sd.history.visible || sd.fullscreen {
+		return
+	}
+	if belowRows < 3 && aboveRows > belowRows {
+		sd.drawHistory(term, 0, aboveRows)
+	} else {
+		sd.drawHistory(term, below, belowRows)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/guard"
+	"sptsong/internal/ui"
+	"sptsong/internal/webapi"
+)
+
+// pickerRows is how many matching items the picker lists at once.
+const pickerRows = 12
+
+// pickerItem is one entry in the picker.
+type pickerItem struct {
+	name string
+	uri  string
+	id   string
+}
+
+// picker is a fuzzy-filtered list overlay, such as the playlists to add the
+// track to. Its items load in the background and pick runs on the one
+// chosen with Enter, or alt, if set, on the one chosen with Tab.
+type picker struct {
+	heading  string
+	caption  string
+	hint     string
+	items    []pickerItem
+	loading  bool
+	err      error
+	query    []rune
+	selected int
+	pick     func(sd *SpotifyDisplay, item pickerItem)
+	alt      func(sd *SpotifyDisplay, item pickerItem)
+	// cache keeps the loaded items for the next time this picker opens.
+	cache *[]pickerItem
+}
+
+// pickerResult is a finished background lookup for a picker, or, when
+// notice is set, a finished action to report.
+type pickerResult struct {
+	picker *picker
+	items  []pickerItem
+	notice string
+	err    error
+}
+
+// setPicker takes a finished lookup or action into use. A lookup for a
+// picker that has since closed is dropped.
+func (sd *SpotifyDisplay) setPicker(result pickerResult) {
+	if result.notice != "" {
+		sd.showNotice(result.notice)
+		return
+	}
+	p := result.picker
+	if p != sd.picker {
+		return
+	}
+	p.loading, p.err = false, result.err
+	if result.err == nil {
+		p.items, *p.cache = result.items, result.items
+	}
+}
+
+// loadPicker opens p and fills it with what load returns, in the
+// background.
+func (sd *SpotifyDisplay) loadPicker(p *picker, load func(context.Context, *webapi.Client) ([]pickerItem, error)) {
+	p.items, p.loading = *p.cache, true
+	sd.picker = p
+	client := newWebAPI(sd.Config)
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		items, err := load(ctx, client)
+		sd.pickerDone <- pickerResult{picker: p, items: items, err: err}
+	}()
+}
+
+// matches returns the items the query fuzzily matches, best first.
+func (p *picker) matches() []pickerItem {
+	type match struct {
+		item  pickerItem
+		score int
+	}
+	var found []match
+	for _, item := range p.items {
+		if score, ok := fuzzyScore(string(p.query), item.name); ok {
+			found = append(found, match{item, score})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+	items := make([]pickerItem, len(found))
+	for i, m := range found {
+		items[i] = m.item
+	}
+	return items
+}
+
+// fuzzyScore reports whether the letters of query appear in order in name,
+// ignoring case, scoring runs of consecutive letters and matches at word
+// starts higher.
+func fuzzyScore(query, name string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	score, run, qi := 0, 0, 0
+	prev := ' '
+	for _, r := range strings.ToLower(name) {
+		if qi < len(q) && r == q[qi] {
+			qi++
+			run++
+			score += run
+			if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				score += 3
+			}
+		} else {
+			run = 0
+		}
+		prev = r
+	}
+	return score, qi == len(q)
+}
+
+// handlePickerKey processes a key while the picker is open and reports
+// whether the screen needs a full repaint.
+func (sd *SpotifyDisplay) handlePickerKey(event *tcell.EventKey) bool {
+	p := sd.picker
+	matches := p.matches()
+	switch key := event.Key(); {
+	case key == tcell.KeyEsc:
+		sd.picker = nil
+		return true
+	case key == tcell.KeyUp:
+		p.selected = max(p.selected-1, 0)
+	case key == tcell.KeyDown:
+		p.selected = min(p.selected+1, max(len(matches)-1, 0))
+	case key == tcell.KeyBackspace || key == tcell.KeyBackspace2:
+		if len(p.query) > 0 {
+			p.query, p.selected = p.query[:len(p.query)-1], 0
+		}
+	case key == tcell.KeyEnter || (key == tcell.KeyTab && p.alt != nil):
+		if p.selected >= len(matches) {
+			return false
+		}
+		sd.picker = nil
+		if key == tcell.KeyTab {
+			p.alt(sd, matches[p.selected])
+		} else {
+			p.pick(sd, matches[p.selected])
+		}
+		return true
+	case keyRune(event) != 0:
+		p.query, p.selected = append(p.query, event.Rune()), 0
+	}
+	return false
+}
+
+// drawPicker draws the picker in a box in the middle of the screen.
+func (sd *SpotifyDisplay) drawPicker(term TerminalSize) {
+	p := sd.picker
+	width := min(max(term.width-4, 20), 56)
+	height := pickerRows + 4
+	box := ui.Rect{X: (term.width - width) / 2, Y: max((term.height-height)/2, 0), Width: width, Height: height}
+	theme := sd.theme()
+	border := sd.Border
+	if _, ok := ui.BorderStyles[border]; !ok {
+		border = "rounded"
+	}
+	fmt.Fprint(sd.out, ui.Frame(box, p.heading, border, theme.Border))
+
+	inner := width - 4
+	line := func(row int, text string, style ui.Style) {
+		text = ui.Truncate(text, inner)
+		pad := strings.Repeat(" ", max(inner-ui.Width(text), 0))
+		fmt.Fprint(sd.out, ui.MoveTo(box.X+1, box.Y+1+row)+" "+style.Render(text)+pad+" ")
+	}
+
+	hint := p.hint
+	if hint == "" {
+		hint = "↑↓ Enter Esc"
+	}
+	line(0, p.caption+" · "+hint, theme.Time)
+	line(1, "› "+string(p.query)+"▏", theme.Title)
+	matches := p.matches()
+	status := ""
+	switch {
+	case p.err != nil:
+		status = webAPIError(p.err)
+	case len(matches) == 0 && p.loading:
+		status = "loading…"
+	case len(matches) == 0:
+		status = "nothing matches"
+	}
+	// Keep the selection in view.
+	first := max(p.selected-pickerRows+1, 0)
+	for row := range pickerRows {
+		i := first + row
+		switch {
+		case row == 0 && status != "":
+			line(row+2, status, theme.Time)
+		case i < len(matches) && i == p.selected:
+			line(row+2, "▸ "+matches[i].name, theme.Accent)
+		case i < len(matches):
+			line(row+2, "  "+matches[i].name, theme.Artist)
+		default:
+			line(row+2, "", theme.Artist)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/webapi"
+)
+
+// openPlaylistPicker lists the user's playlists to add the current track
+// to, which has to be a Spotify track or episode as only those go in
+// playlists.
+func (sd *SpotifyDisplay) openPlaylistPicker() {
+	uri := artwork.SpotifyURI(sd.current.TrackID)
+	switch {
+	case sd.Spotify.ClientID == "":
+		sd.showNotice(webapi.ErrNoClientID.Error())
+		return
+	case !strings.HasPrefix(uri, "spotify:track:") && !strings.HasPrefix(uri, "spotify:episode:"):
+		sd.showNotice("only Spotify tracks can go in playlists")
+		return
+	}
+	p := &picker{
+		heading: "add to playlist",
+		caption: "“" + sd.current.Title + "”",
+		cache:   &sd.playlists,
+		pick: func(sd *SpotifyDisplay, item pickerItem) {
+			sd.runWebAPI("added to "+item.name, func(ctx context.Context, client *webapi.Client) error {
+				return client.AddToPlaylist(ctx, item.id, uri)
+			})
+		},
+	}
+	sd.loadPicker(p, editablePlaylists)
+}
+
+// editablePlaylists lists the playlists the user can change.
+func editablePlaylists(ctx context.Context, client *webapi.Client) ([]pickerItem, error) {
+	playlists, err := client.Playlists(ctx)
+	var items []pickerItem
+	for _, p := range playlists {
+		if p.Editable {
+			items = append(items, pickerItem{name: p.Name, uri: p.URI, id: p.ID})
+		}
+	}
+	return items, err
+}
+
+// runWebAPI runs a Web API action in the background and shows done, or
+// what went wrong, as a notice once it finishes.
+func (sd *SpotifyDisplay) runWebAPI(done string, action func(context.Context, *webapi.Client) error) {
+	client := newWebAPI(sd.Config)
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		result := pickerResult{notice: done}
+		if err := action(ctx, client); err != nil {
+			slog.Error("Web API request failed", "err", err)
+			result.notice = webAPIError(err)
+		}
+		sd.pickerDone <- result
+	}()
+}
+
+// webAPIError explains the Web API errors a picker runs into: logins from
+// before playlists and the library were supported, endpoints Spotify has
+// closed to newer apps, and playback commands without a device to play on.
+func webAPIError(err error) string {
+	var apiErr *webapi.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusForbidden:
+		return "not allowed for this login or Spotify app; try `sptsong login` again"
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound && strings.Contains(apiErr.Message, "device"):
+		return "no active Spotify device; start playing somewhere first"
+	}
+	return err.Error()
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/guard"
+	"sptsong/internal/ui"
+	"sptsong/internal/webapi"
+)
+
+// playlistEditor is the overlay for reordering and removing the tracks of
+// a playlist. Edits show at once and go to Spotify one at a time in the
+// background, even after the overlay closes; u takes them back in turn.
+type playlistEditor struct {
+	playlist pickerItem
+	open     bool
+	items    []webapi.PlaylistItem
+	snapshot string
+	loading  bool
+	err      error
+	selected int
+	// sent is the edit on its way to Spotify, and queued the ones made
+	// since, in order.
+	sent   *playlistEdit
+	queued []playlistEdit
+	undo   []playlistEdit
+}
+
+type editKind int
+
+const (
+	editMove editKind = iota
+	editRemove
+	editInsert
+)
+
+// playlistEdit is one change to a playlist: moving the track at from to
+// to, or removing or inserting item at positions.
+type playlistEdit struct {
+	kind      editKind
+	from, to  int
+	item      webapi.PlaylistItem
+	positions []int
+}
+
+// inverse returns the edit that takes e back.
+func (e playlistEdit) inverse() playlistEdit {
+	switch e.kind {
+	case editMove:
+		return playlistEdit{kind: editMove, from: e.to, to: e.from}
+	case editRemove:
+		return playlistEdit{kind: editInsert, item: e.item, positions: e.positions}
+	}
+	return playlistEdit{kind: editRemove, item: e.item, positions: e.positions}
+}
+
+// apply makes the edit to the editor's copy of the playlist.
+func (p *playlistEditor) apply(e playlistEdit) {
+	switch e.kind {
+	case editMove:
+		item := p.items[e.from]
+		p.items = slices.Insert(slices.Delete(p.items, e.from, e.from+1), e.to, item)
+	case editRemove:
+		for _, i := range slices.Backward(e.positions) {
+			p.items = slices.Delete(p.items, i, i+1)
+		}
+	case editInsert:
+		for _, i := range e.positions {
+			p.items = slices.Insert(p.items, i, e.item)
+		}
+	}
+}
+
+// playlistResult is a finished background load of, or edit to, the
+// playlist of editor.
+type playlistResult struct {
+	editor   *playlistEditor
+	load     bool
+	items    []webapi.PlaylistItem
+	snapshot string
+	err      error
+}
+
+// editingPlaylist reports whether the playlist editor is open.
+func (sd *SpotifyDisplay) editingPlaylist() bool {
+	return sd.playlistEditor != nil && sd.playlistEditor.open
+}
+
+// openPlaylistEditor lists the user's editable playlists, and opens the one
+// picked for editing.
+func (sd *SpotifyDisplay) openPlaylistEditor() {
+	if sd.Spotify.ClientID == "" {
+		sd.showNotice(webapi.ErrNoClientID.Error())
+		return
+	}
+	p := &picker{
+		heading: "edit playlist",
+		caption: "your playlists",
+		cache:   &sd.playlists,
+		pick:    (*SpotifyDisplay).editPlaylist,
+	}
+	sd.loadPicker(p, editablePlaylists)
+}
+
+// editPlaylist opens the editor on playlist. An editor for the same
+// playlist that is still sending edits is opened again rather than
+// loading a playlist those edits haven't reached yet.
+func (sd *SpotifyDisplay) editPlaylist(playlist pickerItem) {
+	if p := sd.playlistEditor; p != nil && p.playlist.id == playlist.id && p.sent != nil {
+		p.open = true
+		return
+	}
+	p := &playlistEditor{playlist: playlist, open: true}
+	sd.playlistEditor = p
+	sd.loadPlaylist(p)
+}
+
+func (sd *SpotifyDisplay) loadPlaylist(p *playlistEditor) {
+	p.loading = true
+	client := newWebAPI(sd.Config)
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		items, snapshot, err := client.PlaylistItems(ctx, p.playlist.id)
+		sd.playlistDone <- playlistResult{editor: p, load: true, items: items, snapshot: snapshot, err: err}
+	}()
+}
+
+// edit makes e to the editor's copy at once, and sends it to Spotify after
+// the edits before it.
+func (sd *SpotifyDisplay) edit(p *playlistEditor, e playlistEdit) {
+	p.apply(e)
+	p.queued = append(p.queued, e)
+	if p.sent == nil {
+		sd.sendEdit(p)
+	}
+}
+
+// sendEdit sends the first queued edit.
+func (sd *SpotifyDisplay) sendEdit(p *playlistEditor) {
+	e := p.queued[0]
+	p.sent, p.queued = &e, p.queued[1:]
+	client := newWebAPI(sd.Config)
+	id, snapshot := p.playlist.id, p.snapshot
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var err error
+		switch e.kind {
+		case editMove:
+			snapshot, err = client.MovePlaylistItem(ctx, id, e.from, e.to, snapshot)
+		case editRemove:
+			snapshot, err = client.RemoveFromPlaylist(ctx, id, e.item.URI, snapshot)
+		case editInsert:
+			for _, i := range e.positions {
+				if snapshot, err = client.InsertIntoPlaylist(ctx, id, e.item.URI, i); err != nil {
+					break
+				}
+			}
+		}
+		sd.playlistDone <- playlistResult{editor: p, snapshot: snapshot, err: err}
+	}()
+}
+
+// setPlaylist takes a finished load or edit into use. When an edit fails
+// the ones queued after it are dropped, since they assumed it went
+// through, and the playlist is loaded again to show what Spotify has.
+func (sd *SpotifyDisplay) setPlaylist(result playlistResult) {
+	p := result.editor
+	if result.load {
+		p.loading, p.err = false, result.err
+		if result.err == nil {
+			p.items, p.snapshot = result.items, result.snapshot
+			p.selected = min(p.selected, max(len(p.items)-1, 0))
+		}
+		return
+	}
+	p.sent = nil
+	if result.err != nil {
+		slog.Error("playlist edit failed", "playlist", p.playlist.name, "err", result.err)
+		sd.showNotice("couldn't change " + p.playlist.name + ": " + webAPIError(result.err))
+		p.queued, p.undo = nil, nil
+		sd.loadPlaylist(p)
+		return
+	}
+	p.snapshot = result.snapshot
+	if len(p.queued) > 0 {
+		sd.sendEdit(p)
+	}
+}
+
+// handlePlaylistKey processes a key while the playlist editor is open and
+// reports whether the screen needs a full repaint.
+func (sd *SpotifyDisplay) handlePlaylistKey(event *tcell.EventKey) bool {
+	p := sd.playlistEditor
+	key, ch := event.Key(), keyRune(event)
+	if key == tcell.KeyEsc {
+		p.open = false
+		return true
+	}
+	if p.loading {
+		return false
+	}
+	shift := event.Modifiers()&tcell.ModShift != 0
+	switch {
+	case key == tcell.KeyUp && shift, ch == 'K':
+		if p.selected > 0 {
+			sd.moveTrack(p, p.selected-1)
+		}
+	case key == tcell.KeyDown && shift, ch == 'J':
+		if p.selected < len(p.items)-1 {
+			sd.moveTrack(p, p.selected+1)
+		}
+	case key == tcell.KeyUp, ch == 'k':
+		p.selected = max(p.selected-1, 0)
+	case key == tcell.KeyDown, ch == 'j':
+		p.selected = min(p.selected+1, max(len(p.items)-1, 0))
+	case key == tcell.KeyDelete, ch == 'x':
+		if p.selected >= len(p.items) {
+			return false
+		}
+		item := p.items[p.selected]
+		if item.URI == "" || strings.HasPrefix(item.URI, "spotify:local:") {
+			// Spotify can't put these back, so there would be no undo.
+			sd.showNotice("local and unavailable tracks can't be removed here")
+			return false
+		}
+		var positions []int
+		for i, other := range p.items {
+			if other.URI == item.URI {
+				positions = append(positions, i)
+			}
+		}
+		// Spotify removes a track wherever it is in the playlist.
+		e := playlistEdit{kind: editRemove, item: item, positions: positions}
+		sd.edit(p, e)
+		p.undo = append(p.undo, e)
+		p.selected = min(p.selected, max(len(p.items)-1, 0))
+	case ch == 'u':
+		if len(p.undo) == 0 {
+			return false
+		}
+		e := p.undo[len(p.undo)-1]
+		p.undo = p.undo[:len(p.undo)-1]
+		undo := e.inverse()
+		sd.edit(p, undo)
+		if undo.kind == editMove {
+			p.selected = undo.to
+		} else {
+			p.selected = undo.positions[0]
+		}
+	}
+	return false
+}
+
+// moveTrack moves the selected track to position to, keeping it selected.
+func (sd *SpotifyDisplay) moveTrack(p *playlistEditor, to int) {
+	e := playlistEdit{kind: editMove, from: p.selected, to: to}
+	sd.edit(p, e)
+	p.undo = append(p.undo, e)
+	p.selected = to
+}
+
+// drawPlaylistEditor draws the playlist editor in a box in the middle of
+// the screen, the way drawPicker draws a picker.
+func (sd *SpotifyDisplay) drawPlaylistEditor(term TerminalSize) {
+	p := sd.playlistEditor
+	width := min(max(term.width-4, 20), 64)
+	height := pickerRows + 4
+	box := ui.Rect{X: (term.width - width) / 2, Y: max((term.height-height)/2, 0), Width: width, Height: height}
+	theme := sd.theme()
+	border := sd.Border
+	if _, ok := ui.BorderStyles[border]; !ok {
+		border = "rounded"
+	}
+	fmt.Fprint(sd.out, ui.Frame(box, "edit playlist", border, theme.Border))
+
+	inner := width - 4
+	line := func(row int, text string, style ui.Style) {
+		text = ui.Truncate(text, inner)
+		pad := strings.Repeat(" ", max(inner-ui.Width(text), 0))
+		fmt.Fprint(sd.out, ui.MoveTo(box.X+1, box.Y+1+row)+" "+style.Render(text)+pad+" ")
+	}
+
+	caption := p.playlist.name
+	if p.sent != nil {
+		caption += " · saving…"
+	}
+	line(0, caption, theme.Title)
+	line(1, "↑↓ select · ⇧↑↓ move · x remove · u undo · Esc", theme.Time)
+	status := ""
+	switch {
+	case p.loading:
+		status = "loading…"
+	case p.err != nil:
+		status = webAPIError(p.err)
+	case len(p.items) == 0:
+		status = "no tracks"
+	}
+	first := max(p.selected-pickerRows+1, 0)
+	for row := range pickerRows {
+		i := first + row
+		switch {
+		case row == 0 && status != "":
+			line(row+2, status, theme.Time)
+		case status == "" && i < len(p.items):
+			item := p.items[i]
+			name := fmt.Sprintf("%3d  %s", i+1, item.Name)
+			if len(item.Artists) > 0 {
+				name += " – " + item.Artists[0].Name
+			}
+			if i == p.selected {
+				line(row+2, "▸"+name, theme.Accent)
+			} else {
+				line(row+2, " "+name, theme.Artist)
+			}
+		default:
+			line(row+2, "", theme.Artist)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"sptsong/internal/webapi"
+)
+
+func TestPlaylistEditsUndo(t *testing.T) {
+	item := func(uri string) webapi.PlaylistItem { return webapi.PlaylistItem{URI: uri, Name: uri} }
+	uris := func(p *playlistEditor) []string {
+		var names []string
+		for _, item := range p.items {
+			names = append(names, item.URI)
+		}
+		return names
+	}
+	p := &playlistEditor{items: []webapi.PlaylistItem{item("a"), item("b"), item("c"), item("b"), item("d")}}
+	edits := []struct {
+		edit playlistEdit
+		want []string
+	}{
+		{playlistEdit{kind: editMove, from: 0, to: 1}, []string{"b", "a", "c", "b", "d"}},
+		{playlistEdit{kind: editMove, from: 4, to: 3}, []string{"b", "a", "c", "d", "b"}},
+		{playlistEdit{kind: editRemove, item: item("b"), positions: []int{0, 4}}, []string{"a", "c", "d"}},
+	}
+	for _, e := range edits {
+		p.apply(e.edit)
+		if got := uris(p); !slices.Equal(got, e.want) {
+			t.Fatalf("after %+v: %q, want %q", e.edit, got, e.want)
+		}
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		p.apply(edits[i].edit.inverse())
+		want := []string{"a", "b", "c", "b", "d"}
+		if i > 0 {
+			want = edits[i-1].want
+		}
+		if got := uris(p); !slices.Equal(got, want) {
+			t.Fatalf("undoing %+v: %q, want %q", edits[i].edit, got, want)
+		}
+	}
+}
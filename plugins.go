@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"sptsong/internal/config"
+	"sptsong/internal/plugin"
+	"sptsong/internal/ui"
+)
+
+// startPlugins runs the executables in the plugins directory for as long as
+// the run loop lasts, and returns the function that stops them. A companion
+// leaves them to the display it accompanies.
+func (sd *SpotifyDisplay) startPlugins() func() {
+	if sd.companion {
+		return func() {}
+	}
+	plugins, err := plugin.Start(filepath.Join(config.Dir(), "plugins"))
+	if err != nil {
+		slog.Warn("starting plugins", "err", err)
+	}
+	sd.plugins = plugins
+	return func() {
+		sd.plugins.Close()
+		sd.plugins, sd.pluginLines = nil, nil
+	}
+}
+
+// pluginMessage carries out what a plugin asked for. It reports whether the
+// screen needs clearing, as it does when a panel changes height.
+func (sd *SpotifyDisplay) pluginMessage(m plugin.Message) bool {
+	if m.Action != "" {
+		if err := sd.player.Call(controlMethods[m.Action]); err != nil {
+			slog.Warn("plugin action", "plugin", m.Plugin, "action", m.Action, "err", err)
+		}
+	}
+	if m.Lines == nil {
+		return false
+	}
+	if sd.pluginLines == nil {
+		sd.pluginLines = make(map[string][]string)
+	}
+	resized := len(m.Lines) != len(sd.pluginLines[m.Plugin])
+	sd.pluginLines[m.Plugin] = m.Lines
+	return resized
+}
+
+// pluginPanels are the panels of the plugins that have something to show,
+// in name order.
+func (sd *SpotifyDisplay) pluginPanels() []sidePanel {
+	names := make([]string, 0, len(sd.pluginLines))
+	for name, lines := range sd.pluginLines {
+		if len(lines) > 0 {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	panels := make([]sidePanel, len(names))
+	for i, name := range names {
+		lines := sd.pluginLines[name]
+		panels[i] = sidePanel{len(lines), func(term TerminalSize, top int) {
+			frame := term.frame
+			for row, text := range lines {
+				text = ui.Truncate(stripControl(text), frame.Width)
+				fmt.Fprint(sd.out, ui.MoveTo(frame.X, top+row)+strings.Repeat(" ", frame.Width)+ui.MoveTo(frame.X, top+row)+text)
+			}
+		}}
+	}
+	return panels
+}
+
+// stripControl drops control characters, so a plugin can't move the cursor
+// or change colors behind the display's back.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
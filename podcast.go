@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+	"sptsong/internal/webapi"
+)
+
+// longEpisode is the length from which an untagged track is taken for an
+// episode rather than a song.
+const longEpisode = 20 * time.Minute
+
+// isEpisode reports whether m is a podcast episode: a Spotify episode, or a
+// long track whose artist is missing or just the show's name again, as
+// podcast feeds tag them.
+func isEpisode(m *mpris.Metadata) bool {
+	if strings.Contains(m.TrackID, "/episode/") {
+		return true
+	}
+	untagged := m.Artist == "" || m.Artist == "Unknown Artist" || m.Artist == m.Album
+	return untagged && time.Duration(m.Length)*time.Second >= longEpisode
+}
+
+// showName is the podcast an episode belongs to: players put it in the
+// album, or failing that the artist.
+func showName(m *mpris.Metadata) string {
+	if m.Album != "" {
+		return m.Album
+	}
+	return m.Artist
+}
+
+// chapterResult is an episode's chapter list, looked up in the background.
+type chapterResult struct {
+	trackID  string
+	chapters []webapi.Chapter
+}
+
+// updateChapters starts looking up the chapters of a newly settled Spotify
+// episode, for the marks on the progress bar. Like the album line it needs
+// the Web API, and results are cached per episode.
+func (sd *SpotifyDisplay) updateChapters(trackID string) {
+	if trackID == sd.chapterTrack {
+		return
+	}
+	sd.chapterTrack, sd.chapters = trackID, nil
+	uri := artwork.SpotifyURI(trackID)
+	if sd.Spotify.ClientID == "" || !strings.HasPrefix(uri, "spotify:episode:") {
+		return
+	}
+	if info, ok := sd.tracks.Get(trackID); ok && info.Chapters != nil {
+		sd.chapters = info.Chapters
+		return
+	}
+
+	client := newWebAPI(sd.Config)
+	go func() {
+		defer guard.Recover()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		chapters, err := client.EpisodeChapters(ctx, strings.TrimPrefix(uri, "spotify:episode:"))
+		if err != nil {
+			return
+		}
+		select {
+		case sd.chapterDone <- chapterResult{trackID, chapters}:
+		default:
+		}
+	}()
+}
+
+// setChapters takes a finished lookup into use if its episode is still
+// playing. Episodes without chapters are cached too, as an empty list.
+func (sd *SpotifyDisplay) setChapters(result chapterResult) {
+	if result.chapters == nil {
+		result.chapters = []webapi.Chapter{}
+	}
+	sd.tracks.Update(result.trackID, fieldChapters, func(info *TrackInfo) { info.Chapters = result.chapters })
+	if result.trackID == sd.chapterTrack {
+		sd.chapters = result.chapters
+	}
+}
+
+// chapterMarks returns where the chapters after the first start, as
+// fractions of the episode, for the progress bar.
+func (sd *SpotifyDisplay) chapterMarks(metadata *mpris.Metadata) []float64 {
+	if sd.chapterTrack != metadata.TrackID || metadata.Length <= 0 {
+		return nil
+	}
+	var marks []float64
+	length := time.Duration(metadata.Length) * time.Second
+	for _, c := range sd.chapters {
+		if c.Start > 0 {
+			marks = append(marks, float64(c.Start)/float64(length))
+		}
+	}
+	return marks
+}
+
+// currentChapter returns the title of the chapter being played, or "".
+func (sd *SpotifyDisplay) currentChapter(metadata *mpris.Metadata) string {
+	if sd.chapterTrack != metadata.TrackID {
+		return ""
+	}
+	position := time.Duration(metadata.Position) * time.Second
+	title := ""
+	for _, c := range sd.chapters {
+		if c.Start <= position {
+			title = c.Title
+		}
+	}
+	return title
+}
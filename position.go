@@ -0,0 +1,58 @@
+package main
+
+import "sptsong/internal/config"
+
+// manual reports whether the display is positioned by hand on both axes.
+func (sd *SpotifyDisplay) manual() bool {
+	return sd.HorizontalAlign == "manual" && sd.VerticalAlign == "manual"
+}
+
+// toggleManual switches to manual positioning where the display is now, or
+// back to the configured alignment.
+func (sd *SpotifyDisplay) toggleManual() {
+	if sd.manual() {
+		sd.HorizontalAlign, sd.VerticalAlign = config.ConfiguredAlignment()
+		if sd.manual() {
+			defaults := config.Default()
+			sd.HorizontalAlign, sd.VerticalAlign = defaults.HorizontalAlign, defaults.VerticalAlign
+		}
+	} else {
+		frame := sd.getTerminalSize().frame
+		sd.HorizontalAlign, sd.VerticalAlign = "manual", "manual"
+		sd.X, sd.Y = frame.X, frame.Y
+	}
+	sd.savePosition()
+}
+
+// move nudges the display by dx, dy in manual mode and otherwise snaps it to
+// the given edges.
+func (sd *SpotifyDisplay) move(dx, dy int, horizontal, vertical string) {
+	if sd.manual() {
+		sd.nudge(dx, dy)
+		return
+	}
+	if horizontal != "" {
+		sd.HorizontalAlign = horizontal
+	}
+	if vertical != "" {
+		sd.VerticalAlign = vertical
+	}
+	sd.savePosition()
+}
+
+// nudge moves the display by dx, dy cells, switching to manual positioning
+// first if needed. It stops at the edges of the terminal.
+func (sd *SpotifyDisplay) nudge(dx, dy int) {
+	term := sd.getTerminalSize()
+	frame := term.frame
+	sd.HorizontalAlign, sd.VerticalAlign = "manual", "manual"
+	sd.X = max(min(frame.X+dx, term.width-frame.Width), 0)
+	sd.Y = max(min(frame.Y+dy, term.height-frame.Height), 0)
+	sd.savePosition()
+}
+
+// savePosition remembers the alignment for the next run. It is only called
+// for moves made by hand.
+func (sd *SpotifyDisplay) savePosition() {
+	config.SavePosition(sd.HorizontalAlign, sd.VerticalAlign, sd.X, sd.Y)
+}
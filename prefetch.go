@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/guard"
+	"sptsong/internal/mpris"
+)
+
+// prefetchWindow is how long before the end of a track the next one's
+// details are looked up, so the open panels show them at the change
+// instead of a lookup in progress.
+const prefetchWindow = 20 * time.Second
+
+// prefetchNext looks up what the open panels will show for the next track
+// once the current one is in its last prefetchWindow: the audio features
+// and liked state, and the artist. It needs the player to share its queue,
+// runs once per track, and only fills the cache.
+func (sd *SpotifyDisplay) prefetchNext(m *mpris.Metadata) {
+	left := m.Duration - m.Elapsed
+	if m.Status != "Playing" || m.Duration <= 0 || left > prefetchWindow || m.TrackID == sd.prefetched {
+		return
+	}
+	if !sd.showFeatures && !sd.showArtist {
+		return
+	}
+	sd.prefetched = m.TrackID
+	next, ok := sd.nextTrack(m.TrackID)
+	if !ok {
+		return
+	}
+
+	var lookups []func()
+	if info, _ := sd.tracks.Get(next.TrackID); sd.showFeatures && sd.Spotify.ClientID != "" && info.Features == nil &&
+		strings.HasPrefix(artwork.SpotifyURI(next.TrackID), "spotify:track:") {
+		client := newWebAPI(sd.Config)
+		lookups = append(lookups, func() { sd.cacheFeatures(lookupFeatures(client, next.TrackID)) })
+	}
+	if info, _ := sd.tracks.Get("artist:" + next.Artist); sd.showArtist && next.Artist != "" && info.Artist == nil {
+		lookup := sd.artistLookup(&next)
+		lookups = append(lookups, func() { sd.cacheArtist(lookup()) })
+	}
+	for _, lookup := range lookups {
+		go func() {
+			defer guard.Recover()
+			lookup()
+		}()
+	}
+}
+
+// nextTrack returns the track queued after trackID: from the up-next panel
+// if it has read the queue for this track, or from the player.
+func (sd *SpotifyDisplay) nextTrack(trackID string) (mpris.Metadata, bool) {
+	if q := &sd.queue; q.trackID == trackID && len(q.tracks) > 0 {
+		return q.tracks[0], true
+	}
+	lister, ok := sd.player.(mpris.TrackLister)
+	if !ok || sd.queue.unsupported {
+		return mpris.Metadata{}, false
+	}
+	tracks, err := lister.UpNext(1)
+	if err != nil || len(tracks) == 0 {
+		return mpris.Metadata{}, false
+	}
+	cleanMetadata(&tracks[0])
+	return tracks[0], true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+func TestPrefetchWindow(t *testing.T) {
+	next := mpris.Metadata{TrackID: "/track/next", Title: "Next\033", Artist: "Band"}
+	playing := func(left time.Duration) *mpris.Metadata {
+		return &mpris.Metadata{TrackID: "/track/now", Status: "Playing", Duration: 3 * time.Minute, Elapsed: 3*time.Minute - left}
+	}
+	tests := []struct {
+		name     string
+		metadata *mpris.Metadata
+		panels   bool
+		want     string
+	}{
+		{"too early", playing(time.Minute), true, ""},
+		{"in the last 20 s", playing(15 * time.Second), true, "/track/now"},
+		{"no panel to fill", playing(15 * time.Second), false, ""},
+		{"paused", &mpris.Metadata{TrackID: "/track/now", Status: "Paused", Duration: time.Minute, Elapsed: 50 * time.Second}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			sd := &SpotifyDisplay{
+				player:       &fakePlayer{queue: []mpris.Metadata{next}},
+				tracks:       newTrackCache(filepath.Join(t.TempDir(), "tracks.json"), time.Hour, clock),
+				showFeatures: tt.panels,
+			}
+			// Without a client id there is nothing to look up, so no
+			// lookup leaves the test.
+			sd.prefetchNext(tt.metadata)
+			if sd.prefetched != tt.want {
+				t.Errorf("prefetched %q, want %q", sd.prefetched, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTrack(t *testing.T) {
+	sd := &SpotifyDisplay{player: &fakePlayer{queue: []mpris.Metadata{{TrackID: "/track/b", Title: "B\033[2J"}}}}
+	if next, ok := sd.nextTrack("/track/a"); !ok || next.TrackID != "/track/b" || next.Title != "B[2J" {
+		t.Errorf("nextTrack from the player = %+v, %v", next, ok)
+	}
+	sd.queue = upNext{trackID: "/track/a", tracks: []mpris.Metadata{{TrackID: "/track/c"}}}
+	if next, ok := sd.nextTrack("/track/a"); !ok || next.TrackID != "/track/c" {
+		t.Errorf("nextTrack from the up-next panel = %+v, %v", next, ok)
+	}
+	sd = &SpotifyDisplay{player: struct{ mpris.Player }{&fakePlayer{}}}
+	if _, ok := sd.nextTrack("/track/a"); ok {
+		t.Error("nextTrack found a track on a player without a queue")
+	}
+}
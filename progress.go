@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"sptsong/internal/ui"
+
+	"sptsong/internal/mpris"
+)
+
+// renderBar draws the progress bar in the current theme and configured style.
+// With reduce_motion the bar grows a whole cell at a time instead of creeping
+// along in eighths.
+func (sd *SpotifyDisplay) renderBar(fraction float64, width int, marks ...float64) string {
+	smooth := sd.Progress.Style == "smooth" && !sd.ReduceMotion
+	return ui.Bar(sd.theme(), smooth, sd.Progress.Gradient, fraction, width, marks...)
+}
+
+// timeText formats the position and length as configured: elapsed or
+// remaining time, optionally followed by the percentage played.
+func (sd *SpotifyDisplay) timeText(metadata *mpris.Metadata) string {
+	text := ui.FormatDuration(metadata.Position) + "/" + ui.FormatDuration(metadata.Length)
+	if sd.Progress.Time == "remaining" {
+		text = "-" + ui.FormatDuration(max(metadata.Length-metadata.Position, 0)) + "/" + ui.FormatDuration(metadata.Length)
+	}
+	if sd.Progress.Percent && metadata.Length > 0 {
+		text += fmt.Sprintf(" %d%%", metadata.Position*100/metadata.Length)
+	}
+	return text
+}
+
+func progressFraction(metadata *mpris.Metadata) float64 {
+	if metadata.Length <= 0 {
+		return 0
+	}
+	return float64(metadata.Position) / float64(metadata.Length)
+}
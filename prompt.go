@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"sptsong/internal/config"
+	"sptsong/internal/ui"
+)
+
+// sgr matches the color sequences Style.Render emits.
+var sgr = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// promptWrappers mark escape sequences as zero-width for each shell's line
+// editor, which would otherwise count them and misplace the cursor.
+var promptWrappers = map[string][2]string{
+	"none": {"", ""},
+	"bash": {"\001", "\002"},
+	"zsh":  {"%{", "%}"},
+}
+
+// runPrompt prints "♫ artist – title" for a shell prompt or a starship
+// custom module. Like tmux, it only reads the status cache, so it never
+// slows the prompt down and prints nothing when no display is running.
+func runPrompt(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("prompt", flag.ContinueOnError)
+	maxLength := flags.Int("max-length", 40, "cut the text to this many characters (0 for no limit)")
+	ellipsis := flags.String("ellipsis", "…", "what to end cut text with")
+	shell := flags.String("shell", "none", "mark colors as zero-width for this shell's prompt: none, bash or zsh")
+	noColor := noColorFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	wrap, ok := promptWrappers[*shell]
+	if !ok {
+		return fmt.Errorf("%w: unknown shell %q, want none, bash or zsh", errUsage, *shell)
+	}
+
+	m, ok := readStatusCache()
+	if !ok {
+		return nil
+	}
+	// A prompt is captured, never a terminal, so only the flag and NO_COLOR
+	// turn colors off.
+	out := newPrinter(cfg, *noColor)
+	out.color = !*noColor && os.Getenv("NO_COLOR") == ""
+
+	parts := []field{
+		{value: "♫ ", style: out.theme.Accent},
+		{value: m.Artist, style: out.theme.Artist},
+		{value: " – ", style: out.theme.Accent},
+		{value: m.Title, style: out.theme.Title},
+	}
+	truncateFields(parts, *maxLength, *ellipsis)
+
+	var b strings.Builder
+	for _, part := range parts {
+		if part.value == "" {
+			continue
+		}
+		b.WriteString(out.render(part.style, part.value))
+	}
+	fmt.Println(sgr.ReplaceAllString(b.String(), wrap[0]+"$0"+wrap[1]))
+	return nil
+}
+
+// truncateFields shortens the values so that together they are at most
+// limit columns, ending in ellipsis; later fields go first.
+func truncateFields(fields []field, limit int, ellipsis string) {
+	total := 0
+	for _, f := range fields {
+		total += ui.Width(f.value)
+	}
+	if limit <= 0 || total <= limit {
+		return
+	}
+	keep := max(limit-ui.Width(ellipsis), 0)
+	for i := range fields {
+		width := ui.Width(fields[i].value)
+		if width > keep {
+			fields[i].value = ui.Clip(fields[i].value, keep) + ellipsis
+			for j := i + 1; j < len(fields); j++ {
+				fields[j].value = ""
+			}
+			return
+		}
+		keep -= width
+	}
+}
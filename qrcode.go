@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"sptsong/internal/qr"
+	"sptsong/internal/ui"
+)
+
+// qrLines renders code two modules per row with half blocks, black on white
+// whatever the theme, inside a quiet zone of quiet modules.
+func qrLines(code *qr.Code, quiet int) []string {
+	size := code.Size() + 2*quiet
+	dark := func(x, y int) bool {
+		x, y = x-quiet, y-quiet
+		return x >= 0 && y >= 0 && x < code.Size() && y < code.Size() && code.Modules[y][x]
+	}
+	var lines []string
+	for y := 0; y < size; y += 2 {
+		var line strings.Builder
+		for x := range size {
+			fg, bg := 97, 107
+			if dark(x, y) {
+				fg = 30
+			}
+			if dark(x, y+1) {
+				bg = 40
+			}
+			fmt.Fprintf(&line, "\033[%d;%dm▀", fg, bg)
+		}
+		line.WriteString("\033[0m")
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// drawQR overlays a QR code of the track's open.spotify.com link in the
+// middle of the screen, for opening the song on a phone.
+func (sd *SpotifyDisplay) drawQR(term TerminalSize) {
+	theme := sd.theme()
+	link := trackLink(sd.current.TrackID)
+	caption := "scan to open the track · Esc closes"
+	var lines []string
+	codeWidth := 0
+	if link == "" {
+		caption = "no Spotify link for this track · Esc closes"
+	} else if code, err := qr.Encode(link); err == nil {
+		// The standard asks for four modules of quiet zone; two still
+		// scan, and keep the code on smaller terminals.
+		quiet := 4
+		if (code.Size()+2*quiet+1)/2+1 > term.height || code.Size()+2*quiet > term.width {
+			quiet = 2
+		}
+		lines = qrLines(code, quiet)
+		codeWidth = code.Size() + 2*quiet
+	}
+	y := max((term.height-len(lines)-1)/2, 0)
+	for i, line := range lines {
+		fmt.Fprint(sd.out, ui.MoveTo(max((term.width-codeWidth)/2, 0), y+i)+line)
+	}
+	x := max((term.width-ui.Width(caption))/2, 0)
+	fmt.Fprint(sd.out, ui.MoveTo(x, y+len(lines))+theme.Accent.Render(caption))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"sptsong/internal/mpris"
+)
+
+// errFixedRate is returned when the player's speed can't be changed.
+var errFixedRate = errors.New("the player has a fixed playback rate")
+
+// rateSteps are the speeds < and > step through, as podcast apps offer them.
+var rateSteps = []float64{0.5, 0.75, 1, 1.25, 1.5, 1.75, 2, 2.5, 3}
+
+// nextRate returns the step above or below current that the player allows,
+// or false at either end.
+func nextRate(m *mpris.Metadata, up bool) (float64, bool) {
+	current := m.Rate
+	if current == 0 {
+		current = 1
+	}
+	if up {
+		for _, r := range rateSteps {
+			if r > current+0.01 && r <= m.MaxRate {
+				return r, true
+			}
+		}
+		return 0, false
+	}
+	for i := len(rateSteps) - 1; i >= 0; i-- {
+		if r := rateSteps[i]; r < current-0.01 && r >= m.MinRate {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// rateLabel is how the header shows a rate other than normal speed, such
+// as "1.5×"; it is empty at normal speed or when the player doesn't say.
+func rateLabel(rate float64) string {
+	if rate == 0 || rate == 1 {
+		return ""
+	}
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "×"
+}
+
+// stepRate speeds playback up or slows it down a step, for players that
+// report a range to do it in.
+func (sd *SpotifyDisplay) stepRate(up bool) {
+	m := &sd.current
+	setter, ok := sd.player.(mpris.RateSetter)
+	if !ok || m.MaxRate <= m.MinRate {
+		sd.showNotice(errFixedRate.Error())
+		return
+	}
+	rate, ok := nextRate(m, up)
+	if !ok {
+		return
+	}
+	if err := setter.SetRate(rate); err != nil {
+		sd.showNotice("couldn't change the rate: " + err.Error())
+		return
+	}
+	m.Rate = rate
+	sd.showNotice("playback rate " + strconv.FormatFloat(rate, 'f', -1, 64) + "×")
+}
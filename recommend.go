@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/webapi"
+)
+
+// recommendationCount is how many tracks the "more like this" list offers.
+const recommendationCount = 30
+
+// openRecommendations lists Spotify's recommendations seeded by the current
+// track and its artist. Enter queues the one picked and Tab plays it right
+// away, to steer a listening session from the terminal.
+func (sd *SpotifyDisplay) openRecommendations() {
+	uri := artwork.SpotifyURI(sd.current.TrackID)
+	switch {
+	case sd.Spotify.ClientID == "":
+		sd.showNotice(webapi.ErrNoClientID.Error())
+		return
+	case !strings.HasPrefix(uri, "spotify:track:"):
+		sd.showNotice("recommendations need a Spotify track to start from")
+		return
+	}
+	if sd.similarTrack != uri {
+		sd.similarTrack, sd.similar = uri, nil
+	}
+	p := &picker{
+		heading: "more like this",
+		caption: "“" + sd.current.Title + "”",
+		hint:    "Enter queue · Tab play · Esc",
+		cache:   &sd.similar,
+		pick: func(sd *SpotifyDisplay, item pickerItem) {
+			sd.runWebAPI("queued "+item.name, func(ctx context.Context, client *webapi.Client) error {
+				return client.Queue(ctx, item.uri)
+			})
+		},
+		alt: func(sd *SpotifyDisplay, item pickerItem) {
+			sd.runWebAPI("playing "+item.name, func(ctx context.Context, client *webapi.Client) error {
+				return client.Play(ctx, item.uri)
+			})
+		},
+	}
+	sd.loadPicker(p, func(ctx context.Context, client *webapi.Client) ([]pickerItem, error) {
+		tracks, err := client.Recommendations(ctx, strings.TrimPrefix(uri, "spotify:track:"), recommendationCount)
+		var items []pickerItem
+		for _, t := range tracks {
+			name := t.Name
+			if len(t.Artists) > 0 {
+				name += " – " + t.Artists[0].Name
+			}
+			items = append(items, pickerItem{name: name, uri: t.URI, id: t.ID})
+		}
+		return items, err
+	})
+}
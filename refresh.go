@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"sptsong/internal/mpris"
+
+	"sptsong/internal/config"
+)
+
+// minRefresh keeps a zero or negative setting from spinning the run loop.
+const minRefresh = 10 * time.Millisecond
+
+// refreshInterval returns the poll interval for the latest Metadata result;
+// an error means no player answered.
+func refreshInterval(r config.RefreshConfig, metadata *mpris.Metadata, err error) time.Duration {
+	switch {
+	case err != nil:
+		return max(r.Idle, minRefresh)
+	case metadata.Status != "Playing":
+		return max(r.Paused, minRefresh)
+	}
+	return max(r.Playing, minRefresh)
+}
+
+// poller is the ticker the run loop reads the player on: at the playing
+// interval while playing, slower while paused or without a player.
+type poller struct {
+	ticker   Ticker
+	interval time.Duration
+	refresh  config.RefreshConfig
+}
+
+func newPoller(clock Clock, r config.RefreshConfig) *poller {
+	interval := refreshInterval(r, &mpris.Metadata{Status: "Playing"}, nil)
+	return &poller{ticker: clock.NewTicker(interval), interval: interval, refresh: r}
+}
+
+func (p *poller) C() <-chan time.Time { return p.ticker.C() }
+
+func (p *poller) Stop() { p.ticker.Stop() }
+
+// observe adjusts the interval to the latest Metadata result.
+func (p *poller) observe(metadata *mpris.Metadata, err error) {
+	p.set(refreshInterval(p.refresh, metadata, err))
+}
+
+// hurry goes back to the playing interval, for when something happened
+// that the next poll should show soon.
+func (p *poller) hurry() {
+	p.set(refreshInterval(p.refresh, &mpris.Metadata{Status: "Playing"}, nil))
+}
+
+// configure takes new intervals from a reloaded config. It starts over at
+// the playing one, and the next poll settles on the one for the player's
+// state.
+func (p *poller) configure(r config.RefreshConfig) {
+	p.refresh = r
+	p.hurry()
+}
+
+func (p *poller) set(interval time.Duration) {
+	if interval != p.interval {
+		p.interval = interval
+		p.ticker.Reset(interval)
+	}
+}
+
+// watchPlayer subscribes to the player's property and track list changes and
+// to bus name changes, so the run loop can go back to fast refresh as soon as
+// anything happens instead of waiting out a slow interval. Other backends
+// have no signals and just poll.
+func (sd *SpotifyDisplay) watchPlayer() chan *dbus.Signal {
+	if sd.Backend != "mpris" {
+		return nil
+	}
+	signals := make(chan *dbus.Signal, 16)
+	sd.bus.AddMatchSignal(
+		dbus.WithMatchSender(mpris.SpotifyBusName),
+		dbus.WithMatchObjectPath("/org/mpris/MediaPlayer2"),
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+	)
+	sd.bus.AddMatchSignal(
+		dbus.WithMatchSender(mpris.SpotifyBusName),
+		dbus.WithMatchObjectPath("/org/mpris/MediaPlayer2"),
+		dbus.WithMatchInterface(mpris.TrackListInterface),
+	)
+	sd.bus.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, mpris.SpotifyBusName),
+	)
+	sd.bus.Signal(signals)
+	return signals
+}
+
+// playerSignal handles a signal from watchPlayer's channel, and reports
+// whether to keep reading it. ok is false once godbus has closed the
+// channel, which it does when the session bus connection drops: a service
+// then leaves the run loop with errBusLost to connect again, and the
+// display falls back to polling, starting at once so the lost player shows.
+func (sd *SpotifyDisplay) playerSignal(signal *dbus.Signal, ok bool) (listening bool, err error) {
+	if !ok {
+		if sd.service {
+			return false, errBusLost
+		}
+		slog.Warn("lost the session bus; polling the player instead of waiting for its signals")
+		sd.poll.hurry()
+		return false, nil
+	}
+	if strings.HasPrefix(signal.Name, mpris.TrackListInterface+".") {
+		sd.queue.stale = true
+	}
+	sd.poll.hurry()
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+)
+
+func TestRefreshInterval(t *testing.T) {
+	r := config.RefreshConfig{Playing: 100 * time.Millisecond, Paused: time.Second, Idle: 5 * time.Second}
+	tests := []struct {
+		name     string
+		refresh  config.RefreshConfig
+		metadata *mpris.Metadata
+		err      error
+		want     time.Duration
+	}{
+		{"playing", r, &mpris.Metadata{Status: "Playing"}, nil, 100 * time.Millisecond},
+		{"paused", r, &mpris.Metadata{Status: "Paused"}, nil, time.Second},
+		{"stopped", r, &mpris.Metadata{Status: "Stopped"}, nil, time.Second},
+		{"no player", r, nil, errors.New("no player"), 5 * time.Second},
+		{"zero setting", config.RefreshConfig{}, &mpris.Metadata{Status: "Playing"}, nil, minRefresh},
+		{"negative setting", config.RefreshConfig{Idle: -time.Second}, nil, errors.New("no player"), minRefresh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refreshInterval(tt.refresh, tt.metadata, tt.err); got != tt.want {
+				t.Errorf("refreshInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// ticks advances clock by d and counts how often p fired on the way.
+func ticks(clock *fakeClock, p *poller, d, step time.Duration) int {
+	n := 0
+	for elapsed := time.Duration(0); elapsed < d; elapsed += step {
+		clock.Advance(step)
+		select {
+		case <-p.C():
+			n++
+		default:
+		}
+	}
+	return n
+}
+
+func TestPollerSwitchesIntervals(t *testing.T) {
+	clock := newFakeClock()
+	p := newPoller(clock, config.RefreshConfig{Playing: 100 * time.Millisecond, Paused: time.Second, Idle: 5 * time.Second})
+	defer p.Stop()
+	step := 50 * time.Millisecond
+
+	if n := ticks(clock, p, time.Second, step); n != 10 {
+		t.Errorf("playing: %d polls a second, want 10", n)
+	}
+
+	p.observe(&mpris.Metadata{Status: "Paused"}, nil)
+	if n := ticks(clock, p, 3*time.Second, step); n != 3 {
+		t.Errorf("paused: %d polls in 3s, want 3", n)
+	}
+
+	p.observe(nil, errors.New("no player"))
+	if n := ticks(clock, p, 10*time.Second, step); n != 2 {
+		t.Errorf("idle: %d polls in 10s, want 2", n)
+	}
+
+	// A key press or a player signal brings the next poll forward.
+	p.hurry()
+	if n := ticks(clock, p, 100*time.Millisecond, step); n != 1 {
+		t.Errorf("after hurry: %d polls in 100ms, want 1", n)
+	}
+
+	p.observe(&mpris.Metadata{Status: "Playing"}, nil)
+	if n := ticks(clock, p, time.Second, step); n != 10 {
+		t.Errorf("playing again: %d polls a second, want 10", n)
+	}
+}
+
+func TestPollerConfigure(t *testing.T) {
+	clock := newFakeClock()
+	p := newPoller(clock, config.RefreshConfig{Playing: 100 * time.Millisecond, Paused: time.Second, Idle: 5 * time.Second})
+	defer p.Stop()
+	step := 50 * time.Millisecond
+
+	p.configure(config.RefreshConfig{Playing: 500 * time.Millisecond, Paused: 2 * time.Second, Idle: 5 * time.Second})
+	if n := ticks(clock, p, time.Second, step); n != 2 {
+		t.Errorf("reloaded playing: %d polls a second, want 2", n)
+	}
+	p.observe(&mpris.Metadata{Status: "Paused"}, nil)
+	if n := ticks(clock, p, 4*time.Second, step); n != 2 {
+		t.Errorf("reloaded paused: %d polls in 4s, want 2", n)
+	}
+}
+
+func TestInvalidateBringsPollForward(t *testing.T) {
+	clock := newFakeClock()
+	sd := &SpotifyDisplay{out: io.Discard, currentArtURL: "https://example.com/cover.jpg"}
+	sd.poll = newPoller(clock, config.RefreshConfig{Playing: 100 * time.Millisecond, Paused: time.Second, Idle: 5 * time.Second})
+	defer sd.poll.Stop()
+	sd.poll.observe(&mpris.Metadata{Status: "Paused"}, nil)
+
+	sd.invalidate()
+	if sd.currentArtURL != "" {
+		t.Error("invalidate kept the cover")
+	}
+	if n := ticks(clock, sd.poll, 100*time.Millisecond, 50*time.Millisecond); n != 1 {
+		t.Errorf("paused display redrawn %d times within 100ms of invalidate, want 1", n)
+	}
+}
+
+func TestClosedSignalsEndService(t *testing.T) {
+	// godbus closes the signal channels when the bus connection drops.
+	signals := make(chan *dbus.Signal)
+	close(signals)
+
+	sd := &SpotifyDisplay{service: true}
+	signal, ok := <-signals
+	if listening, err := sd.playerSignal(signal, ok); listening || !errors.Is(err, errBusLost) {
+		t.Errorf("playerSignal on a closed channel = %v, %v; want errBusLost", listening, err)
+	}
+}
+
+func TestClosedSignalsFallBackToPolling(t *testing.T) {
+	signals := make(chan *dbus.Signal)
+	close(signals)
+
+	clock := newFakeClock()
+	sd := &SpotifyDisplay{}
+	sd.poll = newPoller(clock, config.RefreshConfig{Playing: 100 * time.Millisecond, Paused: time.Second, Idle: 5 * time.Second})
+	defer sd.poll.Stop()
+	sd.poll.observe(&mpris.Metadata{Status: "Paused"}, nil)
+
+	signal, ok := <-signals
+	if listening, err := sd.playerSignal(signal, ok); listening || err != nil {
+		t.Fatalf("playerSignal on a closed channel = %v, %v; want to stop listening and carry on", listening, err)
+	}
+	if n := ticks(clock, sd.poll, 100*time.Millisecond, 50*time.Millisecond); n != 1 {
+		t.Errorf("polled %d times within 100ms of losing the signals, want 1", n)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/config"
+	"sptsong/internal/guard"
+	"sptsong/internal/notify"
+	"sptsong/internal/ui"
+)
+
+// configSettle is how long the watched directories have to be quiet before
+// the config is read again: editors and wal save a file in several steps.
+const configSettle = 200 * time.Millisecond
+
+// configStamp summarizes the names, sizes and modification times of
+// config.toml, the theme files and wal's palette, so that saving, adding or
+// removing any of them changes it. position.toml is left out: the display writes it itself.
+func configStamp() string {
+	paths, _ := filepath.Glob(filepath.Join(config.ThemesDir(), "*.toml"))
+	paths = append(paths, filepath.Join(config.Dir(), "config.toml"), config.WalColors())
+	var b strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// watchConfig reports on the returned channel when config.toml, a theme
+// file or wal's palette may have changed, until done is closed. It watches
+// the directories rather than the files, as editors and wal replace a file
+// by renaming a new one over it; the caller compares configStamp to tell a
+// real change from the display saving position.toml. A directory missing at
+// startup isn't watched, except the themes directory once it's created.
+func watchConfig(done <-chan struct{}) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{config.Dir(), config.ThemesDir(), filepath.Dir(config.WalColors())} {
+		watcher.Add(dir)
+	}
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer guard.Recover()
+		defer watcher.Close()
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case event := <-watcher.Events:
+				if event.Name == config.ThemesDir() && event.Has(fsnotify.Create) {
+					watcher.Add(event.Name)
+				}
+				settle = time.After(configSettle)
+			case err := <-watcher.Errors:
+				slog.Warn("watching the config", "err", err)
+			case <-settle:
+				settle = nil
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changed, nil
+}
+
+// loadThemes returns the bundled themes, the user's, and "wal" while pywal
+// has a palette, unless a theme of the user's has that name. A palette
+// caught halfway through being written gives a blank theme for the moment,
+// so the display stays on "wal" until the next change brings the colors.
+func loadThemes(cfg config.Config) []ui.Theme {
+	themes := maps.Clone(cfg.Themes)
+	_, configured := themes[ui.WalTheme]
+	_, err := os.Stat(filepath.Join(config.ThemesDir(), ui.WalTheme+".toml"))
+	if !configured && err != nil {
+		wal, err := ui.LoadWalTheme(config.WalColors())
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			if themes == nil {
+				themes = make(map[string]ui.Theme)
+			}
+			themes[ui.WalTheme] = wal
+		}
+	}
+	return ui.LoadThemes(themes, config.ThemesDir())
+}
+
+// newNotifier starts the sinks configured in cfg, including --event-log. The
+// global silent setting silences every sink.
+func newNotifier(cfg config.Config) (*notify.Dispatcher, error) {
+	sinks := append([]config.NotifyConfig{}, cfg.Notify...)
+	if cfg.EventLog != "" {
+		sinks = append(sinks, config.NotifyConfig{Type: "jsonl", Path: cfg.EventLog})
+	}
+	for i := range sinks {
+		sinks[i].Silent = sinks[i].Silent || cfg.Silent
+	}
+	return notify.New(sinks, artwork.HTTPClient)
+}
+
+func newCoverCache(cacheDir string, cfg config.Config) artwork.Cache {
+	covers := artwork.Cache{
+		Dir:      filepath.Join(cacheDir, "art"),
+		Size:     cfg.ArtCacheSize,
+		MaxBytes: int64(cfg.Network.MaxArtMB * 1024 * 1024),
+		Failures: &artwork.Failures{},
+	}
+	if cfg.ArtBudgetMB > 0 {
+		covers.Budget = &artwork.Budget{
+			Path:  filepath.Join(cacheDir, "art-budget.json"),
+			Limit: int64(cfg.ArtBudgetMB * 1024 * 1024),
+		}
+	}
+	return covers
+}
+
+// reloadConfig reads config.toml and the themes again, reapplies the command
+// line and takes the result into use. A setting the user changed with a key
+// or the mouse keeps its live value unless the file changed it too. The
+// backend, D-Bus, MPRIS export, network timeout and CA bundle settings only
+// take effect on a restart.
+// If the new config is invalid the old one stays and the error is shown.
+func (sd *SpotifyDisplay) reloadConfig() {
+	cfg, err := config.Load()
+	if err == nil {
+		flags := displayFlags("sptsong", &cfg)
+		flags.String("role", "", "only read at startup")
+		flags.Bool("takeover", false, "only read at startup")
+		flags.Bool("service", false, "only read at startup")
+		flags.Duration("sleep", 0, "only read at startup")
+		flags.SetOutput(io.Discard)
+		err = flags.Parse(sd.args)
+	}
+	if sd.companion {
+		cfg.Notify, cfg.EventLog = nil, ""
+	}
+	var keys []binding
+	if err == nil {
+		keys, err = keyBindings(cfg.Keys)
+	}
+	var notifier *notify.Dispatcher
+	if err == nil {
+		notifier, err = newNotifier(cfg)
+	}
+	if err == nil {
+		base := sd.player
+		if p, ok := base.(*overridePlayer); ok {
+			base = p.Player
+		}
+		if sd.player, err = withOverrides(base, cfg.Overrides); err != nil {
+			sd.player = base
+			notifier.Close()
+		}
+	}
+	sd.requestRedraw()
+	if err != nil {
+		sd.configErr = err
+		slog.Error("config not reloaded", "err", err)
+		return
+	}
+	sd.configErr = nil
+	setLogLevel(cfg)
+	slog.Info("config reloaded")
+
+	old, live := sd.loaded, sd.Config
+	sd.loaded = cfg
+	sd.Config = cfg
+	ui.SetColorDepth(colorDepth(cfg.Terminal))
+	sd.Backend, sd.MPD.Host, sd.DBusAddress, sd.ExportMPRIS = live.Backend, live.MPD.Host, live.DBusAddress, live.ExportMPRIS
+	if cfg.Layout == old.Layout {
+		sd.Layout = live.Layout
+	}
+	if cfg.History.Relative != old.History.Relative {
+		sd.history.relative = cfg.History.Relative
+	}
+	if cfg.Compact == old.Compact {
+		sd.Compact = live.Compact
+	}
+	if cfg.HorizontalAlign == old.HorizontalAlign && cfg.VerticalAlign == old.VerticalAlign && cfg.X == old.X && cfg.Y == old.Y {
+		sd.HorizontalAlign, sd.VerticalAlign, sd.X, sd.Y = live.HorizontalAlign, live.VerticalAlign, live.X, live.Y
+	}
+	theme := sd.themes[sd.themeIndex].Name
+	if cfg.Theme != old.Theme {
+		theme = cfg.Theme
+	}
+	sd.themes = loadThemes(cfg)
+	sd.themeIndex = ui.FindTheme(sd.themes, theme)
+
+	sd.notifier.Close()
+	sd.notifier = notifier
+	// The covers that failed to download stay remembered.
+	failures := sd.covers.Failures
+	sd.covers = newCoverCache(sd.cacheDir, cfg)
+	sd.covers.Failures = failures
+	sd.keys = keys
+	sd.poll.configure(cfg.Refresh)
+	sd.trackSettle.delay = cfg.SettleDelay
+	sd.events.settle.delay = cfg.SettleDelay
+	sd.artAccent = ""
+}
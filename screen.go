@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/guard"
+)
+
+// The display draws on the alternate screen, so the shell's scrollback comes
+// back untouched when it leaves. tcell switches to it itself; these make
+// sure the cursor and colors are right around it, as the display writes its
+// frames straight to the terminal rather than through tcell's cells.
+const (
+	enterAltScreen = "\033[?1049h\033[?25l\033[2J\033[H"
+	leaveAltScreen = "\033[0m\033[?25h\033[?1049l"
+)
+
+// screen is the terminal while it is in the display's hands. It is shared
+// by every goroutine so that whichever of them is leaving, normally or with
+// a panic, hands it back exactly once.
+var screen struct {
+	sync.Mutex
+	tcell.Screen
+}
+
+// takeScreen puts the terminal in raw mode on the alternate screen with the
+// cursor hidden and the mouse reported. restoreScreen undoes it.
+func takeScreen() error {
+	screen.Lock()
+	defer screen.Unlock()
+	s, err := newScreen()
+	if err != nil {
+		return err
+	}
+	if err := s.Init(); err != nil {
+		return err
+	}
+	s.EnableMouse(tcell.MouseButtonEvents | tcell.MouseDragEvents)
+	fmt.Fprint(os.Stdout, enterAltScreen)
+	screen.Screen = s
+	return nil
+}
+
+// restoreScreen gives the terminal back as it was before takeScreen: cooked
+// mode, default colors, the cursor shown and the original screen content. It
+// does nothing if the screen isn't taken, so exit paths can all call it.
+func restoreScreen() {
+	screen.Lock()
+	defer screen.Unlock()
+	if screen.Screen == nil {
+		return
+	}
+	screen.Fini()
+	screen.Screen = nil
+	fmt.Fprint(os.Stdout, leaveAltScreen)
+}
+
+// screenSize returns the size of the taken screen.
+func screenSize() (width, height int) {
+	screen.Lock()
+	defer screen.Unlock()
+	if screen.Screen == nil {
+		return 80, 24
+	}
+	return screen.Size()
+}
+
+// syncScreen has tcell take in the terminal's new size and repaint it from
+// scratch, after a resize.
+func syncScreen() {
+	screen.Lock()
+	defer screen.Unlock()
+	if screen.Screen != nil {
+		screen.Sync()
+	}
+}
+
+// readEvent waits for the next key, mouse or resize event. It returns an
+// interrupt once interruptScreen is called or the screen is given back.
+func readEvent() tcell.Event {
+	screen.Lock()
+	s := screen.Screen
+	screen.Unlock()
+	if s == nil {
+		return tcell.NewEventInterrupt(nil)
+	}
+	if event := s.PollEvent(); event != nil {
+		return event
+	}
+	return tcell.NewEventInterrupt(nil)
+}
+
+// interruptScreen wakes readEvent with an interrupt.
+func interruptScreen() {
+	screen.Lock()
+	defer screen.Unlock()
+	if screen.Screen != nil {
+		screen.PostEvent(tcell.NewEventInterrupt(nil))
+	}
+}
+
+// A panic in any guarded goroutine restores the terminal before it crashes
+// the program, so the trace is printed on the normal screen and the shell
+// isn't left in raw mode.
+func init() {
+	guard.OnPanic(restoreScreen)
+}
@@ -0,0 +1,61 @@
+package main
+
+import "time"
+
+// frameInterval is how long seek and volume keys are gathered before they
+// become player calls. A held key repeats faster than most players answer;
+// adding the repeats up into one call keeps scrubbing smooth instead of
+// queueing a call, and its round trip, per repeat.
+const frameInterval = 50 * time.Millisecond
+
+// seekStep is how far one press of a seek key moves.
+const seekStep = 5 * time.Second
+
+// endMargin is how close to the end of the track a seek may go; seeking to
+// or past the end would skip to the next track.
+const endMargin = time.Second
+
+// heldInput is the seek and volume change gathered since the last frame.
+type heldInput struct {
+	seek   time.Duration
+	volume bool
+}
+
+func (p heldInput) empty() bool {
+	return p.seek == 0 && !p.volume
+}
+
+// seekOffset returns how far to seek from position for a seek of d, kept
+// between the start of the track and endMargin before its end. Tracks of
+// unknown length are only kept from going before the start.
+func seekOffset(position, length, d time.Duration) time.Duration {
+	target := max(position+d, 0)
+	if length > 0 && d > 0 {
+		target = min(target, max(length-endMargin, position))
+	}
+	return target - position
+}
+
+func (sd *SpotifyDisplay) seekBy(d time.Duration) {
+	sd.pending.seek += d
+}
+
+// changeVolume moves the volume the display shows at once; the player hears
+// about it on the next frame.
+func (sd *SpotifyDisplay) changeVolume(step float64) {
+	sd.volume = min(max(sd.volume+step, 0), 1)
+	sd.pending.volume = true
+}
+
+// flushInput sends what was gathered since the last frame as one Seek and
+// one SetVolume at most.
+func (sd *SpotifyDisplay) flushInput() {
+	position, length := precise(&sd.current)
+	if seek := seekOffset(position, length, sd.pending.seek); seek != 0 {
+		sd.player.Call("Seek", seek.Microseconds())
+	}
+	if sd.pending.volume {
+		sd.player.SetVolume(sd.volume)
+	}
+	sd.pending = heldInput{}
+}
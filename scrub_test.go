@@ -0,0 +1,84 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/mpris"
+)
+
+func TestSeekOffset(t *testing.T) {
+	s := func(n float64) time.Duration { return time.Duration(n * float64(time.Second)) }
+	tests := []struct {
+		name                string
+		position, length, d time.Duration
+		want                time.Duration
+	}{
+		{"forward", s(60), s(200), s(5), s(5)},
+		{"back", s(60), s(200), s(-5), s(-5)},
+		{"back past the start", s(3), s(200), s(-5), s(-3)},
+		{"back from the start", 0, s(200), s(-5), 0},
+		{"forward past the end", s(197), s(200), s(5), s(2)},
+		{"forward within the margin", s(199.5), s(200), s(5), 0},
+		{"back within the margin", s(199.5), s(200), s(-5), s(-5)},
+		{"unknown length", s(500), 0, s(60), s(60)},
+		{"unknown length, back past the start", s(2), 0, s(-5), s(-2)},
+		{"nothing", s(60), s(200), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seekOffset(tt.position, tt.length, tt.d); got != tt.want {
+				t.Errorf("seekOffset(%v, %v, %v) = %v, want %v", tt.position, tt.length, tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrubKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		position   time.Duration
+		keys       string
+		wantCalls  []string
+		volume     float64
+		wantVolume float64
+	}{
+		{"one step forward", 60 * time.Second, "]", []string{"Seek[5000000]"}, 0.5, 0.5},
+		{"held key adds up", 60 * time.Second, "]]]]", []string{"Seek[20000000]"}, 0.5, 0.5},
+		{"back and forth cancel out", 60 * time.Second, "[]", nil, 0.5, 0.5},
+		{"clamped at the start", 7 * time.Second, "[[", []string{"Seek[-7000000]"}, 0.5, 0.5},
+		{"clamped at the end", 190 * time.Second, "]]]", []string{"Seek[9000000]"}, 0.5, 0.5},
+		{"volume steps", 0, "++-+", []string{"SetVolume"}, 0.5, 0.6},
+		{"volume clamped at the top", 0, "+++", []string{"SetVolume"}, 0.95, 1},
+		{"volume clamped at the bottom", 0, "--", []string{"SetVolume"}, 0.05, 0},
+		{"seek and volume in one frame", 60 * time.Second, "]-", []string{"Seek[5000000]", "SetVolume"}, 0.5, 0.45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := &fakePlayer{}
+			sd := &SpotifyDisplay{
+				player:  player,
+				volume:  tt.volume,
+				current: mpris.Metadata{Status: "Playing", Duration: 200 * time.Second, Elapsed: tt.position},
+			}
+			for _, ch := range tt.keys {
+				if sd.handleKeyboard(tcell.NewEventKey(tcell.KeyRune, ch, tcell.ModNone)) {
+					t.Errorf("%q asked for a full repaint", ch)
+				}
+			}
+			sd.flushInput()
+			if !slices.Equal(player.calls, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", player.calls, tt.wantCalls)
+			}
+			if tt.wantVolume != tt.volume && (player.volume < tt.wantVolume-1e-9 || player.volume > tt.wantVolume+1e-9) {
+				t.Errorf("volume = %v, want %v", player.volume, tt.wantVolume)
+			}
+			if !sd.pending.empty() {
+				t.Errorf("input left pending: %+v", sd.pending)
+			}
+		})
+	}
+}
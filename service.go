@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// The display's own D-Bus service, for key bindings and scripts that change
+// the display rather than the player:
+//
+//	busctl --user call org.zelferion.sptsong /org/zelferion/sptsong org.zelferion.sptsong SetLayout s art-top
+const (
+	serviceName = "org.zelferion.sptsong"
+	servicePath = dbus.ObjectPath("/org/zelferion/sptsong")
+)
+
+// serviceTimeout bounds how long a method call waits for the run loop.
+const serviceTimeout = 2 * time.Second
+
+// serviceCall is a method call handed to the run loop, which owns the
+// display's state. The loop sends run's result on done.
+type serviceCall struct {
+	run  func(sd *SpotifyDisplay) error
+	done chan error
+}
+
+// displayService holds the exported methods. They run on the bus's
+// goroutines, so each one only passes its work to the run loop.
+type displayService struct {
+	calls chan<- serviceCall
+}
+
+func (s displayService) do(run func(sd *SpotifyDisplay) error) *dbus.Error {
+	call := serviceCall{run: run, done: make(chan error, 1)}
+	select {
+	case s.calls <- call:
+	case <-time.After(serviceTimeout):
+		return dbus.MakeFailedError(fmt.Errorf("the display is busy"))
+	}
+	if err := <-call.done; err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// SetLayout switches to the named layout, as Tab does.
+func (s displayService) SetLayout(name string) *dbus.Error {
+	if !slices.Contains(ui.LayoutNames, name) {
+		return dbus.MakeFailedError(fmt.Errorf("unknown layout %q, want one of %v", name, ui.LayoutNames))
+	}
+	return s.do(func(sd *SpotifyDisplay) error {
+		sd.Layout = name
+		return nil
+	})
+}
+
+// SetTheme switches to the named theme, as t does.
+func (s displayService) SetTheme(name string) *dbus.Error {
+	return s.do(func(sd *SpotifyDisplay) error {
+		i := slices.IndexFunc(sd.themes, func(t ui.Theme) bool { return t.Name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown theme %q", name)
+		}
+		sd.themeIndex = i
+		return nil
+	})
+}
+
+// ToggleFullscreen shows or hides the full-screen artwork, as f does.
+func (s displayService) ToggleFullscreen() *dbus.Error {
+	return s.do(func(sd *SpotifyDisplay) error {
+		sd.fullscreen = !sd.fullscreen
+		return nil
+	})
+}
+
+// Refresh repaints the display and loads the artwork again.
+func (s displayService) Refresh() *dbus.Error {
+	return s.do(func(sd *SpotifyDisplay) error { return nil })
+}
+
+// Reload reads config.toml again, as SIGHUP does.
+func (s displayService) Reload() *dbus.Error {
+	return s.do(func(sd *SpotifyDisplay) error {
+		sd.reloadConfig()
+		return sd.configErr
+	})
+}
+
+// exportService offers the display's service on the session bus and returns
+// the channel its calls arrive on. It connects to the bus if the backend
+// didn't. The service is a convenience, so without a bus, or when another
+// display already owns the name, it returns nil and the display runs
+// without it.
+func (sd *SpotifyDisplay) exportService() <-chan serviceCall {
+	if sd.companion {
+		return nil
+	}
+	if sd.bus == nil {
+		conn, err := mpris.ConnectSessionBus(sd.DBusAddress)
+		if err != nil {
+			return nil
+		}
+		sd.bus = conn
+	}
+
+	calls := make(chan serviceCall)
+	service := displayService{calls: calls}
+	if err := sd.bus.Export(service, servicePath, serviceName); err != nil {
+		return nil
+	}
+	node := &introspect.Node{
+		Name: string(servicePath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: serviceName, Methods: introspect.Methods(service)},
+		},
+	}
+	sd.bus.Export(introspect.NewIntrospectable(node), servicePath, "org.freedesktop.DBus.Introspectable")
+
+	reply, err := sd.bus.RequestName(serviceName, dbus.NameFlagDoNotQueue)
+	if err != nil || (reply != dbus.RequestNameReplyPrimaryOwner && reply != dbus.RequestNameReplyAlreadyOwner) {
+		sd.bus.Export(nil, servicePath, serviceName)
+		return nil
+	}
+	return calls
+}
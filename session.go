@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/config"
+	"sptsong/internal/guard"
+	"sptsong/internal/ui"
+)
+
+// errDetached is returned by Run when the user detached with 'd' or the
+// terminal hung up.
+var errDetached = errors.New("detached")
+
+// viewState is the part of the display a user changes interactively, carried
+// from the terminal to the detached session so attaching restores it exactly.
+type viewState struct {
+	Layout          string `json:"layout"`
+	HorizontalAlign string `json:"horizontal_align"`
+	VerticalAlign   string `json:"vertical_align"`
+	X               int    `json:"x"`
+	Y               int    `json:"y"`
+	Theme           string `json:"theme"`
+	Fullscreen      bool   `json:"fullscreen"`
+	Compact         bool   `json:"compact"`
+	Debug           bool   `json:"debug"`
+}
+
+func (sd *SpotifyDisplay) view() viewState {
+	return viewState{
+		Layout:          sd.Layout,
+		HorizontalAlign: sd.HorizontalAlign,
+		VerticalAlign:   sd.VerticalAlign,
+		X:               sd.X,
+		Y:               sd.Y,
+		Theme:           sd.themes[sd.themeIndex].Name,
+		Fullscreen:      sd.fullscreen,
+		Compact:         sd.Compact,
+		Debug:           sd.debug,
+	}
+}
+
+func (sd *SpotifyDisplay) restoreView(v viewState) {
+	sd.Layout = v.Layout
+	sd.HorizontalAlign = v.HorizontalAlign
+	sd.VerticalAlign = v.VerticalAlign
+	sd.X, sd.Y = v.X, v.Y
+	sd.themeIndex = ui.FindTheme(sd.themes, v.Theme)
+	sd.fullscreen = v.Fullscreen
+	sd.Compact = v.Compact
+	sd.debug = v.Debug
+}
+
+// sessionSocket is where a detached session listens for `sptsong attach`.
+func sessionSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sptsong.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sptsong-%d.sock", os.Getuid()))
+}
+
+// startSession re-executes sptsong as a background session in its own
+// process group, keeping the display flags in args, and waits until it
+// accepts connections.
+func startSession(v viewState, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, append([]string{"session", "--state=" + string(state)}, args...)...)
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("session exited: %v", err)
+		case <-deadline:
+			return errors.New("session did not start listening")
+		case <-time.After(50 * time.Millisecond):
+		}
+		if conn, err := net.Dial("unix", sessionSocket()); err == nil {
+			// Saying nothing and hanging up doesn't disturb the session.
+			conn.Close()
+			return nil
+		}
+	}
+}
+
+// attachMessage is one line of input from `sptsong attach`: the size of its
+// terminal, a key press or a mouse event.
+type attachMessage struct {
+	Type    string  `json:"type"`
+	Width   int     `json:"width,omitempty"`
+	Height  int     `json:"height,omitempty"`
+	Ratio   float64 `json:"ratio,omitempty"`
+	Key     uint16  `json:"key,omitempty"`
+	Ch      rune    `json:"ch,omitempty"`
+	Mod     uint8   `json:"mod,omitempty"`
+	Buttons uint16  `json:"buttons,omitempty"`
+	X       int     `json:"x,omitempty"`
+	Y       int     `json:"y,omitempty"`
+}
+
+// session is the output and screen of a detached display. Output goes to the
+// attached client, or nowhere while there is none; a new attach takes over
+// from the previous one.
+type session struct {
+	mu            sync.Mutex
+	client        net.Conn
+	width, height int
+	ratio         float64
+}
+
+func (s *session) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		s.client.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := s.client.Write(p); err != nil {
+			s.client.Close()
+			s.client = nil
+		}
+	}
+	return len(p), nil
+}
+
+// size reports the attached terminal, or the last one seen.
+func (s *session) size() (width, height int, ratio float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.width, s.height, s.ratio
+}
+
+func (s *session) attach(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		s.client.Close()
+	}
+	s.client = conn
+}
+
+// drop disconnects the client if it is still conn, or whichever is attached
+// if conn is nil.
+func (s *session) drop(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil && (conn == nil || conn == s.client) {
+		s.client.Close()
+		s.client = nil
+	}
+}
+
+// serve accepts clients and turns their messages into tcell events for the
+// display loop.
+func (s *session) serve(listener net.Listener, events chan<- tcell.Event, redraw func()) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		s.attach(conn)
+		go func() {
+			defer s.drop(conn)
+			decoder := json.NewDecoder(conn)
+			for {
+				var m attachMessage
+				if err := decoder.Decode(&m); err != nil {
+					return
+				}
+				switch m.Type {
+				case "size":
+					s.mu.Lock()
+					s.width, s.height, s.ratio = m.Width, m.Height, m.Ratio
+					s.mu.Unlock()
+					redraw()
+				case "key":
+					events <- tcell.NewEventKey(tcell.Key(m.Key), m.Ch, tcell.ModMask(m.Mod))
+				case "mouse":
+					events <- tcell.NewEventMouse(m.X, m.Y, tcell.ButtonMask(m.Buttons), tcell.ModMask(m.Mod))
+				}
+			}
+		}()
+	}
+}
+
+// runSession is the detached display started by startSession. It is not
+// meant to be run by hand.
+func runSession(cfg config.Config, args []string) error {
+	flags := displayFlags("sptsong session", &cfg)
+	state := flags.String("state", "", "view state to restore, as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	stopLogging, err := startLogging(cfg)
+	if err != nil {
+		return err
+	}
+	defer stopLogging()
+	slog.Info("starting detached session", "backend", cfg.Backend)
+
+	path := sessionSocket()
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return errors.New("a detached session is already running")
+	}
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	display, err := NewSpotifyDisplay(cfg)
+	if err != nil {
+		return err
+	}
+	if *state != "" {
+		var v viewState
+		if err := json.Unmarshal([]byte(*state), &v); err != nil {
+			return fmt.Errorf("%w: --state: %v", errUsage, err)
+		}
+		display.restoreView(v)
+	}
+	// Config reloads reapply the display flags, which don't include --state.
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--state=") {
+			display.args = append(display.args, arg)
+		}
+	}
+
+	s := &session{width: 80, height: 24, ratio: ui.DefaultFontRatio}
+	display.out = s
+	display.screenSize = s.size
+	if instances, err := claimInstance(); err == nil {
+		defer instances.Close()
+		display.serveInstances(instances, "session")
+	}
+	events := make(chan tcell.Event)
+	go s.serve(listener, events, display.requestRedraw)
+
+	// Without a terminal of its own, a hangup only ever asks for a reload.
+	err = display.loop(events, func() bool {
+		s.drop(nil)
+		return false
+	}, func() bool { return false })
+	fmt.Fprint(s, "\033[2J\033[H")
+	s.drop(nil)
+	if errors.Is(err, errTakenOver) {
+		return nil
+	}
+	return err
+}
+
+// runAttach connects the terminal to a detached session until the user
+// detaches again with 'd' or quits it with 'q'.
+func runAttach(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("sptsong attach", flag.ContinueOnError)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("unix", sessionSocket())
+	if err != nil {
+		return errors.New("no detached session to attach to")
+	}
+	defer conn.Close()
+
+	if err := takeScreen(); err != nil {
+		return err
+	}
+	defer restoreScreen()
+	defer guard.Recover()
+
+	encoder := json.NewEncoder(conn)
+	sendSize := func() error {
+		width, height := screenSize()
+		return encoder.Encode(attachMessage{Type: "size", Width: width, Height: height, Ratio: fontRatio()})
+	}
+	if err := sendSize(); err != nil {
+		return err
+	}
+
+	// The session hangs up when it detaches us, quits, or another terminal
+	// attaches; any of those ends the loop below.
+	go func() {
+		io.Copy(os.Stdout, conn)
+		interruptScreen()
+	}()
+
+	for {
+		switch event := readEvent().(type) {
+		case *tcell.EventInterrupt:
+			return nil
+		case *tcell.EventResize:
+			if sendSize() != nil {
+				return nil
+			}
+		case *tcell.EventKey:
+			m := attachMessage{Type: "key", Key: uint16(event.Key()), Ch: event.Rune(), Mod: uint8(event.Modifiers())}
+			if encoder.Encode(m) != nil {
+				return nil
+			}
+		case *tcell.EventMouse:
+			x, y := event.Position()
+			m := attachMessage{Type: "mouse", Buttons: uint16(event.Buttons()), Mod: uint8(event.Modifiers()), X: x, Y: y}
+			if encoder.Encode(m) != nil {
+				return nil
+			}
+		}
+	}
+}
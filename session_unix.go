@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// detachedProcAttr starts the session in its own session, so closing the
+// terminal doesn't take it down.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+var errNoSettings = errors.New("the player has no playback settings to change")
+
+// settingsPanel is the overlay for the player's playback settings, such as
+// MPD's crossfade, so they can be tuned without the player's own config.
+type settingsPanel struct {
+	settings []mpris.Setting
+	selected int
+}
+
+// openSettings reads the player's playback settings into the panel.
+func (sd *SpotifyDisplay) openSettings() {
+	player, ok := sd.player.(mpris.PlaybackSettings)
+	if !ok {
+		sd.showNotice(errNoSettings.Error())
+		return
+	}
+	settings, err := player.Settings()
+	if errors.Is(err, errNoSettings) {
+		sd.showNotice(err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("reading the playback settings failed", "err", err)
+		sd.showNotice("can't read the playback settings: " + err.Error())
+		return
+	}
+	sd.settings = &settingsPanel{settings: settings}
+}
+
+// handleSettingsKey processes a key while the settings panel is open and
+// reports whether the screen needs a full repaint. ← and → change the
+// selected setting a step at a time.
+func (sd *SpotifyDisplay) handleSettingsKey(event *tcell.EventKey) bool {
+	p := sd.settings
+	switch event.Key() {
+	case tcell.KeyEsc:
+		sd.settings = nil
+		return true
+	case tcell.KeyUp:
+		p.selected = max(p.selected-1, 0)
+	case tcell.KeyDown:
+		p.selected = min(p.selected+1, len(p.settings)-1)
+	case tcell.KeyLeft, tcell.KeyRight:
+		step := p.settings[p.selected].Step
+		if event.Key() == tcell.KeyLeft {
+			step = -step
+		}
+		sd.changeSetting(step)
+	}
+	return false
+}
+
+// changeSetting moves the selected setting by step, and reads the settings
+// back, since the player may round or link them.
+func (sd *SpotifyDisplay) changeSetting(step float64) {
+	s := &sd.settings.settings[sd.settings.selected]
+	value := min(max(s.Value+step, s.Min), s.Max)
+	if value == s.Value {
+		return
+	}
+	player, ok := sd.player.(mpris.PlaybackSettings)
+	if !ok {
+		return
+	}
+	if err := player.ChangeSetting(s.Name, value); err != nil {
+		slog.Error("changing a playback setting failed", "setting", s.Name, "err", err)
+		sd.showNotice("can't change " + s.Label + ": " + err.Error())
+		return
+	}
+	if settings, err := player.Settings(); err == nil && len(settings) == len(sd.settings.settings) {
+		sd.settings.settings = settings
+	} else {
+		s.Value = value
+	}
+}
+
+// settingValue formats a setting's value for the panel.
+func settingValue(s mpris.Setting) string {
+	if s.Value <= s.Min && s.MinLabel != "" {
+		return s.MinLabel
+	}
+	return strconv.FormatFloat(s.Value, 'f', -1, 64) + " " + s.Unit
+}
+
+// drawSettings draws the settings panel in a box in the middle of the
+// screen.
+func (sd *SpotifyDisplay) drawSettings(term TerminalSize) {
+	p := sd.settings
+	labelWidth := 0
+	for _, s := range p.settings {
+		labelWidth = max(labelWidth, ui.Width(s.Label))
+	}
+	hint := "↑↓ select · ←→ change · Esc"
+	valueWidth := 12
+	width := min(max(labelWidth+valueWidth+7, ui.Width(hint)+4), term.width)
+	height := len(p.settings) + 4
+	box := ui.Rect{X: (term.width - width) / 2, Y: max((term.height-height)/2, 0), Width: width, Height: height}
+	theme := sd.theme()
+	border := sd.Border
+	if _, ok := ui.BorderStyles[border]; !ok {
+		border = "rounded"
+	}
+	fmt.Fprint(sd.out, ui.Frame(box, "playback", border, theme.Border))
+
+	inner := width - 4
+	line := func(row int, text string, style ui.Style) {
+		text = ui.Truncate(text, inner)
+		pad := strings.Repeat(" ", max(inner-ui.Width(text), 0))
+		fmt.Fprint(sd.out, ui.MoveTo(box.X+1, box.Y+1+row)+" "+style.Render(text)+pad+" ")
+	}
+	line(0, hint, theme.Time)
+	line(1, "", theme.Time)
+	for i, s := range p.settings {
+		text := ui.Pad(s.Label, labelWidth) + "  ◂ " + settingValue(s) + " ▸"
+		if i == p.selected {
+			line(i+2, text, theme.Accent)
+		} else {
+			line(i+2, text, theme.Artist)
+		}
+	}
+}
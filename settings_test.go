@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"sptsong/internal/mpris"
+)
+
+// settingsPlayer is a fakePlayer with MPD-like crossfade settings.
+type settingsPlayer struct {
+	fakePlayer
+	crossfade float64
+}
+
+func (p *settingsPlayer) Settings() ([]mpris.Setting, error) {
+	return []mpris.Setting{{Name: "crossfade", Label: "crossfade", Unit: "s", Value: p.crossfade, Max: 3, Step: 1, MinLabel: "off"}}, nil
+}
+
+func (p *settingsPlayer) ChangeSetting(name string, value float64) error {
+	p.crossfade = value
+	return nil
+}
+
+func TestSettingsPanel(t *testing.T) {
+	player := &settingsPlayer{crossfade: 2}
+	sd := &SpotifyDisplay{player: player}
+	sd.openSettings()
+	if sd.settings == nil {
+		t.Fatal("the panel didn't open")
+	}
+	right := tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)
+	left := tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone)
+	steps := []struct {
+		key  *tcell.EventKey
+		want string
+	}{
+		{right, "3 s"},
+		{right, "3 s"}, // stays at Max
+		{left, "2 s"},
+		{left, "1 s"},
+		{left, "off"},
+		{left, "off"},
+	}
+	for i, step := range steps {
+		sd.handleSettingsKey(step.key)
+		if got := settingValue(sd.settings.settings[0]); got != step.want {
+			t.Fatalf("step %d: %q, want %q", i, got, step.want)
+		}
+		if player.crossfade != sd.settings.settings[0].Value {
+			t.Fatalf("step %d: player at %v, panel at %v", i, player.crossfade, sd.settings.settings[0].Value)
+		}
+	}
+}
+
+func TestSettingsPanelWithoutSettings(t *testing.T) {
+	sd := &SpotifyDisplay{player: &overridePlayer{Player: &fakePlayer{}}, clock: newFakeClock()}
+	sd.openSettings()
+	if sd.settings != nil || sd.notice != errNoSettings.Error() {
+		t.Errorf("opened a panel for a player without settings, with notice %q", sd.notice)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "time"
+
+// settler reports a value as settled once it has stayed the same for delay,
+// so rapid skipping only acts on the track the user lands on.
+type settler struct {
+	delay time.Duration
+	value string
+	since time.Time
+}
+
+// update records the latest value and reports whether it has settled. The
+// very first value settles immediately so startup isn't delayed.
+func (s *settler) update(value string, now time.Time) bool {
+	if value != s.value {
+		if s.since.IsZero() {
+			s.since = now.Add(-s.delay)
+		} else {
+			s.since = now
+		}
+		s.value = value
+	}
+	return now.Sub(s.since) >= s.delay
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// compactBarWidth is the width of the compact line's progress bar.
+func (sd *SpotifyDisplay) compactBarWidth() int {
+	if sd.Progress.Width > 0 {
+		return int(sd.Progress.Width)
+	}
+	return 10
+}
+
+// neededSize returns the smallest terminal the current layout can be drawn
+// in: the frame, or for the compact line its glyph, bar, time and a few
+// columns of the track.
+func (sd *SpotifyDisplay) neededSize(metadata *mpris.Metadata, term TerminalSize) (width, height int) {
+	if sd.compact(term) {
+		return 1 + sd.compactBarWidth() + ui.Width(sd.timeText(metadata)) + 5 + 8, 1
+	}
+	return term.frame.Width, term.frame.Height
+}
+
+// tooSmall reports whether the terminal can't hold the layout, in which
+// case drawTooSmall stands in for it until the terminal grows. Full-screen
+// art scales to any size.
+func (sd *SpotifyDisplay) tooSmall(metadata *mpris.Metadata, term TerminalSize) bool {
+	if sd.fullscreen {
+		return false
+	}
+	width, height := sd.neededSize(metadata, term)
+	return term.width < width || term.height < height
+}
+
+// drawTooSmall says how big the terminal needs to be, in the middle of it.
+func (sd *SpotifyDisplay) drawTooSmall(metadata *mpris.Metadata, term TerminalSize) {
+	width, height := sd.neededSize(metadata, term)
+	text := fmt.Sprintf("Terminal too small (need %dx%d, have %dx%d)", width, height, term.width, term.height)
+	text = ui.Truncate(text, term.width)
+	x := max((term.width-ui.Width(text))/2, 0)
+	fmt.Fprint(sd.out, ui.MoveTo(x, max((term.height-1)/2, 0))+sd.theme().Accent.Render(text))
+}
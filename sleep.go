@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	"sptsong/internal/ui"
+)
+
+// sleepStep is how much z adds to the sleep timer.
+const sleepStep = 15 * time.Minute
+
+// setSleep pauses playback after d, replacing any timer running.
+func (sd *SpotifyDisplay) setSleep(d time.Duration) {
+	sd.sleepAt = sd.clock.Now().Add(d)
+}
+
+// extendSleep starts the sleep timer, or adds to the one running.
+func (sd *SpotifyDisplay) extendSleep() {
+	now := sd.clock.Now()
+	if sd.sleepAt.IsZero() {
+		sd.sleepAt = now
+	}
+	sd.sleepAt = sd.sleepAt.Add(sleepStep)
+	sd.showNotice("sleep timer: pausing in " + ui.FormatDuration(int64(sd.sleepAt.Sub(now).Round(time.Second).Seconds())))
+}
+
+// cancelSleep stops the sleep timer.
+func (sd *SpotifyDisplay) cancelSleep() {
+	if sd.sleepAt.IsZero() {
+		return
+	}
+	sd.sleepAt = time.Time{}
+	sd.showNotice("sleep timer cancelled")
+}
+
+// checkSleep pauses the player once the sleep timer runs out.
+func (sd *SpotifyDisplay) checkSleep() {
+	if sd.sleepAt.IsZero() || sd.clock.Now().Before(sd.sleepAt) {
+		return
+	}
+	sd.sleepAt = time.Time{}
+	if err := sd.player.Call("Pause"); err != nil {
+		sd.showNotice("sleep timer: couldn't pause: " + err.Error())
+		return
+	}
+	sd.showNotice("sleep timer: paused")
+}
+
+// sleepLabel is the time left on the sleep timer for the header, such as
+// "☾ 29:41", or "" without one.
+func (sd *SpotifyDisplay) sleepLabel() string {
+	if sd.sleepAt.IsZero() {
+		return ""
+	}
+	left := max(sd.sleepAt.Sub(sd.clock.Now()), 0)
+	return "☾ " + ui.FormatDuration(int64(left.Round(time.Second).Seconds()))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"sptsong/internal/artwork"
+	"sptsong/internal/config"
+	"sptsong/internal/webapi"
+)
+
+func newWebAPI(cfg config.Config) *webapi.Client {
+	return webapi.New(cfg.Spotify.ClientID, filepath.Join(config.Dir(), "token.json"), artwork.HTTPClient)
+}
+
+// apiError maps Web API auth failures onto errAuthRequired so they get their
+// own exit code.
+func apiError(err error) error {
+	if errors.Is(err, webapi.ErrNotLoggedIn) || errors.Is(err, webapi.ErrNoClientID) {
+		return fmt.Errorf("%w: %v", errAuthRequired, err)
+	}
+	return err
+}
+
+// runLogin authorizes sptsong for the user's Spotify account.
+func runLogin(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := newWebAPI(cfg).Login(ctx, cfg.Spotify.RedirectURI, func(authURL string) {
+		fmt.Fprintln(os.Stderr, "Opening the Spotify login page. If no browser opens, visit:")
+		fmt.Fprintln(os.Stderr, authURL)
+		openBrowser(authURL)
+	})
+	if err != nil {
+		return apiError(err)
+	}
+	fmt.Fprintln(os.Stderr, "Logged in.")
+	return nil
+}
+
+func openBrowser(url string) {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+	exec.Command(opener, url).Start()
+}
+
+// runQueue adds tracks to the playback queue. They are given as arguments or,
+// with no arguments, read one per line from stdin.
+func runQueue(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("queue", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sptsong queue [--no-color] <spotify:track:… | open.spotify.com link>...")
+	}
+	noColor := noColorFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	items := flags.Args()
+	if len(items) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				items = append(items, line)
+			}
+		}
+		if len(items) == 0 {
+			return fmt.Errorf("%w: queue needs a Spotify URI or link", errUsage)
+		}
+	}
+
+	uris := make([]string, len(items))
+	for i, item := range items {
+		uri, err := webapi.ParseURI(item)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		uris[i] = uri
+	}
+
+	client := newWebAPI(cfg)
+	out := newPrinter(cfg, *noColor)
+	for _, uri := range uris {
+		if err := client.Queue(context.Background(), uri); err != nil {
+			return apiError(err)
+		}
+		out.fields(field{"queued", uri, out.theme.Title})
+	}
+	return nil
+}
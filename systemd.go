@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"sptsong/internal/config"
+	"sptsong/internal/sdnotify"
+)
+
+// errBusLost is returned by a service's run loop when its D-Bus connection
+// drops, so it can connect again.
+var errBusLost = errors.New("lost the D-Bus connection")
+
+// maxServiceWait caps the wait between attempts to reach the player.
+const maxServiceWait = 30 * time.Second
+
+// waitForDisplay opens the display for --service, trying again with a
+// growing delay for as long as the player or the session bus isn't there
+// yet. It returns a nil display if the service is stopped meanwhile.
+func waitForDisplay(cfg config.Config) (*SpotifyDisplay, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	wait := time.Second
+	if every := sdnotify.WatchdogInterval(); every > 0 {
+		wait = min(wait, every)
+	}
+	for {
+		display, err := NewSpotifyDisplay(cfg)
+		if !errors.Is(err, errSpotifyNotRunning) && !errors.Is(err, errDBusUnavailable) {
+			return display, err
+		}
+		// Waiting is what the service is there to do, so it's ready.
+		slog.Info("waiting for the player", "err", err)
+		sdnotify.Notify(sdnotify.Ready + "\n" + sdnotify.Status("waiting for the player"))
+		if sdnotify.WatchdogInterval() > 0 {
+			sdnotify.Notify(sdnotify.Watchdog)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil
+		}
+		wait = min(2*wait, maxServiceWait)
+		if every := sdnotify.WatchdogInterval(); every > 0 {
+			wait = min(wait, every)
+		}
+	}
+}
+
+// openDisplay opens the display, waiting for the player first if it runs
+// as a service.
+func openDisplay(cfg config.Config, service bool) (*SpotifyDisplay, error) {
+	if service {
+		return waitForDisplay(cfg)
+	}
+	return NewSpotifyDisplay(cfg)
+}
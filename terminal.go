@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sptsong/internal/bidi"
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// terminalState is what the display last put in the terminal's title and
+// progress indicator, so each is only sent when it changes and can be
+// undone on exit.
+type terminalState struct {
+	saved    bool
+	title    string
+	progress string
+}
+
+// colorDepth returns the color depth configured, or for "auto" the one the
+// terminal advertises: COLORTERM for 24-bit color, which terminals set as
+// terminfo has no standard way to say so, then TERM's name.
+func colorDepth(cfg config.TerminalConfig) ui.ColorDepth {
+	if depth, ok := ui.ParseColorDepth(cfg.Colors); ok {
+		return depth
+	}
+	switch colorterm := os.Getenv("COLORTERM"); {
+	case colorterm == "truecolor" || colorterm == "24bit":
+		return ui.ColorsFull
+	case os.Getenv("WT_SESSION") != "":
+		return ui.ColorsFull
+	}
+	switch term := os.Getenv("TERM"); {
+	case strings.HasSuffix(term, "-direct"):
+		return ui.ColorsFull
+	case strings.Contains(term, "256color"):
+		return ui.Colors256
+	case term == "linux" || term == "ansi" || strings.HasPrefix(term, "vt") || strings.HasSuffix(term, "-16color"):
+		return ui.Colors16
+	}
+	return ui.Colors256
+}
+
+// artColors is the depth to render covers at: as configured, or for "auto"
+// the terminal's.
+func (sd *SpotifyDisplay) artColors() string {
+	if sd.Art.Colors != "auto" {
+		return sd.Art.Colors
+	}
+	return colorDepth(sd.Terminal).String()
+}
+
+// bidiTerminal reports whether the terminal reorders right-to-left text
+// itself, as VTE since 0.58, Konsole and mlterm do; reordering it for them
+// too would turn it back around.
+func bidiTerminal() bool {
+	if version, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && version >= 5800 {
+		return true
+	}
+	return os.Getenv("KONSOLE_VERSION") != "" || os.Getenv("MLTERM") != ""
+}
+
+// visual returns a track name in the order to draw it, see bidiTerminal.
+func (sd *SpotifyDisplay) visual(s string) string {
+	if sd.Terminal.Bidi == "off" || (sd.Terminal.Bidi == "auto" && bidiTerminal()) {
+		return s
+	}
+	return bidi.Visual(s)
+}
+
+// progressTerminal reports whether the terminal is one known to show OSC
+// 9;4 progress. Others may take OSC 9 for a notification, as iTerm2 does, so
+// "auto" leaves them alone.
+func progressTerminal() bool {
+	switch {
+	case os.Getenv("TERM_PROGRAM") == "WezTerm", os.Getenv("TERM_PROGRAM") == "ghostty":
+		return true
+	case os.Getenv("ConEmuANSI") == "ON", os.Getenv("WT_SESSION") != "":
+		return true
+	}
+	return false
+}
+
+// printable drops the control characters a track name could use to slip
+// escape sequences into the terminal.
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// cleanMetadata strips control characters from the names the player
+// reports, once as they are read, so no view draws them raw.
+func cleanMetadata(m *mpris.Metadata) {
+	m.Title, m.Artist, m.Album = printable(m.Title), printable(m.Artist), printable(m.Album)
+}
+
+// updateTerminal sets the window title to "♫ artist – title" and the
+// progress indicator to the track's position, as configured under
+// [terminal].
+func (sd *SpotifyDisplay) updateTerminal(metadata *mpris.Metadata) {
+	if sd.Terminal.Title {
+		title := ""
+		if metadata.Title != "" {
+			title = "♫ " + metadata.Artist + " – " + metadata.Title
+		}
+		if title != sd.termState.title {
+			if !sd.termState.saved {
+				// Push the title so restoreTerminal can put it back.
+				fmt.Fprint(sd.out, "\033[22;2t")
+				sd.termState.saved = true
+			}
+			fmt.Fprintf(sd.out, "\033]2;%s\a", title)
+			sd.termState.title = title
+		}
+	}
+
+	if sd.Terminal.Progress == "on" || (sd.Terminal.Progress == "auto" && progressTerminal()) {
+		progress := "\033]9;4;0;0\a"
+		if metadata.Length > 0 && metadata.Status != "Stopped" {
+			state := 1
+			if metadata.Status == "Paused" {
+				state = 4
+			}
+			percent := min(metadata.Position*100/metadata.Length, 100)
+			progress = fmt.Sprintf("\033]9;4;%d;%d\a", state, percent)
+		}
+		if progress != sd.termState.progress {
+			fmt.Fprint(sd.out, progress)
+			sd.termState.progress = progress
+		}
+	}
+}
+
+// restoreTerminal puts back the title and clears the progress indicator.
+func (sd *SpotifyDisplay) restoreTerminal() {
+	if sd.termState.saved {
+		fmt.Fprint(sd.out, "\033[23;2t")
+	}
+	if sd.termState.progress != "" {
+		fmt.Fprint(sd.out, "\033]9;4;0;0\a")
+	}
+	sd.termState = terminalState{}
+}
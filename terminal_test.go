@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"sptsong/internal/mpris"
+)
+
+func TestCleanMetadata(t *testing.T) {
+	m := mpris.Metadata{
+		TrackID: "/track/1",
+		Title:   "Song\033]2;pwned\a",
+		Artist:  "Art\u009bist\r\n",
+		Album:   "Älbum\x7f",
+	}
+	cleanMetadata(&m)
+	want := mpris.Metadata{TrackID: "/track/1", Title: "Song]2;pwned", Artist: "Artist", Album: "Älbum"}
+	if m != want {
+		t.Errorf("cleanMetadata = %+v, want %+v", m, want)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// timeLocale is how one language writes the time a track was played. The
+// relative forms take a number through fmt.
+type timeLocale struct {
+	justNow    string
+	minutesAgo string
+	hoursAgo   string
+	yesterday  string
+	clock      string
+	day        string
+	year       string
+}
+
+// timeLocales are the languages timestamps are written in, the first
+// being the fallback.
+var timeLocales = []struct {
+	tag    language.Tag
+	locale timeLocale
+}{
+	{language.BritishEnglish, timeLocale{"just now", "%d min ago", "%d h ago", "yesterday", "15:04", "2 Jan", "2 Jan 2006"}},
+	{language.AmericanEnglish, timeLocale{"just now", "%d min ago", "%d h ago", "yesterday", "3:04 PM", "Jan 2", "Jan 2, 2006"}},
+	{language.German, timeLocale{"gerade eben", "vor %d Min.", "vor %d Std.", "gestern", "15:04", "2.1.", "2.1.2006"}},
+	{language.French, timeLocale{"à l’instant", "il y a %d min", "il y a %d h", "hier", "15:04", "2/1", "2/1/2006"}},
+	{language.Spanish, timeLocale{"ahora mismo", "hace %d min", "hace %d h", "ayer", "15:04", "2/1", "2/1/2006"}},
+}
+
+var timeMatcher = func() language.Matcher {
+	tags := make([]language.Tag, len(timeLocales))
+	for i, l := range timeLocales {
+		tags[i] = l.tag
+	}
+	return language.NewMatcher(tags)
+}()
+
+// findTimeLocale picks the time locale for a POSIX locale name such as
+// "de_DE.UTF-8"; "C", "POSIX" and names it doesn't know get British
+// English, for its 24-hour clock.
+func findTimeLocale(name string) timeLocale {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return timeLocales[0].locale
+	}
+	_, i, confidence := timeMatcher.Match(tag)
+	if confidence == language.No {
+		return timeLocales[0].locale
+	}
+	return timeLocales[i].locale
+}
+
+// userTimeLocale is the time locale of the environment, from the variables
+// that set LC_TIME in the order the C library reads them.
+func userTimeLocale() timeLocale {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return findTimeLocale(value)
+		}
+	}
+	return timeLocales[0].locale
+}
+
+// played writes when a track was played, as seen at now: relative to it
+// ("2 h ago", "yesterday 23:40"), or as the time of day for today and the
+// date before that.
+func (l timeLocale) played(t, now time.Time, relative bool) string {
+	t = t.In(now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	ago := now.Sub(t)
+	switch {
+	case relative && ago < time.Minute:
+		return l.justNow
+	case relative && ago < time.Hour:
+		return fmt.Sprintf(l.minutesAgo, int(ago/time.Minute))
+	case relative && !t.Before(today):
+		return fmt.Sprintf(l.hoursAgo, int(ago/time.Hour))
+	case relative && !t.Before(today.AddDate(0, 0, -1)):
+		return l.yesterday + " " + t.Format(l.clock)
+	case !t.Before(today):
+		return t.Format(l.clock)
+	case t.Year() == now.Year():
+		return t.Format(l.day) + " " + t.Format(l.clock)
+	}
+	return t.Format(l.year) + " " + t.Format(l.clock)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayed(t *testing.T) {
+	now := time.Date(2026, 3, 14, 15, 30, 0, 0, time.UTC)
+	en, us, de := findTimeLocale("en_GB.UTF-8"), findTimeLocale("en_US.UTF-8"), findTimeLocale("de_DE.UTF-8@euro")
+	tests := []struct {
+		locale   timeLocale
+		played   time.Time
+		relative bool
+		want     string
+	}{
+		{en, now.Add(-20 * time.Second), true, "just now"},
+		{en, now.Add(-42 * time.Minute), true, "42 min ago"},
+		{en, now.Add(-2*time.Hour - 10*time.Minute), true, "2 h ago"},
+		{en, time.Date(2026, 3, 13, 23, 40, 0, 0, time.UTC), true, "yesterday 23:40"},
+		{en, time.Date(2026, 3, 10, 8, 5, 0, 0, time.UTC), true, "10 Mar 08:05"},
+		{en, time.Date(2025, 12, 31, 22, 0, 0, 0, time.UTC), true, "31 Dec 2025 22:00"},
+		{en, now.Add(-42 * time.Minute), false, "14:48"},
+		{en, time.Date(2026, 3, 13, 23, 40, 0, 0, time.UTC), false, "13 Mar 23:40"},
+		{us, time.Date(2026, 3, 13, 23, 40, 0, 0, time.UTC), true, "yesterday 11:40 PM"},
+		{us, time.Date(2026, 3, 10, 8, 5, 0, 0, time.UTC), false, "Mar 10 8:05 AM"},
+		{de, now.Add(-2 * time.Hour), true, "vor 2 Std."},
+		{de, time.Date(2026, 3, 13, 23, 40, 0, 0, time.UTC), true, "gestern 23:40"},
+		{de, time.Date(2026, 3, 10, 8, 5, 0, 0, time.UTC), false, "10.3. 08:05"},
+		{findTimeLocale("C"), now.Add(-42 * time.Minute), true, "42 min ago"},
+		{findTimeLocale("ja_JP.UTF-8"), now.Add(-42 * time.Minute), false, "14:48"},
+	}
+	for _, tt := range tests {
+		if got := tt.locale.played(tt.played, now, tt.relative); got != tt.want {
+			t.Errorf("played(%v, relative %v) = %q, want %q", tt.played, tt.relative, got, tt.want)
+		}
+	}
+}
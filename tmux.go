@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// statusHeartbeat is how often a running display rewrites the status cache
+// even when nothing changed, and statusStale how old the cache may get
+// before `sptsong tmux` assumes no display is running.
+const (
+	statusHeartbeat = 2 * time.Second
+	statusStale     = 10 * time.Second
+)
+
+// statusCache is what a running display leaves behind for `sptsong tmux`.
+type statusCache struct {
+	Updated  time.Time      `json:"updated"`
+	Metadata mpris.Metadata `json:"metadata"`
+}
+
+// statusCachePath lives next to the session socket.
+func statusCachePath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sptsong-status.json")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sptsong-%d-status.json", os.Getuid()))
+}
+
+// writeStatusCache records metadata for `sptsong tmux` when it differs from
+// what was last written, or the heartbeat is due. The file is replaced
+// atomically so a reader never sees half of it.
+func (sd *SpotifyDisplay) writeStatusCache(metadata *mpris.Metadata) {
+	if sd.companion {
+		return
+	}
+	now := sd.clock.Now()
+	if *metadata == sd.cached && now.Sub(sd.cachedAt) < statusHeartbeat {
+		return
+	}
+	data, err := json.Marshal(statusCache{Updated: now, Metadata: *metadata})
+	if err != nil {
+		return
+	}
+	path := statusCachePath()
+	if os.WriteFile(path+".tmp", data, 0o600) != nil || os.Rename(path+".tmp", path) != nil {
+		return
+	}
+	sd.cached, sd.cachedAt = *metadata, now
+}
+
+// readStatusCache returns what a running display last cached, with the
+// position brought up to date, or false if no display has written it lately.
+func readStatusCache() (mpris.Metadata, bool) {
+	data, err := os.ReadFile(statusCachePath())
+	if err != nil {
+		return mpris.Metadata{}, false
+	}
+	var cache statusCache
+	if json.Unmarshal(data, &cache) != nil || time.Since(cache.Updated) > statusStale {
+		return mpris.Metadata{}, false
+	}
+	m := cache.Metadata
+	if m.Status == "Playing" {
+		m.Position = min(m.Position+int64(time.Since(cache.Updated).Seconds()), m.Length)
+	}
+	return m, true
+}
+
+// runTmux prints a short status for a tmux #() status line. It only reads
+// the cache a running display or detached session keeps, so it returns in
+// a few milliseconds and never waits on a player; without a fresh cache it
+// prints nothing.
+func runTmux(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("tmux", flag.ContinueOnError)
+	format := flags.String("format", "{icon} {artist} – {title}", "what to print; takes the placeholders of status --format and {icon}")
+	maxLength := flags.Int("max-length", 40, "cut the status to this many characters (0 for no limit)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	m, ok := readStatusCache()
+	if !ok {
+		return nil
+	}
+
+	fmt.Println(tmuxStatus(*format, m, *maxLength))
+	return nil
+}
+
+// nameHash stands in for a # in a track name until the status is cut to
+// length, so the cut counts it as one column and can't split its escape.
+const nameHash = "\ue000"
+
+// tmuxStatus fills in format for tmux, cut to maxLength columns. tmux reads
+// #[...] in the output as styles, which the format may use but the track
+// names must not, so their # are doubled once the status is cut.
+func tmuxStatus(format string, m mpris.Metadata, maxLength int) string {
+	for _, name := range []*string{&m.Title, &m.Artist, &m.Album} {
+		*name = strings.ReplaceAll(*name, "#", nameHash)
+	}
+	text := formatStatus(format, &m)
+	if maxLength > 0 {
+		text = ui.Truncate(text, maxLength)
+	}
+	return strings.ReplaceAll(text, nameHash, "##")
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"sptsong/internal/mpris"
+)
+
+func TestTmuxStatusEscapesAfterCutting(t *testing.T) {
+	m := mpris.Metadata{Artist: "Band", Title: "#1 Crush"}
+	for _, test := range []struct {
+		maxLength int
+		want      string
+	}{
+		{0, "Band – ##1 Crush"},
+		{10, "Band – ##1…"},
+		{8, "Band – …"},
+	} {
+		if got := tmuxStatus("{artist} – {title}", m, test.maxLength); got != test.want {
+			t.Errorf("tmuxStatus(max %d) = %q, want %q", test.maxLength, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sptsong/internal/ui"
+)
+
+// toastDuration is how long a toast stays on screen.
+const toastDuration = 5 * time.Second
+
+// toast warns about a failure the user would otherwise only notice as a
+// missing cover or a frozen display. Unlike a notice it is drawn in every
+// layout, even while the player can't be read, on the row under the health
+// banner.
+func (sd *SpotifyDisplay) toast(text string) {
+	slog.Warn(text)
+	sd.toastText, sd.toastAt = text, sd.clock.Now()
+}
+
+// drawToast draws the current toast, or blanks its row once it has run its
+// time. With reduced motion a toast doesn't come and go by itself: it stays
+// until Esc dismisses it. It runs last, so whatever else shares the row is
+// drawn again on the next tick.
+func (sd *SpotifyDisplay) drawToast(term TerminalSize) {
+	if sd.toastText == "" {
+		return
+	}
+	row := len(sd.issues)
+	if sd.clock.Now().Sub(sd.toastAt) >= toastDuration && !sd.ReduceMotion {
+		sd.toastText = ""
+		fmt.Fprint(sd.out, ui.MoveTo(0, row)+"\033[2K")
+		return
+	}
+	text := ui.Truncate("⚠ "+sd.toastText, term.width)
+	x := max((term.width-ui.Width(text))/2, 0)
+	fmt.Fprint(sd.out, ui.MoveTo(0, row)+"\033[2K"+ui.MoveTo(x, row)+sd.theme().Accent.Render(text))
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"sptsong/internal/artistinfo"
+	"sptsong/internal/webapi"
+)
+
+// TrackInfo is everything we derive for a track beyond the raw MPRIS
+// metadata, so re-plays of recent tracks can skip the work. An artist's
+// genres and biography are kept under "artist:" and the artist's name.
+// Each field is looked up on its own, so each expires on its own: Fetched
+// holds when it was, under the field's name below.
+type TrackInfo struct {
+	Accent       string                `json:"accent,omitempty"`
+	LookupArtURL string                `json:"lookup_art_url,omitempty"`
+	ArtPath      string                `json:"art_path,omitempty"`
+	Album        *webapi.AlbumPosition `json:"album,omitempty"`
+	Chapters     []webapi.Chapter      `json:"chapters,omitempty"`
+	Features     *webapi.AudioFeatures `json:"features,omitempty"`
+	Liked        *bool                 `json:"liked,omitempty"`
+	Artist       *artistinfo.Info      `json:"artist,omitempty"`
+	Fetched      map[string]time.Time  `json:"fetched"`
+}
+
+// The fields of TrackInfo, as named in Fetched.
+const (
+	fieldAccent       = "accent"
+	fieldLookupArtURL = "lookup_art_url"
+	fieldArtPath      = "art_path"
+	fieldAlbum        = "album"
+	fieldChapters     = "chapters"
+	fieldFeatures     = "features"
+	fieldLiked        = "liked"
+	fieldArtist       = "artist"
+)
+
+// expire clears the fields fetched more than ttl before now, and reports
+// whether any are left. info.Fetched must not be shared.
+func (info *TrackInfo) expire(now time.Time, ttl time.Duration) bool {
+	for field, at := range info.Fetched {
+		if now.Sub(at) <= ttl {
+			continue
+		}
+		delete(info.Fetched, field)
+		switch field {
+		case fieldAccent:
+			info.Accent = ""
+		case fieldLookupArtURL:
+			info.LookupArtURL = ""
+		case fieldArtPath:
+			info.ArtPath = ""
+		case fieldAlbum:
+			info.Album = nil
+		case fieldChapters:
+			info.Chapters = nil
+		case fieldFeatures:
+			info.Features = nil
+		case fieldLiked:
+			info.Liked = nil
+		case fieldArtist:
+			info.Artist = nil
+		}
+	}
+	return len(info.Fetched) > 0
+}
+
+// lastFetched returns when the most recently fetched field was.
+func (info TrackInfo) lastFetched() time.Time {
+	var last time.Time
+	for _, at := range info.Fetched {
+		if at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
+
+// maxTrackCacheEntries caps the cache so week-long sessions stay flat even
+// with a long TTL.
+const maxTrackCacheEntries = 2000
+
+// trackCache keeps TrackInfo per trackid in memory and mirrors it to a JSON
+// file so it survives restarts. Fields older than ttl are ignored and
+// dropped on the next save, as are the least recently fetched entries beyond
+// maxTrackCacheEntries.
+type trackCache struct {
+	mu      sync.Mutex
+	path    string
+	ttl     time.Duration
+	clock   Clock
+	entries map[string]TrackInfo
+	// readOnly keeps new entries in memory, leaving the file to another
+	// display.
+	readOnly bool
+}
+
+func newTrackCache(path string, ttl time.Duration, clock Clock) *trackCache {
+	c := &trackCache{
+		path:    path,
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]TrackInfo),
+	}
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &c.entries) != nil {
+		// A cache from an older version; it fills up again.
+		c.entries = make(map[string]TrackInfo)
+	}
+	return c
+}
+
+// Get returns the fields of trackID's entry that haven't expired, and false
+// if none are left.
+func (c *trackCache) Get(trackID string) (TrackInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, ok := c.entries[trackID]
+	if !ok {
+		return TrackInfo{}, false
+	}
+	info.Fetched = maps.Clone(info.Fetched)
+	if !info.expire(c.clock.Now(), c.ttl) {
+		return TrackInfo{}, false
+	}
+	return info, true
+}
+
+// Update sets field of the entry for trackID with change and marks it
+// fetched now, holding the lock from reading the entry to saving it, so
+// lookups finishing on different goroutines don't undo each other's fields.
+// The entry's other fields keep their own times.
+func (c *trackCache) Update(trackID, field string, change func(info *TrackInfo)) error {
+	if trackID == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	info := c.entries[trackID]
+	info.Fetched = maps.Clone(info.Fetched)
+	info.expire(now, c.ttl)
+	change(&info)
+	if info.Fetched == nil {
+		info.Fetched = make(map[string]time.Time)
+	}
+	info.Fetched[field] = now
+	c.entries[trackID] = info
+	for id, entry := range c.entries {
+		if !entry.expire(now, c.ttl) {
+			delete(c.entries, id)
+		} else {
+			c.entries[id] = entry
+		}
+	}
+	for len(c.entries) > maxTrackCacheEntries {
+		oldest := ""
+		for id, entry := range c.entries {
+			if oldest == "" || entry.lastFetched().Before(c.entries[oldest].lastFetched()) {
+				oldest = id
+			}
+		}
+		delete(c.entries, oldest)
+	}
+
+	if c.readOnly {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"sptsong/internal/artistinfo"
+	"sptsong/internal/webapi"
+)
+
+func TestTrackCacheUpdateKeepsOtherFields(t *testing.T) {
+	c := newTrackCache(filepath.Join(t.TempDir(), "tracks.json"), time.Hour, newFakeClock())
+
+	// The album lookup and the artwork goroutine finish together.
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Update("track", fieldAlbum, func(info *TrackInfo) { info.Album = &webapi.AlbumPosition{Track: i} })
+		}()
+		go func() {
+			defer wg.Done()
+			c.Update("track", fieldAccent, func(info *TrackInfo) { info.Accent = "#336699" })
+		}()
+	}
+	wg.Wait()
+
+	info, ok := c.Get("track")
+	if !ok || info.Album == nil || info.Accent != "#336699" {
+		t.Errorf("entry = %+v, %v; want both the album and the accent", info, ok)
+	}
+}
+
+func TestTrackCacheFieldsExpireSeparately(t *testing.T) {
+	clock := newFakeClock()
+	c := newTrackCache(filepath.Join(t.TempDir(), "tracks.json"), time.Hour, clock)
+	c.Update("track", fieldAccent, func(info *TrackInfo) { info.Accent = "#000000" })
+
+	// Refreshing one field doesn't keep the others alive.
+	clock.Advance(50 * time.Minute)
+	c.Update("track", fieldLookupArtURL, func(info *TrackInfo) { info.LookupArtURL = "https://example.com/cover.jpg" })
+	clock.Advance(20 * time.Minute)
+	info, ok := c.Get("track")
+	if !ok || info.Accent != "" || info.LookupArtURL == "" {
+		t.Errorf("entry = %+v, %v; want the lookup without the expired accent", info, ok)
+	}
+
+	c.Update("track", fieldArtPath, func(info *TrackInfo) {
+		if info.Accent != "" {
+			t.Errorf("expired field passed to change: %+v", *info)
+		}
+		info.ArtPath = "/tmp/cover.jpg"
+	})
+
+	clock.Advance(2 * time.Hour)
+	if info, ok := c.Get("track"); ok {
+		t.Errorf("entry = %+v after every field expired", info)
+	}
+}
+
+func TestTrackCacheSavesToDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tracks.json")
+	clock := newFakeClock()
+	liked := true
+	want := TrackInfo{
+		Accent:       "#336699",
+		LookupArtURL: "https://example.com/cover.jpg",
+		ArtPath:      "/tmp/covers/abc.jpg",
+		Album:        &webapi.AlbumPosition{Track: 3, Tracks: 10},
+		Chapters:     []webapi.Chapter{{Start: 90 * time.Second, Title: "Intro"}},
+		Features:     &webapi.AudioFeatures{Tempo: 120, Key: 2, Mode: 1},
+		Liked:        &liked,
+		Artist:       &artistinfo.Info{Name: "Slowdive", Genres: []string{"shoegaze"}, Source: "musicbrainz"},
+	}
+	c := newTrackCache(path, time.Hour, clock)
+	for _, field := range []string{fieldAccent, fieldLookupArtURL, fieldArtPath, fieldAlbum, fieldChapters, fieldFeatures, fieldLiked, fieldArtist} {
+		if err := c.Update("track", field, func(info *TrackInfo) {
+			fetched := info.Fetched
+			*info = want
+			info.Fetched = fetched
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, ok := newTrackCache(path, time.Hour, clock).Get("track")
+	if !ok {
+		t.Fatal("entry not loaded back")
+	}
+	if len(got.Fetched) != 8 {
+		t.Errorf("fetched times = %v, want one per field", got.Fetched)
+	}
+	got.Fetched = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// upNextRefresh is how often the queue is read again for players that don't
+// signal changes to it, MPD among them.
+const upNextRefresh = 10 * time.Second
+
+// upNext is the player's queue after the current track, as last read.
+type upNext struct {
+	tracks    []mpris.Metadata
+	trackID   string
+	fetchedAt time.Time
+	// stale is set by the TrackList signals; unsupported stops asking
+	// players that have no queue to share.
+	stale       bool
+	unsupported bool
+}
+
+// updateUpNext reads the queue for the rows free under the progress bar,
+// when the track changed, the player said the queue did, or it is due.
+func (sd *SpotifyDisplay) updateUpNext(trackID string, term TerminalSize) {
+	q := &sd.queue
+	rows := term.layout.SpareRows() - 1
+	if !sd.UpNext || q.unsupported || rows < 1 {
+		return
+	}
+	lister, ok := sd.player.(mpris.TrackLister)
+	if !ok {
+		q.unsupported = true
+		return
+	}
+	now := sd.clock.Now()
+	if trackID == q.trackID && !q.stale && now.Sub(q.fetchedAt) < upNextRefresh {
+		return
+	}
+	tracks, err := lister.UpNext(rows)
+	if errors.Is(err, mpris.ErrNoTrackList) {
+		// Hide the panel, and the queue it may have shown before the
+		// player stopped sharing it.
+		q.unsupported = true
+		if len(q.tracks) > 0 {
+			q.tracks = nil
+			sd.invalidate()
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+	for i := range tracks {
+		cleanMetadata(&tracks[i])
+	}
+	q.tracks, q.trackID, q.fetchedAt, q.stale = tracks, trackID, now, false
+}
+
+// drawUpNext lists the queued tracks under the progress bar.
+func (sd *SpotifyDisplay) drawUpNext(metadata *mpris.Metadata, term TerminalSize) {
+	q := &sd.queue
+	rows, top := term.layout.SpareRows(), term.layout.Text.Y+ui.TextHeight
+	live := sd.liveRowsUnderBar(term)
+	rows, top = rows-live, top+live
+	if !sd.UpNext || q.unsupported || rows < 2 {
+		return
+	}
+	text := term.layout.Text
+	theme := sd.theme()
+	for row := range rows {
+		line := ""
+		switch {
+		case q.trackID != metadata.TrackID:
+		case row == 0 && len(q.tracks) > 0:
+			line = theme.Accent.Render("Up next")
+		case row > 0 && row <= len(q.tracks):
+			t := q.tracks[row-1]
+			line = theme.Artist.Render(sd.visual(ui.Truncate("▸ "+t.Title+" – "+t.Artist, text.Width)))
+		}
+		y := top + row
+		fmt.Fprint(sd.out, ui.MoveTo(text.X, y)+strings.Repeat(" ", text.Width)+ui.MoveTo(text.X, y)+line)
+	}
+}
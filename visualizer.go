@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sptsong/internal/spectrum"
+	"sptsong/internal/ui"
+)
+
+// startVisualizer starts the source of the visualizer row, if one is
+// configured. It returns nil without one, or when it can't start, which
+// the notice line then explains.
+func (sd *SpotifyDisplay) startVisualizer(ctx context.Context) <-chan []float64 {
+	if sd.Visualizer == "" || sd.Visualizer == "off" || sd.companion || sd.ReduceMotion {
+		return nil
+	}
+	levels, err := spectrum.Start(ctx, sd.Visualizer, ui.TextWidth)
+	if err != nil {
+		sd.showNotice("visualizer: " + err.Error())
+		return nil
+	}
+	sd.visualizing = true
+	return levels
+}
+
+// startMeter starts measuring the player's stream for the level meter, if
+// it is on. The channel closes when there is no sound server to ask.
+func (sd *SpotifyDisplay) startMeter(ctx context.Context) <-chan spectrum.Levels {
+	if !sd.LevelMeter || sd.companion || sd.ReduceMotion {
+		return nil
+	}
+	app := "spotify"
+	if sd.Backend == "mpd" {
+		app = "mpd"
+	}
+	sd.metering = true
+	return spectrum.Meter(ctx, app)
+}
+
+// liveRows are the rows drawn between the display's regular redraws, each
+// as the function placing it: the visualizer and the level meter.
+func (sd *SpotifyDisplay) liveRows() []func(x, y, width int) {
+	var rows []func(x, y, width int)
+	if sd.visualizing {
+		rows = append(rows, func(x, y, width int) { sd.vizRow = liveRow(x, y, width) })
+	}
+	if sd.metering {
+		rows = append(rows, func(x, y, width int) { sd.meterRow = liveRow(x, y, width) })
+	}
+	return rows
+}
+
+// liveRowsUnderBar is how many live rows go in the spare rows under the
+// progress bar, where the artwork beside the text leaves some. The others
+// are drawn as panels next to the frame.
+func (sd *SpotifyDisplay) liveRowsUnderBar(term TerminalSize) int {
+	return min(len(sd.liveRows()), term.layout.SpareRows())
+}
+
+func liveRow(x, y, width int) ui.Rect {
+	return ui.Rect{X: x, Y: y, Width: min(width, ui.TextWidth), Height: 1}
+}
+
+// drawVisualizer draws a frame of levels on the visualizer row. With reduced
+// motion, turned on by a reload after the visualizer started, it stays blank.
+func (sd *SpotifyDisplay) drawVisualizer(levels []float64) {
+	row := sd.vizRow
+	if row.Width == 0 || sd.ReduceMotion {
+		return
+	}
+	levels = levels[:min(len(levels), row.Width)]
+	fmt.Fprint(sd.out, ui.MoveTo(row.X, row.Y)+sd.theme().Accent.Render(spectrum.Bars(levels)))
+}
+
+// drawMeter draws the left and right levels side by side on the meter row,
+// each bar notched where its peak is. Like the visualizer it stays blank
+// with reduced motion.
+func (sd *SpotifyDisplay) drawMeter(levels spectrum.Levels) {
+	row := sd.meterRow
+	if row.Width == 0 || sd.ReduceMotion {
+		return
+	}
+	theme := sd.theme()
+	half := (row.Width - 5) / 2
+	bar := func(level, peak float64) string {
+		return ui.Bar(theme, true, sd.Progress.Gradient, level, half, peak)
+	}
+	line := theme.Time.Render("L ") + bar(levels.Left, levels.LeftPeak) +
+		theme.Time.Render(" R ") + bar(levels.Right, levels.RightPeak)
+	fmt.Fprint(sd.out, ui.MoveTo(row.X, row.Y)+line+strings.Repeat(" ", row.Width-5-2*half))
+}
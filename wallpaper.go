@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"sptsong/internal/config"
+	"sptsong/internal/mpris"
+	"sptsong/internal/ui"
+)
+
+// runWallpaper keeps the desktop wallpaper showing a "Now Playing" card,
+// re-rendering it whenever the track changes.
+func runWallpaper(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("wallpaper", flag.ContinueOnError)
+	flags.IntVar(&cfg.Wallpaper.Width, "width", cfg.Wallpaper.Width, "wallpaper width in pixels")
+	flags.IntVar(&cfg.Wallpaper.Height, "height", cfg.Wallpaper.Height, "wallpaper height in pixels")
+	flags.StringVar(&cfg.Wallpaper.Output, "output", cfg.Wallpaper.Output, "where to write the PNG")
+	flags.StringVar(&cfg.Wallpaper.Command, "command", cfg.Wallpaper.Command, "command that sets the wallpaper, {} is replaced by the PNG path")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	display, err := NewSpotifyDisplay(cfg)
+	if err != nil {
+		return err
+	}
+
+	output := cfg.Wallpaper.Output
+	if output == "" {
+		output = filepath.Join(display.cacheDir, "wallpaper.png")
+	}
+	setter := &wallpaperSetter{command: cfg.Wallpaper.Command}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	ticker := display.clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var current string
+	for {
+		select {
+		case <-sigChan:
+			return nil
+		case <-ticker.C():
+		}
+
+		metadata, err := display.player.Metadata()
+		if err != nil || metadata.TrackID == current || !display.trackSettle.update(metadata.TrackID, display.clock.Now()) {
+			continue
+		}
+		current = metadata.TrackID
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		imagePath, _ := display.artworkPath(ctx, display.newArtJob(metadata, ui.DefaultFontRatio))
+		cancel()
+
+		accent := ""
+		if imagePath != "" {
+			accent = display.trackAccent(metadata.TrackID, imagePath)
+		}
+		card, err := renderWallpaper(metadata, imagePath, accent, cfg.Wallpaper.Width, cfg.Wallpaper.Height)
+		if err != nil {
+			return err
+		}
+		if err := writePNG(output, card); err != nil {
+			return err
+		}
+		if err := setter.set(output); err != nil {
+			return err
+		}
+	}
+}
+
+func hexToColor(hex string, fallback color.RGBA) color.RGBA {
+	r, g, b, ok := ui.ParseHexColor(hex)
+	if !ok {
+		return fallback
+	}
+	return color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}
+}
+
+func newFace(ttf []byte, size float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// drawText writes s with its baseline at (x, y), cut with an ellipsis to fit
+// maxWidth pixels.
+func drawText(dst draw.Image, face font.Face, c color.Color, x, y, maxWidth int, s string) {
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	runes := []rune(s)
+	for len(runes) > 1 && d.MeasureString(string(runes)).Ceil() > maxWidth {
+		runes = append(runes[:len(runes)-2], '…')
+	}
+	d.Dot = fixed.P(x, y)
+	d.DrawString(string(runes))
+}
+
+// renderWallpaper composites the cover, title, artist and progress onto a
+// width x height card tinted with the album's accent color.
+func renderWallpaper(metadata *mpris.Metadata, imagePath, accent string, width, height int) (image.Image, error) {
+	accentColor := hexToColor(accent, color.RGBA{0x1d, 0xb9, 0x54, 0xff})
+	background := hexToColor(ui.BlendHex(accent, "#000000", 0.85), color.RGBA{0x12, 0x12, 0x12, 0xff})
+	white := color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+	gray := color.RGBA{0xa0, 0xa0, 0xa0, 0xff}
+
+	card := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(card, card.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	artSize := height / 2
+	artX, artY := width/8, (height-artSize)/2
+	if imagePath != "" {
+		if file, err := os.Open(imagePath); err == nil {
+			cover, _, err := image.Decode(file)
+			file.Close()
+			if err == nil {
+				draw.CatmullRom.Scale(card, image.Rect(artX, artY, artX+artSize, artY+artSize), cover, cover.Bounds(), draw.Over, nil)
+			}
+		}
+	}
+
+	titleFace, err := newFace(gobold.TTF, float64(height)/18)
+	if err != nil {
+		return nil, err
+	}
+	textFace, err := newFace(goregular.TTF, float64(height)/30)
+	if err != nil {
+		return nil, err
+	}
+
+	textX := artX + artSize + width/20
+	textWidth := width - textX - width/8
+	centerY := height / 2
+	drawText(card, titleFace, white, textX, centerY-height/20, textWidth, metadata.Title)
+	drawText(card, textFace, gray, textX, centerY+height/40, textWidth, metadata.Artist)
+
+	barY := centerY + height/12
+	barHeight := max(height/180, 2)
+	draw.Draw(card, image.Rect(textX, barY, textX+textWidth, barY+barHeight), image.NewUniform(gray), image.Point{}, draw.Src)
+	filled := int(progressFraction(metadata) * float64(textWidth))
+	draw.Draw(card, image.Rect(textX, barY, textX+filled, barY+barHeight), image.NewUniform(accentColor), image.Point{}, draw.Src)
+	timeText := ui.FormatDuration(metadata.Position) + " / " + ui.FormatDuration(metadata.Length)
+	drawText(card, textFace, gray, textX, barY+barHeight+height/25, textWidth, timeText)
+
+	return card, nil
+}
+
+// writePNG writes the image atomically so a wallpaper setter never reads a
+// half-written file.
+func writePNG(path string, img image.Image) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wallpaper-*.png")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := png.Encode(tmp, img); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// wallpaperSetter applies the wallpaper with a user command, swaybg on
+// Wayland, or feh on X11.
+type wallpaperSetter struct {
+	command string
+	swaybg  *exec.Cmd
+}
+
+func (w *wallpaperSetter) set(path string) error {
+	if w.command != "" {
+		quoted := "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+		return exec.Command("sh", "-c", strings.ReplaceAll(w.command, "{}", quoted)).Run()
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("swaybg"); err == nil {
+			// swaybg keeps running to hold the wallpaper; start the new one
+			// before stopping the old so the desktop never flashes empty.
+			cmd := exec.Command("swaybg", "-m", "fill", "-i", path)
+			if err := cmd.Start(); err != nil {
+				return err
+			}
+			if w.swaybg != nil {
+				w.swaybg.Process.Kill()
+				w.swaybg.Wait()
+			}
+			w.swaybg = cmd
+			return nil
+		}
+	}
+
+	if _, err := exec.LookPath("feh"); err == nil {
+		return exec.Command("feh", "--bg-fill", path).Run()
+	}
+	return errors.New("no wallpaper setter found: install swaybg or feh, or set wallpaper.command")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"time"
+
+	"sptsong/internal/mpris"
+)
+
+// watchdog notices when the player claims to be Playing but Position has not
+// moved for a while, a known Spotify client hiccup.
+type watchdog struct {
+	trackID     string
+	position    int64
+	lastAdvance time.Time
+	nudged      bool
+}
+
+// stuck feeds the latest metadata to the watchdog and reports whether the
+// position has been frozen during playback for longer than timeout.
+func (w *watchdog) stuck(metadata *mpris.Metadata, timeout time.Duration, now time.Time) bool {
+	if metadata.Status != "Playing" || metadata.TrackID != w.trackID || metadata.Position != w.position {
+		w.trackID = metadata.TrackID
+		w.position = metadata.Position
+		w.lastAdvance = now
+		w.nudged = false
+		return false
+	}
+	return now.Sub(w.lastAdvance) > timeout
+}
+
+// nudgePlayer sends Pause followed by Play, which is usually enough to get a
+// stuck Spotify client going again.
+func (sd *SpotifyDisplay) nudgePlayer() error {
+	if err := sd.player.Call("Pause"); err != nil {
+		return err
+	}
+	return sd.player.Call("Play")
+}